@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// Proving backends. Groth16 proves an R1CS with a circuit-specific setup;
+// PLONK proves a sparse constraint system (SCS) over a universal KZG SRS.
+const (
+	backendGroth16 = "groth16"
+	backendPLONK   = "plonk"
+
+	defaultBackend = backendGroth16
+)
+
+var (
+	// command line flags
+	provingBackend string
+)
+
+// artifact is a key or proof of either backend; both serialize the same way
+type artifact interface {
+	io.WriterTo
+	io.ReaderFrom
+}
+
+func validateBackend(name string) error {
+	switch name {
+	case backendGroth16, backendPLONK:
+		return nil
+	default:
+		return fmt.Errorf("unknown backend %q (use %s or %s)", name, backendGroth16, backendPLONK)
+	}
+}
+
+// circuitBuilder returns the frontend builder for the selected backend, with
+// the range checks selected by -range-check
+func circuitBuilder() frontend.NewBuilder {
+	if provingBackend == backendPLONK {
+		return withRangeCheck(scs.NewBuilder)
+	}
+	return withRangeCheck(r1cs.NewBuilder)
+}
+
+// circuitFileName is the compiled circuit in the output directory
+func circuitFileName() string {
+	if provingBackend == backendPLONK {
+		return "circuit.scs"
+	}
+	return "circuit.r1cs"
+}
+
+// proofFileName is the proof for a test case, e.g. proof_1.groth16
+func proofFileName(testCaseNum string) string {
+	return "proof_" + testCaseNum + "." + provingBackend
+}
+
+func newConstraintSystem() constraint.ConstraintSystem {
+	if provingBackend == backendPLONK {
+		return plonk.NewCS(selectedCurve())
+	}
+	return groth16.NewCS(selectedCurve())
+}
+
+func newProvingKey() artifact {
+	if provingBackend == backendPLONK {
+		return plonk.NewProvingKey(selectedCurve())
+	}
+	return groth16.NewProvingKey(selectedCurve())
+}
+
+func newVerifyingKey() artifact {
+	if provingBackend == backendPLONK {
+		return plonk.NewVerifyingKey(selectedCurve())
+	}
+	return groth16.NewVerifyingKey(selectedCurve())
+}
+
+func newProof() artifact {
+	if provingBackend == backendPLONK {
+		return plonk.NewProof(selectedCurve())
+	}
+	return groth16.NewProof(selectedCurve())
+}
+
+// setupKeys runs the setup for the selected backend. PLONK uses the KZG SRS
+// selected with -srs.
+func setupKeys(ccs constraint.ConstraintSystem) (artifact, artifact, error) {
+	if provingBackend == backendPLONK {
+		srs, srsLagrange, err := newKZGSRS(ccs)
+		if err != nil {
+			return nil, nil, err
+		}
+		return plonk.Setup(ccs, srs, srsLagrange)
+	}
+	if srsMode != defaultSRS {
+		slog.Warn("-srs only applies to plonk, ignoring it")
+	}
+	return groth16.Setup(ccs)
+}
+
+// proveCircuit generates a proof with the selected backend. The returned
+// string names the hardware that produced it ("gpu" or "cpu").
+func proveCircuit(ccs constraint.ConstraintSystem, pk artifact, fullWitness witness.Witness) (artifact, string, error) {
+	proof, hardware, _, err := proveCircuitTimed(ccs, pk, fullWitness)
+	return proof, hardware, err
+}
+
+// proveCircuitTimed is proveCircuit for the commands that record the prove
+// time: the duration it returns is that of a failed GPU attempt before the
+// CPU fallback, to take off the time measured around it
+func proveCircuitTimed(ccs constraint.ConstraintSystem, pk artifact, fullWitness witness.Witness) (artifact, string, time.Duration, error) {
+	if provingBackend == backendPLONK {
+		proof, err := plonk.Prove(ccs, pk.(plonk.ProvingKey), fullWitness, proverOptions()...)
+		return proof, "cpu", 0, err
+	}
+	proof, hardware, failed, err := proveGroth16(ccs, pk.(groth16.ProvingKey), fullWitness, useGPU)
+	return proof, hardware, failed, err
+}
+
+func verifyCircuit(proof, vk artifact, publicWitness witness.Witness) error {
+	if provingBackend == backendPLONK {
+		return plonk.Verify(proof.(plonk.Proof), vk.(plonk.VerifyingKey), publicWitness, verifierOptions()...)
+	}
+	return groth16.Verify(proof.(groth16.Proof), vk.(groth16.VerifyingKey), publicWitness, verifierOptions()...)
+}
+
+// requireGroth16 stops commands that only exist for the Groth16 backend
+func requireGroth16(command string) {
+	if provingBackend != backendGroth16 {
+		fatal("Only supported with the groth16 backend", "command", command, "dir", outputDir, "backend", provingBackend)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+var (
+	// command line flags
+	batchSizes string
+)
+
+// BatchSize is how the batched circuit performs at one batch size. The per
+// signature values divide the batch's by its size.
+type BatchSize struct {
+	Size                    int        `json:"size"`
+	Constraints             int        `json:"constraints"`
+	ConstraintsPerSignature float64    `json:"constraints_per_signature"`
+	CompileSecs             float64    `json:"compile_secs"`
+	SetupSecs               float64    `json:"setup_secs"`
+	SetupPeakHeapBytes      uint64     `json:"setup_peak_heap_bytes"`
+	Prove                   PhaseStats `json:"prove"`
+	ProveSecsPerSignature   float64    `json:"prove_secs_per_signature"`
+	ProvePeakHeapBytes      uint64     `json:"prove_peak_heap_bytes"`
+	Verify                  PhaseStats `json:"verify"`
+	VerifySecsPerSignature  float64    `json:"verify_secs_per_signature"`
+	ProofBytes              int64      `json:"proof_bytes"`
+}
+
+// BatchResults is the output of the batch command
+type BatchResults struct {
+	Curve       string      `json:"curve"`
+	Backend     string      `json:"backend"`
+	RangeCheck  string      `json:"range_check"`
+	Runs        int         `json:"runs"`
+	Environment Environment `json:"environment"`
+	Sizes       []BatchSize `json:"sizes"`
+}
+
+// runBatchSweep measures the batched circuit at each of -batch-sizes: it
+// compiles and sets up the circuit for that many signatures, then proves and
+// verifies a batch -runs times. The batch is filled with the test cases in
+// turn. Constraints, proving time and peak heap are reported in total and per
+// signature, to show how the fixed cost of a proof amortizes. Nothing is
+// written to the output directory but benchmarks/batch.json, since the keys of
+// large batches take gigabytes.
+func runBatchSweep(testCaseFiles []string) {
+	defaultSettings()
+	sizes, err := parseBatchSizes(batchSizes)
+	if err != nil {
+		fatal("Invalid -batch-sizes", "error", err)
+	}
+	if len(testCaseFiles) == 0 {
+		testCaseFiles, err = filepath.Glob(filepath.Join(testsDir, circuitVariants["p256"].TestCases))
+		if err != nil {
+			fatal("Failed to find test cases", "dir", testsDir, "error", err)
+		}
+		if len(testCaseFiles) == 0 {
+			fatal("No test cases found", "dir", testsDir, "pattern", circuitVariants["p256"].TestCases)
+		}
+	}
+	var assignments []ECDSACircuit
+	for _, testCaseFile := range testCaseFiles {
+		testCase, err := loadTestCase(testCaseFile)
+		if err != nil {
+			fatal("Failed to load test case", "file", testCaseFile, "error", err)
+		}
+		assignment, err := createAssignment(testCase)
+		if err != nil {
+			fatal("Failed to create witness", "test_case", testCaseID(testCaseFile), "error", err)
+		}
+		assignments = append(assignments, *assignment)
+	}
+
+	results := BatchResults{
+		Curve:       curveName,
+		Backend:     provingBackend,
+		RangeCheck:  rangeCheck,
+		Runs:        benchRuns,
+		Environment: currentEnvironment(),
+	}
+	for _, size := range sizes {
+		slog.Info("Benchmarking batch", "signatures", size, "backend", provingBackend, "curve", curveName, "range_check", rangeCheck)
+		result := BatchSize{Size: size}
+
+		circuit := BatchECDSACircuit{Signatures: make([]ECDSACircuit, size)}
+		start := time.Now()
+		ccs, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), &circuit)
+		result.CompileSecs = time.Since(start).Seconds()
+		if err != nil {
+			fatal("Circuit compilation failed", "signatures", size, "error", err)
+		}
+		result.Constraints = ccs.GetNbConstraints()
+		slog.Info("Compiled", "signatures", size, "constraints", result.Constraints, "secs", result.CompileSecs)
+
+		stopAllocs := trackAllocs()
+		start = time.Now()
+		pk, vk, err := setupKeys(ccs)
+		result.SetupSecs = time.Since(start).Seconds()
+		result.SetupPeakHeapBytes = stopAllocs().PeakHeapBytes
+		if err != nil {
+			fatal("Setup failed", "signatures", size, "error", err)
+		}
+		slog.Info("Set up", "signatures", size, "secs", result.SetupSecs)
+
+		assignment := BatchECDSACircuit{Signatures: make([]ECDSACircuit, size)}
+		for i := range assignment.Signatures {
+			assignment.Signatures[i] = assignments[i%len(assignments)]
+		}
+		witness, err := frontend.NewWitness(&assignment, selectedCurve().ScalarField())
+		if err != nil {
+			fatal("Failed to create witness", "signatures", size, "error", err)
+		}
+		publicWitness, err := witness.Public()
+		if err != nil {
+			fatal("Failed to create public witness", "signatures", size, "error", err)
+		}
+
+		var proveTimes, verifyTimes []time.Duration
+		for run := 1; run <= benchRuns; run++ {
+			// As in bench, memory is sampled on the first run only
+			var stopAllocs func() AllocStats
+			if run == 1 {
+				stopAllocs = trackAllocs()
+			}
+			start := time.Now()
+			proof, _, err := proveCircuit(ccs, pk, witness)
+			proveTimes = append(proveTimes, time.Since(start))
+			if err != nil {
+				fatal("Failed to generate proof", "signatures", size, "run", run, "error", err)
+			}
+			if stopAllocs != nil {
+				result.ProvePeakHeapBytes = stopAllocs().PeakHeapBytes
+				result.ProofBytes = proofSize(proof)
+			}
+
+			start = time.Now()
+			err = verifyCircuit(proof, vk, publicWitness)
+			verifyTimes = append(verifyTimes, time.Since(start))
+			if err != nil {
+				fatal("Proof verification failed", "signatures", size, "run", run, "error", err)
+			}
+			slog.Info("Proved and verified", "signatures", size, "run", run, "prove_secs", proveTimes[run-1].Seconds(), "verify_secs", verifyTimes[run-1].Seconds())
+		}
+		result.Prove = benchSummarize("prove", "", proveTimes)
+		result.Verify = benchSummarize("verify", "", verifyTimes)
+		result.ConstraintsPerSignature = float64(result.Constraints) / float64(size)
+		result.ProveSecsPerSignature = result.Prove.MeanSecs / float64(size)
+		result.VerifySecsPerSignature = result.Verify.MeanSecs / float64(size)
+		results.Sizes = append(results.Sizes, result)
+	}
+
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		fatal("Failed to create results directory", "dir", resultsDir, "error", err)
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		fatal("Failed to encode batch results", "error", err)
+	}
+	path := filepath.Join(resultsDir, "batch.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fatal("Failed to write batch results", "file", path, "error", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("%6s %12s %10s %10s %10s %10s %10s %10s %10s\n", "Size", "Constraints", "Per sig", "Setup", "Prove", "Per sig", "Peak heap", "Verify", "Speedup")
+	for _, r := range results.Sizes {
+		// How much cheaper a signature is than in a batch of the smallest size
+		speedup := results.Sizes[0].ProveSecsPerSignature / r.ProveSecsPerSignature
+		fmt.Printf("%6d %12d %10.0f %10s %10s %10s %10s %10s %9.2fx\n", r.Size, r.Constraints, r.ConstraintsPerSignature,
+			formatSecs(r.SetupSecs), formatSecs(r.Prove.MeanSecs), formatSecs(r.ProveSecsPerSignature),
+			formatBytes(r.ProvePeakHeapBytes), formatSecs(r.Verify.MeanSecs), speedup)
+	}
+	slog.Info("Batch results saved", "file", path)
+}
+
+// parseBatchSizes reads a comma-separated list of batch sizes
+func parseBatchSizes(value string) ([]int, error) {
+	var sizes []int
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q is not a batch size", field)
+		}
+		if !seen[n] {
+			seen[n] = true
+			sizes = append(sizes, n)
+		}
+	}
+	sort.Ints(sizes)
+	return sizes, nil
+}
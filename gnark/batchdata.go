@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/frontend"
+)
+
+var (
+	// command line flags
+	genBatch     int
+	batchKeys    int
+	batchInvalid int
+)
+
+// BatchTestCase is a test case for the batched circuit: its signatures in the
+// order of BatchECDSACircuit.Signatures, each a test case of its own
+type BatchTestCase struct {
+	Signatures []*TestCase `json:"signatures"`
+
+	// ExpectValid is false when any of the signatures is invalid, since one
+	// invalid signature must fail the whole batch
+	ExpectValid *bool  `json:"expect_valid,omitempty"`
+	Source      string `json:"source,omitempty"`
+}
+
+// generateBatchTestData writes -count batch test cases of -batch signatures
+// each into -tests, as batch_test_case_N.json, replacing those already there.
+// The signatures are made as gen-testdata makes them, with the same -message
+// and -seed, by a fresh key each, or by -batch-keys keys in turn so that a
+// batch mixes signers. With -batch-invalid, that many signatures of each
+// batch, at random positions, get a corrupted s and expect_valid false, for
+// negative testing of batches where only some signatures are bad.
+func generateBatchTestData() {
+	if genCount < 1 {
+		fatal("-count must be at least 1")
+	}
+	if batchInvalid < 0 || batchInvalid > genBatch {
+		fatal("-batch-invalid must be between 0 and -batch", "batch", genBatch)
+	}
+	if batchKeys < 0 {
+		fatal("-batch-keys must not be negative")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	stale, err := filepath.Glob(filepath.Join(testsDir, "batch_test_case_*.json"))
+	if err != nil {
+		fatal("Failed to find test cases", "error", err)
+	}
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil {
+			fatal("Failed to remove old test case", "error", err)
+		}
+	}
+
+	var random io.Reader = rand.Reader
+	if genSeed != "" {
+		random = &seededReader{key: sha256.Sum256([]byte("gen-testdata batch " + genSeed))}
+	}
+	newKey := func() *ecdsa.PrivateKey {
+		if genSeed != "" {
+			return seededKey(random)
+		}
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			fatal("Failed to generate key", "error", err)
+		}
+		return key
+	}
+
+	slog.Info("Generating batches of P-256 signatures", "count", genCount, "batch", genBatch, "dir", testsDir)
+	for i := 1; i <= genCount; i++ {
+		var keys []*ecdsa.PrivateKey
+		for k := 0; k < batchKeys; k++ {
+			keys = append(keys, newKey())
+		}
+		invalid := map[int]bool{}
+		for len(invalid) < batchInvalid {
+			var buf [8]byte
+			if _, err := io.ReadFull(random, buf[:]); err != nil {
+				fatal("Failed to pick invalid signatures", "error", err)
+			}
+			invalid[int(binary.BigEndian.Uint64(buf[:])%uint64(genBatch))] = true
+		}
+
+		batch := &BatchTestCase{}
+		for j := 0; j < genBatch; j++ {
+			message := []byte(genMessage)
+			if genMessage == "" {
+				message = make([]byte, 32)
+				if _, err := io.ReadFull(random, message); err != nil {
+					fatal("Failed to generate message", "error", err)
+				}
+			}
+			hash := sha256.Sum256(message)
+
+			var key *ecdsa.PrivateKey
+			if len(keys) > 0 {
+				key = keys[j%len(keys)]
+			} else {
+				key = newKey()
+			}
+			var testCase *TestCase
+			if genSeed == "" {
+				r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+				if err != nil {
+					fatal("Failed to sign", "error", err)
+				}
+				testCase = newTestCase(&key.PublicKey, hash[:], r, s)
+				testCase.Nonce = nonceRandom
+			} else {
+				r, s := signRFC6979(key, hash[:])
+				testCase = newTestCase(&key.PublicKey, hash[:], r, s)
+				testCase.Nonce = nonceRFC6979
+			}
+			if invalid[j] {
+				// s + 1 stays in range but no longer verifies
+				s, _ := parseHexField(testCase.S)
+				s.Add(s, big.NewInt(1)).Mod(s, elliptic.P256().Params().N)
+				testCase.S = fmt.Sprintf("0x%x", s)
+				valid := false
+				testCase.ExpectValid = &valid
+			}
+			batch.Signatures = append(batch.Signatures, testCase)
+		}
+		if batchInvalid > 0 {
+			valid := false
+			batch.ExpectValid = &valid
+		}
+		if genSeed != "" {
+			batch.Source = fmt.Sprintf("gen-testdata -batch %d -seed %q, batch %d", genBatch, genSeed, i)
+		}
+
+		data, err := json.MarshalIndent(batch, "", "  ")
+		if err != nil {
+			fatal("Failed to encode test case", "error", err)
+		}
+		if err := os.WriteFile(filepath.Join(testsDir, fmt.Sprintf("batch_test_case_%d.json", i)), data, 0644); err != nil {
+			fatal("Failed to write test case", "error", err)
+		}
+	}
+	slog.Info("Test cases written", "first", "batch_test_case_1.json", "last", fmt.Sprintf("batch_test_case_%d.json", genCount))
+}
+
+// isBatchTestCase says whether a test case file holds a batch
+func isBatchTestCase(data []byte) bool {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false
+	}
+	_, ok := probe["signatures"]
+	return ok
+}
+
+// loadBatchTestCase reads a batch test case, and validates each of its
+// signatures as a test case
+func loadBatchTestCase(filename string) (*BatchTestCase, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Signatures  []json.RawMessage `json:"signatures"`
+		ExpectValid *bool             `json:"expect_valid"`
+		Source      string            `json:"source"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if len(raw.Signatures) == 0 {
+		return nil, fmt.Errorf("%s: field \"signatures\": missing or empty", filename)
+	}
+	batch := &BatchTestCase{ExpectValid: raw.ExpectValid, Source: raw.Source}
+	for i, signature := range raw.Signatures {
+		testCase, err := parseTestCase(signature)
+		if err != nil {
+			return nil, fmt.Errorf("%s: signature %d: %v", filename, i, err)
+		}
+		batch.Signatures = append(batch.Signatures, testCase)
+	}
+	return batch, nil
+}
+
+// expectation is whether the batch should produce a verifiable proof: only
+// when every signature should, or either way when some may go either way
+func (b *BatchTestCase) expectation() (valid, either bool) {
+	if b.ExpectValid != nil {
+		return *b.ExpectValid, false
+	}
+	valid = true
+	for _, testCase := range b.Signatures {
+		v, e := testCase.expectation()
+		if !v && !e {
+			return false, false
+		}
+		either = either || e
+	}
+	return valid, either
+}
+
+func createBatchWitness(batch *BatchTestCase) (witness.Witness, error) {
+	assignment := BatchECDSACircuit{Signatures: make([]ECDSACircuit, len(batch.Signatures))}
+	for i, testCase := range batch.Signatures {
+		signature, err := createAssignment(testCase)
+		if err != nil {
+			return nil, fmt.Errorf("signature %d: %v", i, err)
+		}
+		assignment.Signatures[i] = *signature
+	}
+	return frontend.NewWitness(&assignment, selectedCurve().ScalarField())
+}
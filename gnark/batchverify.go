@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr/hash_to_field"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/constraint"
+	"gnark-ecdsa-benchmark/internal/gnarkcompat"
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// batchFile holds the proofs bundled by the batch-verify command
+const batchFile = "batch.groth16"
+
+var (
+	// batch-verify command flags
+	testsDir string
+)
+
+// ProofBatch bundles Groth16 proofs made with the same verifying key, together
+// with their public inputs, so they can be verified in one go.
+//
+// This is batch verification, not aggregation: the bundle holds every proof,
+// so it grows linearly with their number. The proofs are folded with powers
+// of a Fiat-Shamir challenge r into a single pairing product,
+//
+//	Π e(r^i·A_i, B_i) = e(α, β)^Σr^i · e(Σ r^i·L_i, γ) · e(Σ r^i·C_i, δ)
+//
+// which costs n+3 pairings instead of 4n. An aggregation scheme such as
+// SnarkPack would also compress the A_i/B_i into a logarithmic size proof
+// with inner-product arguments, which gnark does not implement.
+type ProofBatch struct {
+	Proofs  []*groth16_bn254.Proof
+	Publics []fr.Vector
+}
+
+// BatchVerifyStats compares verifying the proofs one by one with verifying
+// them as a batch
+type BatchVerifyStats struct {
+	Proofs                   int     `json:"proofs"`
+	BatchBytes               int64   `json:"batch_bytes"`
+	BundleTimeSecs           float64 `json:"bundle_time_secs"`
+	IndividualVerifyTimeSecs float64 `json:"individual_verify_time_secs"`
+	BatchVerifyTimeSecs      float64 `json:"batch_verify_time_secs"`
+}
+
+// batchVerifyProofs bundles every Groth16 proof in proofDir into a batch,
+// then benchmarks its verification against verifying the proofs individually.
+// Public inputs come from the test cases in -tests the proofs are named
+// after, by testCaseID, so the proofs of any test case and circuit variant
+// are taken.
+func batchVerifyProofs(proofDir string) {
+	resolveSettings()
+	requireGroth16("Batch verification")
+	if selectedCurve() != ecc.BN254 {
+		fatal("Batch verification is only supported on bn254", "dir", outputDir, "curve", curveName)
+	}
+
+	vk := new(groth16_bn254.VerifyingKey)
+	loadArtifact(filepath.Join(outputDir, "verifying.key"), vk)
+
+	testCaseFiles, err := filepath.Glob(filepath.Join(testsDir, "*.json"))
+	if err != nil {
+		fatal("Failed to find test cases", "dir", testsDir, "error", err)
+	}
+	var matched, matchedTests []string
+	for _, testCaseFile := range sortCases(testCaseFiles) {
+		file := filepath.Join(proofDir, proofFileName(testCaseID(testCaseFile)))
+		if _, err := os.Stat(file); err == nil {
+			matched = append(matched, file)
+			matchedTests = append(matchedTests, testCaseFile)
+		}
+	}
+	if len(matched) == 0 {
+		fatal("No proofs of the test cases found", "dir", proofDir, "tests", testsDir)
+	}
+	if proofs, _ := filepath.Glob(filepath.Join(proofDir, proofFileName("*"))); len(proofs) > len(matched) {
+		slog.Warn("Leaving out proofs without a test case", "dir", proofDir, "tests", testsDir, "proofs", len(proofs)-len(matched))
+	}
+
+	slog.Info("Batching proofs", "proofs", len(matched), "dir", proofDir)
+
+	batch := &ProofBatch{}
+	for i, file := range matched {
+		proof := new(groth16_bn254.Proof)
+		loadArtifact(file, proof)
+
+		testCase, err := loadTestCase(matchedTests[i])
+		if err != nil {
+			fatal("Failed to load test case", "test_case", testCaseID(matchedTests[i]), "proof", file, "error", err)
+		}
+		publicWitness, err := createPublicWitness(testCase)
+		if err != nil {
+			fatal("Failed to create public witness", "test_case", testCaseID(matchedTests[i]), "error", err)
+		}
+
+		batch.Proofs = append(batch.Proofs, proof)
+		batch.Publics = append(batch.Publics, publicWitness.Vector().(fr.Vector))
+	}
+
+	// Baseline: verify every proof on its own
+	start := time.Now()
+	for i, proof := range batch.Proofs {
+		if err := groth16_bn254.Verify(proof, vk, batch.Publics[i], verifierOptions()...); err != nil {
+			fatal("Proof does not verify", "proof", filepath.Base(matched[i]), "error", err)
+		}
+	}
+	individualTime := time.Since(start)
+
+	start = time.Now()
+	var buf bytes.Buffer
+	if _, err := batch.WriteTo(&buf); err != nil {
+		fatal("Failed to encode proof batch", "error", err)
+	}
+	bundleTime := time.Since(start)
+
+	batchPath := filepath.Join(outputDir, batchFile)
+	if err := os.WriteFile(batchPath, buf.Bytes(), 0644); err != nil {
+		fatal("Failed to write proof batch", "error", err)
+	}
+
+	// Verify the batch as a verifier would, from its serialized form
+	var loaded ProofBatch
+	loadArtifact(batchPath, &loaded)
+
+	start = time.Now()
+	err = loaded.Verify(vk)
+	batchVerifyTime := time.Since(start)
+	if err != nil {
+		fatal("✗ Batch verification failed", "error", err)
+	}
+
+	stats := BatchVerifyStats{
+		Proofs:                   len(batch.Proofs),
+		BatchBytes:               int64(buf.Len()),
+		BundleTimeSecs:           bundleTime.Seconds(),
+		IndividualVerifyTimeSecs: individualTime.Seconds(),
+		BatchVerifyTimeSecs:      batchVerifyTime.Seconds(),
+	}
+
+	fmt.Printf("✓ Batch verified (%d proofs, %s)\n", stats.Proofs, formatBytes(uint64(stats.BatchBytes)))
+	fmt.Printf("  Individual verification: %v (%v per proof)\n", individualTime, individualTime/time.Duration(stats.Proofs))
+	fmt.Printf("  Batch verification:      %v (%v per proof)\n", batchVerifyTime, batchVerifyTime/time.Duration(stats.Proofs))
+	fmt.Printf("  Speedup: %.2fx\n", individualTime.Seconds()/batchVerifyTime.Seconds())
+
+	bundleResult := singleRun("batch_bundle", "", bundleTime)
+	bundleResult.ProofBytes = stats.BatchBytes
+	recordResults(artifactSettings(), bundleResult, singleRun("verify_batch", "", batchVerifyTime))
+
+	// Save alongside the other benchmark results
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	err = os.MkdirAll(resultsDir, 0755)
+	if err != nil {
+		fatal("Failed to create results directory", "error", err)
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		fatal("Failed to encode batch verification results", "error", err)
+	}
+
+	statsFile := filepath.Join(resultsDir, "batch_verification.json")
+	err = os.WriteFile(statsFile, append(data, '\n'), 0644)
+	if err != nil {
+		fatal("Failed to write batch verification results", "error", err)
+	}
+
+	slog.Info("Proof batch saved", "file", batchPath, "results", statsFile)
+}
+
+// Verify checks all bundled proofs against vk with a single multi-pairing for
+// the Groth16 equations and one for the Pedersen commitment proofs.
+func (a *ProofBatch) Verify(vk *groth16_bn254.VerifyingKey) error {
+	n := len(a.Proofs)
+	if n == 0 || len(a.Publics) != n {
+		return errors.New("proof batch is empty or malformed")
+	}
+
+	h, err := manifest.NewHashToField(hashToField)
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		h = hash_to_field.New([]byte(constraint.CommitmentDst))
+	}
+
+	nbPublic := len(vk.G1.K) - len(vk.PublicAndCommitmentCommitted) - 1
+	nbCommitments := len(vk.CommitmentKeys)
+	for i, proof := range a.Proofs {
+		if len(a.Publics[i]) != nbPublic {
+			return fmt.Errorf("proof %d: invalid witness size, got %d, expected %d", i, len(a.Publics[i]), nbPublic)
+		}
+		if len(proof.Commitments) != nbCommitments {
+			return fmt.Errorf("proof %d: expected %d commitments, got %d", i, nbCommitments, len(proof.Commitments))
+		}
+		if !proof.Ar.IsInSubGroup() || !proof.Bs.IsInSubGroup() || !proof.Krs.IsInSubGroup() || !proof.CommitmentPok.IsInSubGroup() {
+			return fmt.Errorf("proof %d: subgroup check failed", i)
+		}
+		for j := range proof.Commitments {
+			if !proof.Commitments[j].IsInSubGroup() {
+				return fmt.Errorf("proof %d: commitment subgroup check failed", i)
+			}
+		}
+	}
+
+	// The folding challenge binds every proof and public input
+	var transcript bytes.Buffer
+	if _, err := a.WriteTo(&transcript); err != nil {
+		return err
+	}
+	challenge, err := fr.Hash(transcript.Bytes(), []byte("G16-BATCH"), 1)
+	if err != nil {
+		return err
+	}
+	powers := make([]fr.Element, n)
+	powers[0].SetOne()
+	for i := 1; i < n; i++ {
+		powers[i].Mul(&powers[i-1], &challenge[0])
+	}
+
+	// Σ r^i·L_i is a single MSM over the verifying key with folded public
+	// inputs, plus the folded commitments
+	folded := make(fr.Vector, len(vk.G1.K))
+	commitmentBases := make([]curve.G1Affine, 0, n*nbCommitments)
+	commitmentScalars := make([]fr.Element, 0, n*nbCommitments)
+	pedersenG1 := make([]curve.G1Affine, nbCommitments+1)
+	pedersenG2 := make([]curve.G2Affine, nbCommitments+1)
+	pokBases := make([]curve.G1Affine, n)
+	pedersenScalars := make([][]fr.Element, nbCommitments)
+	for i, proof := range a.Proofs {
+		publicWitness := append(fr.Vector(nil), a.Publics[i]...)
+		commitmentHashes, err := commitmentHashes(h, vk, proof, publicWitness)
+		if err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+		publicWitness = append(publicWitness, commitmentHashes...)
+
+		var term fr.Element
+		folded[0].Add(&folded[0], &powers[i])
+		for j := range publicWitness {
+			term.Mul(&publicWitness[j], &powers[i])
+			folded[j+1].Add(&folded[j+1], &term)
+		}
+		for j := range proof.Commitments {
+			commitmentBases = append(commitmentBases, proof.Commitments[j])
+			commitmentScalars = append(commitmentScalars, powers[i])
+		}
+
+		// Each proof folds its commitment PoKs with its own BSB22 challenge
+		if nbCommitments > 0 {
+			serialized := make([]byte, 0, nbCommitments*fr.Bytes)
+			for j := range commitmentHashes {
+				serialized = append(serialized, commitmentHashes[j].Marshal()...)
+			}
+			pokChallenge, err := fr.Hash(serialized, []byte("G16-BSB22"), 1)
+			if err != nil {
+				return err
+			}
+			coeff := powers[i]
+			for j := 0; j < nbCommitments; j++ {
+				pedersenScalars[j] = append(pedersenScalars[j], coeff)
+				coeff.Mul(&coeff, &pokChallenge[0])
+			}
+			pokBases[i] = proof.CommitmentPok
+		}
+	}
+
+	var lSum curve.G1Jac
+	if _, err := lSum.MultiExp(vk.G1.K, folded, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	if len(commitmentBases) > 0 {
+		var commitmentSum curve.G1Jac
+		if _, err := commitmentSum.MultiExp(commitmentBases, commitmentScalars, ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		lSum.AddAssign(&commitmentSum)
+	}
+
+	krsBases := make([]curve.G1Affine, n)
+	for i, proof := range a.Proofs {
+		krsBases[i] = proof.Krs
+	}
+	var krsSum curve.G1Jac
+	if _, err := krsSum.MultiExp(krsBases, powers, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+
+	var powerSum fr.Element
+	for i := range powers {
+		powerSum.Add(&powerSum, &powers[i])
+	}
+
+	// Π e(r^i·A_i, B_i) · e(-Σr^i·α, β) · e(-Σ r^i·L_i, γ) · e(-Σ r^i·C_i, δ) == 1
+	pairingG1 := make([]curve.G1Affine, n+3)
+	pairingG2 := make([]curve.G2Affine, n+3)
+	var scalar big.Int
+	for i, proof := range a.Proofs {
+		powers[i].BigInt(&scalar)
+		pairingG1[i].ScalarMultiplication(&proof.Ar, &scalar)
+		pairingG2[i] = proof.Bs
+	}
+	powerSum.BigInt(&scalar)
+	pairingG1[n].ScalarMultiplication(&vk.G1.Alpha, &scalar)
+	pairingG1[n].Neg(&pairingG1[n])
+	pairingG2[n] = vk.G2.Beta
+	pairingG1[n+1].FromJacobian(&lSum)
+	pairingG1[n+1].Neg(&pairingG1[n+1])
+	pairingG2[n+1] = vk.G2.Gamma
+	pairingG1[n+2].FromJacobian(&krsSum)
+	pairingG1[n+2].Neg(&pairingG1[n+2])
+	pairingG2[n+2] = vk.G2.Delta
+
+	if ok, err := curve.PairingCheck(pairingG1, pairingG2); err != nil {
+		return err
+	} else if !ok {
+		return errors.New("pairing check failed")
+	}
+
+	if nbCommitments == 0 {
+		return nil
+	}
+
+	// Pedersen proofs of knowledge: e(Σ_i r^i·c_i^j·D_ij, -σ_j·G) · e(Σ_i r^i·π_i, G) == 1
+	for j := 0; j < nbCommitments; j++ {
+		bases := make([]curve.G1Affine, n)
+		for i, proof := range a.Proofs {
+			bases[i] = proof.Commitments[j]
+		}
+		var sum curve.G1Jac
+		if _, err := sum.MultiExp(bases, pedersenScalars[j], ecc.MultiExpConfig{}); err != nil {
+			return err
+		}
+		pedersenG1[j].FromJacobian(&sum)
+		pedersenG2[j] = gnarkcompat.PedersenGSigmaNegBN254(&vk.CommitmentKeys[j])
+		if vk.CommitmentKeys[j].G != vk.CommitmentKeys[0].G {
+			return errors.New("parameter mismatch: G2 element")
+		}
+	}
+	var pokSum curve.G1Jac
+	if _, err := pokSum.MultiExp(pokBases, powers, ecc.MultiExpConfig{}); err != nil {
+		return err
+	}
+	pedersenG1[nbCommitments].FromJacobian(&pokSum)
+	pedersenG2[nbCommitments] = vk.CommitmentKeys[0].G
+
+	if ok, err := curve.PairingCheck(pedersenG1, pedersenG2); err != nil {
+		return err
+	} else if !ok {
+		return errors.New("commitment proof of knowledge check failed")
+	}
+	return nil
+}
+
+// commitmentHashes recomputes the commitment challenges the prover appended to
+// the public witness, as gnark's verifier does.
+func commitmentHashes(h hash.Hash, vk *groth16_bn254.VerifyingKey, proof *groth16_bn254.Proof, publicWitness fr.Vector) ([]fr.Element, error) {
+	res := make([]fr.Element, len(vk.PublicAndCommitmentCommitted))
+	for i, committed := range vk.PublicAndCommitmentCommitted {
+		prehash := proof.Commitments[i].Marshal()
+		for _, j := range committed {
+			if j-1 >= len(publicWitness) {
+				return nil, errors.New("committed public input out of range")
+			}
+			prehash = append(prehash, publicWitness[j-1].Marshal()...)
+		}
+		h.Reset()
+		h.Write(prehash)
+		hashBts := h.Sum(nil)
+		h.Reset()
+		nbBuf := fr.Bytes
+		if h.Size() < fr.Bytes {
+			nbBuf = h.Size()
+		}
+		res[i].SetBytes(hashBts[:nbBuf])
+	}
+	return res, nil
+}
+
+// WriteTo writes the number of proofs followed by each proof and its public
+// inputs.
+func (a *ProofBatch) WriteTo(w io.Writer) (int64, error) {
+	if err := binary.Write(w, binary.BigEndian, uint32(len(a.Proofs))); err != nil {
+		return 0, err
+	}
+	written := int64(4)
+	for i := range a.Proofs {
+		n, err := a.Proofs[i].WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		n, err = a.Publics[i].WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// ReadFrom reads a proof batch written by WriteTo.
+func (a *ProofBatch) ReadFrom(r io.Reader) (int64, error) {
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return 0, err
+	}
+	read := int64(4)
+	a.Proofs = make([]*groth16_bn254.Proof, count)
+	a.Publics = make([]fr.Vector, count)
+	for i := range a.Proofs {
+		a.Proofs[i] = new(groth16_bn254.Proof)
+		n, err := a.Proofs[i].ReadFrom(r)
+		read += n
+		if err != nil {
+			return read, err
+		}
+		n, err = a.Publics[i].ReadFrom(r)
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
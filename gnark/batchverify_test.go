@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+)
+
+// batchTestCircuit proves knowledge of a square root, and commits to it as the
+// lookup range checks of the ECDSA circuit do, so that a batch carries Pedersen
+// commitments and their proofs of knowledge. It sets up in milliseconds.
+type batchTestCircuit struct {
+	X frontend.Variable
+	Y frontend.Variable `gnark:",public"`
+}
+
+func (c *batchTestCircuit) Define(api frontend.API) error {
+	api.AssertIsEqual(api.Mul(c.X, c.X), c.Y)
+	committed, err := api.(frontend.Committer).Commit(c.X)
+	if err != nil {
+		return err
+	}
+	api.AssertIsDifferent(committed, 0)
+	return nil
+}
+
+// newTestBatch proves n instances of batchTestCircuit and bundles the proofs
+func newTestBatch(t *testing.T, n int) (*groth16_bn254.VerifyingKey, *ProofBatch) {
+	t.Helper()
+	compiled := hashToField
+	hashToField = hashToFieldSHA256
+	t.Cleanup(func() { hashToField = compiled })
+
+	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &batchTestCircuit{})
+	if err != nil {
+		t.Fatal("Failed to compile:", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatal("Failed to set up:", err)
+	}
+
+	batch := &ProofBatch{}
+	for i := 0; i < n; i++ {
+		x := i + 2
+		w, err := frontend.NewWitness(&batchTestCircuit{X: x, Y: x * x}, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatal("Failed to create witness:", err)
+		}
+		proof, err := groth16.Prove(ccs, pk, w, backend.WithProverHashToFieldFunction(sha256.New()))
+		if err != nil {
+			t.Fatal("Failed to prove:", err)
+		}
+		public, err := w.Public()
+		if err != nil {
+			t.Fatal("Failed to create public witness:", err)
+		}
+		batch.Proofs = append(batch.Proofs, proof.(*groth16_bn254.Proof))
+		batch.Publics = append(batch.Publics, public.Vector().(fr.Vector))
+	}
+	return vk.(*groth16_bn254.VerifyingKey), batch
+}
+
+func TestProofBatchVerify(t *testing.T) {
+	vk, batch := newTestBatch(t, 3)
+	if err := batch.Verify(vk); err != nil {
+		t.Fatal("Valid batch rejected:", err)
+	}
+
+	// The batch verifies from its serialized form too
+	var buf bytes.Buffer
+	if _, err := batch.WriteTo(&buf); err != nil {
+		t.Fatal("Failed to encode batch:", err)
+	}
+	var loaded ProofBatch
+	if _, err := loaded.ReadFrom(&buf); err != nil {
+		t.Fatal("Failed to decode batch:", err)
+	}
+	if err := loaded.Verify(vk); err != nil {
+		t.Fatal("Decoded batch rejected:", err)
+	}
+}
+
+func TestProofBatchVerifyRejectsTampering(t *testing.T) {
+	_, _, g1, _ := curve.Generators()
+	tests := []struct {
+		name   string
+		tamper func(b *ProofBatch)
+	}{
+		{"A of one proof", func(b *ProofBatch) { b.Proofs[1].Ar.Add(&b.Proofs[1].Ar, &g1) }},
+		{"C of one proof", func(b *ProofBatch) { b.Proofs[2].Krs.Add(&b.Proofs[2].Krs, &g1) }},
+		{"B of one proof", func(b *ProofBatch) { b.Proofs[0].Bs = b.Proofs[1].Bs }},
+		{"public input of one proof", func(b *ProofBatch) { b.Publics[1][0].SetUint64(10) }},
+		{"public inputs swapped", func(b *ProofBatch) { b.Publics[0], b.Publics[2] = b.Publics[2], b.Publics[0] }},
+		{"commitment of one proof", func(b *ProofBatch) { b.Proofs[1].Commitments[0].Add(&b.Proofs[1].Commitments[0], &g1) }},
+		{"commitment proof of knowledge", func(b *ProofBatch) { b.Proofs[2].CommitmentPok.Add(&b.Proofs[2].CommitmentPok, &g1) }},
+		{"one proof twice", func(b *ProofBatch) { b.Proofs[1] = b.Proofs[0] }},
+		{"missing public inputs", func(b *ProofBatch) { b.Publics = b.Publics[:2] }},
+		{"empty", func(b *ProofBatch) { b.Proofs, b.Publics = nil, nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vk, batch := newTestBatch(t, 3)
+			tt.tamper(batch)
+			if err := batch.Verify(vk); err == nil {
+				t.Error("Tampered batch verified")
+			}
+		})
+	}
+}
@@ -0,0 +1,685 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+var (
+	// command line flags
+	benchRuns        int
+	skipCompile      bool
+	benchThreads     string
+	benchCold        bool
+	benchWarmup      int
+	outlierThreshold float64
+	targetCI         string
+	maxRuns          int
+)
+
+// targetCIWidth is -target-ci as a fraction of the mean, and 0 without it
+var targetCIWidth float64
+
+// tQuantiles975 are the 97.5% quantiles of Student's t-distribution with 1 to
+// 30 degrees of freedom, for 95% confidence intervals of a mean. Beyond 30 the
+// normal quantile, 1.96, is within 2%.
+var tQuantiles975 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// PhaseStats summarizes the timings of repeated runs of one benchmark phase
+type PhaseStats struct {
+	Phase      string  `json:"phase"`
+	TestCase   string  `json:"test_case,omitempty"`
+	Runs       int     `json:"runs"`
+	MeanSecs   float64 `json:"mean_secs"`
+	MedianSecs float64 `json:"median_secs"`
+	StdDevSecs float64 `json:"stddev_secs"`
+	MinSecs    float64 `json:"min_secs"`
+	MaxSecs    float64 `json:"max_secs"`
+	P95Secs    float64 `json:"p95_secs"`
+	// CI95Secs is the half-width of the 95% confidence interval of the mean,
+	// and 0 for a single run
+	CI95Secs float64 `json:"ci95_secs,omitempty"`
+	// Threads is the GOMAXPROCS the phase ran with in a thread sweep
+	// (-threads), and 0 otherwise
+	Threads int `json:"threads,omitempty"`
+	// Speedup and Efficiency compare the mean to that of the fewest threads
+	// in the sweep: efficiency is the speedup divided by the added threads
+	Speedup    float64 `json:"speedup,omitempty"`
+	Efficiency float64 `json:"efficiency,omitempty"`
+	// Outliers is the number of runs discarded by -reject-outliers; Runs and
+	// the statistics cover the others
+	Outliers int `json:"outliers,omitempty"`
+}
+
+// BenchResults is the output of the bench command. Every phase ran Warmup
+// times, discarded, before its timed runs, and every proof was made on the
+// Prover hardware: gpu with -gpu, else cpu.
+type BenchResults struct {
+	Curve            string       `json:"curve"`
+	Backend          string       `json:"backend"`
+	RangeCheck       string       `json:"range_check"`
+	Runs             int          `json:"runs"`
+	Warmup           int          `json:"warmup,omitempty"`
+	TargetCI         float64      `json:"target_ci,omitempty"`
+	OutlierThreshold float64      `json:"outlier_threshold,omitempty"`
+	CPULimit         string       `json:"cpu_limit,omitempty"`
+	Device           string       `json:"device,omitempty"`
+	Prover           string       `json:"prover"`
+	Results          []PhaseStats `json:"results"`
+}
+
+// benchProver is the hardware bench proves on, which requireProver holds it to
+func benchProver() string {
+	if useGPU {
+		return "gpu"
+	}
+	return "cpu"
+}
+
+// requireProver stops bench when a proof of -gpu was made on the CPU after
+// the GPU failed, as the statistics would mix GPU and CPU runs under a GPU
+// label
+func requireProver(hardware string, args ...any) {
+	if hardware != benchProver() {
+		fatal("GPU proving failed during the benchmark; fix the GPU or leave out -gpu", append(args, "prover", hardware)...)
+	}
+}
+
+// runBenchmarks compiles the circuit and runs the setup -runs times, then
+// proves and verifies every test case -runs times, and reports statistics for
+// each phase. With -skip-compile it benchmarks the artifacts in the output
+// directory instead. Test cases default to all of those in -tests, or with
+// -tag to every test case in -tests that carries one of its tags. With
+// -threads, proving and verification are repeated at each thread count and
+// their scaling efficiency reported. With -cold, each run also reloads the
+// circuit and proving key from disk before proving, to compare the cold start
+// of a client-side prover with proving from keys already in memory. With
+// -baseline, the results are then checked against a stored baseline. With
+// -metrics-addr, the runs can be followed from Prometheus. Every phase first
+// runs -warmup times untimed, and with -reject-outliers the statistics leave
+// out runs far from the median. With -target-ci, each phase keeps running past
+// -runs until the confidence interval of its mean is narrow enough, or
+// -max-runs is reached. With -compare-hash-to-field, every test case is
+// also proven and verified with each hash-to-field function.
+func runBenchmarks(testCaseFiles []string) {
+	if benchRuns < 1 {
+		fatal("-runs must be at least 1", "runs", benchRuns)
+	}
+	if benchWarmup < 0 {
+		fatal("-warmup must not be negative", "warmup", benchWarmup)
+	}
+	if outlierThreshold < 0 {
+		fatal("-reject-outliers must not be negative", "reject_outliers", outlierThreshold)
+	}
+	if targetCI != "" {
+		var err error
+		targetCIWidth, err = parseRegressionLimit(targetCI)
+		if err != nil || targetCIWidth <= 0 {
+			fatal("Invalid -target-ci, use a percentage of the mean such as 2%", "target_ci", targetCI)
+		}
+		if maxRuns < benchRuns {
+			fatal("-max-runs must be at least -runs", "max_runs", maxRuns, "runs", benchRuns)
+		}
+	}
+	if regressionLimit != "" {
+		if baselineFile == "" {
+			fatal("-fail-on-regression needs a -baseline to compare with")
+		}
+		if _, err := parseRegressionLimit(regressionLimit); err != nil {
+			fatal("Invalid -fail-on-regression", "error", err)
+		}
+	}
+	if benchCold && !skipCompile {
+		fatal("-cold loads the artifacts in -d, use it with -skip-compile")
+	}
+	startMetricsServer()
+
+	threadCounts := []int{0}
+	maxProcs := runtime.GOMAXPROCS(0)
+	if benchThreads != "" {
+		var err error
+		threadCounts, err = parseThreadCounts(benchThreads)
+		if err != nil {
+			fatal("Invalid -threads", "error", err)
+		}
+	}
+	testCaseFiles = selectTestCases(testCaseFiles, "*.json")
+	if len(testCaseFiles) == 0 {
+		var err error
+		testCaseFiles, err = filepath.Glob(filepath.Join(testsDir, selectedCircuit().TestCases))
+		if err != nil {
+			fatal("Failed to find test cases", "dir", testsDir, "error", err)
+		}
+		if len(testCaseFiles) == 0 {
+			fatal("No test cases found", "dir", testsDir, "pattern", selectedCircuit().TestCases)
+		}
+	}
+
+	var ccs constraint.ConstraintSystem
+	var pk, vk artifact
+	var settings Manifest
+	var results []PhaseStats
+	var hashToFieldResults []HashToFieldStats
+	phaseAllocs := make(map[int]AllocStats)
+	proveEnergy := make(map[int]float64)
+	loadIOs := make(map[int]IOStats)
+	if skipCompile {
+		resolveSettings()
+		settings = artifactSettings()
+		ccs = newConstraintSystem()
+		loadArtifact(filepath.Join(outputDir, circuitFileName()), ccs)
+		pk = newProvingKey()
+		loadArtifact(filepath.Join(outputDir, "proving.key"), pk)
+		vk = newVerifyingKey()
+		loadArtifact(filepath.Join(outputDir, "verifying.key"), vk)
+	} else {
+		defaultSettings()
+		slog.Info("Benchmarking compile and setup", "backend", provingBackend, "curve", curveName, "runs", runsLabel())
+
+		for run := 1; run <= benchWarmup; run++ {
+			warmupCCS, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), selectedCircuit().New())
+			if err != nil {
+				fatal("Circuit compilation failed", "error", err)
+			}
+			if _, _, err := setupKeys(warmupCCS); err != nil {
+				fatal("Setup failed", "error", err)
+			}
+			slog.Info("Warm-up done", "phase", "compile", "run", run)
+		}
+
+		var compileTimes, setupTimes []time.Duration
+		var compileAllocs, setupAllocs AllocStats
+		for run := 1; needsMoreRuns(compileTimes) || needsMoreRuns(setupTimes); run++ {
+			// As for proving, memory is sampled on the first run only
+			var stopAllocs func() AllocStats
+			if run == 1 {
+				stopAllocs = trackAllocs()
+			}
+			start := time.Now()
+			var err error
+			ccs, err = frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), selectedCircuit().New())
+			compileTimes = append(compileTimes, time.Since(start))
+			if err != nil {
+				fatal("Circuit compilation failed", "run", run, "error", err)
+			}
+			if stopAllocs != nil {
+				compileAllocs = stopAllocs()
+				stopAllocs = trackAllocs()
+			}
+
+			start = time.Now()
+			pk, vk, err = setupKeys(ccs)
+			setupTimes = append(setupTimes, time.Since(start))
+			if err != nil {
+				fatal("Setup failed", "run", run, "error", err)
+			}
+			if stopAllocs != nil {
+				setupAllocs = stopAllocs()
+			}
+			observePhase("compile", compileTimes[run-1])
+			observePhase("setup", setupTimes[run-1])
+			slog.Info("Compiled and set up", "run", run, "compile_secs", compileTimes[run-1].Seconds(), "setup_secs", setupTimes[run-1].Seconds())
+		}
+		phaseAllocs[len(results)] = compileAllocs
+		phaseAllocs[len(results)+1] = setupAllocs
+		results = append(results, benchSummarize("compile", "", compileTimes), benchSummarize("setup", "", setupTimes))
+		settings = currentSettings()
+	}
+
+	for _, testCaseFile := range testCaseFiles {
+		testCaseNum := testCaseID(testCaseFile)
+		testCase, err := loadTestCase(testCaseFile)
+		if err != nil {
+			fatal("Failed to load test case", "file", testCaseFile, "error", err)
+		}
+		for run := 1; run <= benchWarmup; run++ {
+			if _, err := createWitness(testCase); err != nil {
+				fatal("Failed to create witness", "test_case", testCaseNum, "error", err)
+			}
+		}
+		var witness witness.Witness
+		var witnessTimes []time.Duration
+		var witnessAllocs allocCounter
+		for run := 1; needsMoreRuns(witnessTimes); run++ {
+			witnessAllocs.start()
+			start := time.Now()
+			witness, err = createWitness(testCase)
+			witnessTimes = append(witnessTimes, time.Since(start))
+			witnessAllocs.stop()
+			if err != nil {
+				fatal("Failed to create witness", "test_case", testCaseNum, "error", err)
+			}
+		}
+		var witnessAllocStats AllocStats
+		witnessAllocStats.setPerOp(&witnessAllocs)
+		phaseAllocs[len(results)] = witnessAllocStats
+		results = append(results, benchSummarize("witness", testCaseNum, witnessTimes))
+		publicWitness, err := witness.Public()
+		if err != nil {
+			fatal("Failed to create public witness", "test_case", testCaseNum, "error", err)
+		}
+
+		for _, threads := range threadCounts {
+			if threads > 0 {
+				slog.Info("Benchmarking prove and verify", "test_case", testCaseNum, "threads", threads, "runs", runsLabel())
+				runtime.GOMAXPROCS(threads)
+			} else {
+				slog.Info("Benchmarking prove and verify", "test_case", testCaseNum, "runs", runsLabel())
+			}
+
+			for run := 1; run <= benchWarmup; run++ {
+				proof, _, err := proveCircuit(ccs, pk, witness)
+				if err != nil {
+					fatal("Failed to generate proof", "test_case", testCaseNum, "error", err)
+				}
+				if err := verifyCircuit(proof, vk, publicWitness); err != nil {
+					fatal("Proof verification failed", "test_case", testCaseNum, "error", err)
+				}
+				slog.Info("Warm-up done", "phase", "prove", "test_case", testCaseNum, "run", run)
+			}
+
+			var proveTimes, verifyTimes, loadTimes, coldTimes []time.Duration
+			var solveTimes, backendTimes []time.Duration
+			var allocs, verifyAllocStats AllocStats
+			var proveAllocs, verifyAllocs allocCounter
+			var energy float64
+			var loadIO IOStats
+			for run := 1; needsMoreRuns(proveTimes) || needsMoreRuns(verifyTimes) || benchCold && needsMoreRuns(coldTimes); run++ {
+				if benchCold {
+					load, cold := benchColdProve(witness, &loadIO)
+					loadTimes = append(loadTimes, load)
+					coldTimes = append(coldTimes, cold)
+					observePhase("load", load)
+					observePhase("prove_cold", cold)
+					slog.Info("Proved from disk", "test_case", testCaseNum, "run", run, "secs", cold.Seconds(), "load_secs", load.Seconds())
+				}
+
+				// The memory of proving is sampled on the first run only
+				var stopAllocs func() AllocStats
+				if run == 1 {
+					stopAllocs = trackAllocs()
+				}
+				stopEnergy := startEnergy()
+				proverSolverClock.reset()
+				proveAllocs.start()
+				start := time.Now()
+				proof, proverBackend, gpuFailed, err := proveCircuitTimed(ccs, pk, witness)
+				proveTimes = append(proveTimes, time.Since(start)-gpuFailed)
+				proveAllocs.stop()
+				energy += stopEnergy()
+				if err != nil {
+					fatal("Failed to generate proof", "test_case", testCaseNum, "run", run, "error", err)
+				}
+				requireProver(proverBackend, "test_case", testCaseNum, "run", run)
+				if solveTime, ok := proverSolverClock.last(); ok {
+					solveTimes = append(solveTimes, solveTime)
+					backendTimes = append(backendTimes, proveTimes[run-1]-solveTime)
+				}
+				if stopAllocs != nil {
+					allocs = stopAllocs()
+				}
+				observePhase("prove", proveTimes[run-1])
+				proofsCompleted.WithLabelValues(proverBackend).Inc()
+
+				verifyAllocs.start()
+				start = time.Now()
+				err = verifyCircuit(proof, vk, publicWitness)
+				verifyTimes = append(verifyTimes, time.Since(start))
+				verifyAllocs.stop()
+				if err != nil {
+					fatal("Proof verification failed", "test_case", testCaseNum, "run", run, "error", err)
+				}
+				observePhase("verify", verifyTimes[run-1])
+				verificationsCompleted.Inc()
+				slog.Info("Proved and verified", "test_case", testCaseNum, "run", run, "prove_secs", proveTimes[run-1].Seconds(), "prover", proverBackend, "verify_secs", verifyTimes[run-1].Seconds())
+			}
+
+			prove, verify := benchSummarize("prove", testCaseNum, proveTimes), benchSummarize("verify", testCaseNum, verifyTimes)
+			prove.Threads, verify.Threads = threads, threads
+			allocs.setPerOp(&proveAllocs)
+			verifyAllocStats.setPerOp(&verifyAllocs)
+			phaseAllocs[len(results)] = allocs
+			phaseAllocs[len(results)+1] = verifyAllocStats
+			proveEnergy[len(results)] = energy / float64(len(proveTimes))
+			results = append(results, prove, verify)
+			if len(solveTimes) == len(proveTimes) {
+				solve, backend := benchSummarize("prove_solve", testCaseNum, solveTimes), benchSummarize("prove_backend", testCaseNum, backendTimes)
+				solve.Threads, backend.Threads = threads, threads
+				results = append(results, solve, backend)
+			}
+			if benchCold {
+				load, cold := benchSummarize("load", testCaseNum, loadTimes), benchSummarize("prove_cold", testCaseNum, coldTimes)
+				load.Threads, cold.Threads = threads, threads
+				loadIOs[len(results)] = loadIO.average(len(loadTimes))
+				results = append(results, load, cold)
+			}
+		}
+		if compareHashToField {
+			hashToFieldResults = append(hashToFieldResults, benchHashToFields(ccs, pk, vk, witness, publicWitness, testCaseNum)...)
+		}
+	}
+	runtime.GOMAXPROCS(maxProcs)
+	if benchThreads != "" {
+		computeScaling(results)
+	}
+
+	fmt.Println()
+	fmt.Printf("Prover: %s\n", benchProver())
+	fmt.Printf("%-14s %-10s %5s %10s %10s %10s %10s %10s %10s %10s\n", "Phase", "Test case", "Runs", "Mean", "95% CI", "Median", "Stddev", "Min", "Max", "P95")
+	for _, r := range results {
+		testCase := r.TestCase
+		if testCase == "" {
+			testCase = "-"
+		}
+		if r.Threads > 0 {
+			testCase += fmt.Sprintf(" @%d", r.Threads)
+		}
+		fmt.Printf("%-14s %-10s %5d %10s %10s %10s %10s %10s %10s %10s\n", r.Phase, testCase, r.Runs, formatSecs(r.MeanSecs), "±"+formatSecs(r.CI95Secs), formatSecs(r.MedianSecs), formatSecs(r.StdDevSecs), formatSecs(r.MinSecs), formatSecs(r.MaxSecs), formatSecs(r.P95Secs))
+	}
+	for _, r := range results {
+		if r.Outliers > 0 {
+			slog.Info("Discarded outliers", "phase", r.Phase, "test_case", r.TestCase, "outliers", r.Outliers, "runs", r.Outliers+r.Runs)
+		}
+	}
+
+	if benchThreads != "" {
+		fmt.Println()
+		fmt.Printf("%-14s %-10s %8s %10s %8s %10s\n", "Phase", "Test case", "Threads", "Mean", "Speedup", "Efficiency")
+		for _, r := range results {
+			if r.Threads > 0 {
+				fmt.Printf("%-14s %-10s %8d %10s %7.2fx %9.0f%%\n", r.Phase, r.TestCase, r.Threads, formatSecs(r.MeanSecs), r.Speedup, r.Efficiency*100)
+			}
+		}
+	}
+
+	// Save alongside the other benchmark results
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	err := os.MkdirAll(resultsDir, 0755)
+	if err != nil {
+		fatal("Failed to create results directory", "dir", resultsDir, "error", err)
+	}
+
+	data, err := json.MarshalIndent(BenchResults{
+		Curve:            curveName,
+		Backend:          provingBackend,
+		RangeCheck:       rangeCheck,
+		Runs:             benchRuns,
+		Warmup:           benchWarmup,
+		TargetCI:         targetCIWidth,
+		OutlierThreshold: outlierThreshold,
+		CPULimit:         currentCPULimit,
+		Device:           currentDevice,
+		Prover:           benchProver(),
+		Results:          results,
+	}, "", "  ")
+	if err != nil {
+		fatal("Failed to encode benchmark results", "error", err)
+	}
+
+	benchFile := filepath.Join(resultsDir, "bench.json")
+	err = os.WriteFile(benchFile, append(data, '\n'), 0644)
+	if err != nil {
+		fatal("Failed to write benchmark results", "file", benchFile, "error", err)
+	}
+
+	measurements := make([]Measurement, len(results))
+	for i, r := range results {
+		measurements[i] = Measurement{PhaseStats: r}
+		if r.Phase == "compile" {
+			measurements[i].setCircuitSize(ccs)
+		}
+		if allocs, ok := phaseAllocs[i]; ok {
+			measurements[i].Allocs = &allocs
+		}
+		measurements[i].EnergyJoules = proveEnergy[i]
+		if strings.HasPrefix(r.Phase, "prove") {
+			measurements[i].Hardware = benchProver()
+		}
+		if loadIO, ok := loadIOs[i]; ok {
+			measurements[i].IO = &loadIO
+		}
+	}
+	recordResults(settings, measurements...)
+
+	slog.Info("Results saved", "file", benchFile)
+
+	if compareHashToField {
+		writeHashToFieldComparison(ccs, hashToField, hashToFieldResults)
+	}
+
+	if baselineFile != "" {
+		compareBaseline()
+	}
+}
+
+// benchColdProve proves from scratch: it reads the circuit and proving key from
+// the output directory, then proves. It returns the time spent loading and the
+// total, and adds the time spent reading from disk to loadIO.
+func benchColdProve(fullWitness witness.Witness, loadIO *IOStats) (load, total time.Duration) {
+	start := time.Now()
+	ccs := newConstraintSystem()
+	err := readArtifact(filepath.Join(outputDir, circuitFileName()), ccs, loadIO)
+	if err != nil {
+		fatal("Failed to read circuit", "error", err)
+	}
+	pk := newProvingKey()
+	err = readArtifact(filepath.Join(outputDir, "proving.key"), pk, loadIO)
+	if err != nil {
+		fatal("Failed to read proving key", "error", err)
+	}
+	load = time.Since(start)
+
+	_, proverBackend, gpuFailed, err := proveCircuitTimed(ccs, pk, fullWitness)
+	total = time.Since(start) - gpuFailed
+	if err != nil {
+		fatal("Failed to generate proof", "error", err)
+	}
+	requireProver(proverBackend)
+	proofsCompleted.WithLabelValues(proverBackend).Inc()
+	return load, total
+}
+
+// parseThreadCounts reads a comma-separated list of thread counts, or "all"
+// for the powers of two below the number of CPUs followed by the number of
+// CPUs. Counts are sorted so the first is the baseline for scaling.
+func parseThreadCounts(value string) ([]int, error) {
+	cpus := runtime.NumCPU()
+	var counts []int
+	if value == "all" {
+		for n := 1; n < cpus; n *= 2 {
+			counts = append(counts, n)
+		}
+		return append(counts, cpus), nil
+	}
+
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%q is not a thread count", field)
+		}
+		if n > cpus {
+			slog.Warn("More threads than CPUs", "threads", n, "cpus", cpus)
+		}
+		if !seen[n] {
+			seen[n] = true
+			counts = append(counts, n)
+		}
+	}
+	sort.Ints(counts)
+	return counts, nil
+}
+
+// computeScaling sets the speedup and efficiency of each result of a thread
+// sweep, relative to the same phase and test case at the fewest threads
+func computeScaling(results []PhaseStats) {
+	baselines := make(map[string]PhaseStats)
+	for _, r := range results {
+		key := r.Phase + "/" + r.TestCase
+		if b, ok := baselines[key]; r.Threads > 0 && (!ok || r.Threads < b.Threads) {
+			baselines[key] = r
+		}
+	}
+	for i, r := range results {
+		b, ok := baselines[r.Phase+"/"+r.TestCase]
+		if !ok || r.Threads == 0 {
+			continue
+		}
+		results[i].Speedup = b.MeanSecs / r.MeanSecs
+		results[i].Efficiency = results[i].Speedup * float64(b.Threads) / float64(r.Threads)
+	}
+}
+
+// benchSummarize computes the statistics of a phase's timed runs, after
+// discarding the outliers selected by -reject-outliers
+func benchSummarize(phase, testCase string, times []time.Duration) PhaseStats {
+	kept := rejectOutliers(times, outlierThreshold)
+	stats := summarize(phase, testCase, kept)
+	stats.Outliers = len(times) - len(kept)
+	return stats
+}
+
+// runsLabel describes how many times each phase runs
+func runsLabel() string {
+	if targetCIWidth == 0 {
+		return fmt.Sprintf("%d runs", benchRuns)
+	}
+	return fmt.Sprintf("%d to %d runs, until the 95%% CI is within ±%g%% of the mean", benchRuns, maxRuns, targetCIWidth*100)
+}
+
+// needsMoreRuns reports whether a phase needs another timed run: always before
+// -runs, and with -target-ci until the 95% confidence interval of the mean,
+// after rejecting outliers, is within the target width of it or -max-runs
+// is reached
+func needsMoreRuns(times []time.Duration) bool {
+	if len(times) < benchRuns {
+		return true
+	}
+	if targetCIWidth == 0 || len(times) >= maxRuns {
+		return false
+	}
+	stats := summarize("", "", rejectOutliers(times, outlierThreshold))
+	return stats.Runs < 2 || stats.CI95Secs > targetCIWidth*stats.MeanSecs
+}
+
+// rejectOutliers drops the times whose modified z-score exceeds threshold:
+// 0.6745 times their distance from the median, over the median absolute
+// deviation (MAD) (Iglewicz and Hoaglin suggest 3.5). Unlike the standard
+// deviation, the MAD is not inflated by the outliers themselves. Every time is
+// kept with a threshold of 0, fewer than 3 runs, or a MAD of 0.
+func rejectOutliers(times []time.Duration, threshold float64) []time.Duration {
+	if threshold == 0 || len(times) < 3 {
+		return times
+	}
+	secs := make([]float64, len(times))
+	for i, t := range times {
+		secs[i] = t.Seconds()
+	}
+	median := medianOf(secs)
+	deviations := make([]float64, len(secs))
+	for i, s := range secs {
+		deviations[i] = math.Abs(s - median)
+	}
+	mad := medianOf(deviations)
+	if mad == 0 {
+		return times
+	}
+
+	var kept []time.Duration
+	for i, t := range times {
+		if 0.6745*deviations[i]/mad <= threshold {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// medianOf returns the median of values, which it leaves unsorted
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
+
+// summarize computes the statistics of a phase's run times. The standard
+// deviation is the sample one, and p95 uses the nearest-rank method.
+func summarize(phase, testCase string, times []time.Duration) PhaseStats {
+	secs := make([]float64, len(times))
+	for i, t := range times {
+		secs[i] = t.Seconds()
+	}
+	sort.Float64s(secs)
+
+	n := len(secs)
+	var sum float64
+	for _, s := range secs {
+		sum += s
+	}
+	mean := sum / float64(n)
+
+	var variance float64
+	if n > 1 {
+		for _, s := range secs {
+			variance += (s - mean) * (s - mean)
+		}
+		variance /= float64(n - 1)
+	}
+
+	median := secs[n/2]
+	if n%2 == 0 {
+		median = (secs[n/2-1] + secs[n/2]) / 2
+	}
+
+	stddev := math.Sqrt(variance)
+	var ci95 float64
+	if n > 1 {
+		t := 1.96
+		if n-1 <= len(tQuantiles975) {
+			t = tQuantiles975[n-2]
+		}
+		ci95 = t * stddev / math.Sqrt(float64(n))
+	}
+
+	return PhaseStats{
+		Phase:      phase,
+		TestCase:   testCase,
+		Runs:       n,
+		MeanSecs:   mean,
+		MedianSecs: median,
+		StdDevSecs: stddev,
+		MinSecs:    secs[0],
+		MaxSecs:    secs[n-1],
+		P95Secs:    secs[int(math.Ceil(0.95*float64(n)))-1],
+		CI95Secs:   ci95,
+	}
+}
+
+// formatSecs prints a duration in seconds, switching to milliseconds below one
+// second so verification times stay readable
+func formatSecs(secs float64) string {
+	if secs < 1 {
+		return fmt.Sprintf("%.2fms", secs*1000)
+	}
+	return fmt.Sprintf("%.3fs", secs)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// secs turns seconds into durations, for tables of run times
+func secs(values ...float64) []time.Duration {
+	times := make([]time.Duration, len(values))
+	for i, v := range values {
+		times[i] = time.Duration(v * float64(time.Second))
+	}
+	return times
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name  string
+		times []time.Duration
+		want  PhaseStats
+	}{
+		{
+			name:  "single run",
+			times: secs(2),
+			want:  PhaseStats{Runs: 1, MeanSecs: 2, MedianSecs: 2, MinSecs: 2, MaxSecs: 2, P95Secs: 2},
+		},
+		{
+			name:  "odd count, unsorted",
+			times: secs(3, 1, 2),
+			want:  PhaseStats{Runs: 3, MeanSecs: 2, MedianSecs: 2, StdDevSecs: 1, MinSecs: 1, MaxSecs: 3, P95Secs: 3, CI95Secs: 4.303 / math.Sqrt(3)},
+		},
+		{
+			name:  "even count takes the mean of the middle two",
+			times: secs(4, 1, 3, 2),
+			want:  PhaseStats{Runs: 4, MeanSecs: 2.5, MedianSecs: 2.5, StdDevSecs: math.Sqrt(5.0 / 3), MinSecs: 1, MaxSecs: 4, P95Secs: 4, CI95Secs: 3.182 * math.Sqrt(5.0/3) / 2},
+		},
+		{
+			// nearest rank: ceil(0.95 * 20) = 19
+			name:  "p95 of 20 runs is the 19th",
+			times: secs(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20),
+			want:  PhaseStats{Runs: 20, MeanSecs: 10.5, MedianSecs: 10.5, StdDevSecs: math.Sqrt(35), MinSecs: 1, MaxSecs: 20, P95Secs: 19, CI95Secs: 2.093 * math.Sqrt(35) / math.Sqrt(20)},
+		},
+		{
+			name:  "identical runs",
+			times: secs(0.5, 0.5, 0.5, 0.5),
+			want:  PhaseStats{Runs: 4, MeanSecs: 0.5, MedianSecs: 0.5, MinSecs: 0.5, MaxSecs: 0.5, P95Secs: 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize("prove", "1", tt.times)
+			if got.Phase != "prove" || got.TestCase != "1" || got.Runs != tt.want.Runs {
+				t.Fatalf("got phase %q, test case %q, %d runs", got.Phase, got.TestCase, got.Runs)
+			}
+			for _, stat := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"mean", got.MeanSecs, tt.want.MeanSecs},
+				{"median", got.MedianSecs, tt.want.MedianSecs},
+				{"stddev", got.StdDevSecs, tt.want.StdDevSecs},
+				{"min", got.MinSecs, tt.want.MinSecs},
+				{"max", got.MaxSecs, tt.want.MaxSecs},
+				{"p95", got.P95Secs, tt.want.P95Secs},
+				{"ci95", got.CI95Secs, tt.want.CI95Secs},
+			} {
+				if !approxEqual(stat.got, stat.want) {
+					t.Errorf("%s = %v, want %v", stat.name, stat.got, stat.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMedianOf(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{7}, 7},
+		{[]float64{3, 1, 2}, 2},
+		{[]float64{4, 1, 3, 2}, 2.5},
+		{[]float64{1, 1, 1, 100}, 1},
+	}
+	for _, tt := range tests {
+		values := append([]float64(nil), tt.values...)
+		if got := medianOf(values); got != tt.want {
+			t.Errorf("medianOf(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+		for i := range values {
+			if values[i] != tt.values[i] {
+				t.Errorf("medianOf sorted its input %v into %v", tt.values, values)
+				break
+			}
+		}
+	}
+}
+
+func TestRejectOutliers(t *testing.T) {
+	tests := []struct {
+		name      string
+		times     []time.Duration
+		threshold float64
+		want      []time.Duration
+	}{
+		{"threshold 0 keeps every run", secs(10, 11, 50), 0, secs(10, 11, 50)},
+		{"fewer than 3 runs are kept", secs(10, 50), 3.5, secs(10, 50)},
+		{
+			// deviations 0, 0, 0, 4: a MAD of 0 would make every z-score infinite
+			name: "MAD of 0 keeps every run", times: secs(1, 1, 1, 5), threshold: 3.5, want: secs(1, 1, 1, 5),
+		},
+		{
+			// median 11, MAD 1: 50 scores 0.6745 * 39 = 26.3
+			name: "slow run dropped", times: secs(10, 11, 12, 11, 10, 50), threshold: 3.5, want: secs(10, 11, 12, 11, 10),
+		},
+		{
+			// median 11, MAD 1: 15 scores 2.7, under 3.5 but over 2
+			name: "threshold decides", times: secs(10, 11, 12, 11, 10, 15), threshold: 2, want: secs(10, 11, 12, 11, 10),
+		},
+		{"nothing to drop", secs(10, 11, 12, 11, 10, 15), 3.5, secs(10, 11, 12, 11, 10, 15)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rejectOutliers(tt.times, tt.threshold)
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("kept %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// builtinVectors is the canonical corpus compiled into the binary: three
+// test cases from gen-testdata -seed builtin and the edge cases of
+// gen-testdata -edge-cases. Regenerate them with
+//
+//	go run . gen-testdata -tests vectors -count 3 -seed builtin
+//	go run . gen-testdata -tests vectors -edge-cases
+//
+//go:embed vectors/*.json
+var builtinVectors embed.FS
+
+var (
+	// command line flags
+	useBuiltinVectors bool
+)
+
+// extractBuiltinVectors points -tests at the embedded corpus, written to a
+// temporary directory since every command reads test cases from files, and
+// returns the function that removes it. File arguments that do not exist but
+// name a vector of the corpus, e.g. test_case_1.json, are taken from it, so
+// the tool runs without any test case on disk.
+func extractBuiltinVectors(args []string) func() {
+	dir, err := os.MkdirTemp("", "builtin-vectors-")
+	if err != nil {
+		fatal("Failed to create directory for the built-in vectors", "error", err)
+	}
+	vectors, err := fs.Sub(builtinVectors, "vectors")
+	if err != nil {
+		fatal("Failed to read the built-in vectors", "error", err)
+	}
+	entries, err := fs.ReadDir(vectors, ".")
+	if err != nil {
+		fatal("Failed to read the built-in vectors", "error", err)
+	}
+	for _, entry := range entries {
+		data, err := fs.ReadFile(vectors, entry.Name())
+		if err != nil {
+			fatal("Failed to read the built-in vectors", "error", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, entry.Name()), data, 0644); err != nil {
+			fatal("Failed to write the built-in vectors", "error", err)
+		}
+	}
+	testsDir = dir
+
+	for i, arg := range args {
+		if _, err := os.Stat(arg); err == nil {
+			continue
+		}
+		if _, err := fs.Stat(vectors, filepath.Base(arg)); err == nil {
+			args[i] = filepath.Join(dir, filepath.Base(arg))
+		}
+	}
+	slog.Info("Using the built-in test vectors", "vectors", len(entries), "dir", testsDir)
+	return func() { os.RemoveAll(dir) }
+}
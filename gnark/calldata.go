@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	fp_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	fp_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	fr_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	groth16_bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/backend/witness"
+	"golang.org/x/crypto/sha3"
+)
+
+// EVM calldata is laid out in 32-byte words after the 4-byte function selector
+const (
+	selectorBytes = 4
+	wordBytes     = 32
+)
+
+// Sizes of the EIP-2537 encodings of BLS12-381 points, which pad every
+// coordinate to 64 bytes
+const (
+	eip2537G1Bytes = 128
+	eip2537G2Bytes = 256
+)
+
+// verifierCalldataBytes is the size of the calldata of a call to the exported
+// Solidity verifier's verifyProof, for artifacts produced with settings and a
+// circuit with publicInputs public inputs. It reports false when there is no
+// Solidity verifier for the settings.
+//
+// gnark's bn254 verifier takes the proof, the commitment and its proof of
+// knowledge, and the inputs as uint256 arrays. The BLS12-381 verifier takes
+// the EIP-2537 encoding of the proof's points as bytes, followed by the inputs.
+// The circuit has one commitment with either range check, since the emulated
+// arithmetic derives the challenge of its multiplication checks from it.
+func verifierCalldataBytes(settings Manifest, publicInputs int) (int, bool) {
+	if settings.Backend != "" && settings.Backend != backendGroth16 {
+		return 0, false
+	}
+	switch settings.Curve {
+	case "bn254":
+		words := 8 + 2 + 2 + publicInputs
+		return selectorBytes + words*wordBytes, true
+	case "bls12-381":
+		proof := 4*eip2537G1Bytes + eip2537G2Bytes
+		// The offset and length of the proof bytes, the inputs, and the proof
+		// padded to whole words
+		words := 2 + publicInputs + (proof+wordBytes-1)/wordBytes
+		return selectorBytes + words*wordBytes, true
+	}
+	return 0, false
+}
+
+// Calldata pricing. EIP-2028 charges 4 gas per zero byte and 16 per other
+// byte. EIP-7623 makes data-heavy transactions pay at least 10 gas per token,
+// counting a zero byte as 1 token and another as 4. A blob (EIP-4844) holds
+// 4096 field elements of 31 usable bytes, each costing 32 blob gas.
+const (
+	zeroByteGas        = 4
+	nonZeroByteGas     = 16
+	floorGasPerToken   = 10
+	nonZeroByteTokens  = 4
+	blobFieldBytes     = 32
+	blobUsableBytes    = 31
+	blobFieldsPerBlob  = 4096
+	blobGasPerBlobByte = 1
+)
+
+// CalldataStats is what passing a proof and its public inputs to the Solidity
+// verifier costs in data, apart from the gas of executing the verifier
+type CalldataStats struct {
+	Bytes     int `json:"bytes"`
+	ZeroBytes int `json:"zero_bytes"`
+	// Gas is the calldata gas under EIP-2028, and FloorGas the minimum a
+	// transaction carrying only this data pays under EIP-7623
+	Gas      int64 `json:"gas"`
+	FloorGas int64 `json:"floor_gas"`
+	// BlobGas is the blob gas of posting the same bytes in a blob instead
+	// (EIP-4844), and BlobShare the fraction of a blob they fill
+	BlobGas   int64   `json:"blob_gas"`
+	BlobShare float64 `json:"blob_share"`
+}
+
+// calldataCost prices calldata under each of the rules above. Blob space is
+// taken in whole field elements.
+func calldataCost(data []byte) CalldataStats {
+	stats := CalldataStats{Bytes: len(data)}
+	for _, b := range data {
+		if b == 0 {
+			stats.ZeroBytes++
+		}
+	}
+	nonZero := int64(len(data) - stats.ZeroBytes)
+	stats.Gas = zeroByteGas*int64(stats.ZeroBytes) + nonZeroByteGas*nonZero
+	stats.FloorGas = floorGasPerToken * (int64(stats.ZeroBytes) + nonZeroByteTokens*nonZero)
+	fields := (len(data) + blobUsableBytes - 1) / blobUsableBytes
+	stats.BlobGas = int64(fields*blobFieldBytes) * blobGasPerBlobByte
+	stats.BlobShare = float64(fields) / blobFieldsPerBlob
+	return stats
+}
+
+// proofCalldata encodes the call to the exported Solidity verifier's
+// verifyProof for a proof, as scripts/benchmark-gas.sh makes it. It reports
+// false when there is no Solidity verifier for the proof's backend and curve.
+func proofCalldata(proof artifact, publicWitness witness.Witness) ([]byte, bool) {
+	var inputs [][32]byte
+	switch v := publicWitness.Vector().(type) {
+	case fr_bn254.Vector:
+		for i := range v {
+			inputs = append(inputs, v[i].Bytes())
+		}
+	case fr_bls12381.Vector:
+		for i := range v {
+			inputs = append(inputs, v[i].Bytes())
+		}
+	default:
+		return nil, false
+	}
+
+	var data []byte
+	word := func(b []byte) {
+		var w [wordBytes]byte
+		copy(w[wordBytes-len(b):], b)
+		data = append(data, w[:]...)
+	}
+	switch p := proof.(type) {
+	case *groth16_bn254.Proof:
+		// G2 coordinates go imaginary part first, as the precompiles take them
+		signature := "verifyProof(uint256[8]"
+		points := []bn254.G1Affine{p.Ar, p.Krs}
+		if len(p.Commitments) > 0 {
+			signature += ",uint256[2],uint256[2]"
+			points = append(points, p.Commitments[0], p.CommitmentPok)
+		}
+		data = append(data, functionSelector(fmt.Sprintf("%s,uint256[%d])", signature, len(inputs)))...)
+		for _, c := range []fp_bn254.Element{p.Ar.X, p.Ar.Y, p.Bs.X.A1, p.Bs.X.A0, p.Bs.Y.A1, p.Bs.Y.A0, p.Krs.X, p.Krs.Y} {
+			b := c.Bytes()
+			word(b[:])
+		}
+		for _, point := range points[2:] {
+			x, y := point.X.Bytes(), point.Y.Bytes()
+			word(x[:])
+			word(y[:])
+		}
+	case *groth16_bls12381.Proof:
+		// The proof is dynamic bytes: its offset comes first, then the inputs,
+		// then its length and contents padded to whole words
+		var encoded []byte
+		coordinate := func(c fp_bls12381.Element) {
+			var padded [eip2537G1Bytes / 2]byte
+			b := c.Bytes()
+			copy(padded[len(padded)-len(b):], b[:])
+			encoded = append(encoded, padded[:]...)
+		}
+		g1 := func(point bls12381.G1Affine) {
+			coordinate(point.X)
+			coordinate(point.Y)
+		}
+		g1(p.Ar)
+		coordinate(p.Bs.X.A0)
+		coordinate(p.Bs.X.A1)
+		coordinate(p.Bs.Y.A0)
+		coordinate(p.Bs.Y.A1)
+		g1(p.Krs)
+		if len(p.Commitments) > 0 {
+			g1(p.Commitments[0])
+			g1(p.CommitmentPok)
+		}
+		data = append(data, functionSelector(fmt.Sprintf("verifyProof(bytes,uint256[%d])", len(inputs)))...)
+		word(big.NewInt(int64((1 + len(inputs)) * wordBytes)).Bytes())
+		for i := range inputs {
+			word(inputs[i][:])
+		}
+		word(big.NewInt(int64(len(encoded))).Bytes())
+		data = append(data, encoded...)
+		if rem := len(encoded) % wordBytes; rem != 0 {
+			data = append(data, make([]byte, wordBytes-rem)...)
+		}
+		return data, true
+	default:
+		return nil, false
+	}
+	for i := range inputs {
+		word(inputs[i][:])
+	}
+	return data, true
+}
+
+// functionSelector is the first 4 bytes of the Keccak-256 of a Solidity
+// function signature
+func functionSelector(signature string) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(signature))
+	return h.Sum(nil)[:selectorBytes]
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCalldataCost(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want CalldataStats
+	}{
+		{"empty", nil, CalldataStats{}},
+		{
+			// 4 gas and 1 token per zero byte; one field element of blob space
+			name: "zero bytes", data: make([]byte, 3),
+			want: CalldataStats{Bytes: 3, ZeroBytes: 3, Gas: 12, FloorGas: 30, BlobGas: 32, BlobShare: 1.0 / 4096},
+		},
+		{
+			// 16 gas and 4 tokens per non-zero byte
+			name: "non-zero bytes", data: []byte{1, 0xff},
+			want: CalldataStats{Bytes: 2, Gas: 32, FloorGas: 80, BlobGas: 32, BlobShare: 1.0 / 4096},
+		},
+		{
+			name: "mixed", data: []byte{0, 1, 0, 2},
+			want: CalldataStats{Bytes: 4, ZeroBytes: 2, Gas: 2*4 + 2*16, FloorGas: 10 * (2 + 2*4), BlobGas: 32, BlobShare: 1.0 / 4096},
+		},
+		{
+			// a field element holds 31 usable bytes, so 32 bytes take two
+			name: "blob field boundary", data: bytes.Repeat([]byte{1}, 32),
+			want: CalldataStats{Bytes: 32, Gas: 32 * 16, FloorGas: 10 * 32 * 4, BlobGas: 64, BlobShare: 2.0 / 4096},
+		},
+		{
+			name: "one field element", data: bytes.Repeat([]byte{1}, 31),
+			want: CalldataStats{Bytes: 31, Gas: 31 * 16, FloorGas: 10 * 31 * 4, BlobGas: 32, BlobShare: 1.0 / 4096},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calldataCost(tt.data); got != tt.want {
+				t.Errorf("calldataCost = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
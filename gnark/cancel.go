@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var (
+	// command line flags
+	phaseTimeout time.Duration
+)
+
+// exitInterrupted is the exit code of a command stopped with Ctrl-C or
+// SIGTERM, as shells report a process killed by SIGINT
+const exitInterrupted = 130
+
+// commandContext is cancelled when the command is interrupted with Ctrl-C or
+// SIGTERM. A second interrupt kills the process as usual.
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// runPhase runs a phase until it returns, the command is interrupted or
+// -timeout passes, and returns the error of the context in the latter cases.
+// gnark cannot stop a proof halfway, so a phase given up on keeps running in
+// the background until the process exits; what it returns is dropped, and
+// nothing it would have written is written.
+func runPhase(ctx context.Context, phase func() error) error {
+	if phaseTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, phaseTimeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() { done <- phase() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// aborted says whether runPhase gave up on a phase
+func aborted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// abortReason describes why runPhase gave up on a phase
+func abortReason(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Sprintf("timed out after %s", phaseTimeout)
+	}
+	return "interrupted"
+}
+
+// recordAbort logs a phase runPhase gave up on and records it in the results,
+// so that an interrupted or timed-out run is told apart from one that was
+// never started
+func recordAbort(phase, testCase string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	attrs := []any{"reason", abortReason(err), "secs", elapsed.Seconds()}
+	if testCase != "" {
+		attrs = append([]any{"test_case", testCase}, attrs...)
+	}
+	slog.Error("Aborted "+phase, attrs...)
+	recordAborted(artifactSettings(), Abort{
+		Phase:       phase,
+		TestCase:    testCase,
+		Reason:      abortReason(err),
+		ElapsedSecs: elapsed.Seconds(),
+	})
+}
+
+// exitAborted ends a command whose phase runPhase gave up on: with
+// exitInterrupted when interrupted, and as an error when timed out
+func exitAborted(err error) {
+	if errors.Is(err, context.Canceled) {
+		os.Exit(exitInterrupted)
+	}
+	os.Exit(1)
+}
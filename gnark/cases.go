@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// command line flags
+	caseRanges string
+)
+
+// testCaseNumber splits the name of a numbered test case into its prefix and
+// number, e.g. test_case_ and 7 for test_case_7.json, or eth_test_case_ and 7
+// for eth_test_case_7.json. Other files, such as edge_case_s_one.json or
+// mutant_test_case_1_r_8.json, have no number.
+var testCaseNumber = regexp.MustCompile(`^((?:[a-z0-9]+_)?test_case_)(\d+)\.json$`)
+
+// expandTestCaseArgs turns the test case arguments of prove and verify into
+// files: a glob such as 'tests/test_case_1*.json', quoted so the shell leaves
+// it alone, becomes the files it matches, and a directory the test cases of
+// the circuit in it
+func expandTestCaseArgs(args []string, pattern string) []string {
+	var files []string
+	for _, arg := range args {
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			matches, err := filepath.Glob(filepath.Join(arg, pattern))
+			if err != nil {
+				fatal("Failed to find test cases", "error", err)
+			}
+			if len(matches) == 0 {
+				fatal("No test cases in directory", "pattern", pattern, "dir", arg)
+			}
+			files = append(files, sortCases(matches)...)
+			continue
+		}
+		if !strings.ContainsAny(arg, "*?[") {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			fatal("Invalid pattern", "pattern", arg, "error", err)
+		}
+		if len(matches) == 0 {
+			fatal("No test cases match", "pattern", arg)
+		}
+		files = append(files, sortCases(matches)...)
+	}
+	return files
+}
+
+// selectCases keeps the test cases whose numbers -cases lists, e.g. 1,3,7-9,
+// in the order of their numbers. A range keeps the test cases it holds, which
+// must be at least one, and a single number must have a test case.
+func selectCases(files []string) []string {
+	ranges, err := parseCaseRanges(caseRanges)
+	if err != nil {
+		fatal("Invalid -cases", "error", err)
+	}
+	byNumber := map[int]string{}
+	var numbers []int
+	for _, file := range files {
+		if match := testCaseNumber.FindStringSubmatch(filepath.Base(file)); match != nil {
+			n, _ := strconv.Atoi(match[2])
+			if other, ok := byNumber[n]; ok {
+				if other != file {
+					fatal("Two test cases have the same number: give files rather than -cases", "file", other, "other", file, "number", n)
+				}
+				continue
+			}
+			byNumber[n] = file
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	// The ranges are matched against the numbers there are, so that a wide
+	// range such as 1-1000000 costs no more than a narrow one
+	found := make([]int, len(ranges))
+	var selected []string
+	for _, n := range numbers {
+		in := false
+		for i, r := range ranges {
+			if r.contains(n) {
+				found[i]++
+				in = true
+			}
+		}
+		if in {
+			selected = append(selected, byNumber[n])
+		}
+	}
+	var missing []string
+	for i, r := range ranges {
+		if found[i] == 0 {
+			missing = append(missing, r.String())
+		}
+	}
+	if len(missing) > 0 {
+		fatal("No test case with these numbers among those to select from", "numbers", strings.Join(missing, ", "), "test_cases", len(files))
+	}
+	return selected
+}
+
+// caseRange is a number or range of -cases, from first to last inclusive
+type caseRange struct {
+	first, last int
+}
+
+func (r caseRange) contains(n int) bool {
+	return r.first <= n && n <= r.last
+}
+
+func (r caseRange) String() string {
+	if r.first == r.last {
+		return strconv.Itoa(r.first)
+	}
+	return fmt.Sprintf("%d-%d", r.first, r.last)
+}
+
+// parseCaseRanges parses a list of numbers and ranges such as 1,3,7-9. The
+// ranges are kept as they are rather than expanded into their numbers.
+func parseCaseRanges(s string) ([]caseRange, error) {
+	var ranges []caseRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("%q is not a test case number or range", part)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil || last < first {
+				return nil, fmt.Errorf("%q is not a range of test case numbers", part)
+			}
+		}
+		ranges = append(ranges, caseRange{first, last})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no test case numbers in %q", s)
+	}
+	return ranges, nil
+}
+
+// sortCases sorts test case files by their numbers, so that test_case_10.json
+// comes after test_case_9.json, and the others by name
+func sortCases(files []string) []string {
+	key := func(file string) (string, int) {
+		dir, base := filepath.Split(file)
+		if match := testCaseNumber.FindStringSubmatch(base); match != nil {
+			n, _ := strconv.Atoi(match[2])
+			return dir + match[1], n
+		}
+		return file, -1
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		prefixI, numberI := key(files[i])
+		prefixJ, numberJ := key(files[j])
+		if prefixI != prefixJ {
+			return prefixI < prefixJ
+		}
+		return numberI < numberJ
+	})
+	return files
+}
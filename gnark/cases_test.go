@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCaseRanges(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []caseRange
+		wantErr bool
+	}{
+		{in: "", wantErr: true},
+		{in: " , ,", wantErr: true},
+		{in: "3", want: []caseRange{{3, 3}}},
+		{in: "1,3,7-9", want: []caseRange{{1, 1}, {3, 3}, {7, 9}}},
+		{in: " 1 , 7 - 9 ,", want: []caseRange{{1, 1}, {7, 9}}},
+		{in: "1-5,3-7", want: []caseRange{{1, 5}, {3, 7}}},
+		{in: "4-4", want: []caseRange{{4, 4}}},
+		{in: "0-9223372036854775807", want: []caseRange{{0, 9223372036854775807}}},
+		{in: "3-1", wantErr: true},
+		{in: "-1", wantErr: true},
+		{in: "1-", wantErr: true},
+		{in: "1-2-3", wantErr: true},
+		{in: "a", wantErr: true},
+		{in: "1,b-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCaseRanges(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseCaseRanges(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCaseRanges(%q): %v", tt.in, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCaseRanges(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSelectCases(t *testing.T) {
+	files := []string{
+		"tests/test_case_10.json",
+		"tests/test_case_2.json",
+		"tests/test_case_1.json",
+		"tests/edge_case_s_one.json",
+		"tests/test_case_3.json",
+	}
+	tests := []struct {
+		cases string
+		want  []string
+	}{
+		{"2", []string{"tests/test_case_2.json"}},
+		{"3,1", []string{"tests/test_case_1.json", "tests/test_case_3.json"}},
+		{"1-3,2-10", []string{"tests/test_case_1.json", "tests/test_case_2.json", "tests/test_case_3.json", "tests/test_case_10.json"}},
+		{"3-1000000000000", []string{"tests/test_case_3.json", "tests/test_case_10.json"}},
+		{"5-20", []string{"tests/test_case_10.json"}},
+	}
+	defer func(saved string) { caseRanges = saved }(caseRanges)
+	for _, tt := range tests {
+		caseRanges = tt.cases
+		if got := selectCases(files); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("-cases %s selected %v, want %v", tt.cases, got, tt.want)
+		}
+	}
+}
+
+func TestTestCaseID(t *testing.T) {
+	tests := []struct {
+		file, want string
+	}{
+		{"tests/test_case_7.json", "7"},
+		{"test_case_10.json", "10"},
+		{"tests/eth_test_case_7.json", "eth_test_case_7"},
+		{"tests/batch_test_case_2.json", "batch_test_case_2"},
+		{"tests/edge_case_s_one.json", "edge_case_s_one"},
+		{"tests/mutant_test_case_1_r_8.json", "mutant_test_case_1_r_8"},
+		{"tests/my_test_case_1.json.bak", "my_test_case_1.json.bak"},
+	}
+	for _, tt := range tests {
+		if got := testCaseID(tt.file); got != tt.want {
+			t.Errorf("testCaseID(%q) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestSortCases(t *testing.T) {
+	files := []string{
+		"tests/test_case_10.json",
+		"tests/mutant_test_case_1_r_8.json",
+		"tests/eth_test_case_2.json",
+		"tests/test_case_9.json",
+		"tests/edge_case_s_one.json",
+		"tests/eth_test_case_10.json",
+		"tests/mutant_test_case_1_r_128.json",
+	}
+	want := []string{
+		"tests/edge_case_s_one.json",
+		"tests/eth_test_case_2.json",
+		"tests/eth_test_case_10.json",
+		"tests/mutant_test_case_1_r_128.json",
+		"tests/mutant_test_case_1_r_8.json",
+		"tests/test_case_9.json",
+		"tests/test_case_10.json",
+	}
+	if got := sortCases(files); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortCases = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/consensys/gnark/test"
+)
+
+// checkWitness runs a test case through gnark's test engine, which executes
+// the circuit directly on the witness values. It needs no compiled circuit or
+// keys, so it gives quick feedback while developing circuit variants.
+func checkWitness(testCaseFile string) {
+	defaultSettings()
+
+	testCase, err := loadTestCase(testCaseFile)
+	if err != nil {
+		fatal("Failed to load test case", "error", err)
+	}
+
+	circuit := selectedCircuit()
+	assignment, err := circuit.Assign(testCase)
+	if err != nil {
+		fatal("Failed to create assignment", "error", err)
+	}
+
+	start := time.Now()
+	err = test.IsSolved(circuit.New(), assignment, selectedCurve().ScalarField())
+	checkTime := time.Since(start)
+	if err != nil {
+		fatal("✗ Witness does not satisfy the circuit", "error", err)
+	}
+
+	fmt.Printf("✓ Witness satisfies the %s circuit for %s (checked in %v)\n", circuitName, filepath.Base(testCaseFile), checkTime)
+}
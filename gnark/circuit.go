@@ -43,3 +43,44 @@ func (circuit *ECDSACircuit) Define(api frontend.API) error {
 
 	return nil
 }
+
+// BatchECDSACircuit verifies several P-256 signatures in one proof, so the
+// fixed cost of a proof is shared between them. The number of signatures is
+// fixed at compile time by the length of Signatures.
+type BatchECDSACircuit struct {
+	Signatures []ECDSACircuit
+}
+
+// Define verifies every signature of the batch as ECDSACircuit does
+func (circuit *BatchECDSACircuit) Define(api frontend.API) error {
+	for i := range circuit.Signatures {
+		if err := circuit.Signatures[i].Define(api); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Secp256k1Circuit verifies an ECDSA signature over secp256k1, the curve
+// Ethereum signs with, as ECDSACircuit does over P-256
+type Secp256k1Circuit struct {
+	R       emulated.Element[emulated.Secp256k1Fr] `gnark:",secret"`
+	S       emulated.Element[emulated.Secp256k1Fr] `gnark:",secret"`
+	MsgHash emulated.Element[emulated.Secp256k1Fr] `gnark:",public"`
+	PubKeyX emulated.Element[emulated.Secp256k1Fp] `gnark:",secret"`
+	PubKeyY emulated.Element[emulated.Secp256k1Fp] `gnark:",secret"`
+}
+
+// Define declares the circuit constraints for ECDSA signature verification
+func (circuit *Secp256k1Circuit) Define(api frontend.API) error {
+	pubKey := ecdsa.PublicKey[emulated.Secp256k1Fp, emulated.Secp256k1Fr]{
+		X: circuit.PubKeyX,
+		Y: circuit.PubKeyY,
+	}
+	sig := ecdsa.Signature[emulated.Secp256k1Fr]{
+		R: circuit.R,
+		S: circuit.S,
+	}
+	pubKey.Verify(api, sw_emulated.GetCurveParams[emulated.Secp256k1Fp](), &circuit.MsgHash, &sig)
+	return nil
+}
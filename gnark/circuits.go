@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/emulated"
+)
+
+// circuitVariant is a circuit the binary compiles, proves and verifies with
+type circuitVariant struct {
+	Description string
+	// TestCases is the file pattern of the test cases in -tests it takes,
+	// for -all
+	TestCases string
+	// New returns the circuit to compile
+	New func() frontend.Circuit
+	// Assign fills the circuit with the values of a test case
+	Assign func(*TestCase) (frontend.Circuit, error)
+}
+
+const defaultCircuit = "p256"
+
+// circuitVariants are the circuits of -circuit. A new variant registers here
+// with its test cases, and the commands that take -circuit handle it. The
+// batched circuit of batch is not one of them: it is compiled for each batch
+// size, from P-256 test cases, as gen-mutants only mutates those.
+var circuitVariants = map[string]circuitVariant{
+	"p256": {
+		Description: "ECDSA over P-256, as WebAuthn and passkeys sign (test_case_*.json)",
+		TestCases:   "test_case_*.json",
+		New:         func() frontend.Circuit { return &ECDSACircuit{} },
+		Assign: func(testCase *TestCase) (frontend.Circuit, error) {
+			assignment, err := createAssignment(testCase)
+			if err != nil {
+				return nil, err
+			}
+			return assignment, nil
+		},
+	},
+	"secp256k1": {
+		Description: "ECDSA over secp256k1, as Ethereum signs (eth_test_case_*.json from gen-eth)",
+		TestCases:   "eth_test_case_*.json",
+		New:         func() frontend.Circuit { return &Secp256k1Circuit{} },
+		Assign:      createSecp256k1Assignment,
+	},
+}
+
+var (
+	// command line flags
+	circuitName string
+)
+
+// selectCircuit checks -circuit and moves every variant but the default into a
+// directory of its own under -d, so that the artifacts, proofs and results of
+// several variants sit side by side, while those of the default stay where
+// they were before there were variants
+func selectCircuit() {
+	if circuitName == "" {
+		return
+	}
+	if _, ok := circuitVariants[circuitName]; !ok {
+		fatal("Unknown -circuit, use one of: "+strings.Join(circuitNames(), ", "), "circuit", circuitName)
+	}
+	outputDir = circuitDir(outputDir, circuitName)
+}
+
+// circuitDir is where the artifacts of a circuit variant go under -d: the
+// default variant's in -d itself, the others' in a subdirectory of it
+func circuitDir(dir, circuit string) string {
+	if circuit == "" || circuit == defaultCircuit {
+		return dir
+	}
+	return filepath.Join(dir, circuit)
+}
+
+// selectedCircuit is the variant of -circuit, or the default
+func selectedCircuit() circuitVariant {
+	if circuitName == "" {
+		return circuitVariants[defaultCircuit]
+	}
+	return circuitVariants[circuitName]
+}
+
+// circuitNames lists the variants of -circuit
+func circuitNames() []string {
+	names := make([]string, 0, len(circuitVariants))
+	for name := range circuitVariants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// createSecp256k1Assignment fills the secp256k1 circuit with the values of a
+// test case
+func createSecp256k1Assignment(testCase *TestCase) (frontend.Circuit, error) {
+	if testCase.Curve != curveSecp256k1 {
+		return nil, fmt.Errorf("the circuit verifies %s signatures, not %s", curveSecp256k1, curveOrDefault(testCase.Curve))
+	}
+	r, s, msgHash, pubKeyX, pubKeyY, err := signatureValues(testCase)
+	if err != nil {
+		return nil, err
+	}
+	return &Secp256k1Circuit{
+		R:       emulated.ValueOf[emulated.Secp256k1Fr](r),
+		S:       emulated.ValueOf[emulated.Secp256k1Fr](s),
+		MsgHash: emulated.ValueOf[emulated.Secp256k1Fr](msgHash),
+		PubKeyX: emulated.ValueOf[emulated.Secp256k1Fp](pubKeyX),
+		PubKeyY: emulated.ValueOf[emulated.Secp256k1Fp](pubKeyY),
+	}, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// command line flags
+	cleanProofs bool
+	cleanKeys   bool
+)
+
+// proofPatterns are the proofs prove, bench and batch-verify write to -d
+var proofPatterns = []string{"proof_*.groth16", "proof_*.plonk", batchFile}
+
+// keyPatterns are the compiled circuit, its keys and what describes them
+var keyPatterns = []string{
+	"circuit.r1cs", "circuit.scs", "proving.key", "verifying.key",
+	manifestFile, constraintProfileFile, insecureSeedMarker,
+}
+
+// outputDirs are the directories of benchmark results and runs under -d
+var outputDirs = []string{"benchmarks", "gas-reports", "matrix", "daemon"}
+
+// cleanArtifacts removes what the commands generated in -d and in the
+// <dir>/<circuit> directories of the circuit variants: the proofs with
+// -proofs, the compiled circuit and keys with -keys, and with -all those and
+// the benchmark results, gas reports, matrix cells and daemon queue too. Only
+// files named as the commands name them are removed, so -d pointed at the
+// wrong directory loses nothing else, and the directory itself is kept. The
+// directory of a variant goes too once -all leaves it empty.
+func cleanArtifacts() {
+	if !cleanProofs && !cleanKeys && !selectAll {
+		fatal("Nothing to clean: give -proofs, -keys or -all")
+	}
+
+	var patterns []string
+	if cleanProofs || selectAll {
+		patterns = append(patterns, proofPatterns...)
+	}
+	if cleanKeys || selectAll {
+		patterns = append(patterns, keyPatterns...)
+	}
+	removed := cleanDir(outputDir, patterns)
+	for _, name := range circuitNames() {
+		if name == defaultCircuit {
+			continue
+		}
+		dir := filepath.Join(outputDir, name)
+		if info, err := os.Lstat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		removed += cleanDir(dir, patterns)
+		if selectAll && os.Remove(dir) == nil {
+			fmt.Printf("  removed %s/\n", dir)
+		}
+	}
+	slog.Info("Artifacts removed", "count", removed, "dir", outputDir)
+}
+
+// cleanDir removes the files of patterns from dir, and with -all the
+// directories of outputDirs, and says how many it removed
+func cleanDir(dir string, patterns []string) int {
+	removed := 0
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			fatal("Failed to find artifacts", "error", err)
+		}
+		for _, file := range files {
+			if info, err := os.Lstat(file); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if err := os.Remove(file); err != nil {
+				fatal("Failed to remove artifact", "error", err)
+			}
+			fmt.Printf("  removed %s\n", file)
+			removed++
+		}
+	}
+	if selectAll {
+		for _, name := range outputDirs {
+			sub := filepath.Join(dir, name)
+			if info, err := os.Lstat(sub); err != nil || !info.IsDir() {
+				continue
+			}
+			if err := os.RemoveAll(sub); err != nil {
+				fatal("Failed to remove directory", "error", err)
+			}
+			fmt.Printf("  removed %s/\n", sub)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,374 @@
+// Command compare_stacks collects the benchmark outputs of every stack in the
+// repository and prints them as one comparison table.
+//
+// Each argument is either a stack's output directory (the directory mounted at
+// /out when running its Docker image) or a performance_data.json file from the
+// EC2 benchmarks, which covers several stacks. Prefix an argument with name=
+// to label it, e.g. "go run ./cmd/compare_stacks gnark=data ../snarkjs/out".
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// schemaVersion is bumped whenever a field of Comparison or StackResult
+// changes meaning or is removed
+const schemaVersion = 1
+
+// Comparison is the normalized document written with -json
+type Comparison struct {
+	SchemaVersion int           `json:"schema_version"`
+	Stacks        []StackResult `json:"stacks"`
+}
+
+// StackResult normalizes one stack's measurements. Times are in seconds and
+// pooled across test cases.
+type StackResult struct {
+	Stack        string   `json:"stack"`
+	Variant      string   `json:"variant,omitempty"`
+	Machine      string   `json:"machine,omitempty"`
+	Source       string   `json:"source"`
+	Witness      *Timing  `json:"witness,omitempty"`
+	Proving      *Timing  `json:"proving,omitempty"`
+	Verification *Timing  `json:"verification,omitempty"`
+	Gas          *GasCost `json:"gas,omitempty"`
+}
+
+// Timing summarizes repeated runs of one phase
+type Timing struct {
+	Runs       int     `json:"runs"`
+	MeanSecs   float64 `json:"mean_secs"`
+	MedianSecs float64 `json:"median_secs"`
+	StdDevSecs float64 `json:"stddev_secs"`
+	MinSecs    float64 `json:"min_secs"`
+	MaxSecs    float64 `json:"max_secs"`
+}
+
+// GasCost summarizes the gas used by the on-chain verifier
+type GasCost struct {
+	Mean int64 `json:"mean"`
+	Min  int64 `json:"min"`
+	Max  int64 `json:"max"`
+}
+
+// hyperfineResults is the --export-json output of hyperfine, which every
+// stack's scripts use to time their phases
+type hyperfineResults struct {
+	Results []struct {
+		Mean  float64   `json:"mean"`
+		Min   float64   `json:"min"`
+		Max   float64   `json:"max"`
+		Times []float64 `json:"times"`
+	} `json:"results"`
+}
+
+// gasResults is the all_gas_data.json written by each stack's benchmark-gas.sh
+type gasResults struct {
+	Results []struct {
+		Mean int64 `json:"mean"`
+		Min  int64 `json:"min"`
+		Max  int64 `json:"max"`
+	} `json:"results"`
+}
+
+// performanceData is the per-instance summary of the EC2 benchmarks
+type performanceData struct {
+	InstanceType string `json:"instance_type"`
+	RawData      map[string]struct {
+		ProvingTimes []float64 `json:"proving_times"`
+		GasCosts     []int64   `json:"gas_costs"`
+	} `json:"raw_data"`
+}
+
+func main() {
+	jsonOut := flag.String("json", "", "Also write the normalized results to this file")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("Usage: go run ./cmd/compare_stacks [-json file] [name=]<output dir or performance_data.json>...")
+	}
+
+	var stacks []StackResult
+	for _, arg := range flag.Args() {
+		name, path := "", arg
+		if i := strings.Index(arg, "="); i > 0 {
+			name, path = arg[:i], arg[i+1:]
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Fatal("Failed to read results:", err)
+		}
+		if info.IsDir() {
+			stacks = append(stacks, loadOutputDir(name, path))
+		} else {
+			stacks = append(stacks, loadPerformanceData(name, path)...)
+		}
+	}
+
+	printTable(stacks)
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(Comparison{SchemaVersion: schemaVersion, Stacks: stacks}, "", "  ")
+		if err != nil {
+			log.Fatal("Failed to encode comparison:", err)
+		}
+		if err := os.WriteFile(*jsonOut, append(data, '\n'), 0644); err != nil {
+			log.Fatal("Failed to write comparison:", err)
+		}
+		fmt.Printf("\nNormalized results saved to %s\n", *jsonOut)
+	}
+}
+
+// loadOutputDir reads the hyperfine and gas results a stack's scripts leave in
+// its output directory. The stack is named after the directory unless given.
+func loadOutputDir(name, dir string) StackResult {
+	if name == "" {
+		name = filepath.Base(filepath.Clean(dir))
+	}
+	result := StackResult{Stack: name, Source: dir}
+
+	benchmarks := filepath.Join(dir, "benchmarks")
+	result.Witness = loadHyperfine(filepath.Join(benchmarks, "all_witnesses_benchmark.json"))
+	result.Proving = loadHyperfine(filepath.Join(benchmarks, "all_proofs_benchmark.json"))
+	result.Verification = loadHyperfine(filepath.Join(benchmarks, "all_verifications_benchmark.json"))
+	result.Gas = loadGas(filepath.Join(dir, "gas-reports", "reports", "all_gas_data.json"))
+
+	// gnark runs several backends and curves; say which one this is
+	if settings, ok := loadGnarkSettings(dir); ok {
+		result.Variant = fmt.Sprintf("%s/%s/%s", settings.Backend, settings.Curve, settings.RangeCheck)
+		if settings.Circuit != "" && settings.Circuit != "p256" {
+			result.Variant = settings.Circuit + " " + result.Variant
+		}
+	}
+
+	if result.Witness == nil && result.Proving == nil && result.Verification == nil && result.Gas == nil {
+		log.Fatalf("No benchmark results found in %s", dir)
+	}
+	return result
+}
+
+// loadPerformanceData reads every stack from an EC2 performance_data.json
+func loadPerformanceData(name, path string) []StackResult {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal("Failed to read performance data:", err)
+	}
+	var perf performanceData
+	if err := json.Unmarshal(data, &perf); err != nil {
+		log.Fatalf("Invalid performance data %s: %v", path, err)
+	}
+	machine := perf.InstanceType
+	if name != "" {
+		machine = name
+	}
+
+	suites := make([]string, 0, len(perf.RawData))
+	for suite := range perf.RawData {
+		suites = append(suites, suite)
+	}
+	sort.Strings(suites)
+
+	var stacks []StackResult
+	for _, suite := range suites {
+		raw := perf.RawData[suite]
+		result := StackResult{Stack: suite, Machine: machine, Source: path}
+		if len(raw.ProvingTimes) > 0 {
+			result.Proving = summarize(raw.ProvingTimes)
+		}
+		if len(raw.GasCosts) > 0 {
+			result.Gas = summarizeGas(raw.GasCosts)
+		}
+		stacks = append(stacks, result)
+	}
+	return stacks
+}
+
+func loadHyperfine(path string) *Timing {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Failed to read hyperfine results:", err)
+	}
+	var results hyperfineResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		log.Fatalf("Invalid hyperfine results %s: %v", path, err)
+	}
+
+	var times []float64
+	for _, r := range results.Results {
+		if len(r.Times) > 0 {
+			times = append(times, r.Times...)
+		} else {
+			times = append(times, r.Mean)
+		}
+	}
+	if len(times) == 0 {
+		return nil
+	}
+	return summarize(times)
+}
+
+func loadGas(path string) *GasCost {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Fatal("Failed to read gas results:", err)
+	}
+	var results gasResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		log.Fatalf("Invalid gas results %s: %v", path, err)
+	}
+	if len(results.Results) == 0 {
+		return nil
+	}
+
+	gas := &GasCost{Min: results.Results[0].Min, Max: results.Results[0].Max}
+	var sum int64
+	for _, r := range results.Results {
+		sum += r.Mean
+		if r.Min < gas.Min {
+			gas.Min = r.Min
+		}
+		if r.Max > gas.Max {
+			gas.Max = r.Max
+		}
+	}
+	gas.Mean = sum / int64(len(results.Results))
+	return gas
+}
+
+// loadGnarkSettings reads the settings that tell gnark's variants apart from
+// its results.json, or else its manifest.json
+func loadGnarkSettings(dir string) (manifest.Manifest, bool) {
+	var settings manifest.Manifest
+	if data, err := os.ReadFile(filepath.Join(dir, "benchmarks", "results.json")); err == nil {
+		var results struct {
+			Settings manifest.Manifest `json:"settings"`
+		}
+		if json.Unmarshal(data, &results) == nil {
+			settings = results.Settings
+		}
+	} else if contents, err := manifest.Read(dir); err == nil && contents != nil {
+		settings = contents.Manifest
+	} else {
+		return settings, false
+	}
+
+	if settings.Backend == "" {
+		settings.Backend = "groth16"
+	}
+	if settings.Curve == "" {
+		settings.Curve = "bn254"
+	}
+	if settings.RangeCheck == "" {
+		settings.RangeCheck = "lookup"
+	}
+	return settings, true
+}
+
+// summarize computes the statistics of a set of run times. The standard
+// deviation is the sample one.
+func summarize(times []float64) *Timing {
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	var sum float64
+	for _, t := range sorted {
+		sum += t
+	}
+	mean := sum / float64(n)
+
+	var variance float64
+	if n > 1 {
+		for _, t := range sorted {
+			variance += (t - mean) * (t - mean)
+		}
+		variance /= float64(n - 1)
+	}
+
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+
+	return &Timing{
+		Runs:       n,
+		MeanSecs:   mean,
+		MedianSecs: median,
+		StdDevSecs: math.Sqrt(variance),
+		MinSecs:    sorted[0],
+		MaxSecs:    sorted[n-1],
+	}
+}
+
+func summarizeGas(costs []int64) *GasCost {
+	gas := &GasCost{Min: costs[0], Max: costs[0]}
+	var sum int64
+	for _, c := range costs {
+		sum += c
+		if c < gas.Min {
+			gas.Min = c
+		}
+		if c > gas.Max {
+			gas.Max = c
+		}
+	}
+	gas.Mean = sum / int64(len(costs))
+	return gas
+}
+
+// printTable prints the stacks as a Markdown table
+func printTable(stacks []StackResult) {
+	fmt.Println("| Stack | Variant | Machine | Witness | Proving | Proving stddev | Verification | Verifier gas |")
+	fmt.Println("|---|---|---|---:|---:|---:|---:|---:|")
+	for _, s := range stacks {
+		gas := "-"
+		if s.Gas != nil {
+			gas = fmt.Sprint(s.Gas.Mean)
+		}
+		stddev := "-"
+		if s.Proving != nil {
+			stddev = formatSecs(s.Proving.StdDevSecs)
+		}
+		fmt.Printf("| %s | %s | %s | %s | %s | %s | %s | %s |\n", s.Stack, orDash(s.Variant), orDash(s.Machine),
+			formatTiming(s.Witness), formatTiming(s.Proving), stddev, formatTiming(s.Verification), gas)
+	}
+}
+
+func formatTiming(t *Timing) string {
+	if t == nil {
+		return "-"
+	}
+	return formatSecs(t.MeanSecs)
+}
+
+// formatSecs prints a duration in seconds, switching to milliseconds below one
+// second
+func formatSecs(secs float64) string {
+	if secs < 1 {
+		return fmt.Sprintf("%.2fms", secs*1000)
+	}
+	return fmt.Sprintf("%.3fs", secs)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	curve "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	fr_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+)
+
+// printBLS12381Test prints a Foundry test calling the EIP-2537 verifier with a
+// BLS12-381 proof. The proof is passed as the EIP-2537 encoding of its points.
+func printBLS12381Test(testCaseNum string, testCase *TestCase, proofFile string) {
+	proof := new(groth16_bls12381.Proof)
+	f, err := os.Open(proofFile)
+	if err != nil {
+		log.Fatal("Failed to open proof file:", err)
+	}
+	defer f.Close()
+
+	_, err = proof.ReadFrom(f)
+	if err != nil {
+		log.Fatal("Failed to read proof:", err)
+	}
+
+	witness, err := createWitness(testCase, ecc.BLS12_381)
+	if err != nil {
+		log.Fatal("Failed to create witness:", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		log.Fatal("Failed to extract public witness:", err)
+	}
+
+	publicValues, ok := publicWitness.Vector().(fr_bls12381.Vector)
+	if !ok {
+		log.Fatal("Failed to extract public values from witness")
+	}
+
+	var proofBytes []byte
+	proofBytes = append(proofBytes, eip2537G1(&proof.Ar)...)
+	proofBytes = append(proofBytes, eip2537G2(&proof.Bs)...)
+	proofBytes = append(proofBytes, eip2537G1(&proof.Krs)...)
+	if len(proof.Commitments) > 0 {
+		proofBytes = append(proofBytes, eip2537G1(&proof.Commitments[0])...)
+		proofBytes = append(proofBytes, eip2537G1(&proof.CommitmentPok)...)
+	}
+
+	templateData := struct {
+		TestCaseNum  string
+		Proof        string
+		NbPublic     int
+		PublicInputs []string
+	}{
+		TestCaseNum: testCaseNum,
+		Proof:       hex.EncodeToString(proofBytes),
+		NbPublic:    len(publicValues),
+	}
+	for i := range publicValues {
+		templateData.PublicInputs = append(templateData.PublicInputs, publicValues[i].Text(16))
+	}
+
+	const solTemplate = `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import "forge-std/Test.sol";
+import "../src/GasTest.sol";
+
+contract GasTestTest is Test {
+    GasTest gasTest;
+
+    function setUp() public {
+        gasTest = new GasTest();
+    }
+
+    function testVerifyProof{{.TestCaseNum}}() public {
+        // A, B, C, commitment, commitment PoK (EIP-2537 encoding)
+        bytes memory proof = hex"{{.Proof}}";
+
+        uint256[{{.NbPublic}}] memory inputArr;
+{{range $i, $val := .PublicInputs}}
+        inputArr[{{$i}}] = 0x{{$val}};
+{{end}}
+        gasTest.verifyProof(proof, inputArr);
+    }
+}
+`
+
+	tmpl, err := template.New("solidityTest").Parse(solTemplate)
+	if err != nil {
+		log.Fatalf("failed to parse template: %v", err)
+	}
+
+	var buf strings.Builder
+	err = tmpl.Execute(&buf, templateData)
+	if err != nil {
+		log.Fatalf("failed to execute template: %v", err)
+	}
+
+	fmt.Println(buf.String())
+}
+
+// eip2537Fp pads a base field element to the 64-byte EIP-2537 encoding
+func eip2537Fp(e *fp.Element) []byte {
+	res := make([]byte, 64)
+	b := e.Bytes()
+	copy(res[64-fp.Bytes:], b[:])
+	return res
+}
+
+func eip2537G1(p *curve.G1Affine) []byte {
+	return append(eip2537Fp(&p.X), eip2537Fp(&p.Y)...)
+}
+
+func eip2537G2(p *curve.G2Affine) []byte {
+	res := append(eip2537Fp(&p.X.A0), eip2537Fp(&p.X.A1)...)
+	res = append(res, eip2537Fp(&p.Y.A0)...)
+	return append(res, eip2537Fp(&p.Y.A1)...)
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"math/big"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"text/template"
@@ -19,6 +20,7 @@ import (
 	"github.com/consensys/gnark/std/algebra/emulated/sw_emulated"
 	"github.com/consensys/gnark/std/math/emulated"
 	"github.com/consensys/gnark/std/signature/ecdsa"
+	"gnark-ecdsa-benchmark/internal/manifest"
 )
 
 const numPublicInputs = 4
@@ -82,6 +84,25 @@ func main() {
 		log.Fatal("Failed to parse test case:", err)
 	}
 
+	// The proving curve and circuit are recorded in the manifest next to the
+	// proof. The public inputs written here are those of the p256 circuit.
+	contents, err := manifest.Read(filepath.Dir(proofFile))
+	if err != nil {
+		log.Fatal("Failed to read manifest.json:", err)
+	}
+	if contents != nil {
+		if contents.Circuit != "" && contents.Circuit != "p256" {
+			log.Fatalf("Proofs of the %s circuit are not supported, only p256", contents.Circuit)
+		}
+		if contents.Backend != "" && contents.Backend != "groth16" {
+			log.Fatalf("No Solidity test data for the %s backend (use groth16)", contents.Backend)
+		}
+		if contents.Curve == "bls12-381" {
+			printBLS12381Test(testCaseNum, &testCase, proofFile)
+			return
+		}
+	}
+
 	// Load the existing valid proof from the .groth16 file
 	proof := groth16.NewProof(ecc.BN254)
 	f, err := os.Open(proofFile)
@@ -96,7 +117,7 @@ func main() {
 	}
 
 	// Create witness to get public inputs
-	witness, err := createWitness(&testCase)
+	witness, err := createWitness(&testCase, ecc.BN254)
 	if err != nil {
 		log.Fatal("Failed to create witness:", err)
 	}
@@ -455,7 +476,7 @@ func formatFieldElement(s string) string {
 	return hex
 }
 
-func createWitness(testCase *TestCase) (witness.Witness, error) {
+func createWitness(testCase *TestCase, curveID ecc.ID) (witness.Witness, error) {
 	// Parse hex strings to big integers
 	r, err := parseHexToBigInt(testCase.R)
 	if err != nil {
@@ -492,7 +513,7 @@ func createWitness(testCase *TestCase) (witness.Witness, error) {
 	}
 
 	// Create witness
-	witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	witness, err := frontend.NewWitness(&assignment, curveID.ScalarField())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	curve "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
+	"gnark-ecdsa-benchmark/internal/gnarkcompat"
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// exportBLS12381Solidity writes a Groth16 verifier for a BLS12-381 verifying
+// key. gnark only exports Solidity for BN254, so this template targets the
+// EIP-2537 precompiles available since the Prague/Pectra upgrade instead.
+//
+// The proof is passed as the EIP-2537 encoding of A, B, C, the Pedersen
+// commitment and its proof of knowledge, concatenated.
+func exportBLS12381Solidity(vk *groth16_bls12381.VerifyingKey, hashToField string, w io.Writer) error {
+	if len(vk.CommitmentKeys) > 1 {
+		return fmt.Errorf("%d Pedersen commitments are not supported, at most 1", len(vk.CommitmentKeys))
+	}
+
+	var hashFn string
+	switch hashToField {
+	case manifest.HashToFieldSHA256:
+		hashFn = "sha256"
+	case manifest.HashToFieldKeccak256:
+		hashFn = "keccak256"
+	default:
+		return fmt.Errorf("hash-to-field function %q is not supported by the Solidity verifier (use sha256 or keccak256)", hashToField)
+	}
+
+	nbCommitments := len(vk.CommitmentKeys)
+	nbPublic := len(vk.G1.K) - nbCommitments - 1
+	if nbPublic < 0 {
+		return errors.New("invalid verifying key")
+	}
+
+	data := struct {
+		HashFn            string
+		NbPublic          int
+		HasCommitment     bool
+		Committed         []int
+		ProofSize         int
+		Alpha             string
+		BetaNeg           string
+		GammaNeg          string
+		DeltaNeg          string
+		K                 []string
+		PedersenG         string
+		PedersenGSigmaNeg string
+	}{
+		HashFn:        hashFn,
+		NbPublic:      nbPublic,
+		HasCommitment: nbCommitments == 1,
+		ProofSize:     4*g1Size + g2Size,
+		Alpha:         g1Hex(&vk.G1.Alpha),
+		BetaNeg:       g2Hex(new(curve.G2Affine).Neg(&vk.G2.Beta)),
+		GammaNeg:      g2Hex(new(curve.G2Affine).Neg(&vk.G2.Gamma)),
+		DeltaNeg:      g2Hex(new(curve.G2Affine).Neg(&vk.G2.Delta)),
+	}
+	if nbCommitments == 0 {
+		data.ProofSize = 2*g1Size + g2Size
+	}
+	for i := range vk.G1.K {
+		data.K = append(data.K, g1Hex(&vk.G1.K[i]))
+	}
+	if data.HasCommitment {
+		// committed public inputs are 1-indexed, index 0 being the constant wire
+		for _, j := range vk.PublicAndCommitmentCommitted[0] {
+			data.Committed = append(data.Committed, j-1)
+		}
+		data.PedersenG = g2Hex(&vk.CommitmentKeys[0].G)
+		gSigmaNeg := gnarkcompat.PedersenGSigmaNegBLS12381(&vk.CommitmentKeys[0])
+		data.PedersenGSigmaNeg = g2Hex(&gSigmaNeg)
+	}
+
+	tmpl, err := template.New("verifier").Funcs(template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+		"iterate": func(n int) []int {
+			res := make([]int, n)
+			for i := range res {
+				res[i] = i
+			}
+			return res
+		},
+	}).Parse(bls12381VerifierTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, data)
+}
+
+// EIP-2537 encodes a base field element as 64 big-endian bytes, the top 16 of
+// which are zero, and a point as its coordinates in order (c0 before c1).
+const (
+	fpSize = 64
+	g1Size = 2 * fpSize
+	g2Size = 4 * fpSize
+)
+
+func fpBytes(e *fp.Element) []byte {
+	res := make([]byte, fpSize)
+	b := e.Bytes()
+	copy(res[fpSize-fp.Bytes:], b[:])
+	return res
+}
+
+func g1Hex(p *curve.G1Affine) string {
+	var res []byte
+	res = append(res, fpBytes(&p.X)...)
+	res = append(res, fpBytes(&p.Y)...)
+	return hex.EncodeToString(res)
+}
+
+func g2Hex(p *curve.G2Affine) string {
+	var res []byte
+	res = append(res, fpBytes(&p.X.A0)...)
+	res = append(res, fpBytes(&p.X.A1)...)
+	res = append(res, fpBytes(&p.Y.A0)...)
+	res = append(res, fpBytes(&p.Y.A1)...)
+	return hex.EncodeToString(res)
+}
+
+const bls12381VerifierTemplate = `// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+/// @title Groth16 verifier for BLS12-381
+/// @notice Uses the EIP-2537 precompiles, so it only runs on chains that have
+/// activated the Prague/Pectra upgrade.
+/// @dev The proof is the EIP-2537 encoding of A (G1), B (G2), C (G1){{if .HasCommitment}},
+/// the Pedersen commitment (G1) and its proof of knowledge (G1){{end}}, concatenated.
+contract Verifier {
+    /// The proof is invalid.
+    error ProofInvalid();
+
+    /// A public input is not a scalar field element.
+    error PublicInputNotInField();
+
+    // Scalar field modulus
+    uint256 constant R = 0x73eda753299d7d483339d80809a1d80553bda402fffe5bfeffffffff00000001;
+
+    // EIP-2537 precompiles
+    address constant BLS12_G1MSM = address(0x0c);
+    address constant BLS12_PAIRING_CHECK = address(0x0f);
+
+    uint256 constant PROOF_SIZE = {{.ProofSize}};
+
+    // Verifying key
+    bytes constant ALPHA = hex"{{.Alpha}}";
+    bytes constant BETA_NEG = hex"{{.BetaNeg}}";
+    bytes constant GAMMA_NEG = hex"{{.GammaNeg}}";
+    bytes constant DELTA_NEG = hex"{{.DeltaNeg}}";
+{{- range $i, $k := .K}}
+    bytes constant K{{$i}} = hex"{{$k}}";
+{{- end}}
+{{- if .HasCommitment}}
+
+    // Pedersen commitment key
+    bytes constant PEDERSEN_G = hex"{{.PedersenG}}";
+    bytes constant PEDERSEN_G_SIGMA_NEG = hex"{{.PedersenGSigmaNeg}}";
+{{- end}}
+
+    /// Verify a Groth16 proof, reverting with ProofInvalid if it does not hold.
+    /// @param proof the EIP-2537 encoded proof points.
+    /// @param input the public inputs.
+    function verifyProof(bytes calldata proof, uint256[{{.NbPublic}}] calldata input) public view {
+        if (proof.length != PROOF_SIZE) {
+            revert ProofInvalid();
+        }
+        for (uint256 i = 0; i < input.length; i++) {
+            if (input[i] >= R) {
+                revert PublicInputNotInField();
+            }
+        }
+{{- if .HasCommitment}}
+
+        // Proof of knowledge of the commitment opening:
+        // e(D, -[σ]₂) · e(PoK, G₂) == 1
+        if (!pairingCheck(abi.encodePacked(
+            proof[512:640], PEDERSEN_G_SIGMA_NEG,
+            proof[640:768], PEDERSEN_G
+        ))) {
+            revert ProofInvalid();
+        }
+
+        // Hash of the commitment, with gnark's uncompressed point encoding
+        uint256 commitmentHash = uint256({{.HashFn}}(abi.encodePacked(
+            proof[528:576], proof[592:640]{{range .Committed}}, input[{{.}}]{{end}}
+        ))) % R;
+{{- end}}
+
+        // L = K₀ + Σ inputᵢ·Kᵢ₊₁{{if .HasCommitment}} + hash·Kₙ₊₁ + D{{end}}
+        bytes memory l = g1MSM(abi.encodePacked(
+            K0, uint256(1){{range $i := .NbPublic | iterate}},
+            K{{add $i 1}}, input[{{$i}}]{{end}}{{if .HasCommitment}},
+            K{{add .NbPublic 1}}, commitmentHash,
+            proof[512:640], uint256(1){{end}}
+        ));
+
+        // e(A, B) · e(C, -[δ]₂) · e(α, -[β]₂) · e(L, -[γ]₂) == 1
+        if (!pairingCheck(abi.encodePacked(
+            proof[0:384],
+            proof[384:512], DELTA_NEG,
+            ALPHA, BETA_NEG,
+            l, GAMMA_NEG
+        ))) {
+            revert ProofInvalid();
+        }
+    }
+
+    function g1MSM(bytes memory input) internal view returns (bytes memory) {
+        (bool success, bytes memory output) = BLS12_G1MSM.staticcall(input);
+        if (!success || output.length != 128) {
+            revert ProofInvalid();
+        }
+        return output;
+    }
+
+    function pairingCheck(bytes memory input) internal view returns (bool) {
+        (bool success, bytes memory output) = BLS12_PAIRING_CHECK.staticcall(input);
+        if (!success || output.length != 32) {
+            revert ProofInvalid();
+        }
+        return abi.decode(output, (uint256)) == 1;
+    }
+}
+`
@@ -1,19 +1,78 @@
 package main
 
 import (
-	"crypto/sha256"
+	"flag"
 	"log"
 	"os"
+	"path/filepath"
 
 	"github.com/consensys/gnark-crypto/ecc"
 	"github.com/consensys/gnark/backend/groth16"
+	groth16_bls12381 "github.com/consensys/gnark/backend/groth16/bls12-381"
 	"github.com/consensys/gnark/backend/solidity"
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+var (
+	// command line flags
+	dataDir string
+	outDir  string
 )
 
 func main() {
+	flag.StringVar(&dataDir, "data-dir", "data", "Directory of the verifying key and manifest.json, as -d of the benchmark")
+	flag.StringVar(&outDir, "out", "src", "Directory to write Groth16Verifier.sol to")
+	flag.Parse()
+
+	// The verifier must use the same hash-to-field function as the prover
+	hashToField := manifest.HashToFieldSHA256
+	curveName := "bn254"
+	contents, err := manifest.Read(dataDir)
+	if err != nil {
+		log.Fatal("Failed to read manifest.json:", err)
+	}
+	if contents != nil {
+		if contents.HashToField != "" {
+			hashToField = contents.HashToField
+		}
+		if contents.Curve != "" {
+			curveName = contents.Curve
+		}
+		if contents.Backend != "" && contents.Backend != "groth16" {
+			log.Fatalf("No Solidity verifier for the %s backend (use groth16)", contents.Backend)
+		}
+		// A verifier for a key of another compile run would reject every proof
+		if recorded, ok := contents.Artifacts["verifying.key"]; ok {
+			hash, err := manifest.HashFile(filepath.Join(dataDir, "verifying.key"))
+			if err != nil {
+				log.Fatal("Failed to hash verifying key:", err)
+			}
+			if hash != recorded {
+				log.Fatalf("verifying.key has SHA-256 %s but manifest.json records %s; recompile", hash, recorded)
+			}
+		}
+	}
+
+	// BLS12-381 verifiers target the EIP-2537 precompiles instead of gnark's export
+	if curveName == "bls12-381" {
+		generateBLS12381Verifier(hashToField)
+		return
+	}
+	if curveName != "bn254" {
+		log.Fatalf("No Solidity verifier for curve %s (use bn254 or bls12-381)", curveName)
+	}
+
+	hashFn, err := manifest.NewHashToField(hashToField)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if hashFn == nil {
+		log.Fatalf("Hash-to-field function %q is not supported by the Solidity verifier (use sha256 or keccak256)", hashToField)
+	}
+
 	// Load verifying key generated during setup step
 	vk := groth16.NewVerifyingKey(ecc.BN254)
-	file, err := os.Open("/out/verifying.key")
+	file, err := os.Open(filepath.Join(dataDir, "verifying.key"))
 	if err != nil {
 		log.Fatal("Failed to open verifying.key:", err)
 	}
@@ -23,24 +82,55 @@ func main() {
 		log.Fatal("Failed to read verifying key:", err)
 	}
 
-	// Ensure src directory exists
-	err = os.MkdirAll("src", 0755)
+	// Ensure the output directory exists
+	err = os.MkdirAll(outDir, 0755)
 	if err != nil {
 		log.Fatal("Failed to create src directory:", err)
 	}
 
 	// Create output file for the Solidity verifier
-	solidityFile, err := os.Create("src/Groth16Verifier.sol")
+	solidityFile, err := os.Create(filepath.Join(outDir, "Groth16Verifier.sol"))
 	if err != nil {
 		log.Fatal("Failed to create Solidity verifier file:", err)
 	}
 	defer solidityFile.Close()
 
 	// Use gnark's built-in ExportSolidity method to generate the proper verifier
-	err = vk.ExportSolidity(solidityFile, solidity.WithHashToFieldFunction(sha256.New()))
+	err = vk.ExportSolidity(solidityFile, solidity.WithHashToFieldFunction(hashFn))
+	if err != nil {
+		log.Fatal("Failed to export Solidity verifier:", err)
+	}
+
+	log.Printf("✓ Solidity verifier generated successfully (hash-to-field: %s)", hashToField)
+}
+
+func generateBLS12381Verifier(hashToField string) {
+	vk := new(groth16_bls12381.VerifyingKey)
+	file, err := os.Open(filepath.Join(dataDir, "verifying.key"))
+	if err != nil {
+		log.Fatal("Failed to open verifying.key:", err)
+	}
+	_, err = vk.ReadFrom(file)
+	file.Close()
+	if err != nil {
+		log.Fatal("Failed to read verifying key:", err)
+	}
+
+	err = os.MkdirAll(outDir, 0755)
+	if err != nil {
+		log.Fatal("Failed to create src directory:", err)
+	}
+
+	solidityFile, err := os.Create(filepath.Join(outDir, "Groth16Verifier.sol"))
+	if err != nil {
+		log.Fatal("Failed to create Solidity verifier file:", err)
+	}
+	defer solidityFile.Close()
+
+	err = exportBLS12381Solidity(vk, hashToField, solidityFile)
 	if err != nil {
 		log.Fatal("Failed to export Solidity verifier:", err)
 	}
 
-	log.Println("✓ Solidity verifier generated successfully")
+	log.Printf("✓ EIP-2537 Solidity verifier for BLS12-381 generated successfully (hash-to-field: %s)", hashToField)
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// commandInfo describes a command for the help and for checking its
+// options and arguments. Args is what follows the options: <x> is required,
+// [<x>] optional, and ... repeats. Flags are the options it takes besides
+// commonFlags.
+type commandInfo struct {
+	Name    string
+	Args    string
+	Summary string
+	Flags   []string
+}
+
+// commonFlags are the options of every command
+var commonFlags = []string{"log-level", "log-format", "profile", "config", "d"}
+
+// settingsFlags choose the backend, curve, range checks and hashes of the
+// circuit: those compile sets up, or that the artifacts in -d must have
+var settingsFlags = []string{"backend", "range-check", "curve", "hash-to-field", "challenge-hash"}
+
+// proverFlags tune the prover of the commands that prove
+var proverFlags = []string{"gpu", "solver-tasks", "statistical-zk"}
+
+// commands are the commands in the order the help lists them
+var commands = []commandInfo{
+	{"gen-testdata", "", "Generate P-256 test cases, edge cases (-edge-cases) or batches (-batch) into -tests", []string{"tests", "count", "seed", "edge-cases", "batch", "batch-keys", "batch-invalid", "message"}},
+	{"gen-mutants", "[<test case>...]", "Write bit-flipped variants of valid P-256 test cases for soundness testing", []string{"tests", "builtin-vectors", "bits"}},
+	{"gen-eth", "", "Generate secp256k1 test cases signed as Ethereum signs", []string{"tests", "count", "seed", "message", "key", "password-file"}},
+	{"gen-eth-message", "<message.yaml>...", "Sign EIP-191 and EIP-712 messages into secp256k1 test cases", []string{"tests", "seed", "key", "password-file"}},
+	{"gen-eth-tx", "<transaction hash>...", "Take test cases from on-chain Ethereum transactions fetched from -rpc", []string{"tests", "rpc"}},
+	{"wycheproof", "<file or URL>...", "Import Wycheproof ECDSA vectors", []string{"tests"}},
+	{"webauthn", "<assertion.json>...", "Convert WebAuthn assertions into test cases", []string{"tests"}},
+	{"fido2", "", "Sign with a FIDO2 security key through libfido2", []string{"tests", "count", "authenticator"}},
+	{"hwkey", "<capture.json>...", "Import signatures captured from Secure Enclave and Android Keystore keys", []string{"tests"}},
+	{"from-key", "<message file>", "Sign a message with -key, or take -signature for a public key", []string{"tests", "key", "signature"}},
+	{"convert", "<test case>...", "Convert test cases between the gnark, circom and Noir formats", []string{"tests", "builtin-vectors", "to", "out"}},
+	{"compile", "", "Compile the circuit and run the setup into -d", slices.Concat(settingsFlags, []string{"srs", "circuit", "profile-constraints", "progress-interval", "cpuprofile", "sample-resources", "history", "entropy", "entropy-file", "insecure-seed"})},
+	{"prove", "[<test case>...]", "Prove one test case with its statistics, or several, -all or -tag as a batch", slices.Concat(settingsFlags, proverFlags, []string{"srs", "circuit", "tests", "builtin-vectors", "all", "cases", "tag", "stdin", "proof-format", "dry-run", "timeout", "progress-interval", "fail-fast", "keep-going", "skip-existing", "overwrite", "ignore-hashes", "cpus", "cpu-quota", "device", "metrics-addr", "energy", "cpuprofile", "sample-resources", "prover-stages", "memprofile", "history", "insecure-seed"})},
+	{"verify", "[<test case>...]", "Verify the proof of one test case, or of several, -all or -tag as a batch", slices.Concat(settingsFlags, []string{"circuit", "tests", "builtin-vectors", "all", "cases", "tag", "timeout", "progress-interval", "fail-fast", "keep-going", "ignore-hashes", "metrics-addr", "cpuprofile", "sample-resources", "memprofile", "history"})},
+	{"prove-and-verify", "[<test case>...]", "Prove test cases and verify each proof at once, from keys loaded once, reporting both times and the proof size", slices.Concat(settingsFlags, proverFlags, []string{"circuit", "tests", "builtin-vectors", "all", "cases", "tag", "timeout", "progress-interval", "fail-fast", "keep-going", "ignore-hashes", "cpus", "cpu-quota", "device", "metrics-addr", "history", "insecure-seed"})},
+	{"export", "", "Write the Solidity verifier of the verifying key in -d", slices.Concat(settingsFlags, []string{"circuit", "ignore-hashes", "out"})},
+	{"doctor", "", "Check the artifacts in -d and the directories the commands write to, and say how to fix them", slices.Concat(settingsFlags, proverFlags, []string{"circuit", "tests"})},
+	{"clean", "", "Remove the proofs (-proofs), compiled circuit and keys (-keys), or every artifact (-all) from -d", []string{"all", "keys", "proofs"}},
+	{"check", "<test case>", "Check a test case against the circuit with the test engine", slices.Concat(settingsFlags, []string{"circuit", "builtin-vectors"})},
+	{"solve", "<test case>", "Solve the witness of a test case and time it", slices.Concat(settingsFlags, []string{"circuit", "builtin-vectors", "solver-tasks", "history"})},
+	{"negative", "[<test case>...]", "Prove every test case and check it is accepted or rejected as it expects", slices.Concat(settingsFlags, proverFlags, []string{"srs", "circuit", "tests", "builtin-vectors", "tag"})},
+	{"determinism", "<test case>", "Check that proving a test case gives the same result every time", slices.Concat(settingsFlags, proverFlags, []string{"circuit", "builtin-vectors", "variance-threshold", "insecure-seed"})},
+	{"bench", "[<test case>...]", "Benchmark compile, setup, proving and verification", slices.Concat(settingsFlags, proverFlags, []string{"srs", "circuit", "tests", "builtin-vectors", "tag", "runs", "warmup", "reject-outliers", "target-ci", "max-runs", "skip-compile", "cold", "threads", "compare-hash-to-field", "cpus", "cpu-quota", "device", "baseline", "fail-on-regression", "metrics-addr", "energy", "history", "insecure-seed"})},
+	{"bench compare", "<old results.json> <new results.json>", "Compare two benchmark results", nil},
+	{"serialization", "<test case>", "Compare the serialization formats of the artifacts", slices.Concat(settingsFlags, []string{"circuit", "builtin-vectors", "runs", "bandwidth"})},
+	{"matrix", "<config.yaml>", "Benchmark every combination of settings of a matrix config", []string{"tests", "runs", "warmup", "reject-outliers", "target-ci", "max-runs"}},
+	{"daemon", "[<config.yaml>...]", "Run queued benchmark jobs, and queue configs on -schedule", []string{"queue", "schedule", "metrics-addr"}},
+	{"daemon enqueue", "<config.yaml>...", "Queue matrix configs for the daemon", []string{"queue"}},
+	{"daemon status", "", "Show the jobs of the daemon's queue", []string{"queue"}},
+	{"batch", "[<test case>...]", "Sweep the number of signatures per proof of the batched P-256 circuit", slices.Concat(settingsFlags, proverFlags, []string{"srs", "tests", "builtin-vectors", "batch-sizes", "runs"})},
+	{"throughput", "[<test case>...]", "Measure proofs per second with concurrent provers", slices.Concat(settingsFlags, proverFlags, []string{"circuit", "tests", "builtin-vectors", "workers", "proofs", "metrics-addr", "history"})},
+	{"loadtest", "[<test case>...]", "Offer proving jobs at increasing rates and measure latency", slices.Concat(settingsFlags, proverFlags, []string{"circuit", "tests", "builtin-vectors", "workers", "rates", "duration", "metrics-addr", "history"})},
+	{"minmem", "<test case>", "Find the smallest memory limit proving fits in", slices.Concat(settingsFlags, proverFlags, []string{"circuit", "builtin-vectors", "memory-step", "max-slowdown", "history"})},
+	{"report", "[<results.json>...]", "Render benchmark results as Markdown or HTML", []string{"html"}},
+	{"history", "", "Show a phase's measurements over time", []string{"history", "phase", "html"}},
+	{"variance", "", "Flag test cases and runs whose proving times stand out", []string{"variance-threshold"}},
+	{"gas", "[<gas report>...]", "Record the verifier gas of forge gas reports", []string{"history"}},
+	{"batch-verify", "[<proof dir>]", "Verify the proofs of a directory as one batch, with a single randomized pairing check", slices.Concat(settingsFlags, []string{"circuit", "tests", "history"})},
+	{"tui", "", "Run and browse the benchmarks of every circuit and backend in an interactive terminal UI", []string{"tests", "cases"}},
+	{"version", "", "Show the versions of the binary, gnark and gnark-crypto, the circuits with their hashes, and the curves and backends", slices.Concat(settingsFlags, []string{"hash-circuits"})},
+	{"completion", "<shell>", "Write the completion script of bash, zsh or fish, for the commands, options and test case files", nil},
+	{"stats", "", "Show the constraint statistics of the compiled circuit", slices.Concat(settingsFlags, []string{"circuit"})},
+}
+
+// lookupCommand finds a command by name
+func lookupCommand(name string) (commandInfo, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return commandInfo{}, false
+}
+
+// printUsage lists the commands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: go run . <command> [options] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.Name, c.Summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'go run . help <command>' for the options of a command.")
+}
+
+// printCommandHelp describes a command with only the options it takes
+func printCommandHelp(fs *flag.FlagSet, c commandInfo) {
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage: %s\n\n%s.\n\nOptions:\n", commandUsage(c), c.Summary)
+	options := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	options.SetOutput(w)
+	fs.VisitAll(func(f *flag.Flag) {
+		if flagApplies(f, c.Name) {
+			options.Var(f.Value, f.Name, f.Usage)
+		}
+	})
+	options.PrintDefaults()
+}
+
+// flagApplies says whether a flag is an option of a command, or, given the
+// first word of commands such as daemon, of any of them
+func flagApplies(f *flag.Flag, command string) bool {
+	name := canonicalFlag(f.Name)
+	if slices.Contains(commonFlags, name) {
+		return true
+	}
+	if c, ok := lookupCommand(command); ok {
+		return slices.Contains(c.Flags, name)
+	}
+	for _, c := range commands {
+		if strings.HasPrefix(c.Name, command+" ") && slices.Contains(c.Flags, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// commandsOfFlag is the commands that take a flag, or nil for a flag of every
+// command
+func commandsOfFlag(f *flag.Flag) []string {
+	name := canonicalFlag(f.Name)
+	if slices.Contains(commonFlags, name) {
+		return nil
+	}
+	var names []string
+	for _, c := range commands {
+		if slices.Contains(c.Flags, name) {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// isCommandName says whether a name is a command, or the first word of some
+func isCommandName(name string) bool {
+	for _, c := range commands {
+		if c.Name == name || strings.HasPrefix(c.Name, name+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCommandLine stops a command given an option it does not take, or
+// too few or too many arguments, rather than ignoring them
+func validateCommandLine(fs *flag.FlagSet, c commandInfo) {
+	fs.Visit(func(f *flag.Flag) {
+		if !flagApplies(f, c.Name) {
+			fatal(fmt.Sprintf("-%s is not an option of %s. Run 'go run . help %s' for its options.", f.Name, c.Name, c.Name), "commands", strings.Join(commandsOfFlag(f), ", "))
+		}
+	})
+
+	names := argumentNames(c.Args)
+	required, variadic := 0, false
+	for _, name := range names {
+		if !strings.HasPrefix(name, "[") {
+			required++
+		}
+		variadic = variadic || strings.HasSuffix(name, "...") || strings.HasSuffix(name, "...]")
+	}
+	args := fs.Args()
+	switch {
+	case len(args) < required:
+		fatal("Missing arguments", "command", c.Name, "usage", commandUsage(c))
+	case !variadic && len(args) > len(names):
+		fatal("Too many arguments", "command", c.Name, "args", strings.Join(args, " "), "usage", commandUsage(c))
+	}
+}
+
+// commandUsage is how a command is run
+func commandUsage(c commandInfo) string {
+	return strings.TrimSpace(fmt.Sprintf("go run . %s [options] %s", c.Name, c.Args))
+}
+
+// argumentPattern matches an argument of a command's Args
+var argumentPattern = regexp.MustCompile(`\[?<[^>]*>(\.\.\.)?\]?(\.\.\.)?`)
+
+// argumentNames splits the arguments of a command, e.g. "<old results.json>
+// <new results.json>", into one name per argument
+func argumentNames(args string) []string {
+	return argumentPattern.FindAllString(args, -1)
+}
+
+// flagAliases are the long names of flags, e.g. --data-dir for -d
+var flagAliases = map[string]string{
+	"data-dir":  "d",
+	"tests-dir": "tests",
+}
+
+// defineAliases defines the aliases of flagAliases
+func defineAliases(fs *flag.FlagSet) {
+	for alias, name := range flagAliases {
+		fs.Var(fs.Lookup(name).Value, alias, "Same as -"+name)
+	}
+}
+
+// canonicalFlag is the name of the flag an alias stands for, or the name
+func canonicalFlag(name string) string {
+	if canonical, ok := flagAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// helpCommand prints the usage, or the help of a command
+func helpCommand(args []string) {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return
+	}
+	name := strings.Join(args, " ")
+	c, ok := lookupCommand(name)
+	if !ok {
+		fatal("Unknown command. Run 'go run . help' for the commands.", "command", name)
+	}
+	fs := newFlagSet(c.Name)
+	fs.SetOutput(os.Stdout)
+	printCommandHelp(fs, c)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+// TestCommandFlags checks that the Flags of every command are defined, and
+// that every flag is an option of some command
+func TestCommandFlags(t *testing.T) {
+	taken := map[string]bool{}
+	for _, c := range commands {
+		fs := newFlagSet(c.Name)
+		for _, name := range slices.Concat(commonFlags, c.Flags) {
+			if fs.Lookup(name) == nil {
+				t.Errorf("%s takes -%s, which is not defined", c.Name, name)
+			}
+			taken[name] = true
+		}
+	}
+	newFlagSet("prove").VisitAll(func(f *flag.Flag) {
+		if !taken[canonicalFlag(f.Name)] {
+			t.Errorf("-%s is not an option of any command", f.Name)
+		}
+	})
+}
+
+func TestFlagApplies(t *testing.T) {
+	fs := newFlagSet("verify")
+	tests := []struct {
+		flag, command string
+		want          bool
+	}{
+		{"d", "verify", true},
+		{"data-dir", "daemon status", true},
+		{"tests-dir", "verify", true},
+		{"tests-dir", "stats", false},
+		{"all", "verify", true},
+		{"gpu", "verify", false},
+		{"gpu", "prove", true},
+		{"entropy", "verify", false},
+		{"entropy-file", "compile", true},
+		{"circuit", "compile", true},
+		{"circuit", "else", false},
+		{"queue", "daemon enqueue", true},
+		{"schedule", "daemon enqueue", false},
+		{"schedule", "daemon", true}, // a config section of every daemon subcommand
+		{"schedule", "daemon status", false},
+		{"runs", "bench compare", false},
+	}
+	for _, tt := range tests {
+		if got := flagApplies(fs.Lookup(tt.flag), tt.command); got != tt.want {
+			t.Errorf("flagApplies(-%s, %q) = %v, want %v", tt.flag, tt.command, got, tt.want)
+		}
+	}
+}
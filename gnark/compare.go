@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"strconv"
+)
+
+// significanceLevel is the p-value below which a change in time is reported
+// as significant, as benchstat does
+const significanceLevel = 0.05
+
+// metricDelta is one metric measured in both results files. Timings carry the
+// spread of their runs, and p is the p-value of Welch's t-test on them, or -1
+// for metrics that do not vary between runs.
+type metricDelta struct {
+	metric   string
+	old, new float64
+	oldCI    float64
+	newCI    float64
+	p        float64
+	format   func(float64) string
+}
+
+// compareResultsFiles prints the changes between two results files, such as
+// those of before and after a circuit change: the time of every phase, with a
+// significance test where both sides have repeated runs, and the memory,
+// constraints, artifact sizes, energy, and verifier gas. Baseline files are
+// accepted too.
+func compareResultsFiles(paths []string) {
+	if len(paths) != 2 {
+		fatal("Usage: bench compare <old results.json> <new results.json>")
+	}
+	before, after := loadComparedResults(paths[0]), loadComparedResults(paths[1])
+	if before.Settings != after.Settings {
+		fmt.Printf("Settings differ: %s vs %s\n", describeSettings(before.Settings), describeSettings(after.Settings))
+	}
+	if !before.Environment.sameMachine(after.Environment) {
+		slog.Warn("The results were measured on different machines", "old", before.Environment.describe(), "new", after.Environment.describe())
+	}
+
+	deltas := compareMeasurements(before, after)
+	if len(deltas) == 0 {
+		fmt.Printf("No metrics in common between %s and %s\n", paths[0], paths[1])
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%-30s %20s %20s %9s %8s\n", "Metric", "Old", "New", "Change", "P")
+	for _, d := range deltas {
+		oldValue, newValue := d.format(d.old), d.format(d.new)
+		if d.p >= 0 {
+			oldValue += fmt.Sprintf(" ±%.0f%%", relative(d.oldCI, d.old))
+			newValue += fmt.Sprintf(" ±%.0f%%", relative(d.newCI, d.new))
+		}
+		change := fmt.Sprintf("%+.1f%%", relative(d.new-d.old, d.old))
+		p := "-"
+		switch {
+		case d.p >= significanceLevel:
+			change, p = "~", fmt.Sprintf("p=%.3f", d.p)
+		case d.p >= 0:
+			p = fmt.Sprintf("p=%.3f", d.p)
+		case d.old == d.new:
+			change = "0.0%"
+		}
+		fmt.Printf("%-30s %20s %20s %9s %8s\n", d.metric, oldValue, newValue, change, p)
+	}
+	fmt.Printf("\nTimes show the 95%% confidence interval of their mean, and their p-value is from Welch's t-test.\n")
+	fmt.Printf("\"~\" marks changes that are not significant (p >= %g). Single runs and the other metrics are compared as is.\n", significanceLevel)
+}
+
+// loadComparedResults reads a results file, or a baseline with its verifier
+// gas
+func loadComparedResults(path string) Baseline {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read results", "error", err)
+	}
+	var results Baseline
+	if err := json.Unmarshal(data, &results); err != nil {
+		fatal("Invalid results file", "file", path, "error", err)
+	}
+	if results.SchemaVersion != resultsSchemaVersion {
+		fatal("Unsupported results schema version", "file", path, "version", results.SchemaVersion, "supported", resultsSchemaVersion)
+	}
+	if results.VerifierGas == nil {
+		results.VerifierGas = verifierGas(results.Results, path)
+	}
+	return results
+}
+
+func describeSettings(s Manifest) string {
+	return fmt.Sprintf("%s over %s, %s range checks, %s hash-to-field, gnark %s", s.Backend, s.Curve, s.RangeCheck, s.HashToField, s.GnarkVersion)
+}
+
+// compareMeasurements pairs up the metrics of the measurements in both files
+func compareMeasurements(before, after Baseline) []metricDelta {
+	formatBytesFloat := func(v float64) string { return formatBytes(uint64(v)) }
+	formatCount := func(v float64) string { return strconv.FormatInt(int64(v), 10) }
+	formatJoules := func(v float64) string { return fmt.Sprintf("%.2f J", v) }
+
+	var deltas []metricDelta
+	exact := func(metric string, o, n float64, format func(float64) string) {
+		if o > 0 && n > 0 {
+			deltas = append(deltas, metricDelta{metric: metric, old: o, new: n, p: -1, format: format})
+		}
+	}
+	for _, pair := range pairMeasurements(before.Measurements, after.Measurements) {
+		o, n := pair.old, pair.new
+		name := n.label()
+		d := metricDelta{metric: name + " time", old: o.MeanSecs, new: n.MeanSecs, p: -1, format: formatSecs}
+		if o.Runs > 1 && n.Runs > 1 {
+			d.oldCI, d.newCI = o.CI95Secs, n.CI95Secs
+			d.p = welchTTest(o.PhaseStats, n.PhaseStats)
+		}
+		deltas = append(deltas, d)
+
+		if o.Allocs != nil && n.Allocs != nil {
+			exact(name+" peak heap", float64(o.Allocs.PeakHeapBytes), float64(n.Allocs.PeakHeapBytes), formatBytesFloat)
+			exact(name+" bytes/op", float64(o.Allocs.BytesPerOp), float64(n.Allocs.BytesPerOp), formatBytesFloat)
+		}
+		exact("constraints", float64(o.Constraints), float64(n.Constraints), formatCount)
+		exact("circuit size", float64(o.CircuitBytes), float64(n.CircuitBytes), formatBytesFloat)
+		exact("proving key size", float64(o.ProvingKeyBytes), float64(n.ProvingKeyBytes), formatBytesFloat)
+		exact("verifying key size", float64(o.VerifyingKeyBytes), float64(n.VerifyingKeyBytes), formatBytesFloat)
+		exact(name+" proof size", float64(o.ProofBytes), float64(n.ProofBytes), formatBytesFloat)
+		exact(name+" energy", o.EnergyJoules, n.EnergyJoules, formatJoules)
+	}
+
+	for _, testCase := range gasTestCases(after.VerifierGas) {
+		exact("verifier gas "+testCase, float64(before.VerifierGas[testCase]), float64(after.VerifierGas[testCase]), formatCount)
+	}
+	return deltas
+}
+
+// relative is part as a percentage of whole
+func relative(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
+
+// welchTTest is the two-sided p-value of Welch's t-test for a difference
+// between the means of two phases, from their run counts and sample standard
+// deviations. Unlike Student's test it does not assume equal variances.
+func welchTTest(a, b PhaseStats) float64 {
+	va := a.StdDevSecs * a.StdDevSecs / float64(a.Runs)
+	vb := b.StdDevSecs * b.StdDevSecs / float64(b.Runs)
+	if va+vb == 0 {
+		if a.MeanSecs == b.MeanSecs {
+			return 1
+		}
+		return 0
+	}
+	t := (a.MeanSecs - b.MeanSecs) / math.Sqrt(va+vb)
+	// Welch–Satterthwaite degrees of freedom
+	df := (va + vb) * (va + vb) / (va*va/float64(a.Runs-1) + vb*vb/float64(b.Runs-1))
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regularizedIncompleteBeta is I_x(a, b), evaluated with the continued
+// fraction of Numerical Recipes (betacf) by Lentz's method
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	// The continued fraction converges quickly for x < (a+1)/(a+b+2)
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularizedIncompleteBeta(1-x, b, a)
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	front := math.Exp(lgab-lga-lgb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	const tiny = 1e-300
+	f, c, d := 1.0, 1.0, 0.0
+	for i := 0; i <= 200; i++ {
+		m := float64(i / 2)
+		var numerator float64
+		switch {
+		case i == 0:
+			numerator = 1
+		case i%2 == 0:
+			numerator = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		cd := c * d
+		f *= cd
+		if math.Abs(1-cd) < 1e-12 {
+			return front * (f - 1)
+		}
+	}
+	return front * (f - 1)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRegularizedIncompleteBeta(t *testing.T) {
+	tests := []struct {
+		x, a, b float64
+		want    float64
+	}{
+		{0, 2, 3, 0},
+		{1, 2, 3, 1},
+		{0.3, 1, 1, 0.3},                         // uniform
+		{0.3, 4, 1, math.Pow(0.3, 4)},            // x^a
+		{0.3, 1, 4, 1 - math.Pow(0.7, 4)},        // 1 - (1-x)^b
+		{0.5, 7.5, 7.5, 0.5},                     // symmetric
+		{0.3, 2, 3, 0.3483},                      // binomial tail of 4 draws
+		{0.9, 2, 3, 0.9963},                      // above (a+1)/(a+b+2), by symmetry
+		{10.0 / 14, 5, 0.5, 0.07338803477074189}, // t = 2, df = 10
+	}
+	for _, tt := range tests {
+		if got := regularizedIncompleteBeta(tt.x, tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("I_%g(%g, %g) = %.12f, want %.12f", tt.x, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b PhaseStats
+		want float64
+	}{
+		{
+			// s² = 3 over 6 runs each: t = 2 / sqrt(3/6 + 3/6) = 2, df = 10
+			name: "t=2, df=10",
+			a:    PhaseStats{Runs: 6, MeanSecs: 12, StdDevSecs: math.Sqrt(3)},
+			b:    PhaseStats{Runs: 6, MeanSecs: 10, StdDevSecs: math.Sqrt(3)},
+			want: 0.0734,
+		},
+		{
+			// s² = 1 over 2 runs each: t = 2, df = 2, p = 1 - t/sqrt(2+t²)
+			name: "t=2, df=2",
+			a:    PhaseStats{Runs: 2, MeanSecs: 10, StdDevSecs: 1},
+			b:    PhaseStats{Runs: 2, MeanSecs: 12, StdDevSecs: 1},
+			want: 1 - 2/math.Sqrt(6),
+		},
+		{
+			name: "equal means",
+			a:    PhaseStats{Runs: 5, MeanSecs: 3, StdDevSecs: 0.5},
+			b:    PhaseStats{Runs: 8, MeanSecs: 3, StdDevSecs: 0.2},
+			want: 1,
+		},
+		{
+			name: "no variance, same time",
+			a:    PhaseStats{Runs: 3, MeanSecs: 1},
+			b:    PhaseStats{Runs: 3, MeanSecs: 1},
+			want: 1,
+		},
+		{
+			name: "no variance, different time",
+			a:    PhaseStats{Runs: 3, MeanSecs: 1},
+			b:    PhaseStats{Runs: 3, MeanSecs: 2},
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := welchTTest(tt.a, tt.b); math.Abs(got-tt.want) > 5e-5 {
+				t.Errorf("p = %.6f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairMeasurements(t *testing.T) {
+	run := func(phase, testCase string, threads int, device string, rate float64) Measurement {
+		m := Measurement{PhaseStats: PhaseStats{Phase: phase, TestCase: testCase, Threads: threads}, Device: device, ArrivalRate: rate}
+		if device != "" {
+			m.CPULimit = "2 CPUs at 50%"
+		}
+		return m
+	}
+	before := []Measurement{
+		run("prove", "1", 0, "", 0),
+		run("prove", "1", 0, "iphone12", 0),
+		run("prove", "1", 4, "", 0),
+		run("prove_load", "", 0, "", 1),
+		run("prove_load", "", 0, "", 2),
+		run("verify", "1", 0, "", 0),
+	}
+	after := []Measurement{
+		run("prove_load", "", 0, "", 2),
+		run("prove", "1", 0, "iphone12", 0),
+		run("prove", "1", 0, "", 0),
+		run("prove", "2", 0, "", 0), // not in before
+		run("prove", "1", 8, "", 0), // not in before
+		run("prove_load", "", 0, "", 1),
+	}
+
+	pairs := pairMeasurements(before, after)
+	want := []string{
+		"prove_load at 2/s",
+		"prove 1 (iphone12: 2 CPUs at 50%)",
+		"prove 1",
+		"prove_load at 1/s",
+	}
+	if len(pairs) != len(want) {
+		t.Fatalf("Got %d pairs, want %d", len(pairs), len(want))
+	}
+	for i, pair := range pairs {
+		if !pair.old.sameRun(pair.new) {
+			t.Errorf("Pair %d: %s paired with %s", i, pair.new.label(), pair.old.label())
+		}
+		if got := pair.new.label(); got != want[i] {
+			t.Errorf("Pair %d is %q, want %q", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// programName is the name the completion scripts complete, that of the binary
+// go build writes
+const programName = "gnark-ecdsa-benchmark"
+
+// completionFlag is a flag as the completion scripts know it
+type completionFlag struct {
+	Name  string
+	Usage string
+	Bool  bool
+}
+
+// completionCommand is a command as the completion scripts know it: the
+// flags it takes besides those of every command
+type completionCommand struct {
+	commandInfo
+	Flags []completionFlag
+}
+
+// completionSpec is what the completion scripts complete, gathered from the
+// commands and their flags so that new ones complete without further work
+type completionSpec struct {
+	Commands []completionCommand
+	// Common are the flags of every command
+	Common []completionFlag
+	// Values are the flags that take a value, with the values they take, or
+	// nil for a file
+	Values map[string][]string
+	// Dirs are the flags that take a directory
+	Dirs []string
+}
+
+// completionValues are the values of the flags that take one of a few
+func completionValues() map[string][]string {
+	curves := make([]string, len(supportedCurves))
+	for i, id := range supportedCurves {
+		curves[i] = curveDisplayName(id)
+	}
+	return map[string][]string{
+		"backend":        {backendGroth16, backendPLONK},
+		"challenge-hash": {challengeHashSHA256, challengeHashKeccak256},
+		"circuit":        circuitNames(),
+		"curve":          curves,
+		"device":         deviceNames(),
+		"hash-to-field":  hashToFieldFunctions,
+		"log-format":     {"text", "json"},
+		"log-level":      {"debug", "info", "warn", "error"},
+		"profile":        runtimeProfileNames(),
+		"proof-format":   {proofFormatHex, proofFormatBase64, proofFormatBinary},
+		"range-check":    {rangeCheckLookup, rangeCheckDecompose},
+		"to":             {formatGnark, formatCircom, formatNoir},
+	}
+}
+
+// completionShells are the shells the completion command writes scripts for
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// completionDirs are the flags that take a directory
+var completionDirs = []string{"d", "out", "tests"}
+
+// printCompletion is the completion command: it writes the script completing
+// the commands, flags, flag values and test case files for a shell
+func printCompletion(shell string) {
+	spec := newCompletionSpec()
+	switch shell {
+	case "bash":
+		writeBashCompletion(os.Stdout, spec)
+	case "zsh":
+		writeZshCompletion(os.Stdout, spec)
+	case "fish":
+		writeFishCompletion(os.Stdout, spec)
+	default:
+		fatal("Unknown shell, use "+strings.Join(completionShells, ", "), "shell", shell)
+	}
+}
+
+// newCompletionSpec gathers the commands and the flags each takes. It defines
+// the flags of every command in turn, which resets the options to their
+// defaults; the completion command runs nothing else.
+func newCompletionSpec() completionSpec {
+	spec := completionSpec{Values: map[string][]string{}}
+	values := completionValues()
+	common := map[string]bool{}
+	for i, c := range commands {
+		fs := newFlagSet(c.Name)
+		command := completionCommand{commandInfo: c}
+		fs.VisitAll(func(f *flag.Flag) {
+			if !flagApplies(f, c.Name) {
+				return
+			}
+			cf := completionFlag{Name: f.Name, Usage: f.Usage, Bool: isBoolFlag(f)}
+			if !cf.Bool {
+				spec.Values[f.Name] = values[canonicalFlag(f.Name)]
+			}
+			if commandsOfFlag(f) == nil {
+				if i == 0 {
+					spec.Common = append(spec.Common, cf)
+					common[f.Name] = true
+				}
+				if common[f.Name] {
+					return
+				}
+			}
+			command.Flags = append(command.Flags, cf)
+		})
+		spec.Commands = append(spec.Commands, command)
+	}
+	for alias, name := range flagAliases {
+		for _, dir := range completionDirs {
+			if name == dir {
+				spec.Dirs = append(spec.Dirs, alias)
+			}
+		}
+	}
+	spec.Dirs = append(spec.Dirs, completionDirs...)
+	sort.Strings(spec.Dirs)
+	return spec
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// topCommands are the first words of the commands, as typed after the
+// program name, and subcommands the second words of those that have them
+func (spec completionSpec) topCommands() ([]string, map[string][]string) {
+	var top []string
+	subcommands := map[string][]string{}
+	for _, c := range spec.Commands {
+		first, second, ok := strings.Cut(c.Name, " ")
+		if !slices.Contains(top, first) {
+			top = append(top, first)
+		}
+		if ok {
+			subcommands[first] = append(subcommands[first], second)
+		}
+	}
+	return append(top, "help"), subcommands
+}
+
+// subcommandNames are the commands of two words, e.g. "daemon status"
+func (spec completionSpec) subcommandNames() []string {
+	var names []string
+	for _, c := range spec.Commands {
+		if strings.Contains(c.Name, " ") {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// testCaseCommands are the commands whose arguments are test case files
+func (spec completionSpec) testCaseCommands() []string {
+	var names []string
+	for _, c := range spec.Commands {
+		if strings.Contains(c.Args, "<test case>") {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// valueFlags are the flags that take a value and do not list their values,
+// other than directories
+func (spec completionSpec) valueFlags() []string {
+	var names []string
+	for name, values := range spec.Values {
+		if values == nil && !slices.Contains(spec.Dirs, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// enumFlags are the flags that take one of a few values
+func (spec completionSpec) enumFlags() []string {
+	var names []string
+	for name, values := range spec.Values {
+		if values != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionFunction is the name of the shell function of the scripts
+func completionFunction() string {
+	return "_" + strings.ReplaceAll(programName, "-", "_")
+}
+
+// flagNames are the names of flags with their dash, e.g. -circuit
+func flagNames(flags []completionFlag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.Name
+	}
+	return strings.Join(names, " ")
+}
+
+// shellQuote quotes a string in single quotes for bash, zsh and fish
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeBashCompletion(w io.Writer, spec completionSpec) {
+	top, subcommands := spec.topCommands()
+	fn := completionFunction()
+	fmt.Fprintf(w, "# bash completion for %s. Load it with:\n#   source <(%s completion bash)\n\n", programName, programName)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}\n")
+	fmt.Fprintf(w, "\tif ((COMP_CWORD == 1)); then\n\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n\t\treturn\n\tfi\n", shellQuote(strings.Join(top, " ")))
+	fmt.Fprintf(w, "\tlocal command=${COMP_WORDS[1]}\n")
+	fmt.Fprintf(w, "\tif ((COMP_CWORD == 2)) && [[ $cur != -* ]]; then\n\t\tcase $command in\n")
+	fmt.Fprintf(w, "\t\thelp) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n", shellQuote(strings.Join(top[:len(top)-1], " ")))
+	fmt.Fprintf(w, "\t\tcompletion) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n", shellQuote(strings.Join(completionShells, " ")))
+	for _, first := range sortedKeys(subcommands) {
+		fmt.Fprintf(w, "\t\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")); [[ ${#COMPREPLY[@]} -gt 0 ]] && return ;;\n", first, shellQuote(strings.Join(subcommands[first], " ")))
+	}
+	fmt.Fprintf(w, "\t\tesac\n\tfi\n")
+	fmt.Fprintf(w, "\tif ((COMP_CWORD > 2)); then\n\t\tcase \"$command ${COMP_WORDS[2]}\" in\n")
+	fmt.Fprintf(w, "\t\t%s) command=\"$command ${COMP_WORDS[2]}\" ;;\n", quotedAlternatives(spec.subcommandNames(), " | "))
+	fmt.Fprintf(w, "\t\tesac\n\tfi\n")
+
+	fmt.Fprintf(w, "\tif [[ $prev == -* ]]; then\n\t\tlocal flag=${prev#-}\n\t\tcase ${flag#-} in\n")
+	for _, name := range spec.enumFlags() {
+		fmt.Fprintf(w, "\t\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n", name, shellQuote(strings.Join(spec.Values[name], " ")))
+	}
+	fmt.Fprintf(w, "\t\t%s) COMPREPLY=($(compgen -d -- \"$cur\")); return ;;\n", strings.Join(spec.Dirs, " | "))
+	fmt.Fprintf(w, "\t\t%s) return ;;\n", strings.Join(spec.valueFlags(), " | "))
+	fmt.Fprintf(w, "\t\tesac\n\tfi\n")
+
+	fmt.Fprintf(w, "\tif [[ $cur == -* ]]; then\n\t\tlocal flags=%s\n\t\tcase $command in\n", shellQuote(flagNames(spec.Common)))
+	for _, c := range spec.Commands {
+		if len(c.Flags) > 0 {
+			fmt.Fprintf(w, "\t\t%s) flags+=%s ;;\n", shellQuote(c.Name), shellQuote(" "+flagNames(c.Flags)))
+		}
+	}
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\t\t[[ $cur == --* ]] && flags=$(printf -- '-%%s ' $flags)\n")
+	fmt.Fprintf(w, "\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n\t\treturn\n\tfi\n")
+	fmt.Fprintf(w, "\tcase $command in\n\t%s)\n", quotedAlternatives(spec.testCaseCommands(), " | "))
+	fmt.Fprintf(w, "\t\tcompopt -o filenames 2>/dev/null\n\t\tCOMPREPLY=($(compgen -f -X '!*.json' -- \"$cur\") $(compgen -d -- \"$cur\"))\n\t\t;;\n\tesac\n")
+	fmt.Fprintf(w, "}\n\ncomplete -o default -F %s %s\n", fn, programName)
+}
+
+func writeZshCompletion(w io.Writer, spec completionSpec) {
+	top, subcommands := spec.topCommands()
+	fn := completionFunction()
+	fmt.Fprintf(w, "#compdef %s\n# zsh completion for %s. Load it with:\n#   source <(%s completion zsh)\n# or save it as %s in a directory of $fpath.\n\n", programName, programName, programName, fn)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal command=${words[2]} prev=${words[CURRENT-1]}\n\tlocal -a items\n")
+	fmt.Fprintf(w, "\tif ((CURRENT == 2)); then\n\t\titems=(\n")
+	summaries := map[string]string{}
+	for _, c := range spec.Commands {
+		first, _, _ := strings.Cut(c.Name, " ")
+		if _, ok := summaries[first]; !ok {
+			summaries[first] = c.Summary
+			if first != c.Name {
+				summaries[first] = "Subcommands: " + strings.Join(subcommands[first], ", ")
+			}
+		}
+	}
+	summaries["help"] = "Show the commands, or the options of a command"
+	for _, name := range top {
+		fmt.Fprintf(w, "\t\t\t%s\n", shellQuote(zshItem(name, summaries[name])))
+	}
+	fmt.Fprintf(w, "\t\t)\n\t\t_describe -t commands command items\n\t\treturn\n\tfi\n")
+	fmt.Fprintf(w, "\tif ((CURRENT == 3)) && [[ $PREFIX != -* ]]; then\n\t\tcase $command in\n")
+	fmt.Fprintf(w, "\t\thelp) compadd -- %s; return ;;\n", strings.Join(top[:len(top)-1], " "))
+	fmt.Fprintf(w, "\t\tcompletion) compadd -- %s; return ;;\n", strings.Join(completionShells, " "))
+	for _, first := range sortedKeys(subcommands) {
+		fmt.Fprintf(w, "\t\t%s) compadd -- %s && return ;;\n", first, strings.Join(subcommands[first], " "))
+	}
+	fmt.Fprintf(w, "\t\tesac\n\tfi\n")
+	fmt.Fprintf(w, "\tif ((CURRENT > 3)); then\n\t\tcase \"$command ${words[3]}\" in\n")
+	fmt.Fprintf(w, "\t\t%s) command=\"$command ${words[3]}\" ;;\n", quotedAlternatives(spec.subcommandNames(), " | "))
+	fmt.Fprintf(w, "\t\tesac\n\tfi\n")
+
+	fmt.Fprintf(w, "\tif [[ $prev == -* ]]; then\n\t\tlocal flag=${prev#-}\n\t\tcase ${flag#-} in\n")
+	for _, name := range spec.enumFlags() {
+		fmt.Fprintf(w, "\t\t%s) compadd -- %s; return ;;\n", name, strings.Join(spec.Values[name], " "))
+	}
+	fmt.Fprintf(w, "\t\t%s) _directories; return ;;\n", strings.Join(spec.Dirs, " | "))
+	fmt.Fprintf(w, "\t\t%s) _files; return ;;\n", strings.Join(spec.valueFlags(), " | "))
+	fmt.Fprintf(w, "\t\tesac\n\tfi\n")
+
+	fmt.Fprintf(w, "\tif [[ $PREFIX == -* ]]; then\n\t\titems=(\n")
+	for _, f := range spec.Common {
+		fmt.Fprintf(w, "\t\t\t%s\n", shellQuote(zshItem("-"+f.Name, f.Usage)))
+	}
+	fmt.Fprintf(w, "\t\t)\n\t\tcase $command in\n")
+	for _, c := range spec.Commands {
+		if len(c.Flags) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\t\t%s) items+=(\n", shellQuote(c.Name))
+		for _, f := range c.Flags {
+			fmt.Fprintf(w, "\t\t\t%s\n", shellQuote(zshItem("-"+f.Name, f.Usage)))
+		}
+		fmt.Fprintf(w, "\t\t) ;;\n")
+	}
+	fmt.Fprintf(w, "\t\tesac\n")
+	fmt.Fprintf(w, "\t\t[[ $PREFIX == --* ]] && items=(-${^items})\n")
+	fmt.Fprintf(w, "\t\t_describe -t options option items\n\t\treturn\n\tfi\n")
+	fmt.Fprintf(w, "\tcase $command in\n\t%s) _files -g '*.json' ;;\n\t*) _files ;;\n\tesac\n}\n\n", quotedAlternatives(spec.testCaseCommands(), " | "))
+	fmt.Fprintf(w, "if [[ $funcstack[1] == %s ]]; then\n\t%s \"$@\"\nelse\n\tcompdef %s %s\nfi\n", fn, fn, fn, programName)
+}
+
+// zshItem is a completion with its description for _describe, whose colons
+// in the name separate the description
+func zshItem(name, description string) string {
+	return strings.ReplaceAll(name, ":", `\:`) + ":" + description
+}
+
+func writeFishCompletion(w io.Writer, spec completionSpec) {
+	top, subcommands := spec.topCommands()
+	fmt.Fprintf(w, "# fish completion for %s. Load it with:\n#   %s completion fish | source\n# or save it as ~/.config/fish/completions/%s.fish.\n\n", programName, programName, programName)
+	fmt.Fprintf(w, "complete -c %s -f\n\n", programName)
+
+	summaries := map[string]string{"help": "Show the commands, or the options of a command"}
+	for _, c := range spec.Commands {
+		first, _, _ := strings.Cut(c.Name, " ")
+		if _, ok := summaries[first]; !ok {
+			summaries[first] = c.Summary
+			if first != c.Name {
+				summaries[first] = "Subcommands: " + strings.Join(subcommands[first], ", ")
+			}
+		}
+	}
+	for _, name := range top {
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", programName, name, shellQuote(summaries[name]))
+	}
+	for _, c := range spec.Commands {
+		if first, second, ok := strings.Cut(c.Name, " "); ok {
+			fmt.Fprintf(w, "complete -c %s -n %s -a %s -d %s\n", programName, shellQuote("__fish_seen_subcommand_from "+first+"; and not __fish_seen_subcommand_from "+strings.Join(subcommands[first], " ")), second, shellQuote(c.Summary))
+		}
+	}
+	fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from help' -a %s\n", programName, shellQuote(strings.Join(top[:len(top)-1], " ")))
+	fmt.Fprintf(w, "complete -c %s -n '__fish_seen_subcommand_from completion' -a %s\n\n", programName, shellQuote(strings.Join(completionShells, " ")))
+
+	for _, f := range spec.Common {
+		fmt.Fprintf(w, "complete -c %s -n 'not __fish_use_subcommand' %s\n", programName, fishFlag(spec, f))
+	}
+	for _, c := range spec.Commands {
+		condition := "__fish_seen_subcommand_from " + c.Name
+		if first, second, ok := strings.Cut(c.Name, " "); ok {
+			condition = "__fish_seen_subcommand_from " + first + "; and __fish_seen_subcommand_from " + second
+		}
+		for _, f := range c.Flags {
+			fmt.Fprintf(w, "complete -c %s -n %s %s\n", programName, shellQuote(condition), fishFlag(spec, f))
+		}
+	}
+	fmt.Fprintln(w)
+	for _, name := range spec.testCaseCommands() {
+		fmt.Fprintf(w, "complete -c %s -n %s -a '(__fish_complete_suffix .json)'\n", programName, shellQuote("__fish_seen_subcommand_from "+name))
+	}
+	for _, c := range spec.Commands {
+		if c.Args != "" && !slices.Contains(spec.testCaseCommands(), c.Name) {
+			fmt.Fprintf(w, "complete -c %s -n %s -F\n", programName, shellQuote("__fish_seen_subcommand_from "+strings.ReplaceAll(c.Name, " ", "; and __fish_seen_subcommand_from ")))
+		}
+	}
+}
+
+// fishFlag is the options of complete for a flag: its name, description and
+// the values it takes
+func fishFlag(spec completionSpec, f completionFlag) string {
+	opts := fmt.Sprintf("-o %s -d %s", f.Name, shellQuote(f.Usage))
+	switch {
+	case f.Bool:
+	case spec.Values[f.Name] != nil:
+		opts += fmt.Sprintf(" -x -a %s", shellQuote(strings.Join(spec.Values[f.Name], " ")))
+	case slices.Contains(spec.Dirs, f.Name):
+		opts += " -x -a '(__fish_complete_directories)'"
+	default:
+		opts += " -r -F"
+	}
+	return opts
+}
+
+// quotedAlternatives joins words as the patterns of a case, quoting those of
+// several words
+func quotedAlternatives(names []string, sep string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = name
+		if strings.Contains(name, " ") {
+			quoted[i] = shellQuote(name)
+		}
+	}
+	return strings.Join(quoted, sep)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
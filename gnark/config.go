@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFiles are the config files read, the first that exists, when
+// -config is not given
+var defaultConfigFiles = []string{"gnark.yaml", "gnark.yml", "gnark.toml"}
+
+var (
+	// command line flags
+	configFile string
+)
+
+// applyConfig sets the options of a command from the config file, -config or
+// else the first of defaultConfigFiles in the working directory, so that a
+// benchmark configuration can be committed and reproduced. The keys of the
+// file are option names, e.g. curve, data-dir or range-check (or
+// range_check); a key named after a command, e.g. bench, holds options of
+// that command only, which take precedence over the top-level ones. Options
+// given on the command line take precedence over both. Top-level options the
+// command does not take are left out, so one file serves every command. It
+// returns the file used, if any, for main to log once logging is set up.
+func applyConfig(fs *flag.FlagSet, command string) string {
+	path := configFile
+	if path == "" {
+		for _, name := range defaultConfigFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+		if path == "" {
+			return ""
+		}
+	}
+	config, err := loadConfig(path)
+	if err != nil {
+		fatal("Failed to load config", "file", path, "error", err)
+	}
+
+	options, err := configOptions(fs, config, command)
+	if err != nil {
+		fatal("Invalid config", "file", path, "error", err)
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[canonicalFlag(f.Name)] = true })
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if given[name] {
+			continue
+		}
+		if err := fs.Set(name, options[name]); err != nil {
+			fatal("Invalid option in config", "option", name, "file", path, "error", err)
+		}
+	}
+	return path
+}
+
+// configOptions checks the keys of a config, those of the sections of other
+// commands when those run, and returns the values of the options of one
+// command
+func configOptions(fs *flag.FlagSet, config map[string]any, command string) (map[string]string, error) {
+	options := map[string]string{}
+	var sections []string
+	for key, value := range config {
+		if section, ok := value.(map[string]any); ok {
+			if !isCommandName(key) {
+				return nil, fmt.Errorf("%s is not a command", key)
+			}
+			if command != key && !strings.HasPrefix(command, key+" ") {
+				continue
+			}
+			for name := range section {
+				f := fs.Lookup(configKey(name))
+				if f == nil {
+					return nil, fmt.Errorf("unknown option %s of %s", name, key)
+				}
+				if !flagApplies(f, key) {
+					return nil, fmt.Errorf("%s is not an option of %s", name, key)
+				}
+			}
+			sections = append(sections, key)
+			continue
+		}
+		f := fs.Lookup(configKey(key))
+		if f == nil {
+			return nil, fmt.Errorf("unknown option %s", key)
+		}
+		if flagApplies(f, command) {
+			s, err := configValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			options[canonicalFlag(f.Name)] = s
+		}
+	}
+
+	// A section of daemon applies to every daemon subcommand, and one of the
+	// subcommand after it
+	sort.Slice(sections, func(i, j int) bool { return len(sections[i]) < len(sections[j]) })
+	for _, key := range sections {
+		for name, value := range config[key].(map[string]any) {
+			s, err := configValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s of %s: %v", name, key, err)
+			}
+			options[canonicalFlag(configKey(name))] = s
+		}
+	}
+	return options, nil
+}
+
+// configKey is the option a config key names, which may use _ for -
+func configKey(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// configValue is the command line value of a config value: a list is joined
+// with commas, as for -tag
+func configValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	case map[string]any:
+		return "", fmt.Errorf("unexpected table")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// loadConfig reads a YAML config, or a TOML one for a .toml file
+func loadConfig(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]any{}
+	if filepath.Ext(path) == ".toml" {
+		if err := toml.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
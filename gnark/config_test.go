@@ -0,0 +1,129 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		config  string
+		command string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "yaml", file: "gnark.yaml", command: "bench",
+			config: "curve: bn254\nrange_check: lookup\nbench:\n  runs: 5\n  target_ci: 5%\n",
+			want:   map[string]string{"curve": "bn254", "range-check": "lookup", "runs": "5", "target-ci": "5%"},
+		},
+		{
+			name: "yaml list", file: "gnark.yaml", command: "negative",
+			config: "negative:\n  tag: [edge-case, mutation]\n",
+			want:   map[string]string{"tag": "edge-case,mutation"},
+		},
+		{
+			name: "toml tables", file: "gnark.toml", command: "bench",
+			config: "curve = \"bn254\"\n\n[bench]\nruns = 5\nreject_outliers = 3.5\n\n[negative]\ntag = [\"edge-case\"]\n",
+			want:   map[string]string{"curve": "bn254", "runs": "5", "reject-outliers": "3.5"},
+		},
+		{
+			name: "toml multi-line array", file: "gnark.toml", command: "negative",
+			config: "[negative]\ntag = [\n  \"edge-case\", # boundaries\n  \"mutation\",\n]\n",
+			want:   map[string]string{"tag": "edge-case,mutation"},
+		},
+		{
+			name: "toml string escapes", file: "gnark.toml", command: "gen-testdata",
+			config: "message = \"say \\\"hi\\\" # not a comment \\u00e9\"\ntests = 'C:\\tests'\n",
+			want:   map[string]string{"message": `say "hi" # not a comment é`, "tests": `C:\tests`},
+		},
+		{
+			name: "toml dotted keys", file: "gnark.toml", command: "bench",
+			config: "bench.runs = 7\nbench.warmup = 2\n",
+			want:   map[string]string{"runs": "7", "warmup": "2"},
+		},
+		{
+			name: "toml inline table", file: "gnark.toml", command: "bench",
+			config: "bench = { runs = 3, max_runs = 10 }\n",
+			want:   map[string]string{"runs": "3", "max-runs": "10"},
+		},
+		{
+			name: "toml quoted subcommand table", file: "gnark.toml", command: "daemon status",
+			config: "[daemon]\nqueue = \"a.db\"\n\n[\"daemon status\"]\nqueue = \"b.db\"\n",
+			want:   map[string]string{"queue": "b.db"},
+		},
+		{
+			name: "section overrides the top level", file: "gnark.yaml", command: "bench",
+			config: "runs: 2\nbench:\n  runs: 5\n",
+			want:   map[string]string{"runs": "5"},
+		},
+		{
+			name: "options of other commands are left out", file: "gnark.yaml", command: "verify",
+			config: "gpu: true\ncurve: bn254\nbench:\n  runs: 5\n",
+			want:   map[string]string{"curve": "bn254"},
+		},
+		{
+			name: "alias", file: "gnark.yaml", command: "verify",
+			config: "data_dir: out\n",
+			want:   map[string]string{"d": "out"},
+		},
+		{name: "unknown option", file: "gnark.yaml", command: "bench", config: "rounds: 5\n", wantErr: true},
+		{name: "section of no command", file: "gnark.toml", command: "bench", config: "[benchmark]\nruns = 5\n", wantErr: true},
+		{name: "option the section's command does not take", file: "gnark.toml", command: "verify", config: "[verify]\nruns = 5\n", wantErr: true},
+		{name: "table as a value", file: "gnark.toml", command: "bench", config: "[bench]\nruns = { min = 5 }\n", wantErr: true},
+		{name: "toml table defined twice", file: "gnark.toml", command: "bench", config: "[bench]\nruns = 1\n[bench]\nruns = 2\n", wantErr: true},
+		{name: "toml unterminated string", file: "gnark.toml", command: "bench", config: "curve = \"bn254\n", wantErr: true},
+		{name: "invalid yaml", file: "gnark.yaml", command: "bench", config: "bench: [runs\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config, err := loadConfig(path)
+			var options map[string]string
+			if err == nil {
+				options, err = configOptions(newFlagSet(tt.command), config, tt.command)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(options, tt.want) {
+				t.Errorf("options = %v, want %v", options, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyConfig checks that the command line overrides the section of the
+// command, which overrides the top level
+func TestApplyConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.toml")
+	config := "curve = \"bls12-381\"\nruns = 2\nwarmup = 4\n\n[bench]\nruns = 5\nmax_runs = 20\n"
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fs := newFlagSet("bench")
+	if err := fs.Parse([]string{"-config", path, "-max-runs", "30", "-curve", "bn254"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := applyConfig(fs, "bench"); got != path {
+		t.Errorf("applyConfig used %q, want %q", got, path)
+	}
+	for name, want := range map[string]string{
+		"curve":    "bn254", // command line over top level
+		"max-runs": "30",    // command line over section
+		"runs":     "5",     // section over top level
+		"warmup":   "4",     // top level over default
+		"tests":    "tests", // default
+	} {
+		if got := fs.Lookup(name).Value.String(); got != want {
+			t.Errorf("-%s = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Test case formats of the stacks in this repo
+const (
+	formatGnark  = "gnark"
+	formatCircom = "circom"
+	formatNoir   = "noir"
+)
+
+// circomChunkBits and circomChunks split a 256-bit value into the limbs the
+// circom circuit of snarkjs and rapidsnark takes, least significant first
+const (
+	circomChunkBits = 43
+	circomChunks    = 6
+)
+
+// noirChunkBytes is the width of the fields the Noir circuit packs bytes into
+const noirChunkBytes = 31
+
+var (
+	// command line flags
+	convertTo  string
+	convertOut string
+)
+
+// circomTestCase is the input.json of the circom circuit
+type circomTestCase struct {
+	R       []string   `json:"r"`
+	S       []string   `json:"s"`
+	MsgHash []string   `json:"msghash"`
+	PubKey  [][]string `json:"pubkey"`
+}
+
+// convertTestCases converts test cases between the formats of the stacks, so
+// every stack proves the same signatures: the gnark JSON with hex values, the
+// circom input.json of snarkjs and rapidsnark with each value in six 43-bit
+// limbs, and the Noir TOML with each value's big-endian bytes packed into
+// 31-byte fields, as scripts/generate_test_cases.rs writes them. The format
+// of each input is recognized from the file, and the output keeps its name
+// with the extension of -to. Unless -out names a directory, the files go
+// where each stack reads them: -tests for gnark, ../snarkjs/tests and
+// ../rapidsnark/tests for circom, and ../noir/tests for Noir. Only the
+// signature converts; metadata such as expect_valid stays in the gnark
+// format.
+func convertTestCases(files []string) {
+	if len(files) == 0 {
+		fatal("Missing test case files for convert command")
+	}
+	var dirs []string
+	switch convertTo {
+	case formatGnark:
+		dirs = []string{testsDir}
+	case formatCircom:
+		dirs = []string{filepath.Join("..", "snarkjs", "tests"), filepath.Join("..", "rapidsnark", "tests")}
+	case formatNoir:
+		dirs = []string{filepath.Join("..", "noir", "tests")}
+	default:
+		fatal("Unknown format for -to (use gnark, circom or noir)", "format", convertTo)
+	}
+	if convertOut != "" {
+		dirs = []string{convertOut}
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			fatal("Failed to create test case directory", "error", err)
+		}
+	}
+
+	for _, file := range files {
+		testCase, from, err := readAnyTestCase(file)
+		if err != nil {
+			fatal("Failed to read test case", "file", file, "error", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		var data []byte
+		ext := ".json"
+		switch convertTo {
+		case formatGnark:
+			data, err = json.MarshalIndent(testCase, "", "  ")
+		case formatCircom:
+			data, err = json.MarshalIndent(toCircom(testCase), "", "  ")
+		case formatNoir:
+			data, err = toNoir(testCase)
+			ext = ".toml"
+		}
+		if err != nil {
+			fatal("Failed to convert test case", "file", file, "error", err)
+		}
+		for _, dir := range dirs {
+			path := filepath.Join(dir, name+ext)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				fatal("Failed to write test case", "error", err)
+			}
+			slog.Info("Test case converted", "file", file, "from", from, "to", convertTo, "output", path)
+		}
+	}
+}
+
+// readAnyTestCase reads a test case in any of the formats: TOML is Noir's,
+// and JSON with a pubkey array circom's
+func readAnyTestCase(file string) (*TestCase, string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	if filepath.Ext(file) == ".toml" {
+		testCase, err := fromNoir(data)
+		return testCase, formatNoir, err
+	}
+
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, "", err
+	}
+	if _, ok := probe["pubkey"]; ok {
+		var c circomTestCase
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, "", err
+		}
+		testCase, err := fromCircom(&c)
+		return testCase, formatCircom, err
+	}
+	testCase, err := loadTestCase(file)
+	return testCase, formatGnark, err
+}
+
+// values returns the five values of a test case in the order of the circuits
+func (t *TestCase) values() ([]*big.Int, error) {
+	var values []*big.Int
+	for _, field := range []struct{ name, value string }{
+		{"r", t.R}, {"s", t.S}, {"msghash", t.MsgHash}, {"pubkey_x", t.PubKeyX}, {"pubkey_y", t.PubKeyY},
+	} {
+		v, err := parseHexField(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", field.name, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func newTestCaseFromValues(values []*big.Int) *TestCase {
+	hex := func(v *big.Int) string { return fmt.Sprintf("0x%x", v) }
+	return &TestCase{R: hex(values[0]), S: hex(values[1]), MsgHash: hex(values[2]), PubKeyX: hex(values[3]), PubKeyY: hex(values[4])}
+}
+
+func toCircom(t *TestCase) *circomTestCase {
+	values, _ := t.values()
+	chunks := make([][]string, len(values))
+	mask := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), circomChunkBits), big.NewInt(1))
+	for i, v := range values {
+		v = new(big.Int).Set(v)
+		for j := 0; j < circomChunks; j++ {
+			chunks[i] = append(chunks[i], new(big.Int).And(v, mask).String())
+			v.Rsh(v, circomChunkBits)
+		}
+	}
+	return &circomTestCase{R: chunks[0], S: chunks[1], MsgHash: chunks[2], PubKey: [][]string{chunks[3], chunks[4]}}
+}
+
+func fromCircom(c *circomTestCase) (*TestCase, error) {
+	if len(c.PubKey) != 2 {
+		return nil, fmt.Errorf("pubkey has %d coordinates, not 2", len(c.PubKey))
+	}
+	var values []*big.Int
+	for _, field := range []struct {
+		name   string
+		chunks []string
+	}{
+		{"r", c.R}, {"s", c.S}, {"msghash", c.MsgHash}, {"pubkey[0]", c.PubKey[0]}, {"pubkey[1]", c.PubKey[1]},
+	} {
+		if len(field.chunks) != circomChunks {
+			return nil, fmt.Errorf("%s has %d limbs, not %d", field.name, len(field.chunks), circomChunks)
+		}
+		v := new(big.Int)
+		for j := circomChunks - 1; j >= 0; j-- {
+			chunk, ok := new(big.Int).SetString(field.chunks[j], 10)
+			if !ok || chunk.Sign() < 0 || chunk.BitLen() > circomChunkBits {
+				return nil, fmt.Errorf("%s limb %d is not a %d-bit decimal: %q", field.name, j, circomChunkBits, field.chunks[j])
+			}
+			v.Lsh(v, circomChunkBits).Or(v, chunk)
+		}
+		values = append(values, v)
+	}
+	return newTestCaseFromValues(values), nil
+}
+
+// noirNames are the inputs of the Noir circuit, in the order of values
+var noirNames = []string{"signature_r", "signature_s", "hashed_message", "pub_key_x", "pub_key_y"}
+
+func toNoir(t *TestCase) ([]byte, error) {
+	values, err := t.values()
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string][]string{}
+	for i, v := range values {
+		fields[noirNames[i]] = packBytes(v.FillBytes(make([]byte, 32)))
+	}
+	var b bytes.Buffer
+	b.WriteString("# Field values (matching Noir's pack_bytes - 31-byte chunks)\n")
+	for _, name := range []string{"hashed_message", "pub_key_x", "pub_key_y", "signature_r", "signature_s"} {
+		quoted := make([]string, len(fields[name]))
+		for i, f := range fields[name] {
+			quoted[i] = `"` + f + `"`
+		}
+		fmt.Fprintf(&b, "%s = [%s]\n", name, strings.Join(quoted, ", "))
+	}
+	return b.Bytes(), nil
+}
+
+// packBytes packs bytes into fields of 31 bytes each, little-endian within a
+// field, as the Noir circuit's pack_bytes does
+func packBytes(data []byte) []string {
+	padded := make([]byte, (len(data)/noirChunkBytes+1)*noirChunkBytes)
+	copy(padded, data)
+	var fields []string
+	for start := 0; start < len(padded); start += noirChunkBytes {
+		field := new(big.Int)
+		for i := start + noirChunkBytes - 1; i >= start; i-- {
+			field.Lsh(field, 8).Or(field, big.NewInt(int64(padded[i])))
+		}
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+var noirLine = regexp.MustCompile(`^\s*(\w+)\s*=\s*(.+?)\s*$`)
+
+func fromNoir(data []byte) (*TestCase, error) {
+	fields := map[string][]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		match := noirLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("cannot parse TOML line %q", line)
+		}
+		value := strings.Trim(match[2], "[]")
+		for _, f := range strings.Split(value, ",") {
+			fields[match[1]] = append(fields[match[1]], strings.Trim(strings.TrimSpace(f), `"`))
+		}
+	}
+
+	var values []*big.Int
+	for _, name := range noirNames {
+		packed, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("missing %s", name)
+		}
+		// Unpack the 32 big-endian bytes: 31 from the first field, the last
+		// from the second
+		var buf []byte
+		for _, f := range packed {
+			v, ok := new(big.Int).SetString(f, 10)
+			if !ok || v.Sign() < 0 || v.BitLen() > 8*noirChunkBytes {
+				return nil, fmt.Errorf("%s holds %q, not a packed field", name, f)
+			}
+			chunk := v.FillBytes(make([]byte, noirChunkBytes))
+			for i := noirChunkBytes - 1; i >= 0; i-- {
+				buf = append(buf, chunk[i])
+			}
+		}
+		if len(buf) < 32 {
+			return nil, fmt.Errorf("%s packs %d bytes, not 32", name, len(buf))
+		}
+		for _, extra := range buf[32:] {
+			if extra != 0 {
+				return nil, fmt.Errorf("%s packs more than 32 bytes", name)
+			}
+		}
+		values = append(values, new(big.Int).SetBytes(buf[:32]))
+	}
+	return newTestCaseFromValues(values), nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestConvertRoundTrip converts a test case to the circom and Noir formats
+// and back, which must give back the values it started from
+func TestConvertRoundTrip(t *testing.T) {
+	tc := validTestCase(t)
+	tests := []struct {
+		name string
+		tc   TestCase
+	}{
+		{"signature", tc},
+		{"zero and one", TestCase{R: "0x1", S: "0x0", MsgHash: "0x0", PubKeyX: "0x1", PubKeyY: "0x0"}},
+		{"all bits set", TestCase{
+			R: "0x" + strings.Repeat("f", 64), S: "0x" + strings.Repeat("f", 64), MsgHash: "0x" + strings.Repeat("f", 64),
+			PubKeyX: "0x" + strings.Repeat("f", 64), PubKeyY: "0x" + strings.Repeat("f", 64),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tt.tc.values()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			circom, err := fromCircom(toCircom(&tt.tc))
+			if err != nil {
+				t.Fatalf("circom: %v", err)
+			}
+			if got, _ := circom.values(); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("circom round trip = %v, want %v", got, want)
+			}
+
+			data, err := toNoir(&tt.tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			noir, err := fromNoir(data)
+			if err != nil {
+				t.Fatalf("noir: %v", err)
+			}
+			if got, _ := noir.values(); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("noir round trip = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestConvertEncoding checks the limbs and packed fields of values whose
+// encoding can be worked out by hand: the limbs are least significant first,
+// and the fields hold the big-endian bytes, the first byte least significant,
+// so the last byte of a value is the second field
+func TestConvertEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		limbs  []string
+		fields []string
+	}{
+		{"one", "0x1", []string{"1", "0", "0", "0", "0", "0"}, []string{"0", "1"}},
+		{"2^43", "0x80000000000", []string{"0", "1", "0", "0", "0", "0"}, []string{"3291009114642412084309938365114701009965471731267159726697218048", "0"}},
+		{"2^255", "0x8" + strings.Repeat("0", 63), []string{"0", "0", "0", "0", "0", "1099511627776"}, []string{"128", "0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TestCase{R: tt.value, S: "0x1", MsgHash: "0x1", PubKeyX: "0x1", PubKeyY: "0x1"}
+			if got := toCircom(tc).R; !reflect.DeepEqual(got, tt.limbs) {
+				t.Errorf("circom limbs = %v, want %v", got, tt.limbs)
+			}
+			v, _ := parseHexField(tt.value)
+			if got := packBytes(v.FillBytes(make([]byte, 32))); !reflect.DeepEqual(got, tt.fields) {
+				t.Errorf("noir fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestFromCircomErrors(t *testing.T) {
+	limbs := func(l ...string) []string { return l }
+	valid := limbs("1", "0", "0", "0", "0", "0")
+	tests := []struct {
+		name    string
+		c       circomTestCase
+		wantErr string
+	}{
+		{"one coordinate", circomTestCase{R: valid, S: valid, MsgHash: valid, PubKey: [][]string{valid}}, "pubkey has 1 coordinates"},
+		{"five limbs", circomTestCase{R: valid[:5], S: valid, MsgHash: valid, PubKey: [][]string{valid, valid}}, "r has 5 limbs"},
+		{"limb wider than 43 bits", circomTestCase{R: valid, S: limbs("8796093022208", "0", "0", "0", "0", "0"), MsgHash: valid, PubKey: [][]string{valid, valid}}, "s limb 0 is not a 43-bit decimal"},
+		{"negative limb", circomTestCase{R: valid, S: valid, MsgHash: limbs("1", "-1", "0", "0", "0", "0"), PubKey: [][]string{valid, valid}}, "msghash limb 1"},
+		{"hex limb", circomTestCase{R: valid, S: valid, MsgHash: valid, PubKey: [][]string{valid, limbs("0x1", "0", "0", "0", "0", "0")}}, "pubkey[1] limb 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fromCircom(&tt.c)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fromCircom = %v, want an error with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFromNoirErrors(t *testing.T) {
+	tc := validTestCase(t)
+	valid, err := toNoir(&tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replace := func(name, value string) string {
+		var lines []string
+		for _, line := range strings.Split(string(valid), "\n") {
+			if strings.HasPrefix(line, name+" ") {
+				if value == "" {
+					continue
+				}
+				line = name + " = " + value
+			}
+			lines = append(lines, line)
+		}
+		return strings.Join(lines, "\n")
+	}
+	tests := []struct {
+		name    string
+		toml    string
+		wantErr string
+	}{
+		{"missing input", replace("signature_s", ""), "missing signature_s"},
+		{"not TOML", string(valid) + "pub_key_x\n", "cannot parse TOML line"},
+		{"not decimal", replace("hashed_message", `["0x1", "0"]`), `hashed_message holds "0x1"`},
+		{"field wider than 31 bytes", replace("pub_key_y", `["1", "`+strings.Repeat("9", 80)+`"]`), "pub_key_y holds"},
+		{"one field", replace("signature_r", `["1"]`), "signature_r packs 31 bytes, not 32"},
+		{"more than 32 bytes", replace("pub_key_x", `["0", "256"]`), "pub_key_x packs more than 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fromNoir([]byte(tt.toml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("fromNoir = %v, want an error with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestReadAnyTestCase checks that each format is recognized from its file
+func TestReadAnyTestCase(t *testing.T) {
+	tc := validTestCase(t)
+	gnark, err := json.Marshal(tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	circom, err := json.Marshal(toCircom(&tc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noir, err := toNoir(&tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := tc.values()
+
+	dir := t.TempDir()
+	tests := []struct {
+		file   string
+		data   []byte
+		format string
+	}{
+		{"case.json", gnark, formatGnark},
+		{"input.json", circom, formatCircom},
+		{"Prover.toml", noir, formatNoir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, format, err := readAnyTestCase(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Errorf("format = %s, want %s", format, tt.format)
+			}
+			if values, _ := got.values(); fmt.Sprint(values) != fmt.Sprint(want) {
+				t.Errorf("values = %v, want %v", values, want)
+			}
+		})
+	}
+
+	t.Run("malformed JSON", func(t *testing.T) {
+		path := filepath.Join(dir, "broken.json")
+		if err := os.WriteFile(path, []byte(`{"pubkey": [`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := readAnyTestCase(path); err == nil {
+			t.Error("readAnyTestCase succeeded, want an error")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// cpuLimitEnv marks the child process that runs under the CPU quota, so it
+// does not start another one
+const cpuLimitEnv = "GNARK_BENCH_CPU_LIMITED"
+
+// throttlePeriod is the period the quota is enforced over, the default CFS
+// period of cgroups
+const throttlePeriod = 100 * time.Millisecond
+
+var (
+	// command line flags
+	cpuLimitCores int
+	cpuQuota      string
+)
+
+// currentCPULimit describes the CPU limit the measurements of this process
+// run under, and is empty without one
+var currentCPULimit string
+
+// applyCPULimit simulates a slower device. -cpus limits the threads running Go
+// code (GOMAXPROCS). -cpu-quota lets the process run for that share of every
+// 100ms period and pauses it for the rest, as a cgroup CPU quota does, so
+// -cpus 2 -cpu-quota 50% behaves like docker run --cpuset-cpus 0,1 --cpus 1.
+// Pausing needs no privileges: the command reruns itself as a child process,
+// which is stopped and continued with signals, and whose exit status this
+// process exits with. The timings the child records include the pauses.
+func applyCPULimit() {
+	if cpuLimitCores == 0 && cpuQuota == "" {
+		return
+	}
+	if cpuLimitCores < 0 {
+		fatal("-cpus must be at least 1")
+	}
+	if benchThreads != "" {
+		fatal("-cpus and -cpu-quota cannot be combined with -threads")
+	}
+	quota := 1.0
+	if cpuQuota != "" {
+		var err error
+		quota, err = parseRegressionLimit(cpuQuota)
+		if err != nil || quota <= 0 || quota > 1 {
+			fatal("Invalid -cpu-quota, use a percentage between 0% and 100%", "cpu_quota", cpuQuota)
+		}
+	}
+
+	cores := runtime.NumCPU()
+	if cpuLimitCores > 0 {
+		cores = cpuLimitCores
+		runtime.GOMAXPROCS(cores)
+	}
+	currentCPULimit = fmt.Sprintf("%d CPUs", cores)
+	if quota < 1 {
+		currentCPULimit += fmt.Sprintf(" at %g%%", quota*100)
+	}
+	if quota == 1 || os.Getenv(cpuLimitEnv) != "" {
+		return
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fatal("Failed to find the benchmark binary", "error", err)
+	}
+	cmd := exec.Command(self, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), cpuLimitEnv+"=1")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	if proofOutput != nil {
+		// -stdin sent os.Stdout to stderr; the child writes the proof to the
+		// real stdout
+		cmd.Stdout = proofOutput
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		fatal("Failed to start the CPU limited run", "error", err)
+	}
+	slog.Info("Limiting the CPU", "limit", currentCPULimit, "cpus_of_time", float64(cores)*quota)
+	stopThrottle, err := throttle(cmd.Process, quota)
+	if err != nil {
+		cmd.Process.Kill()
+		fatal("Failed to throttle the CPU", "error", err)
+	}
+	err = cmd.Wait()
+	stopThrottle()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// A child killed by a signal has no exit code
+		os.Exit(max(exitErr.ExitCode(), 1))
+	}
+	if err != nil {
+		fatal("Failed to run with the CPU limit", "error", err)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func throttle(p *os.Process, quota float64) (func(), error) {
+	return nil, errors.New("-cpu-quota needs job control signals, which only Unix systems have")
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// throttle stops and continues a process so it runs for quota of every
+// throttlePeriod. Interrupting this process continues and interrupts the
+// child, which would otherwise stay stopped. Call the returned function once
+// the process exited.
+func throttle(p *os.Process, quota float64) (func(), error) {
+	run := time.Duration(float64(throttlePeriod) * quota)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		defer signal.Stop(interrupts)
+		// The process may exit at any time, so errors from signalling it
+		// are expected and ignored
+		wait := func(d time.Duration) bool {
+			select {
+			case <-done:
+				return false
+			case sig := <-interrupts:
+				p.Signal(syscall.SIGCONT)
+				p.Signal(sig)
+				return false
+			case <-time.After(d):
+				return true
+			}
+		}
+		for wait(run) {
+			p.Signal(syscall.SIGSTOP)
+			ok := wait(throttlePeriod - run)
+			p.Signal(syscall.SIGCONT)
+			if !ok {
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+)
+
+var (
+	// command line flags
+	cpuProfile bool
+)
+
+// startCPUProfile captures a CPU profile of one phase into the profiles
+// directory of the results, e.g. benchmarks/profiles/cpu_prove_1.pprof. It
+// does nothing without -cpuprofile. The returned function stops the profile
+// and returns its path relative to the results directory, or "" when no
+// profile was taken.
+func startCPUProfile(phase, testCase string) func() string {
+	if !cpuProfile {
+		return func() string { return "" }
+	}
+
+	relPath, path := profilePath("cpu", phase, testCase)
+	f, err := os.Create(path)
+	if err != nil {
+		fatal("Failed to create CPU profile", "error", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		fatal("Failed to start CPU profile", "error", err)
+	}
+
+	return func() string {
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			fatal("Failed to write CPU profile", "error", err)
+		}
+		return relPath
+	}
+}
+
+// profilePath names the profile of a kind taken of a phase, both relative to
+// the results directory and in full, and creates its directory
+func profilePath(kind, phase, testCase string) (string, string) {
+	name := kind + "_" + phase
+	if testCase != "" {
+		name += "_" + testCase
+	}
+	relPath := filepath.Join("profiles", name+".pprof")
+	path := filepath.Join(outputDir, "benchmarks", relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fatal("Failed to create profiles directory", "error", err)
+	}
+	return relPath, path
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// Curves the circuit can be compiled and proven over. The ECDSA arithmetic is
+// emulated, so only the outer proving curve changes.
+var supportedCurves = []ecc.ID{
+	ecc.BN254,
+	ecc.BLS12_377,
+	ecc.BLS12_381,
+	ecc.BLS24_315,
+	ecc.BLS24_317,
+	ecc.BW6_761,
+	ecc.BW6_633,
+}
+
+const defaultCurve = "bn254"
+
+var (
+	// command line flags
+	curveName string
+)
+
+// parseCurve maps a curve name such as "bls24-315" or "bls24_315" to its ID
+func parseCurve(name string) (ecc.ID, error) {
+	normalized := strings.ReplaceAll(strings.ToLower(name), "-", "_")
+	for _, id := range supportedCurves {
+		if id.String() == normalized {
+			return id, nil
+		}
+	}
+
+	names := make([]string, len(supportedCurves))
+	for i, id := range supportedCurves {
+		names[i] = curveDisplayName(id)
+	}
+	return ecc.UNKNOWN, fmt.Errorf("unsupported curve %q (use one of: %s)", name, strings.Join(names, ", "))
+}
+
+// curveDisplayName returns the conventional dashed name, e.g. "bls24-315"
+func curveDisplayName(id ecc.ID) string {
+	return strings.ReplaceAll(id.String(), "_", "-")
+}
+
+// selectedCurve returns the proving curve chosen with -curve. The flag is
+// validated by defaultSettings or resolveSettings before use.
+func selectedCurve() ecc.ID {
+	id, _ := parseCurve(curveName)
+	return id
+}
@@ -0,0 +1,21 @@
+package main
+
+import "time"
+
+// daemonPoll is how often an idle daemon looks for jobs queued by another
+// process
+const daemonPoll = time.Minute
+
+// Job states in the queue
+const (
+	jobPending = "pending"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+var (
+	// command line flags
+	daemonSchedule string
+	queuePath      string
+)
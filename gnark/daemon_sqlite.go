@@ -0,0 +1,276 @@
+//go:build !wasm
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	_ "modernc.org/sqlite"
+)
+
+const queueSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	config      TEXT NOT NULL,
+	config_yaml TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	queued_at   TEXT NOT NULL,
+	started_at  TEXT,
+	finished_at TEXT,
+	error       TEXT,
+	results     TEXT
+);
+CREATE TABLE IF NOT EXISTS schedule (
+	config   TEXT PRIMARY KEY,
+	last_run TEXT NOT NULL
+);`
+
+// openQueue opens the job queue, creating it on first use
+func openQueue() *sql.DB {
+	path := queuePath
+	if path == "" {
+		path = filepath.Join(outputDir, "daemon", "queue.db")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fatal("Failed to create the queue directory", "error", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		fatal("Failed to open the job queue", "error", err)
+	}
+	// SQLite allows one writer; the daemon and enqueue may run at once
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA busy_timeout = 5000; PRAGMA journal_mode = WAL;" + queueSchema); err != nil {
+		fatal("Failed to initialize the job queue", "file", path, "error", err)
+	}
+	return db
+}
+
+// enqueueJob queues a matrix config, keeping a copy of it so that the job runs
+// the config as it was when queued
+func enqueueJob(db *sql.DB, configFile string) int64 {
+	loadMatrixConfig(configFile)
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		fatal("Failed to read matrix config", "error", err)
+	}
+	res, err := db.Exec("INSERT INTO jobs (config, config_yaml, status, queued_at) VALUES (?, ?, ?, ?)",
+		configFile, string(data), jobPending, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		fatal("Failed to queue job", "error", err)
+	}
+	id, _ := res.LastInsertId()
+	return id
+}
+
+// daemonEnqueue queues matrix configs to run as soon as the daemon is idle
+func daemonEnqueue(configFiles []string) {
+	if len(configFiles) == 0 {
+		fatal("Missing matrix config file for daemon enqueue")
+	}
+	db := openQueue()
+	defer db.Close()
+	for _, configFile := range configFiles {
+		fmt.Printf("Queued %s as job %d\n", configFile, enqueueJob(db, configFile))
+	}
+}
+
+// daemonStatus lists the jobs in the queue, newest first
+func daemonStatus() {
+	db := openQueue()
+	defer db.Close()
+	rows, err := db.Query("SELECT id, config, status, queued_at, COALESCE(started_at, ''), COALESCE(finished_at, ''), COALESCE(error, '') FROM jobs ORDER BY id DESC")
+	if err != nil {
+		fatal("Failed to read the job queue", "error", err)
+	}
+	defer rows.Close()
+	fmt.Printf("%5s %-8s %-20s %-20s %-20s %s\n", "Job", "Status", "Queued", "Started", "Finished", "Config")
+	for rows.Next() {
+		var id int64
+		var config, status, queued, started, finished, jobErr string
+		if err := rows.Scan(&id, &config, &status, &queued, &started, &finished, &jobErr); err != nil {
+			fatal("Failed to read the job queue", "error", err)
+		}
+		fmt.Printf("%5d %-8s %-20s %-20s %-20s %s\n", id, status, queued, started, finished, config)
+		if jobErr != "" {
+			fmt.Printf("      %s\n", jobErr)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		fatal("Failed to read the job queue", "error", err)
+	}
+}
+
+// runDaemon runs benchmark jobs from a queue kept in SQLite, for continuous
+// performance tracking on dedicated hardware. With -schedule, a cron
+// expression such as "0 2 * * *", the matrix configs given as arguments are
+// queued at every scheduled time; jobs can also be queued at any time with
+// daemon enqueue. Jobs run one at a time, oldest first, each as a matrix
+// child process in <dir>/daemon/job-<id>, and its matrix.json is stored in the
+// queue. The queue and the time of the last scheduled run persist, so after a
+// restart the daemon reruns the job it was interrupted in, and queues once a
+// scheduled run it missed while down. SIGINT or SIGTERM stop the daemon,
+// returning a running job to the queue.
+func runDaemon(configFiles []string) {
+	var schedule cron.Schedule
+	if daemonSchedule != "" {
+		var err error
+		schedule, err = cron.ParseStandard(daemonSchedule)
+		if err != nil {
+			fatal("Invalid -schedule", "schedule", daemonSchedule, "error", err)
+		}
+		if len(configFiles) == 0 {
+			fatal("-schedule needs the matrix config files to queue")
+		}
+		for _, configFile := range configFiles {
+			loadMatrixConfig(configFile)
+		}
+	}
+
+	db := openQueue()
+	defer db.Close()
+	res, err := db.Exec("UPDATE jobs SET status = ?, started_at = NULL WHERE status = ?", jobPending, jobRunning)
+	if err != nil {
+		fatal("Failed to recover interrupted jobs", "error", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		slog.Info("Requeued the jobs interrupted by the last shutdown", "jobs", n)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	startMetricsServer()
+	slog.Info("Benchmark daemon started")
+	for ctx.Err() == nil {
+		wake := time.Now().Add(daemonPoll)
+		if schedule != nil {
+			next := queueScheduled(db, schedule, configFiles)
+			if next.Before(wake) {
+				wake = next
+			}
+		}
+
+		if runNextJob(ctx, db) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(wake)):
+		}
+	}
+	slog.Info("Benchmark daemon stopped")
+}
+
+// queueScheduled queues every config whose scheduled time has passed since its
+// last run, and returns the next scheduled time. A config run for the first
+// time waits for its next scheduled time.
+func queueScheduled(db *sql.DB, schedule cron.Schedule, configFiles []string) time.Time {
+	now := time.Now()
+	next := now.Add(24 * time.Hour * 366)
+	for _, configFile := range configFiles {
+		var lastRun string
+		err := db.QueryRow("SELECT last_run FROM schedule WHERE config = ?", configFile).Scan(&lastRun)
+		if errors.Is(err, sql.ErrNoRows) {
+			lastRun = now.UTC().Format(time.RFC3339)
+			if _, err := db.Exec("INSERT INTO schedule (config, last_run) VALUES (?, ?)", configFile, lastRun); err != nil {
+				fatal("Failed to update the schedule", "error", err)
+			}
+		} else if err != nil {
+			fatal("Failed to read the schedule", "error", err)
+		}
+		last, err := time.Parse(time.RFC3339, lastRun)
+		if err != nil {
+			fatal("Invalid last run in the schedule", "last_run", lastRun, "config", configFile)
+		}
+
+		due := schedule.Next(last)
+		if !due.After(now) {
+			id := enqueueJob(db, configFile)
+			slog.Info("Queued scheduled run", "config", configFile, "job", id)
+			if _, err := db.Exec("UPDATE schedule SET last_run = ? WHERE config = ?", now.UTC().Format(time.RFC3339), configFile); err != nil {
+				fatal("Failed to update the schedule", "error", err)
+			}
+			due = schedule.Next(now)
+		}
+		if due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
+
+// runNextJob runs the oldest pending job, and reports whether there was one
+func runNextJob(ctx context.Context, db *sql.DB) bool {
+	var id int64
+	var config, configYAML string
+	err := db.QueryRow("SELECT id, config, config_yaml FROM jobs WHERE status = ? ORDER BY id LIMIT 1", jobPending).Scan(&id, &config, &configYAML)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		fatal("Failed to read the job queue", "error", err)
+	}
+	if _, err := db.Exec("UPDATE jobs SET status = ?, started_at = ? WHERE id = ?", jobRunning, time.Now().UTC().Format(time.RFC3339), id); err != nil {
+		fatal("Failed to start job", "error", err)
+	}
+
+	dir := filepath.Join(outputDir, "daemon", "job-"+strconv.FormatInt(id, 10))
+	slog.Info("Running job", "job", id, "config", config, "dir", dir)
+	jobErr := func() error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		configCopy := filepath.Join(dir, "matrix.yaml")
+		if err := os.WriteFile(configCopy, []byte(configYAML), 0644); err != nil {
+			return err
+		}
+		self, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, self, "matrix", "-d", dir, configCopy)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}()
+
+	if ctx.Err() != nil {
+		// Stopped by a signal: run the job again after a restart
+		if _, err := db.Exec("UPDATE jobs SET status = ?, started_at = NULL WHERE id = ?", jobPending, id); err != nil {
+			slog.Error("Failed to requeue job", "job", id, "error", err)
+		}
+		return true
+	}
+	finishedAt := time.Now().UTC().Format(time.RFC3339)
+	if jobErr != nil {
+		slog.Error("Job failed", "job", id, "error", jobErr)
+		daemonJobs.WithLabelValues(jobFailed).Inc()
+		if _, err := db.Exec("UPDATE jobs SET status = ?, finished_at = ?, error = ? WHERE id = ?", jobFailed, finishedAt, jobErr.Error(), id); err != nil {
+			fatal("Failed to record job failure", "error", err)
+		}
+		return true
+	}
+	results, err := os.ReadFile(filepath.Join(dir, "benchmarks", matrixFile))
+	if err != nil {
+		fatal("Failed to read the job's matrix results", "error", err)
+	}
+	if _, err := db.Exec("UPDATE jobs SET status = ?, finished_at = ?, results = ? WHERE id = ?", jobDone, finishedAt, string(results), id); err != nil {
+		fatal("Failed to record job results", "error", err)
+	}
+	daemonJobs.WithLabelValues(jobDone).Inc()
+	slog.Info("Job done", "job", id)
+	return true
+}
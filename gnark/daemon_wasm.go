@@ -0,0 +1,17 @@
+//go:build wasm
+
+package main
+
+// The job queue is kept in SQLite, whose driver does not build for wasm
+
+func runDaemon(configFiles []string) {
+	fatal("The daemon is not available in the wasm build")
+}
+
+func daemonEnqueue(configFiles []string) {
+	runDaemon(configFiles)
+}
+
+func daemonStatus() {
+	runDaemon(nil)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// command line flags
+	reportHTML bool
+)
+
+const (
+	dashboardLabelWidth = 360
+	dashboardPlotWidth  = 520
+	dashboardBarHeight  = 24
+	dashboardLineHeight = 260
+	dashboardMargin     = 16
+)
+
+// dashboardColors tell the configurations apart in every chart
+var dashboardColors = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// dashboardSeries is one configuration: the latest of its results files, for
+// comparing configurations, and all of them in time order, for the trend
+type dashboardSeries struct {
+	title    string
+	color    string
+	latest   reportSection
+	sections []reportSection
+	times    []time.Time
+}
+
+// chartBar is one bar of a bar chart, with the value shown next to it
+type chartBar struct {
+	series int
+	value  float64
+	text   string
+}
+
+// printDashboard renders results files as a self-contained HTML page, with
+// charts of proving time, memory, and gas across configurations, and of
+// proving time over time when several results files share a configuration.
+// Hovering a bar or point shows its value, and clicking a configuration in
+// the legend hides it from every chart.
+func printDashboard(sections []reportSection) {
+	series := groupSeries(sections)
+
+	w := bufio.NewWriter(os.Stdout)
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ECDSA benchmark dashboard</title>
+<style>
+body { font-family: Verdana, sans-serif; font-size: 13px; margin: 24px; color: #222; }
+h1 { font-size: 20px; }
+h2 { font-size: 16px; margin-top: 32px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+.legend span { cursor: pointer; margin-right: 16px; user-select: none; }
+.legend .swatch { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; }
+.legend .off { opacity: 0.35; }
+.hidden { display: none; }
+svg rect:hover, svg circle:hover { opacity: 0.75; }
+</style>
+</head>
+<body>
+<h1>ECDSA benchmark dashboard</h1>
+`)
+
+	fmt.Fprintln(w, `<div class="legend">`)
+	for i, s := range series {
+		fmt.Fprintf(w, `<span data-legend="%d"><span class="swatch" style="background:%s"></span>%s</span>`+"\n",
+			i, s.color, html.EscapeString(s.title))
+	}
+	fmt.Fprintln(w, `</div>`)
+
+	fmt.Fprintln(w, `<h2>Overview</h2>`)
+	fmt.Fprintln(w, `<table>`)
+	fmt.Fprintln(w, `<tr><th>Configuration</th><th>Constraints</th><th>Setup</th><th>Prove</th><th>Verify</th><th>Peak heap (prove)</th><th>Proof size</th><th>Verifier gas</th><th>Recorded</th></tr>`)
+	for i, s := range series {
+		constraints, setup := "-", "-"
+		if m := s.latest.find("compile", ""); m != nil && m.Constraints > 0 {
+			constraints = fmt.Sprint(m.Constraints)
+		}
+		if m := s.latest.find("setup", ""); m != nil {
+			setup = formatSecs(m.MeanSecs)
+		}
+		heap := "-"
+		if peak := s.latest.provePeakHeap(); peak > 0 {
+			heap = formatBytes(peak)
+		}
+		recorded := "-"
+		if at := s.times[len(s.times)-1]; !at.IsZero() {
+			recorded = at.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "<tr data-series=\"%d\"><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			i, html.EscapeString(s.title), constraints, setup, s.latest.meanOver("prove"), s.latest.meanOver("verify"),
+			heap, s.latest.proofSize(), s.latest.meanGas(), recorded)
+	}
+	fmt.Fprintln(w, `</table>`)
+
+	var prove, heap, gas []chartBar
+	for i, s := range series {
+		if mean, ok := s.latest.meanSecs("prove"); ok {
+			prove = append(prove, chartBar{series: i, value: mean, text: formatSecs(mean)})
+		}
+		if peak := s.latest.provePeakHeap(); peak > 0 {
+			heap = append(heap, chartBar{series: i, value: float64(peak), text: formatBytes(peak)})
+		}
+		if mean, ok := s.latest.gasMean(); ok {
+			gas = append(gas, chartBar{series: i, value: float64(mean), text: fmt.Sprint(mean)})
+		}
+	}
+	writeBarChart(w, "Proving time (mean over test cases)", series, prove)
+	writeBarChart(w, "Peak heap while proving", series, heap)
+	writeBarChart(w, "Verifier gas (mean over test cases)", series, gas)
+	writeTrendChart(w, "Proving time over time", series)
+
+	fmt.Fprint(w, `<script>
+document.querySelectorAll("[data-legend]").forEach(function (entry) {
+  entry.addEventListener("click", function () {
+    entry.classList.toggle("off");
+    document.querySelectorAll('[data-series="' + entry.dataset.legend + '"]').forEach(function (el) {
+      el.classList.toggle("hidden");
+    });
+  });
+});
+</script>
+</body>
+</html>
+`)
+	if err := w.Flush(); err != nil {
+		fatal("Failed to write dashboard", "error", err)
+	}
+}
+
+// groupSeries groups results files by configuration, in the order each
+// configuration first appears, and orders each group by the time it was
+// recorded
+func groupSeries(sections []reportSection) []*dashboardSeries {
+	var series []*dashboardSeries
+	byTitle := make(map[string]*dashboardSeries)
+	for _, section := range sections {
+		title := section.title()
+		s, ok := byTitle[title]
+		if !ok {
+			s = &dashboardSeries{title: title, color: dashboardColors[len(series)%len(dashboardColors)]}
+			byTitle[title] = s
+			series = append(series, s)
+		}
+		s.sections = append(s.sections, section)
+	}
+
+	for _, s := range series {
+		sort.SliceStable(s.sections, func(i, j int) bool {
+			return s.sections[i].recordedAt().Before(s.sections[j].recordedAt())
+		})
+		s.times = make([]time.Time, len(s.sections))
+		for i, section := range s.sections {
+			s.times[i] = section.recordedAt()
+		}
+		s.latest = s.sections[len(s.sections)-1]
+	}
+	return series
+}
+
+// recordedAt is when the proofs of a results file were last measured, or its
+// latest measurement of any phase when it has no proofs
+func (s reportSection) recordedAt() time.Time {
+	var latest, latestProve time.Time
+	for _, m := range s.results.Measurements {
+		at, err := time.Parse(time.RFC3339, m.RecordedAt)
+		if err != nil {
+			continue
+		}
+		if at.After(latest) {
+			latest = at
+		}
+		if m.Phase == "prove" && at.After(latestProve) {
+			latestProve = at
+		}
+	}
+	if !latestProve.IsZero() {
+		return latestProve
+	}
+	return latest
+}
+
+// provePeakHeap is the largest peak heap of proving any test case, or 0 when
+// memory was not tracked
+func (s reportSection) provePeakHeap() uint64 {
+	var peak uint64
+	for _, m := range s.results.Measurements {
+		if m.Phase == "prove" && m.fullCPU() && m.Allocs != nil && m.Allocs.PeakHeapBytes > peak {
+			peak = m.Allocs.PeakHeapBytes
+		}
+	}
+	return peak
+}
+
+// writeBarChart draws one horizontal bar per configuration, scaled to the
+// largest value
+func writeBarChart(w *bufio.Writer, title string, series []*dashboardSeries, bars []chartBar) {
+	if len(bars) == 0 {
+		return
+	}
+	var largest float64
+	for _, b := range bars {
+		largest = math.Max(largest, b.value)
+	}
+
+	width := dashboardLabelWidth + dashboardPlotWidth + 100
+	height := len(bars)*dashboardBarHeight + 2*dashboardMargin
+	fmt.Fprintf(w, "<h2>%s</h2>\n", html.EscapeString(title))
+	fmt.Fprintf(w, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" font-family="Verdana,sans-serif" font-size="11">`+"\n", width, height)
+	for i, b := range bars {
+		s := series[b.series]
+		y := dashboardMargin + i*dashboardBarHeight
+		barWidth := 0.0
+		if largest > 0 {
+			barWidth = b.value / largest * dashboardPlotWidth
+		}
+		fmt.Fprintf(w, `<g data-series="%d"><title>%s: %s</title>`, b.series, html.EscapeString(s.title), html.EscapeString(b.text))
+		fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%s</text>`, dashboardLabelWidth-8, y+16, html.EscapeString(s.title))
+		fmt.Fprintf(w, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s" rx="2"/>`, dashboardLabelWidth, y+4, barWidth, dashboardBarHeight-8, s.color)
+		fmt.Fprintf(w, `<text x="%.1f" y="%d">%s</text></g>`+"\n", float64(dashboardLabelWidth)+barWidth+6, y+16, html.EscapeString(b.text))
+	}
+	fmt.Fprintln(w, "</svg>")
+}
+
+// writeTrendChart plots the mean proving time of each configuration against
+// the time it was recorded, when at least one configuration was measured more
+// than once
+func writeTrendChart(w *bufio.Writer, title string, series []*dashboardSeries) {
+	var first, last time.Time
+	var largest float64
+	repeated := false
+	for _, s := range series {
+		if len(s.sections) > 1 {
+			repeated = true
+		}
+		for i, section := range s.sections {
+			mean, ok := section.meanSecs("prove")
+			if !ok || s.times[i].IsZero() {
+				continue
+			}
+			if first.IsZero() || s.times[i].Before(first) {
+				first = s.times[i]
+			}
+			if s.times[i].After(last) {
+				last = s.times[i]
+			}
+			largest = math.Max(largest, mean)
+		}
+	}
+	if !repeated || largest == 0 {
+		return
+	}
+
+	left, top := 80, dashboardMargin
+	width := dashboardLabelWidth + dashboardPlotWidth + 100
+	plotWidth := width - left - 2*dashboardMargin
+	x := func(at time.Time) float64 {
+		if !last.After(first) {
+			return float64(left + plotWidth/2)
+		}
+		return float64(left) + float64(at.Sub(first))/float64(last.Sub(first))*float64(plotWidth)
+	}
+	y := func(secs float64) float64 {
+		return float64(top) + (1-secs/largest)*dashboardLineHeight
+	}
+
+	fmt.Fprintf(w, "<h2>%s</h2>\n", html.EscapeString(title))
+	fmt.Fprintf(w, `<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" font-family="Verdana,sans-serif" font-size="11">`+"\n",
+		width, top+dashboardLineHeight+40)
+	// Axes, with the time range and the largest proving time
+	fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`, left, top, left, top+dashboardLineHeight)
+	fmt.Fprintf(w, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#999"/>`+"\n", left, top+dashboardLineHeight, left+plotWidth, top+dashboardLineHeight)
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%s</text>`, left-6, top+4, formatSecs(largest))
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">0</text>`, left-6, top+dashboardLineHeight+4)
+	fmt.Fprintf(w, `<text x="%d" y="%d">%s</text>`, left, top+dashboardLineHeight+20, first.Format("2006-01-02 15:04"))
+	fmt.Fprintf(w, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", left+plotWidth, top+dashboardLineHeight+20, last.Format("2006-01-02 15:04"))
+
+	for i, s := range series {
+		// The line goes below its points, so hovering a point shows its value
+		var points, circles strings.Builder
+		for j, section := range s.sections {
+			mean, ok := section.meanSecs("prove")
+			if !ok || s.times[j].IsZero() {
+				continue
+			}
+			px, py := x(s.times[j]), y(mean)
+			fmt.Fprintf(&points, "%.1f,%.1f ", px, py)
+			fmt.Fprintf(&circles, `<circle cx="%.1f" cy="%.1f" r="4" fill="%s"><title>%s, %s: %s</title></circle>`,
+				px, py, s.color, html.EscapeString(s.title), s.times[j].Format(time.RFC3339), formatSecs(mean))
+		}
+		fmt.Fprintf(w, `<g data-series="%d"><polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>%s</g>`+"\n",
+			i, strings.TrimSpace(points.String()), s.color, circles.String())
+	}
+	fmt.Fprintln(w, "</svg>")
+}
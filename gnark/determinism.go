@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// determinismRun is what one proof of the determinism check produced
+type determinismRun struct {
+	witness, publicWitness, proof []byte
+	prove                         time.Duration
+	verified                      bool
+}
+
+// checkDeterminism proves a test case twice from the same artifacts and
+// compares what the runs produced: the witness, the public witness, the proof
+// bytes, and the proving time. The witness never depends on randomness, so
+// two different witnesses point at nondeterminism in parsing or assignment.
+// The proof is only reproducible with -insecure-seed, which is reset before
+// each run so that both draw the same randomness; without it the proofs are
+// expected to differ and only their verification is checked. Proving times
+// are flagged when they differ by more than -variance-threshold, but do not
+// fail the check, as they vary with the load of the machine. The command
+// fails when anything that should be reproducible is not.
+func checkDeterminism(testCaseFile string) {
+	resolveSettings()
+
+	ccs := newConstraintSystem()
+	loadArtifact(filepath.Join(outputDir, circuitFileName()), ccs)
+	pk := newProvingKey()
+	loadArtifact(filepath.Join(outputDir, "proving.key"), pk)
+	vk := newVerifyingKey()
+	loadArtifact(filepath.Join(outputDir, "verifying.key"), vk)
+
+	testCase, err := loadTestCase(testCaseFile)
+	if err != nil {
+		fatal("Failed to load test case", "error", err)
+	}
+
+	prove := func() determinismRun {
+		if insecureSeed != "" {
+			reseed()
+		}
+		witness, err := createWitness(testCase)
+		if err != nil {
+			fatal("Failed to create witness", "error", err)
+		}
+		publicWitness, err := witness.Public()
+		if err != nil {
+			fatal("Failed to create public witness", "error", err)
+		}
+		var run determinismRun
+		if run.witness, err = witness.MarshalBinary(); err != nil {
+			fatal("Failed to serialize witness", "error", err)
+		}
+		if run.publicWitness, err = publicWitness.MarshalBinary(); err != nil {
+			fatal("Failed to serialize public witness", "error", err)
+		}
+
+		start := time.Now()
+		proof, _, err := proveCircuit(ccs, pk, witness)
+		run.prove = time.Since(start)
+		if err != nil {
+			fatal("Failed to generate proof", "error", err)
+		}
+		var buf bytes.Buffer
+		if _, err := proof.WriteTo(&buf); err != nil {
+			fatal("Failed to serialize proof", "error", err)
+		}
+		run.proof = buf.Bytes()
+		run.verified = verifyCircuit(proof, vk, publicWitness) == nil
+		return run
+	}
+
+	slog.Info("Proving twice", "test_case", filepath.Base(testCaseFile), "backend", provingBackend, "curve", curveName)
+	first := prove()
+	second := prove()
+
+	failures := 0
+	report := func(what string, same, required bool, detail string) {
+		mark := "✓"
+		switch {
+		case !same && required:
+			mark = "✗"
+			failures++
+		case !same:
+			mark = "-"
+		}
+		fmt.Printf("%s %s: %s\n", mark, what, detail)
+	}
+	identical := func(a, b []byte) string {
+		if bytes.Equal(a, b) {
+			return fmt.Sprintf("identical (%d bytes)", len(a))
+		}
+		return fmt.Sprintf("differ (%d and %d bytes)", len(a), len(b))
+	}
+
+	report("Witness", bytes.Equal(first.witness, second.witness), true, identical(first.witness, second.witness))
+	report("Public witness", bytes.Equal(first.publicWitness, second.publicWitness), true, identical(first.publicWitness, second.publicWitness))
+	proofDetail := identical(first.proof, second.proof)
+	if insecureSeed == "" {
+		proofDetail += ", as expected of randomized proofs without -insecure-seed"
+	}
+	report("Proof", bytes.Equal(first.proof, second.proof), insecureSeed != "", proofDetail)
+	report("Verification", first.verified && second.verified, true, fmt.Sprintf("first %t, second %t", first.verified, second.verified))
+
+	a, b := first.prove.Seconds(), second.prove.Seconds()
+	change := math.Abs(a-b) / math.Min(a, b)
+	report("Proving time", change <= varianceThreshold, false,
+		fmt.Sprintf("%s and %s, %.1f%% apart (threshold %.1f%%)", formatSecs(a), formatSecs(b), change*100, varianceThreshold*100))
+
+	if failures > 0 {
+		fmt.Printf("%d of the checks are not reproducible\n", failures)
+		os.Exit(1)
+	}
+	fmt.Println("Proving is reproducible")
+}
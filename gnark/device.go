@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// devicePreset approximates a target device by the CPUs proving may use, the
+// share of their time it gets, standing in for cores slower than the host's,
+// and a soft memory limit standing in for the memory an app gets
+type devicePreset struct {
+	Description      string
+	Cores            int
+	CPUQuota         string
+	MemoryLimitBytes uint64
+}
+
+// devicePresets are rough: a quota cannot model big.LITTLE cores or thermal
+// throttling, so the results show the trend on a device rather than its times
+var devicePresets = map[string]devicePreset{
+	"iphone12": {
+		Description:      "iPhone 12: 2 fast and 4 efficiency cores, about 3 GiB available to an app",
+		Cores:            4,
+		CPUQuota:         "60%",
+		MemoryLimitBytes: 3 << 30,
+	},
+	"midrange-android": {
+		Description:      "mid-range Android phone: 8 slow cores, about 2 GiB available to an app",
+		Cores:            4,
+		CPUQuota:         "40%",
+		MemoryLimitBytes: 2 << 30,
+	},
+	"laptop": {
+		Description:      "laptop: 8 cores, 16 GiB of memory",
+		Cores:            8,
+		MemoryLimitBytes: 16 << 30,
+	},
+}
+
+var (
+	// command line flags
+	deviceName string
+)
+
+// currentDevice is the preset the measurements of this process run under,
+// and is empty without one
+var currentDevice string
+
+// deviceMemoryLimit is the memory limit of the preset, 0 without one
+var deviceMemoryLimit uint64
+
+// applyDevicePreset sets the CPU limit and memory limit of the -device preset.
+// -cpus and -cpu-quota given explicitly take precedence over the preset's.
+// The memory limit is a soft one (debug.SetMemoryLimit): the Go runtime
+// collects garbage harder near it rather than failing, so phases whose peak
+// heap exceeds it are reported as not fitting on the device.
+func applyDevicePreset() {
+	if deviceName == "" {
+		return
+	}
+	preset, ok := devicePresets[deviceName]
+	if !ok {
+		fatal("Unknown -device, use one of: "+strings.Join(deviceNames(), ", "), "device", deviceName)
+	}
+	if cpuLimitCores == 0 {
+		cpuLimitCores = min(preset.Cores, runtime.NumCPU())
+		if preset.Cores > runtime.NumCPU() {
+			slog.Warn("This machine has fewer CPUs than the device", "cpus", runtime.NumCPU(), "device", deviceName, "device_cpus", preset.Cores)
+		}
+	}
+	if cpuQuota == "" {
+		cpuQuota = preset.CPUQuota
+	}
+	if preset.MemoryLimitBytes > 0 {
+		debug.SetMemoryLimit(int64(preset.MemoryLimitBytes))
+		deviceMemoryLimit = preset.MemoryLimitBytes
+	}
+	currentDevice = deviceName
+	slog.Info("Approximating a device", "device", deviceName, "description", preset.Description)
+}
+
+// checkDeviceMemory warns when a phase needed more heap than the device has
+func checkDeviceMemory(m Measurement) {
+	if deviceMemoryLimit == 0 || m.Allocs == nil || m.Allocs.PeakHeapBytes <= deviceMemoryLimit {
+		return
+	}
+	slog.Warn("The phase peaked at more heap than the device preset allows", "phase", m.Phase,
+		"peak_heap", formatBytes(m.Allocs.PeakHeapBytes), "limit", formatBytes(deviceMemoryLimit), "device", currentDevice)
+}
+
+// deviceNames lists the presets for error messages
+func deviceNames() []string {
+	var names []string
+	for name := range devicePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
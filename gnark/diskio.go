@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IOStats is the disk I/O of a phase: reading the circuit and proving key,
+// which is part of the load time along with decoding them, or writing the
+// proof, which happens after the timed proving
+type IOStats struct {
+	ReadBytes  int64   `json:"read_bytes,omitempty"`
+	ReadSecs   float64 `json:"read_secs,omitempty"`
+	WriteBytes int64   `json:"write_bytes,omitempty"`
+	WriteSecs  float64 `json:"write_secs,omitempty"`
+}
+
+// average divides accumulated I/O by the number of runs
+func (s IOStats) average(runs int) IOStats {
+	n := float64(runs)
+	return IOStats{
+		ReadBytes:  s.ReadBytes / int64(runs),
+		ReadSecs:   s.ReadSecs / n,
+		WriteBytes: s.WriteBytes / int64(runs),
+		WriteSecs:  s.WriteSecs / n,
+	}
+}
+
+// readArtifact reads a file into memory before decoding it, so the time spent
+// reading from disk is measured apart from decoding. Files still in the page
+// cache are read from memory.
+func readArtifact(path string, r io.ReaderFrom, stats *IOStats) error {
+	start := time.Now()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stats.ReadSecs += time.Since(start).Seconds()
+	stats.ReadBytes += int64(len(data))
+
+	_, err = r.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// loadArtifact reads a file the command cannot go on without, such as the
+// proving key, and stops the command when it cannot
+func loadArtifact(path string, r io.ReaderFrom) {
+	f, err := os.Open(path)
+	if err != nil {
+		fatal("Failed to open file", "file", path, "error", err)
+	}
+	defer f.Close()
+
+	if _, err := r.ReadFrom(f); err != nil {
+		fatal("Failed to read file", "file", path, "error", err)
+	}
+}
+
+// writeArtifact encodes into memory before writing the file, and syncs it so
+// the write reaches the disk rather than just the page cache. The file is
+// written under a temporary name and renamed into place, so that a run
+// interrupted while writing leaves the previous file or none, never a
+// truncated one.
+func writeArtifact(path string, w io.WriterTo, stats *IOStats) error {
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	stats.WriteSecs += time.Since(start).Seconds()
+	stats.WriteBytes += int64(buf.Len())
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// doctor tallies the checks of the doctor command
+type doctor struct {
+	passed, warned, failed int
+}
+
+func (d *doctor) pass(format string, args ...any) {
+	fmt.Printf("✓ %s\n", fmt.Sprintf(format, args...))
+	d.passed++
+}
+
+func (d *doctor) warn(msg, fix string) {
+	fmt.Printf("! %s\n    fix: %s\n", msg, fix)
+	d.warned++
+}
+
+func (d *doctor) fail(msg, fix string) {
+	fmt.Printf("✗ %s\n    fix: %s\n", msg, fix)
+	d.failed++
+}
+
+// runDoctor checks that the artifacts in -d can be used, and says how to fix
+// what cannot: that the manifest matches the settings given and the gnark of
+// this binary, that the circuit and keys are there, decode and have the
+// SHA-256 the manifest records, that the circuit is the one this code
+// compiles, that the circuit and keys come from the same compile run, found by
+// proving a built-in test vector with them and verifying the proof, and that
+// -d and -tests are writable. It exits with an error when a check fails.
+func runDoctor() {
+	d := &doctor{}
+	slog.Info("Checking the setup", "dir", outputDir, "gnark", gnarkVersion())
+
+	// Manifest and settings
+	contents, err := loadManifestContents()
+	switch {
+	case err != nil:
+		d.fail(fmt.Sprintf("%s: %v", filepath.Join(outputDir, manifestFile), err), compileFix())
+		contents = &manifestContents{}
+	case contents == nil:
+		d.warn(fmt.Sprintf("No %s, so the settings of the artifacts are unknown", filepath.Join(outputDir, manifestFile)), compileFix())
+		contents = &manifestContents{}
+	}
+	manifest := &contents.Manifest
+	if manifest.Curve != "" {
+		d.pass("%s records %s over %s, %s range checks, %s hash-to-field", manifestFile, manifest.Backend, manifest.Curve, manifest.RangeCheck, manifest.HashToField)
+	}
+	for _, setting := range []struct {
+		flag     string
+		value    *string
+		recorded string
+	}{
+		{"curve", &curveName, manifest.Curve},
+		{"backend", &provingBackend, manifest.Backend},
+		{"range-check", &rangeCheck, manifest.RangeCheck},
+		{"hash-to-field", &hashToField, manifest.HashToField},
+		{"challenge-hash", &challengeHash, manifest.ChallengeHash},
+	} {
+		if *setting.value == "" || setting.recorded == "" || canonicalSetting(setting.flag, *setting.value) == canonicalSetting(setting.flag, setting.recorded) {
+			continue
+		}
+		d.fail(fmt.Sprintf("-%s is %s but the artifacts were compiled with %s", setting.flag, *setting.value, setting.recorded),
+			fmt.Sprintf("leave out -%s, or recompile with go run . compile -d %s -%s %s", setting.flag, outputDir, setting.flag, *setting.value))
+		*setting.value = ""
+	}
+	if manifest.GnarkVersion != "" {
+		if manifest.GnarkVersion == gnarkVersion() {
+			d.pass("Artifacts produced with gnark %s, as this binary uses", manifest.GnarkVersion)
+		} else {
+			d.fail(fmt.Sprintf("Artifacts produced with gnark %s, this binary uses %s", manifest.GnarkVersion, gnarkVersion()),
+				fmt.Sprintf("recompile with this binary, or build it against gnark %s (go get github.com/consensys/gnark@%s)", manifest.GnarkVersion, manifest.GnarkVersion))
+		}
+	}
+	gpu := useGPU
+	useGPU = false
+	resolveSettings()
+	if gpu {
+		if err := checkGPU(); err != nil {
+			d.fail(fmt.Sprintf("GPU proving is not available: %v", err), "leave out -gpu")
+		} else {
+			d.pass("Built with ICICLE for -gpu proving over %s", curveName)
+		}
+	}
+
+	// Circuit and keys
+	ccs := newConstraintSystem()
+	pk := newProvingKey()
+	vk := newVerifyingKey()
+	loaded := true
+	for _, a := range []struct {
+		name string
+		into artifact
+	}{
+		{circuitFileName(), ccs},
+		{"proving.key", pk},
+		{"verifying.key", vk},
+	} {
+		path := filepath.Join(outputDir, a.name)
+		var stats IOStats
+		switch err := readArtifact(path, a.into, &stats); {
+		case errors.Is(err, fs.ErrNotExist):
+			d.fail(fmt.Sprintf("%s is missing", path), compileFix())
+			loaded = false
+		case err != nil:
+			d.fail(fmt.Sprintf("%s does not decode as %s over %s: %v", path, provingBackend, curveName, err), compileFix())
+			loaded = false
+		default:
+			d.pass("%s decodes (%s)", path, formatBytes(uint64(stats.ReadBytes)))
+		}
+	}
+
+	d.checkHashes(contents.Artifacts)
+	if loaded {
+		d.checkCircuit(ccs)
+		if circuitName == defaultCircuit {
+			d.checkRoundTrip(ccs, pk, vk)
+		}
+	}
+
+	// Directories
+	d.checkWritable(outputDir, "-d")
+	d.checkWritable(testsDir, "-tests")
+
+	fmt.Printf("\n%d checks passed, %d warnings, %d failed\n", d.passed, d.warned, d.failed)
+	if d.failed > 0 {
+		exitCode = 1
+	}
+}
+
+// checkHashes compares the circuit and keys with the SHA-256 the manifest
+// records for them
+func (d *doctor) checkHashes(hashes map[string]string) {
+	if len(hashes) == 0 {
+		d.warn(fmt.Sprintf("%s records no artifact hashes, so prove, verify and export cannot check the artifacts", manifestFile), compileFix())
+		return
+	}
+	for _, name := range []string{circuitFileName(), "proving.key", "verifying.key"} {
+		recorded, ok := hashes[name]
+		if !ok {
+			d.warn(fmt.Sprintf("%s records no hash of %s", manifestFile, name), compileFix())
+			continue
+		}
+		hash, err := manifest.HashFile(filepath.Join(outputDir, name))
+		if err != nil {
+			continue // reported as missing above
+		}
+		if hash != recorded {
+			d.fail(fmt.Sprintf("%s has SHA-256 %s, %s records %s", filepath.Join(outputDir, name), hash[:16], manifestFile, recorded[:min(16, len(recorded))]), compileFix())
+			continue
+		}
+		d.pass("%s matches the SHA-256 in %s", name, manifestFile)
+	}
+}
+
+// checkCircuit compiles the circuit of this code and compares it with the
+// compiled one, which differs after the circuit or gnark changed
+func (d *doctor) checkCircuit(ccs constraint.ConstraintSystem) {
+	current, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), selectedCircuit().New())
+	if err != nil {
+		d.fail(fmt.Sprintf("The circuit does not compile: %v", err), "fix the circuit")
+		return
+	}
+	if current.GetNbConstraints() != ccs.GetNbConstraints() || current.GetNbPublicVariables() != ccs.GetNbPublicVariables() {
+		d.fail(fmt.Sprintf("The compiled circuit has %d constraints and %d public variables, the %s circuit of this code %d and %d",
+			ccs.GetNbConstraints(), ccs.GetNbPublicVariables(), rangeCheck, current.GetNbConstraints(), current.GetNbPublicVariables()), compileFix())
+		return
+	}
+	d.pass("The compiled circuit is the %s circuit of this code (%d constraints)", rangeCheck, ccs.GetNbConstraints())
+}
+
+// checkRoundTrip proves a built-in test vector with the circuit and proving
+// key and verifies the proof with the verifying key, which only succeeds
+// when all three come from the same compile run
+func (d *doctor) checkRoundTrip(ccs constraint.ConstraintSystem, pk, vk artifact) {
+	data, err := builtinVectors.ReadFile("vectors/test_case_1.json")
+	if err != nil {
+		d.fail(fmt.Sprintf("Failed to read the built-in test vector: %v", err), "rebuild the binary")
+		return
+	}
+	testCase, err := parseTestCase(data)
+	if err != nil {
+		d.fail(fmt.Sprintf("Failed to parse the built-in test vector: %v", err), "rebuild the binary")
+		return
+	}
+	witness, err := createWitness(testCase)
+	if err != nil {
+		d.fail(fmt.Sprintf("Failed to create the witness of the built-in test vector: %v", err), "rebuild the binary")
+		return
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		d.fail(fmt.Sprintf("Failed to create the public witness of the built-in test vector: %v", err), "rebuild the binary")
+		return
+	}
+	start := time.Now()
+	proof, _, err := proveCircuit(ccs, pk, witness)
+	if err != nil {
+		d.fail(fmt.Sprintf("The proving key does not prove the circuit: %v", err), compileFix())
+		return
+	}
+	if err := verifyCircuit(proof, vk, publicWitness); err != nil {
+		d.fail(fmt.Sprintf("The verifying key rejects a proof of the proving key: %v", err), compileFix())
+		return
+	}
+	d.pass("Circuit, proving key and verifying key come from the same compile run (proved and verified a test vector in %s)", formatSecs(time.Since(start).Seconds()))
+}
+
+// checkWritable checks that the commands can write to a directory, or create
+// it when it does not exist yet
+func (d *doctor) checkWritable(dir, flag string) {
+	target := dir
+	for {
+		if _, err := os.Stat(target); err == nil {
+			break
+		}
+		parent := filepath.Dir(target)
+		if parent == target {
+			break
+		}
+		target = parent
+	}
+	f, err := os.CreateTemp(target, ".doctor-")
+	if err != nil {
+		d.fail(fmt.Sprintf("%s (%s) is not writable: %v", dir, flag, err), fmt.Sprintf("give a writable directory with %s, or chmod u+w %s", flag, target))
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+	if target != dir {
+		d.pass("%s (%s) does not exist yet and can be created", dir, flag)
+		return
+	}
+	d.pass("%s (%s) is writable", dir, flag)
+}
+
+// compileFix is how to produce the artifacts again
+func compileFix() string {
+	return fmt.Sprintf("go run . compile -d %s", outputDir)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+var (
+	// command line flags
+	dryRun bool
+)
+
+// calibrationSizes are the constraints of the reference circuits dry-run
+// proves to calibrate its estimates
+var calibrationSizes = []int{1 << 13, 1 << 15}
+
+// calibrationRuns is how many times each reference circuit is proved, the
+// median counting
+const calibrationRuns = 3
+
+// calibrationCircuit squares its secret n times to reach its public input,
+// one constraint per squaring
+type calibrationCircuit struct {
+	X frontend.Variable `gnark:",public"`
+	Y frontend.Variable
+	n int
+}
+
+func (c *calibrationCircuit) Define(api frontend.API) error {
+	v := c.Y
+	for i := 0; i < c.n; i++ {
+		v = api.Mul(v, v)
+	}
+	api.AssertIsEqual(v, c.X)
+	return nil
+}
+
+// calibration is how proving went for a reference circuit on this machine
+type calibration struct {
+	constraints  int
+	proveSecs    float64
+	peakHeap     uint64
+	provingBytes int64
+}
+
+// estimateProving is prove -dry-run: it loads the compiled circuit in -d, or
+// compiles it when there is none, and reports its size and the proving time,
+// peak heap and proving key size to expect without proving. The time and heap
+// are extrapolated linearly in the number of constraints from proving
+// reference circuits of known size with the same backend and curve, under the
+// same -cpus and -device limits, so they are estimates that hold to within
+// tens of percent; the key size is exact when the key is in -d.
+func estimateProving() {
+	resolveSettings()
+	if srsMode == "" {
+		srsMode = defaultSRS
+	}
+
+	ccs, source := dryRunCircuit()
+	n := ccs.GetNbConstraints()
+	fmt.Printf("Dry run of %s over %s (%s, %s range checks), circuit %s\n", provingBackend, curveName, circuitName, rangeCheck, source)
+	fmt.Printf("  Constraints:   %d\n", n)
+	fmt.Printf("  Variables:     %d public, %d secret, %d internal\n", ccs.GetNbPublicVariables(), ccs.GetNbSecretVariables(), ccs.GetNbInternalVariables())
+
+	slog.Info("Calibrating with reference circuits", "constraints", calibrationSizes[:])
+	var points []calibration
+	for _, size := range calibrationSizes {
+		point, err := calibrate(size)
+		if err != nil {
+			fatal("Calibration failed", "error", err)
+		}
+		fmt.Printf("  %d constraints: proved in %s, %s peak heap\n", size, formatSecs(point.proveSecs), formatBytes(point.peakHeap))
+		points = append(points, point)
+	}
+
+	proveSecs := extrapolate(points, n, func(c calibration) float64 { return c.proveSecs })
+	peakHeap := uint64(extrapolate(points, n, func(c calibration) float64 { return float64(c.peakHeap) }))
+	fmt.Printf("Estimates for %d constraints:\n", n)
+	fmt.Printf("  Proving time:  %s\n", formatSecs(proveSecs))
+	fmt.Printf("  Peak heap:     %s\n", formatBytes(peakHeap))
+	if info, err := os.Stat(filepath.Join(outputDir, "proving.key")); err == nil {
+		fmt.Printf("  Proving key:   %s (%s)\n", formatBytes(uint64(info.Size())), filepath.Join(outputDir, "proving.key"))
+	} else {
+		provingBytes := extrapolate(points, n, func(c calibration) float64 { return float64(c.provingBytes) })
+		fmt.Printf("  Proving key:   %s, estimated\n", formatBytes(uint64(provingBytes)))
+	}
+	if deviceMemoryLimit > 0 {
+		if peakHeap > deviceMemoryLimit {
+			fmt.Printf("✗ The estimated peak heap exceeds the %s the %s preset allows\n", formatBytes(deviceMemoryLimit), currentDevice)
+		} else {
+			fmt.Printf("✓ The estimated peak heap fits in the %s the %s preset allows\n", formatBytes(deviceMemoryLimit), currentDevice)
+		}
+	}
+}
+
+// dryRunCircuit reads the compiled circuit in -d, or compiles the circuit when
+// it is not there, and says which it did
+func dryRunCircuit() (constraint.ConstraintSystem, string) {
+	path := filepath.Join(outputDir, circuitFileName())
+	ccs := newConstraintSystem()
+	err := readArtifact(path, ccs, &IOStats{})
+	switch {
+	case err == nil:
+		return ccs, "read from " + path
+	case !errors.Is(err, fs.ErrNotExist):
+		fatal("Failed to read circuit", "error", err)
+	}
+	start := time.Now()
+	compiled, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), selectedCircuit().New())
+	if err != nil {
+		fatal("Circuit compilation failed", "error", err)
+	}
+	return compiled, fmt.Sprintf("compiled in %s", formatSecs(time.Since(start).Seconds()))
+}
+
+// calibrate sets up and proves a reference circuit of a number of constraints
+func calibrate(size int) (calibration, error) {
+	field := selectedCurve().ScalarField()
+	ccs, err := frontend.Compile(field, circuitBuilder(), &calibrationCircuit{n: size})
+	if err != nil {
+		return calibration{}, err
+	}
+	pk, _, err := setupKeys(ccs)
+	if err != nil {
+		return calibration{}, err
+	}
+	provingBytes, err := pk.WriteTo(io.Discard)
+	if err != nil {
+		return calibration{}, err
+	}
+
+	// A random secret, as the multi-scalar multiplications of the prover are
+	// faster with small scalars than with those of a real witness
+	y, err := rand.Int(rand.Reader, field)
+	if err != nil {
+		return calibration{}, err
+	}
+	x := new(big.Int).Exp(y, new(big.Int).Lsh(big.NewInt(1), uint(size)), field)
+	witness, err := frontend.NewWitness(&calibrationCircuit{X: x, Y: y, n: size}, field)
+	if err != nil {
+		return calibration{}, err
+	}
+
+	times := make([]float64, calibrationRuns)
+	var peakHeap uint64
+	for run := range times {
+		stopTracking := trackAllocs()
+		start := time.Now()
+		_, _, err := proveCircuit(ccs, pk, witness)
+		times[run] = time.Since(start).Seconds()
+		allocs := stopTracking()
+		if err != nil {
+			return calibration{}, err
+		}
+		peakHeap = max(peakHeap, allocs.PeakHeapBytes)
+	}
+	sort.Float64s(times)
+	return calibration{
+		constraints:  ccs.GetNbConstraints(),
+		proveSecs:    times[len(times)/2],
+		peakHeap:     peakHeap,
+		provingBytes: provingBytes,
+	}, nil
+}
+
+// extrapolate fits a line through the first and last calibration point and
+// evaluates it at n constraints. A negative fixed cost, from noise in the
+// measurements, is dropped in favour of the cost per constraint of the larger
+// reference circuit.
+func extrapolate(points []calibration, n int, value func(calibration) float64) float64 {
+	first, last := points[0], points[len(points)-1]
+	perConstraint := (value(last) - value(first)) / float64(last.constraints-first.constraints)
+	fixed := value(first) - perConstraint*float64(first.constraints)
+	if fixed < 0 || perConstraint < 0 {
+		return value(last) / float64(last.constraints) * float64(n)
+	}
+	return fixed + perConstraint*float64(n)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// command line flags
+	genEdgeCases bool
+)
+
+// edgeCase is a boundary vector: a valid P-256 signature whose r, s, message
+// hash or public key sits at the edge of its range
+type edgeCase struct {
+	name        string
+	description string
+	generate    func() (pub *ecdsa.PublicKey, hash, r, s *big.Int)
+}
+
+// knownEdgeCaseFailures are the edge cases gnark cannot prove, by the reason
+// why. They are tagged known-failure.
+var knownEdgeCaseFailures = map[string]string{
+	"msghash_zero": "gnark v0.12's scalar decomposition hint divides by zero",
+}
+
+// generateEdgeCases writes a valid signature for each boundary the circuit
+// and the tooling have to handle into -tests as edge_case_<name>.json, next
+// to the test cases of gen-testdata. The signatures are built backwards from
+// the values they must hold: for any r that is the x coordinate of a point R,
+// and any s and message hash z, the public key r⁻¹(sR − zG) verifies them.
+// The public key with a small x is fixed first instead, and the signature
+// derived from a point R = aG + bQ. Every signature is checked with
+// crypto/ecdsa before it is written. High-S signatures are kept as they are,
+// so they are valid ECDSA but not what the other test cases produce.
+func generateEdgeCases() {
+	curve := elliptic.P256()
+	params := curve.Params()
+	n := params.N
+	half := new(big.Int).Rsh(n, 1)
+	byteRange := func(zeroBytes int) *big.Int {
+		// A random value with exactly zeroBytes leading zero bytes
+		top := new(big.Int).Lsh(big.NewInt(1), uint(256-8*zeroBytes))
+		for {
+			v := randomScalar(top)
+			if v.BitLen() > 256-8*(zeroBytes+1) {
+				return v
+			}
+		}
+	}
+
+	cases := []edgeCase{
+		{"r_leading_zero", "r with a leading zero byte", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(nextX(byteRange(1)), randomScalar(n), randomScalar(n))
+		}},
+		{"r_small", "r the smallest x coordinate of a point", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(nextX(big.NewInt(1)), randomScalar(n), randomScalar(n))
+		}},
+		{"r_below_n", "r the largest x coordinate of a point below n", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(prevX(new(big.Int).Sub(n, big.NewInt(1))), randomScalar(n), randomScalar(n))
+		}},
+		{"s_leading_zero", "s with a leading zero byte", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(randomX(), byteRange(1), randomScalar(n))
+		}},
+		{"s_one", "s = 1", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(randomX(), big.NewInt(1), randomScalar(n))
+		}},
+		{"s_half", "s = (n-1)/2, the largest low-S value", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(randomX(), new(big.Int).Set(half), randomScalar(n))
+		}},
+		{"s_high", "a high-S signature, s > n/2", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(randomX(), new(big.Int).Add(half, randomScalar(half)), randomScalar(n))
+		}},
+		{"s_below_n", "s = n-1", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(randomX(), new(big.Int).Sub(n, big.NewInt(1)), randomScalar(n))
+		}},
+		{"msghash_zero", "message hash 0", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			return signWithR(randomX(), randomScalar(n), big.NewInt(0))
+		}},
+		{"msghash_above_n", "message hash at least n, which ECDSA reduces mod n", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			// The gap between n and 2^256 is about 2^224 wide
+			gap := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), n)
+			return signWithR(randomX(), randomScalar(n), new(big.Int).Add(n, randomScalar(gap)))
+		}},
+		{"pubkey_small_x", "public key with the smallest x coordinate of a point", func() (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+			x := nextX(big.NewInt(1))
+			pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: curveY(x)}
+			// R = aG + bQ verifies with u1 = a and u2 = b, so s = r/b and
+			// z = a·s
+			a, b := randomScalar(n), randomScalar(n)
+			ax, ay := curve.ScalarBaseMult(a.Bytes())
+			bx, by := curve.ScalarMult(pub.X, pub.Y, b.Bytes())
+			rx, _ := curve.Add(ax, ay, bx, by)
+			r := new(big.Int).Mod(rx, n)
+			s := new(big.Int).Mul(r, new(big.Int).ModInverse(b, n))
+			s.Mod(s, n)
+			hash := new(big.Int).Mul(a, s)
+			return pub, hash.Mod(hash, n), r, s
+		}},
+	}
+
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	slog.Info("Generating P-256 edge cases", "count", len(cases), "dir", testsDir)
+	valid := true
+	for _, c := range cases {
+		pub, hash, r, s := c.generate()
+		hashBytes := hash.FillBytes(make([]byte, 32))
+		if !ecdsa.Verify(pub, hashBytes, r, s) {
+			fatal("Generated an invalid signature for edge case", "edge_case", c.name)
+		}
+		testCase := &TestCase{
+			R:           fmt.Sprintf("0x%x", r),
+			S:           fmt.Sprintf("0x%x", s),
+			MsgHash:     fmt.Sprintf("0x%x", hash),
+			PubKeyX:     fmt.Sprintf("0x%x", pub.X),
+			PubKeyY:     fmt.Sprintf("0x%x", pub.Y),
+			ExpectValid: &valid,
+			Source:      "edge case: " + c.description,
+			Description: c.description,
+			Tags:        []string{tagEdgeCase},
+		}
+		reason, known := knownEdgeCaseFailures[c.name]
+		if known {
+			testCase.Tags = append(testCase.Tags, tagKnownFailure)
+		}
+		writeTestCase(filepath.Join(testsDir, "edge_case_"+c.name+".json"), testCase)
+		fmt.Printf("  edge_case_%s.json: %s\n", c.name, c.description)
+		if known {
+			fmt.Printf("    known failure: %s\n", reason)
+		}
+	}
+}
+
+// signWithR derives the public key that the signature (r, s) verifies for
+// over the message hash, where r must be the x coordinate of a point
+func signWithR(r, s, hash *big.Int) (*ecdsa.PublicKey, *big.Int, *big.Int, *big.Int) {
+	curve := elliptic.P256()
+	n := curve.Params().N
+	rx, ry := r, curveY(r)
+	// Q = r⁻¹(sR − zG), with −zG computed as (n − z mod n)G
+	sx, sy := curve.ScalarMult(rx, ry, s.Bytes())
+	negZ := new(big.Int).Sub(n, new(big.Int).Mod(hash, n))
+	zx, zy := curve.ScalarBaseMult(negZ.Bytes())
+	px, py := curve.Add(sx, sy, zx, zy)
+	qx, qy := curve.ScalarMult(px, py, new(big.Int).ModInverse(r, n).Bytes())
+	return &ecdsa.PublicKey{Curve: curve, X: qx, Y: qy}, hash, r, s
+}
+
+// curveY returns a y coordinate of the point with x coordinate x, which must
+// be on the curve
+func curveY(x *big.Int) *big.Int {
+	params := elliptic.P256().Params()
+	y := new(big.Int).ModSqrt(curveRHS(x), params.P)
+	if y == nil {
+		fatal("No point has the x coordinate", "x", fmt.Sprintf("0x%x", x))
+	}
+	return y
+}
+
+// curveRHS is x³ − 3x + b
+func curveRHS(x *big.Int) *big.Int {
+	params := elliptic.P256().Params()
+	rhs := new(big.Int).Exp(x, big.NewInt(3), params.P)
+	rhs.Sub(rhs, new(big.Int).Mul(big.NewInt(3), x))
+	rhs.Add(rhs, params.B)
+	return rhs.Mod(rhs, params.P)
+}
+
+func isX(x *big.Int) bool {
+	return big.Jacobi(curveRHS(x), elliptic.P256().Params().P) >= 0
+}
+
+// nextX returns the smallest x coordinate of a point from x up
+func nextX(x *big.Int) *big.Int {
+	x = new(big.Int).Set(x)
+	for !isX(x) {
+		x.Add(x, big.NewInt(1))
+	}
+	return x
+}
+
+// prevX returns the largest x coordinate of a point from x down
+func prevX(x *big.Int) *big.Int {
+	x = new(big.Int).Set(x)
+	for !isX(x) {
+		x.Sub(x, big.NewInt(1))
+	}
+	return x
+}
+
+// randomX returns the x coordinate of a random point below n, so that it
+// is a valid r
+func randomX() *big.Int {
+	return prevX(randomScalar(elliptic.P256().Params().N))
+}
+
+// randomScalar returns a random value in [1, max)
+func randomScalar(max *big.Int) *big.Int {
+	for {
+		v, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			fatal("Failed to generate random value", "error", err)
+		}
+		if v.Sign() > 0 {
+			return v
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+var (
+	// command line flags
+	measureEnergy bool
+)
+
+// startEnergy measures the energy the processors use until the returned
+// function is called, which returns it in joules. It reads the RAPL counters
+// on Linux and samples powermetrics on macOS, both of which need root. The
+// counters cover the whole processor package, so other load on the machine
+// is included. It does nothing without -energy.
+func startEnergy() func() float64 {
+	if !measureEnergy {
+		return func() float64 { return 0 }
+	}
+
+	stop, err := startEnergyMeter()
+	if err != nil {
+		fatal("Failed to measure energy", "error", err)
+	}
+	return func() float64 {
+		joules, err := stop()
+		if err != nil {
+			fatal("Failed to measure energy", "error", err)
+		}
+		return joules
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// powermetricsInterval is the sampling period of powermetrics in milliseconds.
+// Energy used during the last, unfinished sample is not counted.
+const powermetricsInterval = 100
+
+var (
+	// powermetrics reports the elapsed time of each sample in its header,
+	// followed by the power drawn by the processor: combined for the CPU, GPU
+	// and neural engine on Apple silicon, in W for the package on Intel Macs
+	sampleElapsed     = regexp.MustCompile(`\(([0-9.]+)ms elapsed\)`)
+	combinedPower     = regexp.MustCompile(`^Combined Power \(CPU \+ GPU \+ ANE\): ([0-9.]+) mW`)
+	intelPackagePower = regexp.MustCompile(`^Intel energy model derived package power \(CPUs\+GT\+SA\): ([0-9.]+)W`)
+)
+
+// startEnergyMeter runs powermetrics and integrates the processor power it
+// samples
+func startEnergyMeter() (func() (float64, error), error) {
+	if os.Geteuid() != 0 {
+		return nil, errors.New("powermetrics needs root, run with sudo")
+	}
+
+	cmd := exec.Command("powermetrics", "--samplers", "cpu_power", "-i", strconv.Itoa(powermetricsInterval))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan float64)
+	go func() {
+		var joules float64
+		elapsed := float64(powermetricsInterval)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if m := sampleElapsed.FindStringSubmatch(line); m != nil {
+				elapsed, _ = strconv.ParseFloat(m[1], 64)
+			} else if m := combinedPower.FindStringSubmatch(line); m != nil {
+				milliwatts, _ := strconv.ParseFloat(m[1], 64)
+				joules += milliwatts / 1000 * elapsed / 1000
+			} else if m := intelPackagePower.FindStringSubmatch(line); m != nil {
+				watts, _ := strconv.ParseFloat(m[1], 64)
+				joules += watts * elapsed / 1000
+			}
+		}
+		done <- joules
+	}()
+
+	return func() (float64, error) {
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return 0, err
+		}
+		joules := <-done
+		// powermetrics exits with a signal status when interrupted
+		cmd.Wait()
+		return joules, nil
+	}, nil
+}
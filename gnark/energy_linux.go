@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// raplDir holds the powercap zones of Intel RAPL, which recent kernels also use
+// for AMD processors
+const raplDir = "/sys/class/powercap"
+
+// raplZone is the energy counter of one processor package
+type raplZone struct {
+	path     string
+	maxRange uint64
+	start    uint64
+}
+
+// startEnergyMeter reads the energy counters of every processor package
+func startEnergyMeter() (func() (float64, error), error) {
+	paths, err := filepath.Glob(filepath.Join(raplDir, "intel-rapl:*"))
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []raplZone
+	for _, path := range paths {
+		// Subzones such as intel-rapl:0:0 (cores) are part of their package
+		if strings.Count(filepath.Base(path), ":") != 1 {
+			continue
+		}
+		maxRange, err := readRAPLCounter(filepath.Join(path, "max_energy_range_uj"))
+		if err != nil {
+			return nil, err
+		}
+		start, err := readRAPLCounter(filepath.Join(path, "energy_uj"))
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, raplZone{path: path, maxRange: maxRange, start: start})
+	}
+	if len(zones) == 0 {
+		return nil, errors.New("no RAPL energy counters in " + raplDir)
+	}
+
+	return func() (float64, error) {
+		var microjoules uint64
+		for _, zone := range zones {
+			end, err := readRAPLCounter(filepath.Join(zone.path, "energy_uj"))
+			if err != nil {
+				return 0, err
+			}
+			// The counter wraps around at max_energy_range_uj
+			if end < zone.start {
+				end += zone.maxRange
+			}
+			microjoules += end - zone.start
+		}
+		return float64(microjoules) / 1e6, nil
+	}, nil
+}
+
+func readRAPLCounter(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsPermission(err) {
+		return 0, fmt.Errorf("%w (reading RAPL counters needs root)", err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin
+
+package main
+
+import "errors"
+
+func startEnergyMeter() (func() (float64, error), error) {
+	return nil, errors.New("energy is only measured on Linux (RAPL) and macOS (powermetrics)")
+}
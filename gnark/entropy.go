@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var (
+	// command line flags
+	entropyHex  string
+	entropyFile string
+
+	// entropyHash is the SHA-256 of the external entropy mixed into the setup,
+	// recorded in the manifest so the setup can be audited against it
+	entropyHash string
+)
+
+// useExternalEntropy mixes externally supplied entropy, such as a drand beacon
+// value, into the randomness gnark draws the setup toxic waste and commitment
+// keys from. The new stream is keyed by SHA-256(r || SHA-256(entropy)), where r
+// is read from the current crypto/rand.Reader, so the result is at least as
+// unpredictable as either input. Combined with -insecure-seed, r comes from the
+// seed and the output stays reproducible.
+func useExternalEntropy() {
+	entropy, err := loadEntropy()
+	if err != nil {
+		fatal("Failed to load external entropy", "error", err)
+	}
+	if len(entropy) == 0 {
+		fatal("External entropy is empty")
+	}
+
+	sum := sha256.Sum256(entropy)
+	entropyHash = hex.EncodeToString(sum[:])
+
+	var local [32]byte
+	if _, err := io.ReadFull(rand.Reader, local[:]); err != nil {
+		fatal("Failed to read local randomness", "error", err)
+	}
+	rand.Reader = &seededReader{key: sha256.Sum256(append(local[:], sum[:]...))}
+
+	slog.Info("Mixing external entropy into the setup", "bytes", len(entropy), "sha256", entropyHash)
+}
+
+func loadEntropy() ([]byte, error) {
+	switch {
+	case entropyHex != "" && entropyFile != "":
+		return nil, fmt.Errorf("use either -entropy or -entropy-file, not both")
+	case entropyFile != "":
+		return os.ReadFile(entropyFile)
+	default:
+		return hex.DecodeString(strings.TrimPrefix(entropyHex, "0x"))
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/secp256k1"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fr"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// command line flags
+	passwordFile string
+)
+
+// ethKeystore is an Ethereum keystore file (Web3 Secret Storage, version 3)
+type ethKeystore struct {
+	Address string `json:"address"`
+	Crypto  struct {
+		Cipher       string `json:"cipher"`
+		CipherText   string `json:"ciphertext"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		KDF       string          `json:"kdf"`
+		KDFParams json.RawMessage `json:"kdfparams"`
+		MAC       string          `json:"mac"`
+	} `json:"crypto"`
+	Version int `json:"version"`
+}
+
+// generateEthTestData writes -count secp256k1 test cases into -tests as
+// eth_test_case_N.json, the Ethereum counterpart of gen-testdata. Each signs
+// the Keccak-256 of -message, or of a random message when it is empty, as
+// Ethereum hashes what it signs, with s in the lower half of the group order
+// as Ethereum requires (EIP-2). Besides the signature, the test case records
+// the signer's Ethereum address and the recovery id v (27 or 28), so it can be
+// checked against ecrecover. Keys are fresh for every test case, drawn from
+// -seed when it is set, or all test cases are signed with the key of the
+// keystore file in -key, decrypted with the password in -password-file.
+// Nonces are derived with RFC 6979, so seeded runs are reproducible as with
+// gen-testdata. These test cases are for -circuit secp256k1, which proves
+// them from -all; the default P-256 circuit rejects them.
+func generateEthTestData() {
+	if genCount < 1 {
+		fatal("-count must be at least 1")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	stale, err := filepath.Glob(filepath.Join(testsDir, "eth_test_case_*.json"))
+	if err != nil {
+		fatal("Failed to find test cases", "error", err)
+	}
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil {
+			fatal("Failed to remove old test case", "error", err)
+		}
+	}
+
+	var random io.Reader = rand.Reader
+	if genSeed != "" {
+		random = &seededReader{key: sha256.Sum256([]byte("gen-eth " + genSeed))}
+	}
+	var keystoreKey *big.Int
+	if keyFile != "" {
+		keystoreKey = readEthKeystore(keyFile)
+	}
+
+	slog.Info("Generating secp256k1 test cases", "count", genCount, "dir", testsDir)
+	for i := 1; i <= genCount; i++ {
+		message := []byte(genMessage)
+		if genMessage == "" {
+			message = make([]byte, 32)
+			if _, err := io.ReadFull(random, message); err != nil {
+				fatal("Failed to generate message", "error", err)
+			}
+		}
+		hash := keccak256(message)
+
+		d := keystoreKey
+		if d == nil {
+			d = secp256k1Key(random)
+		}
+		testCase := signSecp256k1(d, hash)
+		testCase.Nonce = nonceRFC6979
+		testCase.Message = fmt.Sprintf("0x%x", message)
+		testCase.HashAlg = hashKeccak256
+		testCase.Tags = []string{tagEthereum}
+		switch {
+		case keystoreKey != nil:
+			testCase.Source = fmt.Sprintf("gen-eth with keystore %s", filepath.Base(keyFile))
+		case genSeed != "":
+			testCase.Source = fmt.Sprintf("gen-eth -seed %q, test case %d", genSeed, i)
+		}
+		writeTestCase(filepath.Join(testsDir, fmt.Sprintf("eth_test_case_%d.json", i)), testCase)
+	}
+	slog.Info("Test cases written", "first", "eth_test_case_1.json", "last", fmt.Sprintf("eth_test_case_%d.json", genCount))
+}
+
+// signSecp256k1 signs a hash with the private key d and the RFC 6979 nonce,
+// normalizes s to the lower half of the group order, and returns the test
+// case with the signer's address and recovery id
+func signSecp256k1(d *big.Int, hash []byte) *TestCase {
+	n := fr.Modulus()
+	k := rfc6979Nonce(n, d, hash)
+
+	var pub, point secp256k1.G1Affine
+	pub.ScalarMultiplicationBase(d)
+	point.ScalarMultiplicationBase(k)
+	x := point.X.BigInt(new(big.Int))
+	r := new(big.Int).Mod(x, n)
+	s := new(big.Int).Mul(r, d)
+	s.Add(s, new(big.Int).SetBytes(hash))
+	s.Mul(s, new(big.Int).ModInverse(k, n))
+	s.Mod(s, n)
+	if r.Sign() == 0 || s.Sign() == 0 {
+		fatal("Failed to sign: the RFC 6979 nonce gave a zero r or s")
+	}
+
+	// The recovery id is the parity of the y coordinate of kG, which flips
+	// when s is negated. An x of kG at least n, which would need a second
+	// recovery bit, is too unlikely to happen.
+	recovery := point.Y.BigInt(new(big.Int)).Bit(0)
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+		recovery ^= 1
+	}
+
+	pubX := pub.X.BigInt(new(big.Int))
+	pubY := pub.Y.BigInt(new(big.Int))
+	return &TestCase{
+		R:       fmt.Sprintf("0x%x", r),
+		S:       fmt.Sprintf("0x%x", s),
+		MsgHash: fmt.Sprintf("0x%x", new(big.Int).SetBytes(hash)),
+		PubKeyX: fmt.Sprintf("0x%x", pubX),
+		PubKeyY: fmt.Sprintf("0x%x", pubY),
+		Curve:   curveSecp256k1,
+		Address: ethAddress(pubX, pubY),
+		V:       27 + recovery,
+	}
+}
+
+// ethAddress is the last 20 bytes of the Keccak-256 of the public key, with
+// the EIP-55 checksum
+func ethAddress(x, y *big.Int) string {
+	key := append(x.FillBytes(make([]byte, 32)), y.FillBytes(make([]byte, 32))...)
+	address := hex.EncodeToString(keccak256(key)[12:])
+	checksum := hex.EncodeToString(keccak256([]byte(address)))
+	var b strings.Builder
+	for i, c := range address {
+		if c >= 'a' && checksum[i] >= '8' {
+			c -= 'a' - 'A'
+		}
+		b.WriteRune(c)
+	}
+	return "0x" + b.String()
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// secp256k1Key draws a private key in [1, n) from a random stream
+func secp256k1Key(random io.Reader) *big.Int {
+	buf := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			fatal("Failed to generate key", "error", err)
+		}
+		d := new(big.Int).SetBytes(buf)
+		if d.Sign() > 0 && d.Cmp(fr.Modulus()) < 0 {
+			return d
+		}
+	}
+}
+
+// readEthKeystore decrypts the private key of a version 3 keystore file, as
+// geth and most wallets write them, with scrypt or PBKDF2 and AES-128-CTR
+func readEthKeystore(path string) *big.Int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read keystore", "error", err)
+	}
+	var ks ethKeystore
+	if err := json.Unmarshal(data, &ks); err != nil {
+		fatal("Failed to decode keystore", "file", path, "error", err)
+	}
+	if ks.Version != 3 {
+		fatal("Unsupported keystore version, only version 3 is supported", "file", path, "version", ks.Version)
+	}
+	if ks.Crypto.Cipher != "aes-128-ctr" {
+		fatal("Unsupported keystore cipher, only aes-128-ctr is supported", "file", path, "cipher", ks.Crypto.Cipher)
+	}
+	var password []byte
+	if passwordFile != "" {
+		if password, err = os.ReadFile(passwordFile); err != nil {
+			fatal("Failed to read password", "error", err)
+		}
+		password = bytes.TrimRight(password, "\r\n")
+	}
+
+	var params struct {
+		DKLen int    `json:"dklen"`
+		Salt  string `json:"salt"`
+		N     int    `json:"n"`
+		R     int    `json:"r"`
+		P     int    `json:"p"`
+		C     int    `json:"c"`
+		PRF   string `json:"prf"`
+	}
+	if err := json.Unmarshal(ks.Crypto.KDFParams, &params); err != nil {
+		fatal("Failed to decode the key derivation parameters of keystore", "file", path, "error", err)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		fatal("Invalid salt in keystore", "file", path, "error", err)
+	}
+	var derived []byte
+	switch ks.Crypto.KDF {
+	case "scrypt":
+		derived, err = scrypt.Key(password, salt, params.N, params.R, params.P, params.DKLen)
+		if err != nil {
+			fatal("Failed to derive the key of keystore", "file", path, "error", err)
+		}
+	case "pbkdf2":
+		if params.PRF != "hmac-sha256" {
+			fatal("Unsupported keystore PBKDF2 function, only hmac-sha256 is supported", "file", path, "prf", params.PRF)
+		}
+		derived = pbkdf2.Key(password, salt, params.C, params.DKLen, sha256.New)
+	default:
+		fatal("Unsupported keystore key derivation, only scrypt and pbkdf2 are supported", "file", path, "kdf", ks.Crypto.KDF)
+	}
+	if len(derived) < 32 {
+		fatal("Keystore derives too short a key", "file", path, "bytes", len(derived))
+	}
+
+	ciphertext, err := hex.DecodeString(ks.Crypto.CipherText)
+	if err != nil {
+		fatal("Invalid ciphertext in keystore", "file", path, "error", err)
+	}
+	mac := keccak256(append(append([]byte{}, derived[16:32]...), ciphertext...))
+	if hex.EncodeToString(mac) != strings.ToLower(ks.Crypto.MAC) {
+		fatal("Wrong password for keystore", "file", path)
+	}
+	iv, err := hex.DecodeString(ks.Crypto.CipherParams.IV)
+	if err != nil {
+		fatal("Invalid IV in keystore", "file", path, "error", err)
+	}
+	block, err := aes.NewCipher(derived[:16])
+	if err != nil {
+		fatal("Failed to decrypt keystore", "error", err)
+	}
+	key := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(key, ciphertext)
+
+	d := new(big.Int).SetBytes(key)
+	if d.Sign() == 0 || d.Cmp(fr.Modulus()) >= 0 {
+		fatal("Keystore does not hold a secp256k1 private key", "file", path)
+	}
+	if ks.Address != "" {
+		var pub secp256k1.G1Affine
+		pub.ScalarMultiplicationBase(d)
+		address := ethAddress(pub.X.BigInt(new(big.Int)), pub.Y.BigInt(new(big.Int)))
+		if !strings.EqualFold(strings.TrimPrefix(address, "0x"), strings.TrimPrefix(ks.Address, "0x")) {
+			fatal("Keystore holds the key of another address", "file", path, "key_address", address, "address", ks.Address)
+		}
+	}
+	return d
+}
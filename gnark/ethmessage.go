@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of Ethereum messages
+const (
+	ethPersonalSign = "personal_sign"
+	ethTypedData    = "eip712"
+)
+
+// eip191Prefix starts what personal_sign signs (EIP-191 version 0x45)
+const eip191Prefix = "\x19Ethereum Signed Message:\n"
+
+// ethMessageDescription is the YAML description of a message for
+// gen-eth-message. A personal_sign message is text, or bytes in 0x hex. An
+// EIP-712 message has the types, domain and values of eth_signTypedData_v4.
+type ethMessageDescription struct {
+	Kind        string                   `yaml:"kind"`
+	Message     any                      `yaml:"message"`
+	Domain      map[string]any           `yaml:"domain"`
+	PrimaryType string                   `yaml:"primaryType"`
+	Types       map[string][]eip712Field `yaml:"types"`
+}
+
+type eip712Field struct {
+	Name string `yaml:"name" json:"name"`
+	Type string `yaml:"type" json:"type"`
+}
+
+// EthMessageData is what a circuit that rebuilds an Ethereum message needs:
+// the EIP-191 prefix and payload, or the EIP-712 hashes and the encoded
+// struct. The test case's message is the whole signed preimage.
+type EthMessageData struct {
+	Kind string `json:"kind"`
+
+	// personal_sign
+	Prefix  string `json:"prefix,omitempty"`
+	Payload string `json:"payload,omitempty"`
+
+	// eip712
+	PrimaryType     string                   `json:"primary_type,omitempty"`
+	EncodedType     string                   `json:"encoded_type,omitempty"`
+	TypeHash        string                   `json:"type_hash,omitempty"`
+	EncodedData     string                   `json:"encoded_data,omitempty"`
+	StructHash      string                   `json:"struct_hash,omitempty"`
+	DomainSeparator string                   `json:"domain_separator,omitempty"`
+	Domain          map[string]any           `json:"domain,omitempty"`
+	Types           map[string][]eip712Field `json:"types,omitempty"`
+	Values          map[string]any           `json:"values,omitempty"`
+}
+
+// eip712DomainFields are the fields EIP712Domain may have, in their order
+var eip712DomainFields = []eip712Field{
+	{"name", "string"},
+	{"version", "string"},
+	{"chainId", "uint256"},
+	{"verifyingContract", "address"},
+	{"salt", "bytes32"},
+}
+
+// generateEthMessages writes a secp256k1 test case for each YAML message
+// description, as tests/eth_message_<file>.json, for circuits that verify
+// Ethereum signed messages: personal_sign (EIP-191) or typed data (EIP-712).
+// The message is encoded as wallets encode it and signed with its
+// Keccak-256, as gen-eth signs, by a key from -seed or the keystore in -key,
+// or a fresh one. Besides the signature and the whole signed preimage, the
+// test case keeps the structured inputs under eth_message: the prefix string
+// and payload, or the domain, types and values with the domain separator,
+// the encoded struct and its hash.
+func generateEthMessages(files []string) {
+	if len(files) == 0 {
+		fatal("Missing message description for gen-eth-message command")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	var random io.Reader = rand.Reader
+	if genSeed != "" {
+		random = &seededReader{key: sha256.Sum256([]byte("gen-eth-message " + genSeed))}
+	}
+	var keystoreKey *big.Int
+	if keyFile != "" {
+		keystoreKey = readEthKeystore(keyFile)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fatal("Failed to read message description", "error", err)
+		}
+		var description ethMessageDescription
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&description); err != nil {
+			fatal("Failed to decode message description", "file", file, "error", err)
+		}
+
+		var preimage []byte
+		var ethMessage *EthMessageData
+		switch description.Kind {
+		case ethPersonalSign:
+			preimage, ethMessage, err = encodePersonalSign(description.Message)
+		case ethTypedData:
+			preimage, ethMessage, err = encodeTypedData(&description)
+		default:
+			err = fmt.Errorf("kind is %q, not %s or %s", description.Kind, ethPersonalSign, ethTypedData)
+		}
+		if err != nil {
+			fatal("Invalid message description", "file", file, "error", err)
+		}
+
+		d := keystoreKey
+		if d == nil {
+			d = secp256k1Key(random)
+		}
+		testCase := signSecp256k1(d, keccak256(preimage))
+		testCase.Nonce = nonceRFC6979
+		testCase.Message = fmt.Sprintf("0x%x", preimage)
+		testCase.HashAlg = hashKeccak256
+		testCase.EthMessage = ethMessage
+		testCase.Source = fmt.Sprintf("gen-eth-message %s", filepath.Base(file))
+		testCase.Tags = []string{tagEthereum, tagOf(description.Kind)}
+
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		path := filepath.Join(testsDir, "eth_message_"+name+".json")
+		writeTestCase(path, testCase)
+		slog.Info("Test case written", "file", path, "kind", description.Kind, "address", testCase.Address)
+	}
+}
+
+// encodePersonalSign prefixes a message as personal_sign does
+func encodePersonalSign(message any) ([]byte, *EthMessageData, error) {
+	text, ok := message.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("a personal_sign message is text or 0x hex, not %T", message)
+	}
+	payload := []byte(text)
+	if strings.HasPrefix(text, "0x") {
+		decoded, err := hex.DecodeString(text[2:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("message: %v", err)
+		}
+		payload = decoded
+	}
+	prefix := eip191Prefix + strconv.Itoa(len(payload))
+	return append([]byte(prefix), payload...), &EthMessageData{
+		Kind:    ethPersonalSign,
+		Prefix:  prefix,
+		Payload: fmt.Sprintf("0x%x", payload),
+	}, nil
+}
+
+// encodeTypedData encodes typed data as eth_signTypedData_v4 does:
+// 0x19 0x01 || domainSeparator || hashStruct(message)
+func encodeTypedData(description *ethMessageDescription) ([]byte, *EthMessageData, error) {
+	values, ok := description.Message.(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("an eip712 message is a map of values, not %T", description.Message)
+	}
+	if _, ok := description.Types[description.PrimaryType]; !ok {
+		return nil, nil, fmt.Errorf("primaryType %q is not one of the types", description.PrimaryType)
+	}
+	if len(description.Domain) == 0 {
+		return nil, nil, fmt.Errorf("missing domain")
+	}
+
+	types := map[string][]eip712Field{}
+	for name, fields := range description.Types {
+		types[name] = fields
+	}
+	var domainType []eip712Field
+	for _, field := range eip712DomainFields {
+		if _, ok := description.Domain[field.Name]; ok {
+			domainType = append(domainType, field)
+		}
+	}
+	if len(domainType) != len(description.Domain) {
+		return nil, nil, fmt.Errorf("domain has fields other than name, version, chainId, verifyingContract and salt")
+	}
+	types["EIP712Domain"] = domainType
+
+	encoder := &eip712Encoder{types: types}
+	domainSeparator, err := encoder.hashStruct("EIP712Domain", description.Domain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("domain: %v", err)
+	}
+	encoded, err := encoder.encodeData(description.PrimaryType, values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("message: %v", err)
+	}
+	structHash := keccak256(encoded)
+
+	preimage := append([]byte{0x19, 0x01}, domainSeparator...)
+	preimage = append(preimage, structHash...)
+	encodedType := encoder.encodeType(description.PrimaryType)
+	return preimage, &EthMessageData{
+		Kind:            ethTypedData,
+		PrimaryType:     description.PrimaryType,
+		EncodedType:     encodedType,
+		TypeHash:        fmt.Sprintf("0x%x", keccak256([]byte(encodedType))),
+		EncodedData:     fmt.Sprintf("0x%x", encoded),
+		StructHash:      fmt.Sprintf("0x%x", structHash),
+		DomainSeparator: fmt.Sprintf("0x%x", domainSeparator),
+		Domain:          description.Domain,
+		Types:           description.Types,
+		Values:          values,
+	}, nil
+}
+
+// eip712Encoder encodes values of a set of EIP-712 struct types
+type eip712Encoder struct {
+	types map[string][]eip712Field
+}
+
+var eip712Array = regexp.MustCompile(`^(.*)\[(\d*)\]$`)
+
+// encodeType is the primary type followed by the types it references,
+// sorted by name, each as Name(type name,...)
+func (e *eip712Encoder) encodeType(primary string) string {
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		if _, ok := e.types[name]; !ok {
+			return
+		}
+		seen[name] = true
+		for _, field := range e.types[name] {
+			visit(eip712BaseType(field.Type))
+		}
+	}
+	visit(primary)
+	delete(seen, primary)
+	deps := []string{primary}
+	var rest []string
+	for name := range seen {
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	deps = append(deps, rest...)
+
+	var b strings.Builder
+	for _, name := range deps {
+		fields := make([]string, len(e.types[name]))
+		for i, field := range e.types[name] {
+			fields[i] = field.Type + " " + field.Name
+		}
+		fmt.Fprintf(&b, "%s(%s)", name, strings.Join(fields, ","))
+	}
+	return b.String()
+}
+
+func eip712BaseType(typ string) string {
+	for {
+		match := eip712Array.FindStringSubmatch(typ)
+		if match == nil {
+			return typ
+		}
+		typ = match[1]
+	}
+}
+
+// hashStruct is keccak256(encodeData)
+func (e *eip712Encoder) hashStruct(name string, values map[string]any) ([]byte, error) {
+	encoded, err := e.encodeData(name, values)
+	if err != nil {
+		return nil, err
+	}
+	return keccak256(encoded), nil
+}
+
+// encodeData is the type hash followed by each field's 32-byte encoding
+func (e *eip712Encoder) encodeData(name string, values map[string]any) ([]byte, error) {
+	fields := e.types[name]
+	known := map[string]bool{}
+	encoded := keccak256([]byte(e.encodeType(name)))
+	for _, field := range fields {
+		known[field.Name] = true
+		value, ok := values[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s.%s is missing", name, field.Name)
+		}
+		word, err := e.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", name, field.Name, err)
+		}
+		encoded = append(encoded, word...)
+	}
+	for field := range values {
+		if !known[field] {
+			return nil, fmt.Errorf("%s has no field %q", name, field)
+		}
+	}
+	return encoded, nil
+}
+
+// encodeValue encodes a value of a type in 32 bytes
+func (e *eip712Encoder) encodeValue(typ string, value any) ([]byte, error) {
+	if match := eip712Array.FindStringSubmatch(typ); match != nil {
+		items, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a list, not %T", typ, value)
+		}
+		if match[2] != "" {
+			if n, _ := strconv.Atoi(match[2]); n != len(items) {
+				return nil, fmt.Errorf("%s needs %d items, not %d", typ, n, len(items))
+			}
+		}
+		var encoded []byte
+		for i, item := range items {
+			word, err := e.encodeValue(match[1], item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %v", i, err)
+			}
+			encoded = append(encoded, word...)
+		}
+		return keccak256(encoded), nil
+	}
+	if _, ok := e.types[typ]; ok {
+		values, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a map, not %T", typ, value)
+		}
+		return e.hashStruct(typ, values)
+	}
+
+	switch {
+	case typ == "string":
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("string needs text, not %T", value)
+		}
+		return keccak256([]byte(text)), nil
+	case typ == "bytes":
+		data, err := eip712Bytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return keccak256(data), nil
+	case typ == "bool":
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("bool needs true or false, not %T", value)
+		}
+		word := make([]byte, 32)
+		if flag {
+			word[31] = 1
+		}
+		return word, nil
+	case typ == "address":
+		data, err := eip712Bytes(value)
+		if err != nil || len(data) != 20 {
+			return nil, fmt.Errorf("address needs 20 bytes of 0x hex")
+		}
+		return append(make([]byte, 12), data...), nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("unknown type %s", typ)
+		}
+		data, err := eip712Bytes(value)
+		if err != nil || len(data) != size {
+			return nil, fmt.Errorf("%s needs %d bytes of 0x hex", typ, size)
+		}
+		return append(data, make([]byte, 32-size)...), nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		bits, err := strconv.Atoi(strings.TrimPrefix(strings.TrimPrefix(typ, "u"), "int"))
+		if err != nil || bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("unknown type %s", typ)
+		}
+		n, err := eip712Integer(value)
+		if err != nil {
+			return nil, err
+		}
+		min, max := big.NewInt(0), new(big.Int).Lsh(big.NewInt(1), uint(bits))
+		if signed {
+			max.Rsh(max, 1)
+			min.Neg(max)
+		}
+		if n.Cmp(min) < 0 || n.Cmp(max) >= 0 {
+			return nil, fmt.Errorf("%s does not fit in %s", n, typ)
+		}
+		// Two's complement in 256 bits
+		if n.Sign() < 0 {
+			n.Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+		}
+		return n.FillBytes(make([]byte, 32)), nil
+	default:
+		return nil, fmt.Errorf("unknown type %s", typ)
+	}
+}
+
+func eip712Bytes(value any) ([]byte, error) {
+	text, ok := value.(string)
+	if !ok || !strings.HasPrefix(text, "0x") {
+		return nil, fmt.Errorf("needs 0x hex, not %v", value)
+	}
+	return hex.DecodeString(text[2:])
+}
+
+// eip712Integer reads an integer from YAML: a number, or a decimal or 0x hex
+// string for values too large for YAML
+func eip712Integer(value any) (*big.Int, error) {
+	switch v := value.(type) {
+	case int:
+		return big.NewInt(int64(v)), nil
+	case string:
+		n, ok := new(big.Int).SetString(v, 0)
+		if !ok {
+			return nil, fmt.Errorf("%q is not an integer", v)
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("needs an integer, not %T", value)
+	}
+}
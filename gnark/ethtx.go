@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/secp256k1"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fr"
+)
+
+var (
+	// command line flags
+	rpcURL string
+)
+
+// tagTransaction is the tag of the test cases gen-eth-tx writes
+const tagTransaction = "transaction"
+
+// EthTransactionData is the Ethereum transaction a gen-eth-tx test case
+// comes from. The test case's message is the transaction's signing preimage.
+type EthTransactionData struct {
+	Hash        string `json:"hash"`
+	Type        uint64 `json:"type"`
+	ChainID     uint64 `json:"chain_id,omitempty"`
+	BlockNumber uint64 `json:"block_number,omitempty"`
+}
+
+// rpcTransaction is a transaction as eth_getTransactionByHash returns it,
+// with quantities and data in 0x hex
+type rpcTransaction struct {
+	Hash                 string  `json:"hash"`
+	Type                 string  `json:"type"`
+	BlockNumber          string  `json:"blockNumber"`
+	From                 string  `json:"from"`
+	To                   *string `json:"to"`
+	ChainID              string  `json:"chainId"`
+	Nonce                string  `json:"nonce"`
+	GasPrice             string  `json:"gasPrice"`
+	MaxPriorityFeePerGas string  `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         string  `json:"maxFeePerGas"`
+	MaxFeePerBlobGas     string  `json:"maxFeePerBlobGas"`
+	Gas                  string  `json:"gas"`
+	Value                string  `json:"value"`
+	Input                string  `json:"input"`
+	AccessList           []struct {
+		Address     string   `json:"address"`
+		StorageKeys []string `json:"storageKeys"`
+	} `json:"accessList"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes"`
+	AuthorizationList   []struct {
+		ChainID string `json:"chainId"`
+		Address string `json:"address"`
+		Nonce   string `json:"nonce"`
+		YParity string `json:"yParity"`
+		R       string `json:"r"`
+		S       string `json:"s"`
+	} `json:"authorizationList"`
+	V string `json:"v"`
+	R string `json:"r"`
+	S string `json:"s"`
+}
+
+// generateEthTxTestData fetches each transaction from the JSON-RPC endpoint
+// in -rpc and writes its signature as a secp256k1 test case in -tests, named
+// eth_tx_<hash>.json. The message is the signing preimage of the
+// transaction: the RLP of its fields, with the chain id for EIP-155, after
+// the type byte for typed transactions (EIP-2718: access list, dynamic fee,
+// blob and set-code ones). The public key is recovered from the signature,
+// as ecrecover does, and must be the key of the sender the node reports. The
+// encoding is also checked by hashing the signed transaction to its hash.
+func generateEthTxTestData(hashes []string) {
+	if rpcURL == "" {
+		fatal("-rpc is required for gen-eth-tx")
+	}
+	if len(hashes) == 0 {
+		fatal("Missing transaction hash for gen-eth-tx command")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	endpoint := rpcURL
+	if u, err := url.Parse(rpcURL); err == nil {
+		endpoint = u.Host // keep API keys in the path or query out of the test case
+	}
+
+	for _, hash := range hashes {
+		tx, err := fetchTransaction(hash)
+		if err != nil {
+			fatal("Failed to fetch transaction", "hash", hash, "error", err)
+		}
+		preimage, signed, recovery, chainID, err := tx.encode()
+		if err != nil {
+			fatal("Invalid transaction", "hash", hash, "error", err)
+		}
+		if got := fmt.Sprintf("0x%x", keccak256(signed)); !strings.EqualFold(got, tx.Hash) {
+			fatal("The signed encoding of the transaction does not hash to its hash", "hash", hash, "encoding_hash", got)
+		}
+
+		r, s := new(big.Int).SetBytes(rpcQuantity(tx.R)), new(big.Int).SetBytes(rpcQuantity(tx.S))
+		digest := keccak256(preimage)
+		x, y, err := ecrecover(digest, r, s, recovery)
+		if err != nil {
+			fatal("Invalid transaction", "hash", hash, "error", err)
+		}
+		address := ethAddress(x, y)
+		if !strings.EqualFold(address, tx.From) {
+			fatal("The signature of the transaction does not recover its sender", "hash", hash, "recovered", address, "from", tx.From)
+		}
+
+		valid := true
+		testCase := &TestCase{
+			R:           fmt.Sprintf("0x%x", r),
+			S:           fmt.Sprintf("0x%x", s),
+			MsgHash:     fmt.Sprintf("0x%x", new(big.Int).SetBytes(digest)),
+			PubKeyX:     fmt.Sprintf("0x%x", x),
+			PubKeyY:     fmt.Sprintf("0x%x", y),
+			ExpectValid: &valid,
+			Curve:       curveSecp256k1,
+			Source:      fmt.Sprintf("gen-eth-tx %s from %s", tx.Hash, endpoint),
+			Address:     address,
+			V:           27 + recovery,
+			Message:     fmt.Sprintf("0x%x", preimage),
+			HashAlg:     hashKeccak256,
+			Tags:        []string{tagEthereum, tagTransaction},
+			EthTransaction: &EthTransactionData{
+				Hash:        strings.ToLower(tx.Hash),
+				Type:        new(big.Int).SetBytes(rpcQuantity(tx.Type)).Uint64(),
+				ChainID:     chainID,
+				BlockNumber: new(big.Int).SetBytes(rpcQuantity(tx.BlockNumber)).Uint64(),
+			},
+		}
+		path := filepath.Join(testsDir, "eth_tx_"+strings.TrimPrefix(strings.ToLower(tx.Hash), "0x")+".json")
+		writeTestCase(path, testCase)
+		slog.Info("Test case written", "file", path, "type", testCase.EthTransaction.Type, "from", address)
+	}
+}
+
+// fetchTransaction calls eth_getTransactionByHash on -rpc
+func fetchTransaction(hash string) (*rpcTransaction, error) {
+	request, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_getTransactionByHash",
+		"params":  []string{hash},
+	})
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(rpcURL, "application/json", bytes.NewReader(request))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	var response struct {
+		Result *rpcTransaction `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("RPC error %d: %s", response.Error.Code, response.Error.Message)
+	}
+	if response.Result == nil {
+		return nil, fmt.Errorf("not found")
+	}
+	return response.Result, nil
+}
+
+// encode returns what the sender signed and the signed transaction, with the
+// recovery id and the chain id of the signature
+func (tx *rpcTransaction) encode() (preimage, signed []byte, recovery uint, chainID uint64, err error) {
+	txType := new(big.Int).SetBytes(rpcQuantity(tx.Type)).Uint64()
+	to := []byte{}
+	if tx.To != nil {
+		to = rpcData(*tx.To)
+	}
+	accessList := []any{}
+	for _, entry := range tx.AccessList {
+		keys := []any{}
+		for _, key := range entry.StorageKeys {
+			keys = append(keys, rpcData(key))
+		}
+		accessList = append(accessList, []any{rpcData(entry.Address), keys})
+	}
+	signature := []any{rpcQuantity(tx.V), rpcQuantity(tx.R), rpcQuantity(tx.S)}
+	v := new(big.Int).SetBytes(rpcQuantity(tx.V)).Uint64()
+	if txType > 0 {
+		chainID = new(big.Int).SetBytes(rpcQuantity(tx.ChainID)).Uint64()
+		if v > 1 {
+			return nil, nil, 0, 0, fmt.Errorf("y parity %d is not 0 or 1", v)
+		}
+		recovery = uint(v)
+	}
+
+	var fields []any
+	switch txType {
+	case 0:
+		fields = []any{rpcQuantity(tx.Nonce), rpcQuantity(tx.GasPrice), rpcQuantity(tx.Gas), to, rpcQuantity(tx.Value), rpcData(tx.Input)}
+		signed = rlpEncode(append(fields, signature...))
+		switch {
+		case v == 27 || v == 28:
+			// Before EIP-155, the signature is not bound to a chain
+			recovery = uint(v - 27)
+			return rlpEncode(fields), signed, recovery, 0, nil
+		case v >= 35:
+			chainID = (v - 35) / 2
+			recovery = uint((v - 35) % 2)
+			unsigned := append(fields, new(big.Int).SetUint64(chainID).Bytes(), []byte{}, []byte{})
+			return rlpEncode(unsigned), signed, recovery, chainID, nil
+		default:
+			return nil, nil, 0, 0, fmt.Errorf("legacy v %d is not 27, 28 or EIP-155", v)
+		}
+	case 1:
+		fields = []any{rpcQuantity(tx.ChainID), rpcQuantity(tx.Nonce), rpcQuantity(tx.GasPrice), rpcQuantity(tx.Gas), to, rpcQuantity(tx.Value), rpcData(tx.Input), accessList}
+	case 2:
+		fields = []any{rpcQuantity(tx.ChainID), rpcQuantity(tx.Nonce), rpcQuantity(tx.MaxPriorityFeePerGas), rpcQuantity(tx.MaxFeePerGas), rpcQuantity(tx.Gas), to, rpcQuantity(tx.Value), rpcData(tx.Input), accessList}
+	case 3:
+		blobHashes := []any{}
+		for _, blobHash := range tx.BlobVersionedHashes {
+			blobHashes = append(blobHashes, rpcData(blobHash))
+		}
+		fields = []any{rpcQuantity(tx.ChainID), rpcQuantity(tx.Nonce), rpcQuantity(tx.MaxPriorityFeePerGas), rpcQuantity(tx.MaxFeePerGas), rpcQuantity(tx.Gas), to, rpcQuantity(tx.Value), rpcData(tx.Input), accessList, rpcQuantity(tx.MaxFeePerBlobGas), blobHashes}
+	case 4:
+		authorizations := []any{}
+		for _, auth := range tx.AuthorizationList {
+			authorizations = append(authorizations, []any{rpcQuantity(auth.ChainID), rpcData(auth.Address), rpcQuantity(auth.Nonce), rpcQuantity(auth.YParity), rpcQuantity(auth.R), rpcQuantity(auth.S)})
+		}
+		fields = []any{rpcQuantity(tx.ChainID), rpcQuantity(tx.Nonce), rpcQuantity(tx.MaxPriorityFeePerGas), rpcQuantity(tx.MaxFeePerGas), rpcQuantity(tx.Gas), to, rpcQuantity(tx.Value), rpcData(tx.Input), accessList, authorizations}
+	default:
+		return nil, nil, 0, 0, fmt.Errorf("transaction type %d is not supported", txType)
+	}
+	prefix := []byte{byte(txType)}
+	preimage = append(prefix, rlpEncode(fields)...)
+	signed = append(prefix, rlpEncode(append(fields, signature...))...)
+	return preimage, signed, recovery, chainID, nil
+}
+
+// ecrecover recovers the public key that signed a hash, from the signature
+// and the parity of the y coordinate of kG
+func ecrecover(hash []byte, r, s *big.Int, recovery uint) (x, y *big.Int, err error) {
+	n := fr.Modulus()
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf("r or s is out of range")
+	}
+	rx, ry, err := decompressPubKey(curveSecp256k1, fmt.Sprintf("0%d%064x", 2+recovery, r))
+	if err != nil {
+		return nil, nil, fmt.Errorf("r is not the x coordinate of a point: %v", err)
+	}
+
+	// Q = r⁻¹(sR − zG)
+	var R, sR, zG, Q secp256k1.G1Affine
+	R.X.SetBigInt(rx)
+	R.Y.SetBigInt(ry)
+	sR.ScalarMultiplication(&R, s)
+	zG.ScalarMultiplicationBase(new(big.Int).SetBytes(hash))
+	Q.Sub(&sR, &zG)
+	Q.ScalarMultiplication(&Q, new(big.Int).ModInverse(r, n))
+	if Q.IsInfinity() {
+		return nil, nil, fmt.Errorf("the signature recovers the point at infinity")
+	}
+	return Q.X.BigInt(new(big.Int)), Q.Y.BigInt(new(big.Int)), nil
+}
+
+// rlpEncode encodes byte strings and lists of them with RLP
+func rlpEncode(item any) []byte {
+	switch item := item.(type) {
+	case []byte:
+		if len(item) == 1 && item[0] < 0x80 {
+			return item
+		}
+		return append(rlpHeader(0x80, len(item)), item...)
+	case []any:
+		var payload []byte
+		for _, element := range item {
+			payload = append(payload, rlpEncode(element)...)
+		}
+		return append(rlpHeader(0xc0, len(payload)), payload...)
+	default:
+		panic(fmt.Sprintf("rlpEncode: unsupported %T", item))
+	}
+}
+
+func rlpHeader(offset byte, length int) []byte {
+	if length < 56 {
+		return []byte{offset + byte(length)}
+	}
+	size := new(big.Int).SetInt64(int64(length)).Bytes()
+	return append([]byte{offset + 55 + byte(len(size))}, size...)
+}
+
+// rpcQuantity is a JSON-RPC quantity as RLP takes it: big-endian bytes
+// without leading zeros, empty for zero
+func rpcQuantity(value string) []byte {
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(value, "0x"), 16)
+	if !ok {
+		return []byte{}
+	}
+	return v.Bytes()
+}
+
+// rpcData is JSON-RPC data, hex bytes that keep their length
+func rpcData(value string) []byte {
+	data, err := hex.DecodeString(strings.TrimPrefix(value, "0x"))
+	if err != nil {
+		return []byte{}
+	}
+	return data
+}
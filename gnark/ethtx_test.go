@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// TestRLPEncode checks rlpEncode against the examples of the RLP
+// specification in the Ethereum yellow paper and wiki
+func TestRLPEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		item any
+		want string
+	}{
+		{"string", []byte("dog"), "83646f67"},
+		{"list of strings", []any{[]byte("cat"), []byte("dog")}, "c88363617483646f67"},
+		{"empty string", []byte{}, "80"},
+		{"empty list", []any{}, "c0"},
+		{"zero", rpcQuantity("0x0"), "80"},
+		{"single byte", []byte{0x0f}, "0f"},
+		{"single byte above 0x7f", []byte{0x80}, "8180"},
+		{"integer", rpcQuantity("0x400"), "820400"},
+		{"set of three", []any{[]any{}, []any{[]any{}}, []any{[]any{}, []any{[]any{}}}}, "c7c0c1c0c3c0c1c0"},
+		{
+			// 56 bytes take a long string header
+			name: "long string",
+			item: []byte("Lorem ipsum dolor sit amet, consectetur adipisicing elit"),
+			want: "b838" + hex.EncodeToString([]byte("Lorem ipsum dolor sit amet, consectetur adipisicing elit")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(rlpEncode(tt.item)); got != tt.want {
+				t.Errorf("rlpEncode = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestEthTransactionEncode checks the signing preimages and signed encodings
+// of published transactions, and that their signatures recover the sender
+func TestEthTransactionEncode(t *testing.T) {
+	to := func(address string) *string { return &address }
+	tests := []struct {
+		name string
+		tx   rpcTransaction
+		// signingHash is the Keccak-256 of the preimage, when known
+		signingHash string
+		signed      string
+		recovery    uint
+		chainID     uint64
+		from        string
+	}{
+		{
+			// The example of EIP-155, signed with the key 0x4646…46
+			name: "EIP-155",
+			tx: rpcTransaction{
+				Type: "0x0", Nonce: "0x9", GasPrice: "0x4a817c800", Gas: "0x5208",
+				To: to("0x3535353535353535353535353535353535353535"), Value: "0xde0b6b3a7640000", Input: "0x",
+				V: "0x25",
+				R: "0x28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276",
+				S: "0x67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+			},
+			signingHash: "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53",
+			signed:      "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+			recovery:    0,
+			chainID:     1,
+			from:        "0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F",
+		},
+		{
+			// The legacy transaction of the EIP-1559 block of the Ethereum
+			// tests, signed before EIP-155
+			name: "legacy",
+			tx: rpcTransaction{
+				Type: "0x0", Nonce: "0x0", GasPrice: "0xa", Gas: "0xc350",
+				To: to("0x095e7baea6a6c7c4c2dfeb977efac326af552d87"), Value: "0xa", Input: "0x",
+				V: "0x1b",
+				R: "0x9bea4c4daac7c7c52e093e6a4c35dbbcf8856f1af7b059ba20253e70848d094f",
+				S: "0x8a8fae537ce25ed8cb5af9adac3f141af69bd515bd2ba031522df09b97dd72b1",
+			},
+			signed:   "f85f800a82c35094095e7baea6a6c7c4c2dfeb977efac326af552d870a801ba09bea4c4daac7c7c52e093e6a4c35dbbcf8856f1af7b059ba20253e70848d094fa08a8fae537ce25ed8cb5af9adac3f141af69bd515bd2ba031522df09b97dd72b1",
+			recovery: 0,
+			from:     "0xa94f5374fce5edbc8e2a8697c15331677e6ebf0b",
+		},
+		{
+			// The access list transaction of go-ethereum's EIP-2718 test. Its
+			// signature was not made with a known key, so the sender and
+			// signing hash are those go-ethereum recovers and computes.
+			name: "EIP-2930",
+			tx: rpcTransaction{
+				Type: "0x1", ChainID: "0x1", Nonce: "0x3", GasPrice: "0x1", Gas: "0x61a8",
+				To: to("0xb94f5374fce5edbc8e2a8697c15331677e6ebf0b"), Value: "0xa", Input: "0x5544",
+				V: "0x1",
+				R: "0xc9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b2660",
+				S: "0x32f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d37521",
+			},
+			signingHash: "49b486f0ec0a60dfbbca2d30cb07c9e8ffb2a2ff41f29a1ab6737475f6ff69f3",
+			signed:      "01f8630103018261a894b94f5374fce5edbc8e2a8697c15331677e6ebf0b0a825544c001a0c9519f4f2b30335884581971573fadf60c6204f59a911df35ee8a540456b2660a032f1e8e2c5dd761f9e4f88f41c8310aeaba26a8bfcdacfedfa12ec3862d37521",
+			recovery:    1,
+			chainID:     1,
+			from:        "0x27cf7d8449c9da59189427619Ba59f985CEE9C0F",
+		},
+		{
+			// The dynamic fee transaction of the EIP-1559 block of the
+			// Ethereum tests, with the sender and signing hash of go-ethereum
+			name: "EIP-1559",
+			tx: rpcTransaction{
+				Type: "0x2", ChainID: "0x1", Nonce: "0x0", MaxPriorityFeePerGas: "0x0", MaxFeePerGas: "0x3b9aca00", Gas: "0x1e241",
+				To: to("0x095e7baea6a6c7c4c2dfeb977efac326af552d87"), Value: "0x0", Input: "0x",
+				AccessList: []struct {
+					Address     string   `json:"address"`
+					StorageKeys []string `json:"storageKeys"`
+				}{{
+					Address:     "0x0000000000000000000000000000000000000001",
+					StorageKeys: []string{"0x0000000000000000000000000000000000000000000000000000000000000000"},
+				}},
+				V: "0x0",
+				R: "0xfe38ca4e44a30002ac54af7cf922a6ac2ba11b7d22f548e8ecb3f51f41cb31b0",
+				S: "0x6de6a5cbae13c0c856e33acf021b51819636cfc009d39eafb9f606d546e305a8",
+			},
+			signingHash: "cdb92fd0725cbeabdff219fcff9c7682b55df80adf1d0a0944148d615fbbf498",
+			signed:      "02f8a0018080843b9aca008301e24194095e7baea6a6c7c4c2dfeb977efac326af552d878080f838f7940000000000000000000000000000000000000001e1a0000000000000000000000000000000000000000000000000000000000000000080a0fe38ca4e44a30002ac54af7cf922a6ac2ba11b7d22f548e8ecb3f51f41cb31b0a06de6a5cbae13c0c856e33acf021b51819636cfc009d39eafb9f606d546e305a8",
+			recovery:    0,
+			chainID:     1,
+			from:        "0xa8E20d02Fb65adAa95f9279B325D8092724C81ee",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preimage, signed, recovery, chainID, err := tt.tx.encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(signed); got != tt.signed {
+				t.Errorf("signed encoding = %s, want %s", got, tt.signed)
+			}
+			if recovery != tt.recovery || chainID != tt.chainID {
+				t.Errorf("recovery id %d and chain id %d, want %d and %d", recovery, chainID, tt.recovery, tt.chainID)
+			}
+			hash := keccak256(preimage)
+			if tt.signingHash != "" && hex.EncodeToString(hash) != tt.signingHash {
+				t.Errorf("signing hash = %x, want %s", hash, tt.signingHash)
+			}
+			r, s := new(big.Int).SetBytes(rpcQuantity(tt.tx.R)), new(big.Int).SetBytes(rpcQuantity(tt.tx.S))
+			x, y, err := ecrecover(hash, r, s, recovery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ethAddress(x, y); !strings.EqualFold(got, tt.from) {
+				t.Errorf("the signature recovers %s, want %s", got, tt.from)
+			}
+		})
+	}
+}
+
+// TestEthTransactionEncodeErrors checks the signatures encode rejects
+func TestEthTransactionEncodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   rpcTransaction
+	}{
+		{"legacy v neither 27, 28 nor EIP-155", rpcTransaction{Type: "0x0", V: "0x1d"}},
+		{"typed y parity above 1", rpcTransaction{Type: "0x2", ChainID: "0x1", V: "0x2"}},
+		{"unknown type", rpcTransaction{Type: "0x7f", V: "0x0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, _, err := tt.tx.encode(); err == nil {
+				t.Error("encode succeeded, want an error")
+			}
+		})
+	}
+}
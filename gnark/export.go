@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/consensys/gnark/backend/solidity"
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// solidityExporter is a verifying key gnark can export a Solidity verifier of
+type solidityExporter interface {
+	ExportSolidity(w io.Writer, exportOpts ...solidity.ExportOption) error
+}
+
+// exportVerifier writes the Solidity verifier of the verifying key in -d to
+// -out, Groth16Verifier.sol or PlonkVerifier.sol, for the gas benchmark and
+// on-chain verification. gnark exports BN254 verifiers only: the EIP-2537
+// verifier for BLS12-381 is written by cmd/generate_verifier. The verifier
+// must hash as the prover does, so groth16 artifacts need sha256 or keccak256
+// hash-to-field, and plonk ones, whose verifier is fixed, sha256 challenges
+// and rfc9380 hash-to-field.
+func exportVerifier() {
+	resolveSettings()
+
+	switch {
+	case curveName == "bls12-381" && provingBackend == backendGroth16:
+		fatal("gnark exports Solidity verifiers for bn254 only: run cmd/generate_verifier for the EIP-2537 verifier of bls12-381")
+	case curveName != "bn254":
+		fatal("No Solidity verifier for the curve (use bn254)", "curve", curveName)
+	}
+
+	var opts []solidity.ExportOption
+	name := "Groth16Verifier.sol"
+	if provingBackend == backendPLONK {
+		name = "PlonkVerifier.sol"
+		if challengeHash != challengeHashSHA256 || hashToField != hashToFieldRFC9380 {
+			fatal(fmt.Sprintf("The plonk Solidity verifier hashes challenges with %s and commitments with %s hash-to-field", challengeHashSHA256, hashToFieldRFC9380),
+				"dir", outputDir, "challenge_hash", challengeHash, "hash_to_field", hashToField)
+		}
+	} else {
+		h, err := manifest.NewHashToField(hashToField)
+		if err != nil {
+			fatal("Invalid -hash-to-field", "error", err)
+		}
+		if h == nil {
+			fatal(fmt.Sprintf("Hash-to-field function not supported by the Solidity verifier (use %s or %s)", hashToFieldSHA256, hashToFieldKeccak256), "hash_to_field", hashToField)
+		}
+		opts = append(opts, solidity.WithHashToFieldFunction(h))
+	}
+
+	checkArtifacts("verifying.key")
+	vk := newVerifyingKey()
+	if err := readArtifact(filepath.Join(outputDir, "verifying.key"), vk, &IOStats{}); err != nil {
+		fatal("Failed to read verifying key", "error", err)
+	}
+	exporter, ok := vk.(solidityExporter)
+	if !ok {
+		fatal("No Solidity verifier", "backend", provingBackend, "curve", curveName)
+	}
+
+	dir := exportDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fatal("Failed to create output directory", "error", err)
+	}
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		fatal("Failed to create Solidity verifier file", "error", err)
+	}
+	defer f.Close()
+	if err := exporter.ExportSolidity(f, opts...); err != nil {
+		fatal("Failed to export Solidity verifier", "error", err)
+	}
+	slog.Info("Solidity verifier written", "file", path, "backend", provingBackend, "curve", curveName, "hash_to_field", hashToField)
+}
+
+// exportDir is where export writes the verifier: -out, else src as the gas
+// benchmark expects, or src/<circuit> for a variant other than the default
+func exportDir() string {
+	if convertOut != "" {
+		return convertOut
+	}
+	if circuitName != defaultCircuit {
+		return filepath.Join("src", circuitName)
+	}
+	return "src"
+}
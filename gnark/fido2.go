@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// fido2RelyingParty is the relying party the credential is made for
+const fido2RelyingParty = "zk-snark-ecdsa-benchmarks.local"
+
+var (
+	// command line flags
+	authenticator string
+)
+
+// importFIDO2 makes an ES256 credential on a security key, or a platform
+// authenticator libfido2 reaches, and writes -count assertions with it into
+// -tests as fido2_test_case_N.json. It talks CTAP2 through the fido2-cred
+// and fido2-assert tools of libfido2, which prompt for the PIN when the
+// authenticator wants one. Each assertion needs a touch of the key. The
+// authenticator signs its authenticator data || client data hash, where the
+// client data hash is random here, so the test case takes the SHA-256 of that
+// as its message hash. Like the webauthn command, the signature is kept as the
+// authenticator made it, with s not normalized, and must verify before it is
+// written.
+func importFIDO2() {
+	if genCount < 1 {
+		fatal("-count must be at least 1")
+	}
+	device := authenticator
+	if device == "" {
+		device = findAuthenticator()
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+
+	// Make the credential: client data hash, relying party, user name and
+	// user id in, the attested credential data of the authenticator data out
+	userID := randomBytes(32)
+	slog.Info("Making a credential, touch the authenticator", "device", device)
+	made := runFIDO2Tool("fido2-cred", device, []string{"-M"},
+		base64.StdEncoding.EncodeToString(randomBytes(32)),
+		fido2RelyingParty,
+		"benchmark",
+		base64.StdEncoding.EncodeToString(userID),
+	)
+	if len(made) < 5 {
+		fatal("fido2-cred returned too few lines, expected at least 5", "lines", len(made))
+	}
+	credAuthData := decodeFIDO2AuthData(made[3])
+	pub, aaguid, err := parseAttestedCredential(credAuthData)
+	if err != nil {
+		fatal("Failed to read the credential", "error", err)
+	}
+	credentialID := made[4]
+
+	for i := 1; i <= genCount; i++ {
+		clientDataHash := randomBytes(32)
+		slog.Info("Asserting, touch the authenticator", "assertion", i, "count", genCount)
+		asserted := runFIDO2Tool("fido2-assert", device, []string{"-G", "-p"},
+			base64.StdEncoding.EncodeToString(clientDataHash),
+			fido2RelyingParty,
+			credentialID,
+		)
+		if len(asserted) < 4 {
+			fatal("fido2-assert returned too few lines, expected at least 4", "lines", len(asserted))
+		}
+		authData := decodeFIDO2AuthData(asserted[2])
+		if len(authData) < 37 {
+			fatal("Authenticator data is shorter than its fixed 37 bytes", "bytes", len(authData))
+		}
+		sig, err := decodeBase64URL(asserted[3])
+		if err != nil {
+			fatal("Invalid signature from fido2-assert", "error", err)
+		}
+		var parsed struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &parsed); err != nil || len(rest) > 0 {
+			fatal("The authenticator's signature is not a DER ECDSA signature")
+		}
+
+		hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash...))
+		if !ecdsa.Verify(pub, hash[:], parsed.R, parsed.S) {
+			fatal("The authenticator's signature does not verify for its credential")
+		}
+		counter := binary.BigEndian.Uint32(authData[33:37])
+
+		valid := true
+		testCase := &TestCase{
+			R:           fmt.Sprintf("0x%x", parsed.R),
+			S:           fmt.Sprintf("0x%x", parsed.S),
+			MsgHash:     fmt.Sprintf("0x%x", new(big.Int).SetBytes(hash[:])),
+			PubKeyX:     fmt.Sprintf("0x%x", pub.X),
+			PubKeyY:     fmt.Sprintf("0x%x", pub.Y),
+			ExpectValid: &valid,
+			Source:      fmt.Sprintf("fido2 assertion by authenticator %x, signature counter %d", aaguid, counter),
+			Tags:        []string{tagFIDO2},
+		}
+		path := filepath.Join(testsDir, fmt.Sprintf("fido2_test_case_%d.json", i))
+		writeTestCase(path, testCase)
+		slog.Info("Test case written", "file", path)
+	}
+}
+
+// findAuthenticator returns the first authenticator fido2-token lists
+func findAuthenticator() string {
+	out, err := exec.Command("fido2-token", "-L").Output()
+	if err != nil {
+		fatal("Failed to list authenticators with fido2-token (install libfido2's tools)", "error", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// Each line is "<path>: vendor=..., product=... (<name>)"
+		if path, _, ok := strings.Cut(line, ": "); ok && path != "" {
+			return path
+		}
+	}
+	fatal("No FIDO2 authenticator found, connect a security key or pass -authenticator")
+	return ""
+}
+
+// runFIDO2Tool runs fido2-cred or fido2-assert on device with its input
+// lines on stdin, leaving the terminal to it for PIN prompts, and returns
+// the lines it writes
+func runFIDO2Tool(tool, device string, args []string, input ...string) []string {
+	var stdout bytes.Buffer
+	cmd := exec.Command(tool, append(args, device)...)
+	cmd.Stdin = strings.NewReader(strings.Join(input, "\n") + "\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fatal("FIDO2 tool failed", "tool", tool, "error", err)
+	}
+	return strings.Split(strings.TrimSpace(stdout.String()), "\n")
+}
+
+// decodeFIDO2AuthData decodes authenticator data as libfido2's tools write
+// it: base64 of the CBOR byte string holding it
+func decodeFIDO2AuthData(line string) []byte {
+	data, err := decodeBase64URL(line)
+	if err != nil {
+		fatal("Invalid authenticator data", "error", err)
+	}
+	var authData []byte
+	if err := cbor.Unmarshal(data, &authData); err != nil {
+		fatal("Authenticator data is not a CBOR byte string", "error", err)
+	}
+	return authData
+}
+
+// parseAttestedCredential reads the AAGUID and the COSE public key from the
+// attested credential data that follows the fixed 37 bytes of the
+// authenticator data of a new credential
+func parseAttestedCredential(authData []byte) (*ecdsa.PublicKey, []byte, error) {
+	const attestedFlag = 0x40
+	if len(authData) < 37+18 || authData[32]&attestedFlag == 0 {
+		return nil, nil, fmt.Errorf("authenticator data without attested credential data")
+	}
+	aaguid := authData[37:53]
+	idLen := int(binary.BigEndian.Uint16(authData[53:55]))
+	if len(authData) < 55+idLen {
+		return nil, nil, fmt.Errorf("authenticator data shorter than its credential id")
+	}
+	// The COSE key may be followed by extensions, so only its CBOR item is
+	// decoded
+	var key cbor.RawMessage
+	if _, err := cbor.UnmarshalFirst(authData[55+idLen:], &key); err != nil {
+		return nil, nil, fmt.Errorf("invalid credential public key: %v", err)
+	}
+	pub, err := parseCOSEKey(key)
+	return pub, aaguid, err
+}
+
+func randomBytes(n int) []byte {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		fatal("Failed to generate random bytes", "error", err)
+	}
+	return buf
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/pprof/profile"
+)
+
+// Flamegraph layout, in pixels
+const (
+	flameWidth       = 1200
+	flameFrameHeight = 16
+	flameMargin      = 10
+	flameTitleHeight = 40
+	flameCharWidth   = 7
+)
+
+// flameNode is a function in the merged call tree of a profile
+type flameNode struct {
+	name     string
+	value    int64
+	children map[string]*flameNode
+}
+
+// renderFlamegraph turns a CPU profile into folded stacks (one line per stack,
+// root first, as consumed by flamegraph.pl and speedscope) and an SVG
+// flamegraph next to it, e.g. cpu_prove_1.folded and cpu_prove_1.svg.
+func renderFlamegraph(profilePath, title string) {
+	f, err := os.Open(profilePath)
+	if err != nil {
+		fatal("Failed to open CPU profile", "error", err)
+	}
+	defer f.Close()
+
+	prof, err := profile.Parse(f)
+	if err != nil {
+		fatal("Failed to read CPU profile", "error", err)
+	}
+
+	// The samples count is the first value of Go CPU profiles
+	folded := make(map[string]int64)
+	root := &flameNode{name: "all", children: make(map[string]*flameNode)}
+	for _, sample := range prof.Sample {
+		var stack []string
+		for i := len(sample.Location) - 1; i >= 0; i-- {
+			lines := sample.Location[i].Line
+			for j := len(lines) - 1; j >= 0; j-- {
+				stack = append(stack, shortFunctionName(lines[j].Function.Name))
+			}
+		}
+		if len(stack) == 0 || sample.Value[0] == 0 {
+			continue
+		}
+
+		folded[strings.Join(stack, ";")] += sample.Value[0]
+		node := root
+		node.value += sample.Value[0]
+		for _, name := range stack {
+			child, ok := node.children[name]
+			if !ok {
+				child = &flameNode{name: name, children: make(map[string]*flameNode)}
+				node.children[name] = child
+			}
+			child.value += sample.Value[0]
+			node = child
+		}
+	}
+
+	base := strings.TrimSuffix(profilePath, filepath.Ext(profilePath))
+	writeFolded(base+".folded", folded)
+	writeFlamegraphSVG(base+".svg", title, root)
+}
+
+// shortFunctionName drops the package path, e.g. github.com/consensys/
+// gnark-crypto/ecc/bn254/fp.mul becomes fp.mul
+func shortFunctionName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+func writeFolded(path string, folded map[string]int64) {
+	stacks := make([]string, 0, len(folded))
+	for stack := range folded {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	f, err := os.Create(path)
+	if err != nil {
+		fatal("Failed to create folded stacks", "error", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, stack := range stacks {
+		fmt.Fprintf(w, "%s %d\n", stack, folded[stack])
+	}
+	if err := w.Flush(); err != nil {
+		fatal("Failed to write folded stacks", "error", err)
+	}
+}
+
+func writeFlamegraphSVG(path, title string, root *flameNode) {
+	depth := root.depth()
+	height := flameTitleHeight + depth*flameFrameHeight + flameMargin
+
+	f, err := os.Create(path)
+	if err != nil {
+		fatal("Failed to create flamegraph", "error", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" font-family="Verdana,sans-serif" font-size="11">
+<rect x="0" y="0" width="100%%" height="100%%" fill="#f8f8f8"/>
+<text x="%d" y="24" font-size="16" text-anchor="middle">%s</text>
+`, flameWidth, height, flameWidth/2, html.EscapeString(title))
+
+	if root.value > 0 {
+		scale := float64(flameWidth-2*flameMargin) / float64(root.value)
+		// The root sits at the bottom and callees stack on top of their callers
+		bottom := float64(height - flameMargin - flameFrameHeight)
+		root.render(w, flameMargin, bottom, scale, root.value)
+	}
+
+	fmt.Fprintln(w, "</svg>")
+	if err := w.Flush(); err != nil {
+		fatal("Failed to write flamegraph", "error", err)
+	}
+}
+
+func (n *flameNode) depth() int {
+	deepest := 0
+	for _, child := range n.children {
+		if d := child.depth(); d > deepest {
+			deepest = d
+		}
+	}
+	return deepest + 1
+}
+
+func (n *flameNode) render(w *bufio.Writer, x, y, scale float64, total int64) {
+	width := float64(n.value) * scale
+	if width < 0.5 {
+		return
+	}
+
+	label := fmt.Sprintf("%s (%d samples, %.2f%%)", n.name, n.value, 100*float64(n.value)/float64(total))
+	fmt.Fprintf(w, `<g><title>%s</title><rect x="%.1f" y="%.1f" width="%.1f" height="%d" fill="%s" rx="2"/>`,
+		html.EscapeString(label), x, y, width, flameFrameHeight-1, flameColor(n.name))
+	if chars := int(width-6) / flameCharWidth; chars >= 3 {
+		text := n.name
+		if len(text) > chars {
+			text = text[:chars-2] + ".."
+		}
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f">%s</text>`, x+3, y+11.5, html.EscapeString(text))
+	}
+	fmt.Fprintln(w, "</g>")
+
+	// Lay children out alphabetically, like flamegraph.pl
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		child := n.children[name]
+		child.render(w, x, y-flameFrameHeight, scale, total)
+		x += float64(child.value) * scale
+	}
+}
+
+// flameColor picks a warm color from the function name, so a function has the
+// same color in every flamegraph
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, 80+(v>>8)%150, 40+(v>>16)%50)
+}
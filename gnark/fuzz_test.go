@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The fuzz targets check that no malformed test case can panic the tooling,
+// from the JSON down to the witness. Their seed corpus is in
+// testdata/fuzz, plus the test cases in tests when they have been generated:
+//
+//	go test -run '^$' -fuzz FuzzParseTestCase -fuzztime 1m
+
+// addTestCaseSeeds adds the generated test cases in tests to the seed corpus
+func addTestCaseSeeds(f *testing.F) {
+	f.Helper()
+	files, _ := filepath.Glob(filepath.Join("tests", "*.json"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal("Failed to read test case:", err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzParseTestCase(f *testing.F) {
+	addTestCaseSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		testCase, err := parseTestCase(data)
+		if err != nil {
+			return
+		}
+		// Whatever parses must be complete for the circuits
+		for _, value := range []string{testCase.R, testCase.S, testCase.MsgHash, testCase.PubKeyX, testCase.PubKeyY} {
+			if _, err := parseHexToBigInt(value); err != nil {
+				t.Fatalf("Parsed test case has an unusable value: %v", err)
+			}
+		}
+	})
+}
+
+func FuzzParseHexField(f *testing.F) {
+	for _, seed := range []string{"", "0x", "0x0", "0xzz12", "ff", "0x" + string(make([]byte, 70))} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		v, err := parseHexField(value)
+		if err == nil && v.BitLen() > 256 {
+			t.Fatalf("parseHexField(%q) accepted a %d-bit value", value, v.BitLen())
+		}
+	})
+}
+
+func FuzzCreateWitness(f *testing.F) {
+	defaultSettings()
+	addTestCaseSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		testCase, err := parseTestCase(data)
+		if err != nil {
+			return
+		}
+		// A test case that validates may still be rejected, for a curve the
+		// circuit does not verify, but must not panic
+		createWitness(testCase)
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GasStats is the gas the Solidity verifier used to verify the proof of a
+// test case, as measured by forge
+type GasStats struct {
+	// Contract is the contract whose verifyProof the numbers are for, or the
+	// test contract when forge only reported the gas of the test
+	Contract string `json:"contract"`
+	Calls    int    `json:"calls"`
+	Min      int64  `json:"min"`
+	Mean     int64  `json:"mean"`
+	Median   int64  `json:"median"`
+	Max      int64  `json:"max"`
+}
+
+// forgeGasReport is the output of `forge test --gas-report --json`: one entry
+// per deployed contract with the gas of each function called
+type forgeGasReport []struct {
+	Contract  string `json:"contract"`
+	Functions map[string]struct {
+		Calls  int   `json:"calls"`
+		Min    int64 `json:"min"`
+		Mean   int64 `json:"mean"`
+		Median int64 `json:"median"`
+		Max    int64 `json:"max"`
+	} `json:"functions"`
+}
+
+// forgeTestResults is the output of `forge test --json`, which is what forge
+// releases without JSON gas reports print for `--gas-report --json`
+type forgeTestResults map[string]struct {
+	TestResults map[string]struct {
+		Status string `json:"status"`
+		Kind   struct {
+			Unit *struct {
+				Gas int64 `json:"gas"`
+			} `json:"Unit"`
+		} `json:"kind"`
+	} `json:"test_results"`
+}
+
+// recordGas parses the forge JSON reports of scripts/benchmark-gas.sh, one
+// per test case named gas_report_N.json, and adds the verifier gas of each
+// test case to its verify measurement in the results file. Reports default to
+// those in the gas-reports directory of the output directory.
+func recordGas(reportFiles []string) {
+	if len(reportFiles) == 0 {
+		var err error
+		reportFiles, err = filepath.Glob(filepath.Join(outputDir, "gas-reports", "reports", "gas_report_*.json"))
+		if err != nil {
+			fatal("Failed to find gas reports", "error", err)
+		}
+		if len(reportFiles) == 0 {
+			fatal("No gas reports found", "dir", filepath.Join(outputDir, "gas-reports", "reports"))
+		}
+	}
+
+	gas := make(map[string]GasStats)
+	for _, file := range reportFiles {
+		match := regexp.MustCompile(`gas_report_(\d+)\.json`).FindStringSubmatch(filepath.Base(file))
+		if match == nil {
+			fatal("Invalid gas report filename format", "file", file)
+		}
+		stats, err := parseForgeGas(file, match[1])
+		if err != nil {
+			fatal("Invalid gas report", "file", file, "error", err)
+		}
+		gas[match[1]] = stats
+	}
+
+	path := filepath.Join(outputDir, "benchmarks", resultsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read results", "error", err)
+	}
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		fatal("Invalid results file", "file", path, "error", err)
+	}
+	if results.SchemaVersion != resultsSchemaVersion {
+		fatal("Unsupported results schema version", "file", path, "version", results.SchemaVersion, "supported", resultsSchemaVersion)
+	}
+
+	// Gas does not depend on the thread count, so every verify measurement of
+	// a test case gets it
+	var updated []Measurement
+	found := make(map[string]bool)
+	for i, m := range results.Measurements {
+		if stats, ok := gas[m.TestCase]; ok && m.Phase == "verify" {
+			results.Measurements[i].Gas = &stats
+			updated = append(updated, results.Measurements[i])
+			found[m.TestCase] = true
+		}
+	}
+
+	testCases := make([]string, 0, len(gas))
+	for testCase := range gas {
+		testCases = append(testCases, testCase)
+	}
+	sort.Slice(testCases, func(i, j int) bool {
+		a, _ := strconv.Atoi(testCases[i])
+		b, _ := strconv.Atoi(testCases[j])
+		return a < b
+	})
+	for _, testCase := range testCases {
+		if !found[testCase] {
+			slog.Warn("No verify measurement of the test case, run verify first", "test_case", testCase, "file", path)
+			continue
+		}
+		stats := gas[testCase]
+		fmt.Printf("Test case %s: %d gas (%s)\n", testCase, stats.Mean, stats.Contract)
+	}
+	if len(updated) == 0 {
+		fatal("No verify measurements to add the gas to")
+	}
+
+	data, err = json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		fatal("Failed to encode results", "error", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fatal("Failed to write results", "error", err)
+	}
+	appendHistory(results.Settings, results.Environment, time.Now().UTC().Format(time.RFC3339), updated)
+	slog.Info("Verifier gas saved", "file", path)
+}
+
+// parseForgeGas reads the verifier gas from a forge JSON report. A gas report
+// gives the verifyProof calls of the verifier contract itself, without the
+// test harness around them. Test results only give the gas of the whole
+// testVerifyProofN test.
+func parseForgeGas(file, testCaseNum string) (GasStats, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return GasStats{}, err
+	}
+
+	var report forgeGasReport
+	if err := json.Unmarshal(data, &report); err == nil {
+		var found *GasStats
+		for _, contract := range report {
+			for name, f := range contract.Functions {
+				if !strings.HasPrefix(name, "verifyProof(") {
+					continue
+				}
+				stats := GasStats{Contract: contract.Contract, Calls: f.Calls, Min: f.Min, Mean: f.Mean, Median: f.Median, Max: f.Max}
+				// The verifier is called through the GasTest wrapper, which
+				// reports it too; prefer the verifier
+				if found == nil || strings.HasSuffix(contract.Contract, ":Verifier") {
+					found = &stats
+				}
+			}
+		}
+		if found == nil {
+			return GasStats{}, fmt.Errorf("no verifyProof calls in the gas report")
+		}
+		return *found, nil
+	}
+
+	var tests forgeTestResults
+	if err := json.Unmarshal(data, &tests); err != nil {
+		return GasStats{}, fmt.Errorf("neither a forge gas report nor test results: %w", err)
+	}
+	testName := "testVerifyProof" + testCaseNum + "()"
+	for contract, suite := range tests {
+		result, ok := suite.TestResults[testName]
+		if !ok {
+			continue
+		}
+		if result.Status != "Success" {
+			return GasStats{}, fmt.Errorf("%s %s", testName, strings.ToLower(result.Status))
+		}
+		if result.Kind.Unit == nil {
+			return GasStats{}, fmt.Errorf("%s is not a unit test", testName)
+		}
+		g := result.Kind.Unit.Gas
+		return GasStats{Contract: contract, Calls: 1, Min: g, Mean: g, Median: g, Max: g}, nil
+	}
+	return GasStats{}, fmt.Errorf("no %s in the test results", testName)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/groth16"
+	icicle_bn254 "github.com/consensys/gnark/backend/groth16/bn254/icicle"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// gpuAvailable reports whether the binary was built with ICICLE support
+// (go build -tags icicle). Without it, gnark panics if GPU proving is requested.
+func gpuAvailable() bool {
+	return icicle_bn254.HasIcicle
+}
+
+// checkGPU says why -gpu cannot prove with the settings: ICICLE only proves
+// Groth16 over bn254, and only in a binary built with the icicle tag. The
+// commands stop on it rather than prove on the CPU and record that as a GPU
+// run.
+func checkGPU() error {
+	switch {
+	case provingBackend != backendGroth16:
+		return fmt.Errorf("-gpu proves groth16 only, not %s", provingBackend)
+	case selectedCurve() != ecc.BN254:
+		return fmt.Errorf("-gpu proves over bn254 only, not %s", curveName)
+	case !gpuAvailable():
+		return errors.New("-gpu needs a binary built with -tags icicle")
+	}
+	return nil
+}
+
+// proveGroth16 generates a Groth16 proof, using the ICICLE GPU backend when
+// requested, which checkGPU has allowed. If the GPU prover fails, as it does
+// without a CUDA device, it warns and falls back to the CPU prover. The
+// returned string names the hardware that produced the proof ("gpu" or
+// "cpu"), and the duration is the time a failed GPU attempt took before the
+// fallback, which is not part of the proof's time.
+func proveGroth16(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, fullWitness witness.Witness, gpu bool) (groth16.Proof, string, time.Duration, error) {
+	var failed time.Duration
+	if gpu {
+		start := time.Now()
+		proof, err := proveOnGPU(ccs, pk, fullWitness)
+		if err == nil {
+			return proof, "gpu", 0, nil
+		}
+		failed = time.Since(start)
+		slog.Warn("GPU proving failed, proving on the CPU", "error", err)
+	}
+
+	proof, err := groth16.Prove(ccs, pk, fullWitness, proverOptions()...)
+	if err != nil {
+		return nil, "cpu", failed, err
+	}
+	return proof, "cpu", failed, nil
+}
+
+// proveOnGPU runs the ICICLE prover, converting panics raised by the ICICLE
+// runtime (e.g. no CUDA device present) into errors.
+func proveOnGPU(ccs constraint.ConstraintSystem, pk groth16.ProvingKey, fullWitness witness.Witness) (proof groth16.Proof, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("icicle: %v", r)
+		}
+	}()
+
+	return groth16.Prove(ccs, pk, fullWitness, append(proverOptions(), backend.WithIcicleAcceleration())...)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// Hash-to-field functions used to derive the Pedersen commitment challenge.
+// The prover, the verifier and the Solidity verifier must all use the same one.
+const (
+	hashToFieldSHA256    = manifest.HashToFieldSHA256
+	hashToFieldKeccak256 = manifest.HashToFieldKeccak256
+	hashToFieldRFC9380   = manifest.HashToFieldRFC9380 // gnark's default; not supported by the Solidity verifier
+
+	defaultHashToField = hashToFieldSHA256
+)
+
+var (
+	// command line flags
+	hashToField        string
+	compareHashToField bool
+)
+
+func proverHashToFieldOption() backend.ProverOption {
+	h, err := manifest.NewHashToField(hashToField)
+	if err != nil {
+		fatal("Invalid -hash-to-field", "error", err)
+	}
+	if h == nil {
+		return func(*backend.ProverConfig) error { return nil }
+	}
+	return backend.WithProverHashToFieldFunction(h)
+}
+
+func verifierHashToFieldOption() backend.VerifierOption {
+	h, err := manifest.NewHashToField(hashToField)
+	if err != nil {
+		fatal("Invalid -hash-to-field", "error", err)
+	}
+	if h == nil {
+		return func(*backend.VerifierConfig) error { return nil }
+	}
+	return backend.WithVerifierHashToFieldFunction(h)
+}
+
+// HashToFieldStats is how proving and verification of one test case perform
+// with one hash-to-field function, and where its proofs verify
+type HashToFieldStats struct {
+	HashToField string     `json:"hash_to_field"`
+	TestCase    string     `json:"test_case"`
+	Prove       PhaseStats `json:"prove"`
+	Verify      PhaseStats `json:"verify"`
+	// VerifiesWithCompiled is whether its proofs pass a verifier using the
+	// function the artifacts were compiled with, such as the exported
+	// Solidity verifier
+	VerifiesWithCompiled bool `json:"verifies_with_compiled"`
+	// Solidity is whether a Solidity verifier can be exported for it, and if
+	// not, why
+	Solidity string `json:"solidity"`
+}
+
+// HashToFieldResults is the output of bench -compare-hash-to-field
+type HashToFieldResults struct {
+	Curve       string             `json:"curve"`
+	Backend     string             `json:"backend"`
+	RangeCheck  string             `json:"range_check"`
+	Compiled    string             `json:"compiled_hash_to_field"`
+	Commitments int                `json:"commitments"`
+	Runs        int                `json:"runs"`
+	Results     []HashToFieldStats `json:"results"`
+}
+
+// hashToFieldFunctions lists every supported hash-to-field function
+var hashToFieldFunctions = []string{hashToFieldSHA256, hashToFieldKeccak256, hashToFieldRFC9380}
+
+// benchHashToFields proves and verifies a test case -runs times with each
+// hash-to-field function in turn. The keys do not depend on the function, so
+// the same ones serve all of them. The last proof of each is also checked
+// against the function the artifacts were compiled with, to show which proofs
+// the exported verifier would reject.
+func benchHashToFields(ccs constraint.ConstraintSystem, pk, vk artifact, fullWitness, publicWitness witness.Witness, testCaseNum string) []HashToFieldStats {
+	compiled := hashToField
+	defer func() { hashToField = compiled }()
+
+	var results []HashToFieldStats
+	for _, name := range hashToFieldFunctions {
+		slog.Info("Benchmarking prove and verify", "test_case", testCaseNum, "hash_to_field", name, "runs", benchRuns)
+		hashToField = name
+		for run := 1; run <= benchWarmup; run++ {
+			if _, _, err := proveCircuit(ccs, pk, fullWitness); err != nil {
+				fatal("Failed to generate proof", "test_case", testCaseNum, "hash_to_field", name, "error", err)
+			}
+		}
+
+		var proof artifact
+		var proveTimes, verifyTimes []time.Duration
+		for run := 1; run <= benchRuns; run++ {
+			start := time.Now()
+			var hardware string
+			var err error
+			proof, hardware, err = proveCircuit(ccs, pk, fullWitness)
+			proveTimes = append(proveTimes, time.Since(start))
+			if err != nil {
+				fatal("Failed to generate proof", "test_case", testCaseNum, "hash_to_field", name, "run", run, "error", err)
+			}
+			requireProver(hardware, "test_case", testCaseNum, "hash_to_field", name, "run", run)
+
+			start = time.Now()
+			err = verifyCircuit(proof, vk, publicWitness)
+			verifyTimes = append(verifyTimes, time.Since(start))
+			if err != nil {
+				fatal("Proof verification failed", "test_case", testCaseNum, "hash_to_field", name, "run", run, "error", err)
+			}
+			slog.Info("Proved and verified", "test_case", testCaseNum, "hash_to_field", name, "run", run, "prove_secs", proveTimes[run-1].Seconds(), "verify_secs", verifyTimes[run-1].Seconds())
+		}
+
+		hashToField = compiled
+		results = append(results, HashToFieldStats{
+			HashToField:          name,
+			TestCase:             testCaseNum,
+			Prove:                benchSummarize("prove", testCaseNum, proveTimes),
+			Verify:               benchSummarize("verify", testCaseNum, verifyTimes),
+			VerifiesWithCompiled: verifyCircuit(proof, vk, publicWitness) == nil,
+			Solidity:             solidityHashToField(name),
+		})
+	}
+	return results
+}
+
+// solidityHashToField reports whether a Solidity verifier can be exported for
+// the hash-to-field function with the current backend and curve, and if not,
+// why
+func solidityHashToField(name string) string {
+	switch {
+	case provingBackend != backendGroth16:
+		return "no (groth16 only)"
+	case curveName != "bn254" && curveName != "bls12-381":
+		return "no (bn254 and bls12-381 only)"
+	case name == hashToFieldRFC9380:
+		return "no"
+	}
+	return "yes"
+}
+
+// writeHashToFieldComparison prints the hash-to-field comparison of bench and
+// saves it to benchmarks/hash_to_field.json
+func writeHashToFieldComparison(ccs constraint.ConstraintSystem, compiled string, results []HashToFieldStats) {
+	comparison := HashToFieldResults{
+		Curve:       curveName,
+		Backend:     provingBackend,
+		RangeCheck:  rangeCheck,
+		Compiled:    compiled,
+		Commitments: len(ccs.GetCommitments().CommitmentIndexes()),
+		Runs:        benchRuns,
+		Results:     results,
+	}
+
+	// Compare each function with the compiled one on the same test case
+	compiledMeans := make(map[string]float64)
+	for _, r := range results {
+		if r.HashToField == compiled {
+			compiledMeans[r.TestCase] = r.Prove.MeanSecs
+		}
+	}
+	fmt.Println()
+	fmt.Printf("%-12s %-10s %10s %10s %10s %-10s %s\n", "Hash", "Test case", "Prove", "Verify", "vs "+compiled, "Verifies", "Solidity")
+	for _, r := range results {
+		name := r.HashToField
+		if name == compiled {
+			name += " *"
+		}
+		change := (r.Prove.MeanSecs/compiledMeans[r.TestCase] - 1) * 100
+		verifies := "no"
+		if r.VerifiesWithCompiled {
+			verifies = "yes"
+		}
+		fmt.Printf("%-12s %-10s %10s %10s %+9.1f%% %-10s %s\n", name, r.TestCase, formatSecs(r.Prove.MeanSecs), formatSecs(r.Verify.MeanSecs), change, verifies, r.Solidity)
+	}
+	fmt.Printf("* compiled with. \"Verifies\" is whether the proofs pass the verifier of the artifacts, and of the Solidity verifier exported from them.\n")
+	if comparison.Commitments == 0 {
+		fmt.Println("The circuit has no commitments, so the hash-to-field function is never used.")
+	}
+
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	data, err := json.MarshalIndent(comparison, "", "  ")
+	if err != nil {
+		fatal("Failed to encode hash-to-field comparison", "error", err)
+	}
+	path := filepath.Join(resultsDir, "hash_to_field.json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fatal("Failed to write hash-to-field comparison", "file", path, "error", err)
+	}
+	slog.Info("Hash-to-field comparison saved", "file", path)
+}
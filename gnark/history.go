@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyFile collects every recorded measurement in the output directory,
+// one JSON entry per line. Unlike the results file it is never cleared.
+const historyFile = "history.jsonl"
+
+var (
+	// command line flags
+	historyPath  string
+	historyPhase string
+)
+
+// HistoryEntry is one batch of measurements appended to the history, with
+// what is needed to tell apart the code and machine that produced it
+type HistoryEntry struct {
+	RecordedAt string `json:"recorded_at"`
+	GitCommit  string `json:"git_commit,omitempty"`
+	GitDirty   bool   `json:"git_dirty,omitempty"`
+	// GnarkVersion is the gnark release of the binary that measured, which
+	// may differ from the one the artifacts were compiled with (settings)
+	GnarkVersion string        `json:"gnark_version"`
+	Machine      Machine       `json:"machine"`
+	Settings     Manifest      `json:"settings"`
+	Environment  Environment   `json:"environment"`
+	Measurements []Measurement `json:"measurements"`
+}
+
+// Machine identifies the hardware measurements ran on. The fingerprint hashes
+// the other fields, so runs on the same kind of machine compare with each
+// other even when their host names differ, as in containers.
+type Machine struct {
+	Fingerprint string `json:"fingerprint"`
+	CPUModel    string `json:"cpu_model,omitempty"`
+	CPUs        int    `json:"cpus"`
+	MemoryBytes uint64 `json:"memory_bytes,omitempty"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+}
+
+// appendHistory adds measurements to the history file
+func appendHistory(settings Manifest, environment Environment, recordedAt string, measurements []Measurement) {
+	path := resolveHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fatal("Failed to create history directory", "dir", filepath.Dir(path), "error", err)
+	}
+
+	commit, dirty := gitCommit()
+	data, err := json.Marshal(HistoryEntry{
+		RecordedAt:   recordedAt,
+		GitCommit:    commit,
+		GitDirty:     dirty,
+		GnarkVersion: gnarkVersion(),
+		Machine:      currentMachine(),
+		Settings:     settings,
+		Environment:  environment,
+		Measurements: measurements,
+	})
+	if err != nil {
+		fatal("Failed to encode history entry", "error", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		fatal("Failed to open history", "file", path, "error", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		fatal("Failed to write history", "file", path, "error", err)
+	}
+}
+
+// resolveHistoryPath is -history, or the history file of the output directory
+func resolveHistoryPath() string {
+	if historyPath != "" {
+		return historyPath
+	}
+	return filepath.Join(outputDir, "benchmarks", historyFile)
+}
+
+// loadHistory reads every entry of a history file, oldest first
+func loadHistory(path string) []HistoryEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read history", "error", err)
+	}
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			fatal("Invalid history entry", "file", path, "line", line, "error", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		fatal("Failed to read history", "error", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].RecordedAt < entries[j].RecordedAt })
+	return entries
+}
+
+// gitCommit returns the commit the binary was built from, falling back to the
+// checkout in the working directory for `go run`, and whether it had
+// uncommitted changes. Outside a git checkout, such as in Docker, it is empty.
+func gitCommit() (string, bool) {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision string
+		var dirty bool
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				dirty = setting.Value == "true"
+			}
+		}
+		if revision != "" {
+			return revision, dirty
+		}
+	}
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false
+	}
+	status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output()
+	return strings.TrimSpace(string(out)), err == nil && len(bytes.TrimSpace(status)) > 0
+}
+
+// currentMachine describes the machine this process runs on
+func currentMachine() Machine {
+	machine := Machine{
+		CPUModel:    cpuModel(),
+		CPUs:        runtime.NumCPU(),
+		MemoryBytes: totalMemory(),
+		OS:          runtime.GOOS,
+		Arch:        runtime.GOARCH,
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%s|%s", machine.CPUModel, machine.CPUs, machine.MemoryBytes, machine.OS, machine.Arch)))
+	machine.Fingerprint = hex.EncodeToString(sum[:6])
+	return machine
+}
+
+// cpuModel names the processor, from /proc/cpuinfo on Linux or sysctl on macOS
+func cpuModel() string {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/cpuinfo")
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			// x86 reports "model name", some ARM kernels only "Hardware"
+			if key, value, ok := strings.Cut(line, ":"); ok {
+				key = strings.TrimSpace(key)
+				if key == "model name" || key == "Hardware" {
+					return strings.TrimSpace(value)
+				}
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out))
+		}
+	}
+	return ""
+}
+
+// totalMemory is the physical memory in bytes, or 0 when unknown
+func totalMemory() uint64 {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/meminfo")
+		if err != nil {
+			return 0
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "MemTotal:" {
+				kib, _ := strconv.ParseUint(fields[1], 10, 64)
+				return kib * 1024
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+		if err == nil {
+			n, _ := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+// historyPoint is one measurement of a phase from the history
+type historyPoint struct {
+	entry HistoryEntry
+	m     Measurement
+}
+
+// showHistory compares a phase over time: for each configuration, machine and
+// test case it lists every measurement with its commit, its change from the
+// one before and from the first, and a bar to plot the trend. With -html it
+// renders the history as the report dashboard instead.
+func showHistory() {
+	entries := loadHistory(resolveHistoryPath())
+	if len(entries) == 0 {
+		fatal("The history is empty")
+	}
+
+	if reportHTML {
+		printDashboard(historySections(entries))
+		return
+	}
+
+	groups := make(map[string][]historyPoint)
+	var keys []string
+	for _, e := range entries {
+		for _, m := range e.Measurements {
+			if m.Phase != historyPhase || !m.fullCPU() {
+				continue
+			}
+			title := reportSection{results: Results{Stack: "gnark", Settings: e.Settings}}.title()
+			key := fmt.Sprintf("%s on %s (%s)", title, e.Machine.Fingerprint, e.Machine.CPUModel)
+			if m.TestCase != "" {
+				key += ", test case " + m.TestCase
+			}
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], historyPoint{entry: e, m: m})
+		}
+	}
+	if len(keys) == 0 {
+		fatal("No measurements of the phase in the history", "phase", historyPhase)
+	}
+
+	const plotWidth = 30
+	for _, key := range keys {
+		points := groups[key]
+		var largest float64
+		for _, p := range points {
+			largest = max(largest, p.m.MeanSecs)
+		}
+
+		fmt.Printf("%s: %s\n", historyPhase, key)
+		fmt.Printf("%-20s %-12s %-8s %10s %9s %9s  %s\n", "Recorded", "Commit", "gnark", "Mean", "Δ prev", "Δ first", "")
+		for i, p := range points {
+			commit := "-"
+			if p.entry.GitCommit != "" {
+				commit = p.entry.GitCommit[:min(10, len(p.entry.GitCommit))]
+				if p.entry.GitDirty {
+					commit += "+"
+				}
+			}
+			prev, first := "-", "-"
+			if i > 0 {
+				prev = formatChange(points[i-1].m.MeanSecs, p.m.MeanSecs)
+				first = formatChange(points[0].m.MeanSecs, p.m.MeanSecs)
+			}
+			bar := ""
+			if largest > 0 {
+				bar = strings.Repeat("█", int(p.m.MeanSecs/largest*plotWidth+0.5))
+			}
+			recorded := p.entry.RecordedAt
+			if at, err := time.Parse(time.RFC3339, recorded); err == nil {
+				recorded = at.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%-20s %-12s %-8s %10s %9s %9s  %s\n", recorded, commit, p.entry.GnarkVersion,
+				formatSecs(p.m.MeanSecs), prev, first, bar)
+		}
+		fmt.Println()
+	}
+}
+
+// historySections replays the history into the results file of each
+// configuration as it stood after every entry. Consecutive snapshots whose
+// proofs were recorded at the same time are merged into the later one, so the
+// proving time trend has one point per proving run.
+func historySections(entries []HistoryEntry) []reportSection {
+	var sections []reportSection
+	current := make(map[Manifest][]Measurement)
+	latest := make(map[Manifest]int)
+	for _, e := range entries {
+		current[e.Settings] = upsertMeasurements(current[e.Settings], e.Measurements)
+		section := reportSection{results: Results{
+			SchemaVersion: resultsSchemaVersion,
+			Stack:         "gnark",
+			Settings:      e.Settings,
+			Environment:   e.Environment,
+			Measurements:  append([]Measurement(nil), current[e.Settings]...),
+		}}
+		section.gas = verifierGas(section.results, "")
+		if i, ok := latest[e.Settings]; ok && sections[i].recordedAt().Equal(section.recordedAt()) {
+			sections[i] = section
+			continue
+		}
+		latest[e.Settings] = len(sections)
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// formatChange renders the relative change from before to after
+func formatChange(before, after float64) string {
+	if before == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+}
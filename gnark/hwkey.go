@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Platforms of hardware-backed keys
+const (
+	platformSecureEnclave   = "secure-enclave"
+	platformAndroidKeystore = "android-keystore"
+)
+
+// androidKeyAttestationOID is the extension of an Android attestation
+// certificate that describes the key
+var androidKeyAttestationOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 1, 17}
+
+// Android attestation security levels
+var androidSecurityLevels = map[int]string{0: "software", 1: "tee", 2: "strongbox"}
+
+// HardwareKeyData records the hardware-backed key a captured signature came
+// from, with the attestation that vouches for it
+type HardwareKeyData struct {
+	Platform      string `json:"platform"`
+	SecurityLevel string `json:"security_level,omitempty"`
+	// Attestation is the payload as captured, base64: the certificate chain
+	// of an Android key, or the App Attest object of a Secure Enclave key
+	Attestation []string `json:"attestation,omitempty"`
+}
+
+// hardwareCapture is the capture format the companion apps export, with
+// binary fields in base64
+type hardwareCapture struct {
+	Platform  string `json:"platform"`
+	PublicKey string `json:"public_key"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	// Attestation is the certificate chain of an Android key, leaf first, or
+	// a single App Attest attestation object of a Secure Enclave key
+	Attestation []string `json:"attestation"`
+}
+
+// importHardwareKey turns signatures captured from Apple Secure Enclave or
+// Android Keystore (TEE or StrongBox) P-256 keys into test cases in -tests,
+// named hwkey_<file>.json. Both platforms sign the SHA-256 of the message
+// and return a DER signature, which is kept as it is, with s not normalized.
+// The public key is X9.63 (as SecKeyCopyExternalRepresentation exports it)
+// or PKIX DER (as Android's getEncoded does). When an attestation is given,
+// its leaf certificate must hold the signing key and each certificate of the
+// chain must be signed by the next; the Android security level is read from
+// the key description. The chain is not checked against the Apple or Google
+// roots, so it records where a key claims to live rather than proves it.
+func importHardwareKey(files []string) {
+	if len(files) == 0 {
+		fatal("Missing capture file for hwkey command")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fatal("Failed to read capture", "error", err)
+		}
+		var capture hardwareCapture
+		if err := json.Unmarshal(data, &capture); err != nil {
+			fatal("Failed to decode capture", "file", file, "error", err)
+		}
+		fail := func(format string, args ...any) {
+			fatal(fmt.Sprintf(format, args...), "file", file)
+		}
+		if capture.Platform != platformSecureEnclave && capture.Platform != platformAndroidKeystore {
+			fail("platform is %q, not %s or %s", capture.Platform, platformSecureEnclave, platformAndroidKeystore)
+		}
+
+		keyBytes, err := base64.StdEncoding.DecodeString(capture.PublicKey)
+		if err != nil {
+			fail("invalid public_key: %v", err)
+		}
+		pub, err := parseHardwarePublicKey(keyBytes)
+		if err != nil {
+			fail("%v", err)
+		}
+		message, err := base64.StdEncoding.DecodeString(capture.Message)
+		if err != nil {
+			fail("invalid message: %v", err)
+		}
+		sig, err := base64.StdEncoding.DecodeString(capture.Signature)
+		if err != nil {
+			fail("invalid signature: %v", err)
+		}
+		var parsed struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &parsed); err != nil || len(rest) > 0 {
+			fail("signature is not a DER ECDSA signature")
+		}
+		hash := sha256.Sum256(message)
+		if !ecdsa.Verify(pub, hash[:], parsed.R, parsed.S) {
+			fail("the signature does not verify for the public key")
+		}
+
+		hardware := &HardwareKeyData{Platform: capture.Platform, Attestation: capture.Attestation}
+		if len(capture.Attestation) > 0 {
+			level, err := checkAttestation(capture.Platform, capture.Attestation, pub)
+			if err != nil {
+				fail("attestation: %v", err)
+			}
+			hardware.SecurityLevel = level
+		}
+
+		valid := true
+		testCase := &TestCase{
+			R:           fmt.Sprintf("0x%x", parsed.R),
+			S:           fmt.Sprintf("0x%x", parsed.S),
+			MsgHash:     fmt.Sprintf("0x%x", new(big.Int).SetBytes(hash[:])),
+			PubKeyX:     fmt.Sprintf("0x%x", pub.X),
+			PubKeyY:     fmt.Sprintf("0x%x", pub.Y),
+			ExpectValid: &valid,
+			Source:      fmt.Sprintf("hwkey capture %s", filepath.Base(file)),
+			Message:     fmt.Sprintf("0x%x", message),
+			Hardware:    hardware,
+			Tags:        []string{tagHardware, capture.Platform},
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		path := filepath.Join(testsDir, "hwkey_"+name+".json")
+		writeTestCase(path, testCase)
+		if hardware.SecurityLevel != "" {
+			slog.Info("Test case written", "file", path, "platform", capture.Platform, "security_level", hardware.SecurityLevel)
+		} else {
+			slog.Info("Test case written", "file", path, "platform", capture.Platform)
+		}
+	}
+}
+
+// parseHardwarePublicKey decodes a P-256 public key in X9.63 uncompressed
+// form or PKIX DER
+func parseHardwarePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	if len(data) == 65 && data[0] == 4 {
+		x, y := elliptic.Unmarshal(elliptic.P256(), data)
+		if x == nil {
+			return nil, fmt.Errorf("public key is not a point of P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("public key is neither X9.63 nor PKIX: %v", err)
+	}
+	pub, ok := parsed.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("public key is not a P-256 key")
+	}
+	return pub, nil
+}
+
+// checkAttestation checks that an attestation vouches for the signing key,
+// and returns the security level it claims, when the platform says one
+func checkAttestation(platform string, attestation []string, pub *ecdsa.PublicKey) (string, error) {
+	var chain []*x509.Certificate
+	switch platform {
+	case platformAndroidKeystore:
+		for i, encoded := range attestation {
+			der, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", fmt.Errorf("certificate %d: %v", i, err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return "", fmt.Errorf("certificate %d: %v", i, err)
+			}
+			chain = append(chain, cert)
+		}
+	case platformSecureEnclave:
+		if len(attestation) != 1 {
+			return "", fmt.Errorf("expected one App Attest object, got %d", len(attestation))
+		}
+		object, err := base64.StdEncoding.DecodeString(attestation[0])
+		if err != nil {
+			return "", err
+		}
+		var attest struct {
+			Fmt     string `cbor:"fmt"`
+			AttStmt struct {
+				X5C [][]byte `cbor:"x5c"`
+			} `cbor:"attStmt"`
+		}
+		if err := cbor.Unmarshal(object, &attest); err != nil {
+			return "", fmt.Errorf("not an App Attest object: %v", err)
+		}
+		if attest.Fmt != "apple-appattest" {
+			return "", fmt.Errorf("format is %q, not apple-appattest", attest.Fmt)
+		}
+		for i, der := range attest.AttStmt.X5C {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return "", fmt.Errorf("certificate %d: %v", i, err)
+			}
+			chain = append(chain, cert)
+		}
+	}
+	if len(chain) == 0 {
+		return "", fmt.Errorf("no certificates")
+	}
+
+	leaf, ok := chain[0].PublicKey.(*ecdsa.PublicKey)
+	if !ok || !leaf.Equal(pub) {
+		return "", fmt.Errorf("the leaf certificate is not for the signing key")
+	}
+	for i := 0; i+1 < len(chain); i++ {
+		if err := chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return "", fmt.Errorf("certificate %d is not signed by certificate %d: %v", i, i+1, err)
+		}
+	}
+
+	if platform != platformAndroidKeystore {
+		return "", nil
+	}
+	for _, ext := range chain[0].Extensions {
+		if !ext.Id.Equal(androidKeyAttestationOID) {
+			continue
+		}
+		// KeyDescription is a sequence that starts with attestationVersion
+		// and attestationSecurityLevel
+		var description asn1.RawValue
+		var version int
+		var level asn1.Enumerated
+		rest, err := asn1.Unmarshal(ext.Value, &description)
+		if err == nil {
+			rest, err = asn1.Unmarshal(description.Bytes, &version)
+		}
+		if err == nil {
+			_, err = asn1.Unmarshal(rest, &level)
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid key description: %v", err)
+		}
+		if name, ok := androidSecurityLevels[int(level)]; ok {
+			return name, nil
+		}
+		return "", fmt.Errorf("unknown security level %d", level)
+	}
+	return "", fmt.Errorf("the leaf certificate has no key description")
+}
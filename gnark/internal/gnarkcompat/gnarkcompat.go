@@ -0,0 +1,24 @@
+//go:build !gnark_v0_11
+
+// Package gnarkcompat reaches the gnark and gnark-crypto APIs that differ
+// between the gnark releases the benchmark builds against. This file is for
+// gnark v0.12; gnarkcompat_v0_11.go has the same functions for gnark v0.11,
+// built with -tags gnark_v0_11, as scripts/compare-versions.sh does.
+package gnarkcompat
+
+import (
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	pedersen_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/pedersen"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254"
+	pedersen_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/pedersen"
+)
+
+// PedersenGSigmaNegBN254 returns G^{-σ} of a bn254 Pedersen verifying key
+func PedersenGSigmaNegBN254(vk *pedersen_bn254.VerifyingKey) bn254.G2Affine {
+	return vk.GSigmaNeg
+}
+
+// PedersenGSigmaNegBLS12381 returns G^{-σ} of a bls12-381 Pedersen verifying key
+func PedersenGSigmaNegBLS12381(vk *pedersen_bls12381.VerifyingKey) bls12381.G2Affine {
+	return vk.GSigmaNeg
+}
@@ -0,0 +1,23 @@
+//go:build gnark_v0_11
+
+package gnarkcompat
+
+import (
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	pedersen_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/fr/pedersen"
+	bn254 "github.com/consensys/gnark-crypto/ecc/bn254"
+	pedersen_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/fr/pedersen"
+)
+
+// gnark v0.11 uses gnark-crypto v0.14, which names G^{-σ} of a Pedersen
+// verifying key GSigma
+
+// PedersenGSigmaNegBN254 returns G^{-σ} of a bn254 Pedersen verifying key
+func PedersenGSigmaNegBN254(vk *pedersen_bn254.VerifyingKey) bn254.G2Affine {
+	return vk.GSigma
+}
+
+// PedersenGSigmaNegBLS12381 returns G^{-σ} of a bls12-381 Pedersen verifying key
+func PedersenGSigmaNegBLS12381(vk *pedersen_bls12381.VerifyingKey) bls12381.G2Affine {
+	return vk.GSigma
+}
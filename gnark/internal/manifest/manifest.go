@@ -0,0 +1,107 @@
+// Package manifest reads and writes manifest.json, the record compile keeps of
+// the settings a circuit and its keys were produced with and of their SHA-256.
+// The benchmark and the tools in cmd share it, so that every tool working on
+// the artifacts honours the same settings.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// FileName is the name of the manifest in an artifacts directory
+const FileName = "manifest.json"
+
+// Hash-to-field functions used to derive the Pedersen commitment challenge.
+// The prover, the verifier and the Solidity verifier must all use the same one.
+const (
+	HashToFieldSHA256    = "sha256"
+	HashToFieldKeccak256 = "keccak256"
+	HashToFieldRFC9380   = "rfc9380" // gnark's default; not supported by the Solidity verifier
+)
+
+// Manifest describes how the compiled circuit and keys were produced
+type Manifest struct {
+	Circuit       string `json:"circuit,omitempty"`
+	Curve         string `json:"curve"`
+	HashToField   string `json:"hash_to_field"`
+	Backend       string `json:"backend,omitempty"`
+	RangeCheck    string `json:"range_check,omitempty"`
+	ChallengeHash string `json:"challenge_hash,omitempty"`
+	SRS           string `json:"srs,omitempty"`
+	SRSHash       string `json:"srs_sha256,omitempty"`
+	GnarkVersion  string `json:"gnark_version,omitempty"`
+	EntropyHash   string `json:"entropy_sha256,omitempty"`
+}
+
+// Contents is what the manifest file holds: the settings, and the SHA-256 of
+// the circuit and key files, which are kept out of Manifest as results are
+// compared by their settings, not by compile run
+type Contents struct {
+	Manifest
+	Artifacts map[string]string `json:"artifacts_sha256,omitempty"`
+}
+
+// Read reads the manifest in dir. It returns nil without error if the
+// artifacts predate manifests.
+func Read(dir string) (*Contents, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var contents Contents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", FileName, err)
+	}
+	return &contents, nil
+}
+
+// Write writes the manifest into dir
+func Write(dir string, contents Contents) error {
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, FileName), append(data, '\n'), 0644)
+}
+
+// HashFile returns the hex SHA-256 of a file
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewHashToField returns the hash for the named hash-to-field function. A nil
+// hash selects gnark's built-in RFC 9380 hash-to-field.
+func NewHashToField(name string) (hash.Hash, error) {
+	switch name {
+	case HashToFieldSHA256:
+		return sha256.New(), nil
+	case HashToFieldKeccak256:
+		return sha3.NewLegacyKeccak256(), nil
+	case HashToFieldRFC9380:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown hash-to-field function %q (use %s, %s or %s)", name, HashToFieldSHA256, HashToFieldKeccak256, HashToFieldRFC9380)
+	}
+}
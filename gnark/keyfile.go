@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// command line flags
+	keyFile       string
+	signatureFile string
+)
+
+// testCaseFromKey writes a test case for a message file signed with a P-256
+// key from -key, as tests/key_<message>.json. The key is a PEM private key,
+// SEC 1 ("EC PRIVATE KEY") or PKCS#8 ("PRIVATE KEY"), which signs the
+// SHA-256 of the message with s normalized like the generated test cases.
+// Or it is a public key, PEM or DER in PKIX form, and -signature the DER
+// signature it made over the message, which is kept as it is. A signature
+// that does not verify is still written, with expect_valid false, so keys
+// from elsewhere can feed the negative command too. The private key never
+// leaves the signing step; only the public key is written.
+func testCaseFromKey(messageFile string) {
+	if keyFile == "" {
+		fatal("-key is required for from-key")
+	}
+	message, err := os.ReadFile(messageFile)
+	if err != nil {
+		fatal("Failed to read message", "error", err)
+	}
+	hash := sha256.Sum256(message)
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		fatal("Failed to read key", "error", err)
+	}
+	der, kind := data, ""
+	if block, _ := pem.Decode(data); block != nil {
+		der, kind = block.Bytes, block.Type
+		if _, encrypted := block.Headers["Proc-Type"]; encrypted {
+			fatal("Encrypted PEM keys are not supported, decrypt the key first")
+		}
+	}
+
+	var testCase *TestCase
+	if priv := parsePrivateKey(der); priv != nil {
+		if signatureFile != "" {
+			fatal("-signature is for a public key; a private key signs the message itself")
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, priv, hash[:])
+		if err != nil {
+			fatal("Failed to sign", "error", err)
+		}
+		testCase = newTestCase(&priv.PublicKey, hash[:], r, s)
+		testCase.Nonce = nonceRandom
+		slog.Info("Signed the message with the private key", "message", filepath.Base(messageFile), "key", keyFile)
+	} else {
+		parsed, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			fatal("Key file holds neither an EC private key nor a public key", "file", keyFile, "pem_type", kind, "error", err)
+		}
+		pub, ok := parsed.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			fatal("Not a P-256 public key", "file", keyFile)
+		}
+		if signatureFile == "" {
+			fatal("-signature is required with a public key")
+		}
+		sig, err := os.ReadFile(signatureFile)
+		if err != nil {
+			fatal("Failed to read signature", "error", err)
+		}
+		if block, _ := pem.Decode(sig); block != nil {
+			sig = block.Bytes
+		}
+		var rs struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+			fatal("Not a DER ECDSA signature", "file", signatureFile)
+		}
+		valid := ecdsa.Verify(pub, hash[:], rs.R, rs.S)
+		testCase = &TestCase{
+			R:           fmt.Sprintf("0x%x", rs.R),
+			S:           fmt.Sprintf("0x%x", rs.S),
+			MsgHash:     fmt.Sprintf("0x%x", new(big.Int).SetBytes(hash[:])),
+			PubKeyX:     fmt.Sprintf("0x%x", pub.X),
+			PubKeyY:     fmt.Sprintf("0x%x", pub.Y),
+			ExpectValid: &valid,
+		}
+		if valid {
+			fmt.Printf("✓ Signature in %s verifies for %s\n", signatureFile, filepath.Base(messageFile))
+		} else {
+			fmt.Printf("✗ Signature in %s does not verify for %s, writing it with expect_valid false\n", signatureFile, filepath.Base(messageFile))
+		}
+	}
+	testCase.Source = fmt.Sprintf("from-key %s over %s", filepath.Base(keyFile), filepath.Base(messageFile))
+
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(messageFile), filepath.Ext(messageFile))
+	path := filepath.Join(testsDir, "key_"+name+".json")
+	writeTestCase(path, testCase)
+	slog.Info("Test case written", "file", path)
+}
+
+// parsePrivateKey decodes a SEC 1 or PKCS#8 P-256 private key, or returns nil
+// when der is not a private key
+func parsePrivateKey(der []byte) *ecdsa.PrivateKey {
+	priv, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil
+		}
+		var ok bool
+		if priv, ok = key.(*ecdsa.PrivateKey); !ok {
+			fatal("Not an EC private key", "file", keyFile, "type", fmt.Sprintf("%T", key))
+		}
+	}
+	if priv.Curve != elliptic.P256() {
+		fatal("Not a P-256 key", "file", keyFile, "curve", priv.Curve.Params().Name)
+	}
+	return priv
+}
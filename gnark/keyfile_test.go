@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTestCaseFromKey writes a key in each PEM and DER form from-key reads,
+// and checks that the test case it writes holds the key and a signature that
+// verifies over the message, or is expected not to when it was made over
+// another message
+func TestTestCaseFromKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("from-key")
+	hash := sha256.Sum256(message)
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := sha256.Sum256([]byte("another message"))
+	r, s, err = ecdsa.Sign(rand.Reader, key, other[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSignature, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBlock := func(kind string, der []byte) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der})
+	}
+
+	tests := []struct {
+		name      string
+		key       []byte
+		signature []byte
+		valid     bool
+	}{
+		{"SEC 1 PEM", pemBlock("EC PRIVATE KEY", sec1), nil, true},
+		{"PKCS#8 PEM", pemBlock("PRIVATE KEY", pkcs8), nil, true},
+		{"PKCS#8 DER", pkcs8, nil, true},
+		{"PKIX PEM with DER signature", pemBlock("PUBLIC KEY", pkix), signature, true},
+		{"PKIX DER with PEM signature", pkix, pemBlock("SIGNATURE", signature), true},
+		{"signature of another message", pemBlock("PUBLIC KEY", pkix), otherSignature, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			defer func(tests, key, signature string) { testsDir, keyFile, signatureFile = tests, key, signature }(testsDir, keyFile, signatureFile)
+			testsDir, keyFile, signatureFile = dir, filepath.Join(dir, "key"), ""
+			messageFile := filepath.Join(dir, "message.txt")
+			if err := os.WriteFile(messageFile, message, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(keyFile, tt.key, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if tt.signature != nil {
+				signatureFile = filepath.Join(dir, "signature")
+				if err := os.WriteFile(signatureFile, tt.signature, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			testCaseFromKey(messageFile)
+			tc, err := loadTestCase(filepath.Join(dir, "key_message.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := fmt.Sprintf("0x%x", key.X); tc.PubKeyX != want {
+				t.Errorf("pubkey_x = %s, want %s", tc.PubKeyX, want)
+			}
+			if want := fmt.Sprintf("0x%x", key.Y); tc.PubKeyY != want {
+				t.Errorf("pubkey_y = %s, want %s", tc.PubKeyY, want)
+			}
+			values, err := tc.values()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ecdsa.Verify(&key.PublicKey, hash[:], values[0], values[1]) != tt.valid {
+				t.Errorf("the signature of the test case verifies: %v, want %v", !tt.valid, tt.valid)
+			}
+			if valid, _ := tc.expectation(); valid != tt.valid {
+				t.Errorf("the test case expects a valid signature: %v, want %v", valid, tt.valid)
+			}
+		})
+	}
+}
+
+// TestParsePrivateKey checks that what is not a private key, including a
+// public key, is left to be parsed as one
+func TestParsePrivateKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec1, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		der  []byte
+	}{
+		{"public key", pkix},
+		{"PEM text", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1})},
+		{"truncated", sec1[:len(sec1)/2]},
+		{"empty", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if priv := parsePrivateKey(tt.der); priv != nil {
+				t.Error("parsePrivateKey returned a key, want nil")
+			}
+		})
+	}
+	if priv := parsePrivateKey(sec1); priv == nil || !priv.Equal(key) {
+		t.Error("parsePrivateKey does not give back the SEC 1 key")
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+)
+
+// saturationShare is the share of the offered arrival rate the provers must
+// complete for the rate to count as sustained
+const saturationShare = 0.95
+
+var (
+	// command line flags
+	arrivalRates string
+	loadDuration time.Duration
+)
+
+// loadTestRun is the outcome of offering proving jobs at one arrival rate
+type loadTestRun struct {
+	// latencies has one entry per job proven, and arrived counts the jobs
+	// offered; the run ends once every job is proven
+	latencies []time.Duration
+	arrived   int
+	elapsed   time.Duration
+	maxQueued int
+	cpu       cpuClasses
+	peakHeap  uint64
+}
+
+// runLoadTest models a multi-tenant proving service under load. For each of
+// the -rates, proving jobs arrive for -duration as a Poisson process at that
+// many per second and queue for -workers provers sharing the circuit and
+// proving key. The latency of a job runs from its arrival to its proof, so it
+// includes the time spent queued. Every rate reports its p50, p95 and p99
+// latency and the proofs per second completed. A rate is sustained while the
+// provers complete at least 95% of it; the first rate they fall behind is the
+// saturation point, past which the queue and latencies grow without bound.
+// The completed rate counts until the last proof, so -duration should be many
+// proofs long. Arrivals are seeded, so every run offers the same jobs at the
+// same times.
+func runLoadTest(testCaseFiles []string) {
+	resolveSettings()
+	startMetricsServer()
+	rates, err := parseArrivalRates(arrivalRates)
+	if err != nil {
+		fatal("Invalid -rates", "error", err)
+	}
+	if loadDuration <= 0 {
+		fatal("-duration must be positive")
+	}
+	if throughputWorkers == 0 {
+		throughputWorkers = runtime.NumCPU()
+	}
+	if throughputWorkers < 1 {
+		fatal("-workers must be at least 1")
+	}
+	if len(testCaseFiles) == 0 {
+		testCaseFiles, err = filepath.Glob(filepath.Join(testsDir, selectedCircuit().TestCases))
+		if err != nil {
+			fatal("Failed to find test cases", "error", err)
+		}
+		if len(testCaseFiles) == 0 {
+			fatal("No test cases found", "dir", testsDir)
+		}
+	}
+
+	ccs := newConstraintSystem()
+	loadArtifact(filepath.Join(outputDir, circuitFileName()), ccs)
+	pk := newProvingKey()
+	loadArtifact(filepath.Join(outputDir, "proving.key"), pk)
+
+	witnesses := make([]witness.Witness, len(testCaseFiles))
+	for i, testCaseFile := range testCaseFiles {
+		testCase, err := loadTestCase(testCaseFile)
+		if err != nil {
+			fatal("Failed to load test case", "error", err)
+		}
+		witnesses[i], err = createWitness(testCase)
+		if err != nil {
+			fatal("Failed to create witness", "error", err)
+		}
+	}
+
+	slog.Info("Offering proving jobs", "duration", loadDuration, "rates", arrivalRates, "workers", throughputWorkers)
+	fmt.Printf("\n%10s %8s %10s %10s %10s %10s %10s %6s\n", "Offered/s", "Proofs", "Done/s", "p50", "p95", "p99", "Max queue", "CPU")
+	var results []Measurement
+	saturation := 0.0
+	for _, rate := range rates {
+		run := offerLoad(ccs, pk, witnesses, rate)
+		stats := summarize("prove_load", "", run.latencies)
+		secs := make([]float64, len(run.latencies))
+		for i, l := range run.latencies {
+			secs[i] = l.Seconds()
+		}
+		sort.Float64s(secs)
+
+		m := Measurement{PhaseStats: stats}
+		m.Workers = throughputWorkers
+		m.ArrivalRate = rate
+		m.ProofsPerSec = float64(len(run.latencies)) / run.elapsed.Seconds()
+		m.P99Secs = secs[int(math.Ceil(0.99*float64(len(secs))))-1]
+		m.MaxQueued = run.maxQueued
+		m.CPUUtilization = run.cpu.utilization()
+		m.Allocs = &AllocStats{PeakHeapBytes: run.peakHeap}
+		m.Saturated = m.ProofsPerSec < saturationShare*float64(run.arrived)/loadDuration.Seconds()
+		if m.Saturated && saturation == 0 {
+			saturation = rate
+		}
+		results = append(results, m)
+
+		mark := ""
+		if m.Saturated {
+			mark = "  saturated"
+		}
+		fmt.Printf("%10g %8d %10.3f %10s %10s %10s %10d %5.0f%%%s\n", rate, len(run.latencies), m.ProofsPerSec,
+			formatSecs(m.MedianSecs), formatSecs(m.P95Secs), formatSecs(m.P99Secs), m.MaxQueued, m.CPUUtilization*100, mark)
+	}
+	recordResults(artifactSettings(), results...)
+
+	fmt.Println()
+	var sustained float64
+	for _, m := range results {
+		if !m.Saturated {
+			sustained = max(sustained, m.ProofsPerSec)
+		}
+	}
+	if saturation > 0 {
+		fmt.Printf("Saturates at %g proofs/s offered; the most sustained was %.3f proofs/s with %d workers\n", saturation, sustained, throughputWorkers)
+	} else {
+		fmt.Printf("Sustained every rate offered, up to %g proofs/s, with %d workers\n", rates[len(rates)-1], throughputWorkers)
+	}
+}
+
+// offerLoad issues proving jobs at a Poisson arrival rate for -duration and
+// waits for the provers to finish them all
+func offerLoad(ccs constraint.ConstraintSystem, pk artifact, witnesses []witness.Witness, rate float64) loadTestRun {
+	var run loadTestRun
+	arrivals := rand.New(rand.NewSource(1))
+
+	type job struct {
+		arrived time.Time
+		witness witness.Witness
+	}
+	var (
+		mu    sync.Mutex
+		cond  = sync.NewCond(&mu)
+		queue []job
+		done  bool
+	)
+	var wg sync.WaitGroup
+	stopAllocs := trackAllocs()
+	cpuBefore := readCPUClasses()
+	start := time.Now()
+	for w := 0; w < throughputWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				for len(queue) == 0 && !done {
+					cond.Wait()
+				}
+				if len(queue) == 0 {
+					mu.Unlock()
+					return
+				}
+				j := queue[0]
+				queue = queue[1:]
+				mu.Unlock()
+
+				_, hardware, err := proveCircuit(ccs, pk, j.witness)
+				if err != nil {
+					fatal("Failed to generate proof", "error", err)
+				}
+				latency := time.Since(j.arrived)
+				observePhase("prove_load", latency)
+				proofsCompleted.WithLabelValues(hardware).Inc()
+				mu.Lock()
+				run.latencies = append(run.latencies, latency)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Exponential gaps between arrivals make a Poisson process
+	next := start
+	for i := 0; ; i++ {
+		next = next.Add(time.Duration(arrivals.ExpFloat64() / rate * float64(time.Second)))
+		if next.Sub(start) > loadDuration {
+			break
+		}
+		time.Sleep(time.Until(next))
+		mu.Lock()
+		queue = append(queue, job{arrived: time.Now(), witness: witnesses[i%len(witnesses)]})
+		run.maxQueued = max(run.maxQueued, len(queue))
+		run.arrived++
+		cond.Signal()
+		mu.Unlock()
+	}
+	mu.Lock()
+	done = true
+	cond.Broadcast()
+	mu.Unlock()
+	wg.Wait()
+
+	run.elapsed = time.Since(start)
+	run.cpu = readCPUClasses().sub(cpuBefore)
+	run.peakHeap = stopAllocs().PeakHeapBytes
+	if run.arrived == 0 {
+		fatal("No job arrived, offer a higher rate or a longer -duration", "duration", loadDuration, "rate", rate)
+	}
+	return run
+}
+
+// parseArrivalRates parses a comma-separated list of positive rates, sorted
+// from the lowest
+func parseArrivalRates(s string) ([]float64, error) {
+	var rates []float64
+	for _, field := range strings.Split(s, ",") {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("%q is not a positive number of proofs per second", field)
+		}
+		rates = append(rates, rate)
+	}
+	sort.Float64s(rates)
+	return rates, nil
+}
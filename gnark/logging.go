@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	// command line flags
+	logLevel  string
+	logFormat string
+)
+
+// setupLogging sends the log to stderr through slog, as text or, with
+// -log-format json, one JSON object per line for scripts and CI to parse,
+// leaving out the records below -log-level. The messages libraries write with
+// the log package go through it too, as warnings. So does gnark's own log,
+// which is still written to the console as text.
+func setupLogging() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		fatal("Unknown -log-level (use debug, info, warn or error)", "log_level", logLevel)
+	}
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch logFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		fatal("Unknown -log-format (use text or json)", "log_format", logFormat)
+	}
+	slog.SetDefault(slog.New(handler))
+	log.SetFlags(0)
+	log.SetOutput(logBridge{})
+
+	var gnarkLog io.Writer = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "15:04:05"}
+	if logFormat == "json" {
+		gnarkLog = os.Stderr
+	}
+	setGnarkLogger(&levelFilter{w: gnarkLog, min: zerologLevel(level)})
+}
+
+// logBridge passes the messages of the log package to slog
+type logBridge struct{}
+
+func (logBridge) Write(p []byte) (int, error) {
+	slog.Warn(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// fatal logs an error with its attributes and exits with status 1, as
+// log.Fatal does with a message
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// levelFilter writes the records of gnark's log at or above a level
+type levelFilter struct {
+	w   io.Writer
+	min zerolog.Level
+}
+
+func (f *levelFilter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+func (f *levelFilter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < f.min {
+		return len(p), nil
+	}
+	return f.w.Write(p)
+}
+
+// zerologLevel is the zerolog level of a slog level
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level <= slog.LevelDebug:
+		return zerolog.DebugLevel
+	case level <= slog.LevelInfo:
+		return zerolog.InfoLevel
+	case level <= slog.LevelWarn:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
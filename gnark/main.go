@@ -1,26 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"math/big"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 	"time"
 
-	"crypto/sha256"
-
-	"github.com/consensys/gnark-crypto/ecc"
-	"github.com/consensys/gnark/backend"
-	"github.com/consensys/gnark/backend/groth16"
 	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
 	"github.com/consensys/gnark/frontend"
-	"github.com/consensys/gnark/frontend/cs/r1cs"
 	"github.com/consensys/gnark/std/math/emulated"
 )
 
@@ -31,343 +28,878 @@ type TestCase struct {
 	MsgHash string `json:"msghash"`
 	PubKeyX string `json:"pubkey_x"`
 	PubKeyY string `json:"pubkey_y"`
+
+	// ExpectValid says whether the signature should verify, which is assumed
+	// when it is absent. The negative command checks that invalid ones do not.
+	ExpectValid *bool `json:"expect_valid,omitempty"`
+
+	// Imported test cases also record their curve, absent for P-256, the
+	// result their source expects, and where they came from
+	Curve  string `json:"curve,omitempty"`
+	Result string `json:"result,omitempty"`
+	Source string `json:"source,omitempty"`
+
+	// Nonce is how gen-testdata drew the signing nonce: random, or derived
+	// with RFC 6979 so that the signature can be regenerated
+	Nonce string `json:"nonce,omitempty"`
+
+	// Address and V are the Ethereum address of the signer and the recovery
+	// id of the signature, for secp256k1 test cases from gen-eth
+	Address string `json:"address,omitempty"`
+	V       uint   `json:"v,omitempty"`
+
+	// WebAuthn is what a converted WebAuthn assertion signed
+	WebAuthn *WebAuthnData `json:"webauthn,omitempty"`
+
+	// Message is the raw signed message in hex, and HashAlg the hash it is
+	// signed with, SHA-256 when absent. MsgHash may be left out when they are
+	// set: it is computed when the test case is loaded, for circuits that take
+	// the hash, while circuits that hash in-circuit take the message.
+	Message string `json:"message,omitempty"`
+	HashAlg string `json:"hash_alg,omitempty"`
+
+	// PubKeyCompressed is the public key in SEC 1 compressed form. PubKeyX
+	// and PubKeyY may be left out when it is set: they are decompressed when
+	// the test case is loaded, for circuits that take both coordinates.
+	PubKeyCompressed string `json:"pubkey_compressed,omitempty"`
+
+	// Hardware is the Secure Enclave or Android Keystore key a captured
+	// signature came from
+	Hardware *HardwareKeyData `json:"hardware_key,omitempty"`
+
+	// EthMessage is the structured Ethereum message a gen-eth-message test
+	// case signs
+	EthMessage *EthMessageData `json:"eth_message,omitempty"`
+
+	// EthTransaction is the transaction a gen-eth-tx test case is taken from
+	EthTransaction *EthTransactionData `json:"eth_transaction,omitempty"`
+
+	// Description says what the test case exercises, and Tags group it with
+	// others of its kind, e.g. edge-case or webauthn, for -tag to select
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
 }
 
 var (
 	// command line flags
 	outputDir string
+	useGPU    bool
+	selectAll bool
+	failFast  bool
+	keepGoing bool
+
+	skipExisting    bool
+	overwriteProofs bool
+	ignoreHashes    bool
 )
 
 func main() {
+	// Errors in the command line are logged before -log-format is known
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
 	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run main.go <command> [options]\nCommands: compile, prove, verify")
+		printUsage(os.Stderr)
+		os.Exit(2)
 	}
 
 	// Separate command and arguments
 	command := os.Args[1]
 	args := os.Args[2:]
+	switch command {
+	case "help", "-h", "-help", "--help":
+		helpCommand(args)
+		return
+	}
 
-	// Define and parse flags for the specific command
-	fs := flag.NewFlagSet(command, flag.ExitOnError)
-	fs.StringVar(&outputDir, "d", "data", "Output directory for compiled circuit and keys")
-	fs.Parse(args) // This will parse flags like -d
+	if command == "daemon" && len(args) > 0 && (args[0] == "enqueue" || args[0] == "status") {
+		command = "daemon " + args[0]
+		args = args[1:]
+	}
+	if command == "bench" && len(args) > 0 && args[0] == "compare" {
+		command = "bench compare"
+		args = args[1:]
+	}
+	info, ok := lookupCommand(command)
+	if !ok {
+		fatal("Unknown command. Run 'go run . help' for the commands.", "command", command)
+	}
+
+	// Parse the flags, and stop on any the command does not take
+	fs := newFlagSet(command)
+	fs.Usage = func() { printCommandHelp(fs, info) }
+	fs.Parse(args)
+	reserveStdout()
+	validateCommandLine(fs, info)
+	config := applyConfig(fs, command)
+	applyRuntimeProfile(fs, command)
+	reserveStdout() // -stdin may come from the config file
+
+	// The config and -profile may set the log level and format
+	setupLogging()
+	selectCircuit()
+	if config != "" {
+		slog.Info("Using config", "file", config)
+	}
+	if runtimeProfileName != "" {
+		slog.Info("Using profile", "profile", runtimeProfileName, "description", runtimeProfiles[runtimeProfileName].Description)
+	}
+	if insecureSeed != "" {
+		useInsecureSeed(insecureSeed)
+	}
+	if entropyHex != "" || entropyFile != "" {
+		useExternalEntropy()
+	}
+	if proverStages {
+		cpuProfile = true
+	}
+	enableMemProfile()
+
+	// Fail before any slow work when energy cannot be measured
+	if measureEnergy {
+		startEnergy()()
+	}
 
 	// The remaining non-flag arguments can be retrieved with fs.Args()
 	remainingArgs := fs.Args()
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+	if useBuiltinVectors {
+		defer extractBuiltinVectors(remainingArgs)()
+	}
 
 	switch command {
 	case "compile":
 		compileCircuit()
 	case "prove":
-		if len(remainingArgs) == 0 {
-			log.Fatal("Missing test case file for prove command")
+		applyDevicePreset()
+		applyCPULimit()
+		if dryRun {
+			estimateProving()
+			break
+		}
+		ctx, stop := commandContext()
+		defer stop()
+		if proveStdin {
+			proveFromStdin(ctx, remainingArgs)
+		} else if testCaseFiles, batch := proofTestCases(remainingArgs); batch {
+			generateProofs(ctx, testCaseFiles)
+		} else {
+			generateSingleProof(ctx, testCaseFiles[0])
 		}
-		testCaseFile := remainingArgs[0]
-		generateSingleProof(testCaseFile)
 	case "verify":
-		if len(remainingArgs) == 0 {
-			log.Fatal("Missing test case file for verify command")
+		ctx, stop := commandContext()
+		defer stop()
+		if testCaseFiles, batch := proofTestCases(remainingArgs); batch {
+			verifyProofs(ctx, testCaseFiles)
+		} else {
+			verifySingleProof(ctx, testCaseFiles[0])
+		}
+	case "prove-and-verify":
+		applyDevicePreset()
+		applyCPULimit()
+		ctx, stop := commandContext()
+		defer stop()
+		testCaseFiles, _ := proofTestCases(remainingArgs)
+		proveAndVerify(ctx, testCaseFiles)
+	case "tui":
+		runTUI()
+	case "completion":
+		printCompletion(remainingArgs[0])
+	case "version":
+		printVersion()
+	case "export":
+		exportVerifier()
+	case "clean":
+		cleanArtifacts()
+	case "doctor":
+		runDoctor()
+	case "determinism":
+		checkDeterminism(remainingArgs[0])
+	case "serialization":
+		benchSerialization(remainingArgs[0])
+	case "gen-testdata":
+		generateTestData()
+	case "gen-mutants":
+		generateMutants(remainingArgs)
+	case "gen-eth":
+		generateEthTestData()
+	case "gen-eth-message":
+		generateEthMessages(remainingArgs)
+	case "gen-eth-tx":
+		generateEthTxTestData(remainingArgs)
+	case "wycheproof":
+		importWycheproof(remainingArgs)
+	case "fido2":
+		importFIDO2()
+	case "hwkey":
+		importHardwareKey(remainingArgs)
+	case "from-key":
+		testCaseFromKey(remainingArgs[0])
+	case "webauthn":
+		convertWebAuthn(remainingArgs)
+	case "convert":
+		convertTestCases(remainingArgs)
+	case "negative":
+		runNegative(selectTestCases(remainingArgs, "*.json"))
+	case "check":
+		checkWitness(remainingArgs[0])
+	case "solve":
+		solveWitness(remainingArgs[0])
+	case "bench":
+		applyDevicePreset()
+		applyCPULimit()
+		runBenchmarks(remainingArgs)
+	case "bench compare":
+		compareResultsFiles(remainingArgs)
+	case "matrix":
+		runMatrix(remainingArgs[0])
+	case "daemon":
+		runDaemon(remainingArgs)
+	case "daemon enqueue":
+		daemonEnqueue(remainingArgs)
+	case "daemon status":
+		daemonStatus()
+	case "report":
+		printReport(remainingArgs)
+	case "history":
+		showHistory()
+	case "batch-verify":
+		proofDir := outputDir
+		if len(remainingArgs) > 0 {
+			proofDir = remainingArgs[0]
 		}
-		testCaseFile := remainingArgs[0]
-		verifySingleProof(testCaseFile)
+		batchVerifyProofs(proofDir)
+	case "batch":
+		runBatchSweep(remainingArgs)
+	case "throughput":
+		measureThroughput(remainingArgs)
+	case "loadtest":
+		runLoadTest(remainingArgs)
+	case "variance":
+		analyzeVariance()
+	case "gas":
+		recordGas(remainingArgs)
+	case "minmem":
+		findMinMemory(remainingArgs[0])
+	case "stats":
+		circuitStats()
 	default:
-		log.Fatal("Unknown command. Use: compile, prove, or verify")
+		fatal("Unknown command. Run 'go run . help' for the commands.", "command", command)
 	}
 }
 
+// newFlagSet defines the flags of every command; the Flags of its commandInfo
+// say which of them a command takes. A name may stand for different options
+// in different commands, as -proofs does.
+func newFlagSet(command string) *flag.FlagSet {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.StringVar(&logLevel, "log-level", "info", "Least severe log records to write: debug, info, warn or error")
+	fs.StringVar(&logFormat, "log-format", "text", "Format of the log on stderr: text, or json for one object per line")
+	fs.StringVar(&runtimeProfileName, "profile", "", "Preset of CPUs, memory limit, log level and bench runs for a scenario: ci, server, mobile-sim or low-memory; options given override it")
+	fs.StringVar(&configFile, "config", "", "YAML or TOML config file of options, which those given on the command line override (default: gnark.yaml, gnark.yml or gnark.toml if present)")
+	fs.StringVar(&outputDir, "d", "data", "Directory of the compiled circuit, keys, proofs and benchmark results")
+	fs.BoolVar(&useGPU, "gpu", false, "Prove on the GPU with ICICLE, for groth16 over bn254 in a binary built with -tags icicle; a proof the GPU fails is made on the CPU, with a warning, and recorded as such")
+	fs.StringVar(&testsDir, "tests", "tests", "Directory holding the test cases")
+	fs.IntVar(&genCount, "count", 10, "Number of test cases to generate")
+	fs.StringVar(&genSeed, "seed", "", "Draw keys and random messages from this seed and derive nonces with RFC 6979, so the same test cases are written every time")
+	fs.BoolVar(&genEdgeCases, "edge-cases", false, "Write boundary vectors as edge_case_*.json instead of random test cases")
+	fs.StringVar(&mutationBits, "bits", "0,1,8,64,128,255", "Comma-separated bit positions to flip in each value, or \"all\"")
+	fs.IntVar(&genBatch, "batch", 0, "Write batch test cases of this many signatures, for the batched circuit, as batch_test_case_N.json")
+	fs.IntVar(&batchKeys, "batch-keys", 0, "Number of keys that sign a batch of -batch in turn, 0 for a fresh key per signature")
+	fs.IntVar(&batchInvalid, "batch-invalid", 0, "Number of signatures of each batch of -batch to corrupt, for negative testing")
+	fs.StringVar(&genMessage, "message", defaultMessage, "Message every test case signs, or \"\" for a random one per test case")
+	fs.StringVar(&keyFile, "key", "", "PEM or DER P-256 private key to sign the message with, or public key to take -signature for, or an Ethereum keystore file to sign with")
+	fs.StringVar(&authenticator, "authenticator", "", "libfido2 path of the authenticator to sign with, e.g. /dev/hidraw0 or windows://hello (default: the first fido2-token -L lists)")
+	fs.StringVar(&passwordFile, "password-file", "", "File holding the password of the keystore in -key")
+	fs.StringVar(&rpcURL, "rpc", "", "Ethereum JSON-RPC endpoint to fetch transactions from, e.g. https://eth.llamarpc.com")
+	fs.StringVar(&signatureFile, "signature", "", "DER signature of the message by the public key in -key")
+	fs.StringVar(&convertTo, "to", formatGnark, "Format to convert test cases to: gnark, circom (snarkjs and rapidsnark) or noir")
+	fs.StringVar(&convertOut, "out", "", "Directory to write converted test cases or the verifier to (default: where the stack of -to reads them, or src)")
+	fs.BoolVar(&useBuiltinVectors, "builtin-vectors", false, "Take test cases from the corpus built into the binary instead of -tests; file arguments may name its vectors, e.g. test_case_1.json")
+	fs.BoolVar(&selectAll, "all", false, "Prove or verify every test case of -circuit in -tests as a batch, or remove every artifact and benchmark result")
+	fs.BoolVar(&proveStdin, "stdin", false, "Read one test case as JSON from stdin and write its proof to stdout in -proof-format, writing nothing to -d; everything else printed goes to stderr")
+	fs.StringVar(&proofFormat, "proof-format", proofFormatHex, "Encoding of the proof -stdin writes to stdout: hex, base64 or binary")
+	fs.BoolVar(&dryRun, "dry-run", false, "Report the size of the circuit and estimate the proving time, peak heap and proving key size, calibrated on this machine, without proving")
+	fs.DurationVar(&phaseTimeout, "timeout", 0, "Give up on loading the keys, a proof or a verification that runs longer than this, e.g. 10m, recording it as aborted in results.json; a batch stops there (default: no limit)")
+	fs.DurationVar(&progressInterval, "progress-interval", 10*time.Second, "Log the progress of compile, setup and batches, with the time left, at this interval; 0 to turn it off")
+	fs.BoolVar(&failFast, "fail-fast", false, "Stop a batch at the first test case that fails")
+	fs.BoolVar(&skipExisting, "skip-existing", false, "Leave out the test cases of a batch whose proof is already in -d, to resume an interrupted run")
+	fs.BoolVar(&overwriteProofs, "overwrite", false, "Prove every test case of a batch again, replacing its proof, the default")
+	fs.BoolVar(&ignoreHashes, "ignore-hashes", false, "Warn instead of failing when the circuit or keys in -d differ from the SHA-256 recorded in manifest.json")
+	fs.BoolVar(&cleanKeys, "keys", false, "Remove the compiled circuit, keys and manifest")
+	fs.BoolVar(&keepGoing, "keep-going", false, "Carry on past the test cases of a batch that fail, the default")
+	fs.BoolVar(&hashCircuits, "hash-circuits", false, "Compile every circuit variant with -curve, -backend and -range-check to hash it, instead of showing the hashes recorded in -d")
+	fs.StringVar(&caseRanges, "cases", "", "Test case numbers and ranges to run as a batch, e.g. 1,3,7-9, picked from the files given or, with none, from -tests")
+	fs.StringVar(&tagFilter, "tag", "", "Comma-separated tags to select test cases by, running those with any of them; with no files given, every test case in -tests that has one")
+	fs.IntVar(&benchRuns, "runs", 5, "Number of runs per phase and test case")
+	fs.BoolVar(&skipCompile, "skip-compile", false, "Benchmark the compiled circuit and keys in -d instead of compiling")
+	fs.StringVar(&benchThreads, "threads", "", "Comma-separated thread counts to sweep proving over, or \"all\" for powers of two up to the CPU count")
+	fs.IntVar(&benchWarmup, "warmup", 0, "Number of untimed runs before the timed runs of each phase")
+	fs.Float64Var(&outlierThreshold, "reject-outliers", 0, "Discard runs whose modified z-score from the median (MAD) exceeds this, e.g. 3.5; 0 keeps every run")
+	fs.StringVar(&targetCI, "target-ci", "", "Keep running each phase past -runs until the 95% confidence interval of its mean is within this percentage of it, e.g. 2%")
+	fs.IntVar(&maxRuns, "max-runs", 100, "Most runs per phase with -target-ci")
+	fs.BoolVar(&benchCold, "cold", false, "Also time proving with the circuit and proving key loaded from disk on each run, with -skip-compile")
+	fs.IntVar(&cpuLimitCores, "cpus", 0, "Number of CPUs to run Go code on, to simulate a smaller device")
+	fs.StringVar(&cpuQuota, "cpu-quota", "", "Share of each CPU's time to run for, e.g. 50%, pausing the process for the rest")
+	fs.StringVar(&deviceName, "device", "", "Approximate a device with its CPU and memory limits: iphone12, midrange-android or laptop")
+	fs.StringVar(&baselineFile, "baseline", "", "Compare the results with this baseline file, saving them as the baseline if it does not exist")
+	fs.StringVar(&regressionLimit, "fail-on-regression", "", "Fail when proving time, memory, constraints, or gas regress by more than this percentage, e.g. 10%")
+	fs.StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics of the runs at http://<addr>/metrics, e.g. :9090")
+	fs.StringVar(&batchSizes, "batch-sizes", "1,2,4,8,16,32,64", "Comma-separated numbers of signatures per proof to sweep over")
+	fs.Float64Var(&bandwidthMbps, "bandwidth", 100, "Download bandwidth in Mbit/s that clients fetch artifacts at")
+	fs.IntVar(&throughputWorkers, "workers", 0, "Number of proofs generated concurrently (default: one per CPU)")
+	// -proofs of clean is a switch, of throughput a number
+	if command == "clean" {
+		fs.BoolVar(&cleanProofs, "proofs", false, "Remove the proofs")
+	} else {
+		fs.IntVar(&throughputProofs, "proofs", 0, "Number of proofs to generate in total (default: 4 per worker)")
+	}
+	fs.StringVar(&arrivalRates, "rates", "0.25,0.5,1,2,4", "Comma-separated proving jobs per second to offer, each for -duration")
+	fs.DurationVar(&loadDuration, "duration", time.Minute, "How long jobs arrive at each rate")
+	fs.Float64Var(&memoryStep, "memory-step", 0.05, "Fraction of the unlimited peak heap to lower the memory limit by at each step")
+	fs.Float64Var(&maxSlowdown, "max-slowdown", 2, "How many times slower than without a limit proving may get and still fit")
+	fs.Float64Var(&varianceThreshold, "variance-threshold", 0.05, "Smallest relative difference of a test case from the others, or of two proving times, that is flagged")
+	fs.BoolVar(&reportHTML, "html", false, "Render the results as an HTML dashboard with charts instead of Markdown")
+	fs.StringVar(&daemonSchedule, "schedule", "", "Cron expression to queue the given matrix configs at, e.g. \"0 2 * * *\" for every night at 2:00")
+	fs.StringVar(&queuePath, "queue", "", "SQLite database holding the job queue (default: <dir>/daemon/queue.db)")
+	fs.StringVar(&historyPath, "history", "", "History file every measurement is appended to (default: <dir>/benchmarks/history.jsonl)")
+	fs.StringVar(&historyPhase, "phase", "prove", "Phase to compare over time")
+	fs.BoolVar(&profileConstraints, "profile-constraints", false, "Write a pprof profile of the constraints added by each call site and summarize it")
+	fs.BoolVar(&measureEnergy, "energy", false, "Measure the processor energy used by each proof, with RAPL on Linux or powermetrics on macOS, as root")
+	fs.BoolVar(&cpuProfile, "cpuprofile", false, "Capture a CPU profile of each phase into <dir>/benchmarks/profiles")
+	fs.DurationVar(&resourceInterval, "sample-resources", 0, "Sample RSS, CPU, goroutines and GC pauses at this interval, e.g. 100ms, into <dir>/benchmarks/resources")
+	fs.BoolVar(&proverStages, "prover-stages", false, "Split proving time into MSM, FFT, solving, and GC from a CPU profile of the prover; implies -cpuprofile")
+	fs.BoolVar(&memProfile, "memprofile", false, "Capture a profile of the heap allocations of each phase into <dir>/benchmarks/profiles")
+	fs.StringVar(&provingBackend, "backend", "", "Proving backend: groth16 or plonk (default: as compiled, else groth16)")
+	fs.StringVar(&circuitName, "circuit", "", "Circuit variant: "+strings.Join(circuitNames(), " or ")+"; variants other than "+defaultCircuit+" keep their artifacts in <dir>/<circuit> (default: as compiled, else "+defaultCircuit+")")
+	fs.StringVar(&rangeCheck, "range-check", "", "Range checks for the emulated arithmetic: lookup or decompose (default: as compiled, else lookup)")
+	fs.StringVar(&curveName, "curve", "", "Proving curve: bn254, bls12-377, bls12-381, bls24-315, bls24-317, bw6-761 or bw6-633 (default: as compiled, else bn254)")
+	fs.StringVar(&hashToField, "hash-to-field", "", "Hash-to-field function for commitments: sha256, keccak256 or rfc9380 (default: as compiled, else sha256)")
+	fs.BoolVar(&compareHashToField, "compare-hash-to-field", false, "Also prove and verify with every hash-to-field function and compare their timings and verifier compatibility")
+	fs.StringVar(&challengeHash, "challenge-hash", "", "Fiat-Shamir challenge hash for plonk: sha256 or keccak256 (default: as compiled, else sha256)")
+	fs.StringVar(&srsMode, "srs", "", "KZG SRS for the plonk setup: dev (generated locally, insecure) or file:<path> (default: dev)")
+	fs.IntVar(&solverTasks, "solver-tasks", 0, "Number of parallel tasks for the constraint solver (default: gnark's, one per CPU)")
+	fs.BoolVar(&statisticalZK, "statistical-zk", false, "Blind plonk proofs for statistical rather than perfect zero knowledge (faster)")
+	fs.StringVar(&entropyHex, "entropy", "", "External entropy (hex, e.g. a drand beacon value) to mix into the setup randomness")
+	fs.StringVar(&entropyFile, "entropy-file", "", "File whose contents are mixed into the setup randomness as external entropy")
+	fs.StringVar(&insecureSeed, "insecure-seed", "", "INSECURE: derive all setup and prover randomness from this seed for reproducible artifacts")
+	defineAliases(fs)
+	return fs
+}
+
 func compileCircuit() {
-	fmt.Println("Compiling ECDSA circuit...")
+	defaultSettings()
+
+	attrs := []any{"circuit", circuitName, "backend", provingBackend, "curve", curveName, "gnark", gnarkVersion(), "range_check", rangeCheck}
+	if provingBackend == backendPLONK {
+		attrs = append(attrs, "srs", srsMode)
+	}
+	slog.Info("Compiling circuit", attrs...)
 
 	// Create circuit instance
-	var circuit ECDSACircuit
+	circuit := selectedCircuit().New()
 
 	// Compile the circuit
-	ccs, err := frontend.Compile(ecc.BN254.ScalarField(), r1cs.NewBuilder, &circuit)
+	var stopProfile func() []ProfileEntry
+	if profileConstraints {
+		stopProfile = startConstraintProfile()
+	}
+	stopCPUProfile := startCPUProfile("compile", "")
+	stopSampler := startResourceSampler("compile", "")
+	stopTracking := trackAllocs()
+	compileProgress := startPhaseProgress("compile")
+	start := time.Now()
+	ccs, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), circuit)
+	compileTime := time.Since(start)
+	compileProgress.finish()
+	compileAllocs := stopTracking()
+	compileResources := stopSampler()
+	compileProfile := stopCPUProfile()
 	if err != nil {
-		log.Fatal("Circuit compilation failed:", err)
+		fatal("Circuit compilation failed", "error", err)
+	}
+	var constraintProfile []ProfileEntry
+	if stopProfile != nil {
+		constraintProfile = stopProfile()
 	}
 
-	fmt.Printf("Circuit compiled successfully. Constraints: %d\n", ccs.GetNbConstraints())
+	slog.Info("Circuit compiled", "secs", compileTime.Seconds(), "constraints", ccs.GetNbConstraints())
+	if constraintProfile != nil {
+		printConstraintProfile(constraintProfile)
+	}
 
 	// Setup phase
-	fmt.Println("Running setup phase...")
-	pk, vk, err := groth16.Setup(ccs)
+	slog.Info("Running setup")
+	stopCPUProfile = startCPUProfile("setup", "")
+	stopSampler = startResourceSampler("setup", "")
+	stopTracking = trackAllocs()
+	setupProgress := startPhaseProgress("setup")
+	start = time.Now()
+	pk, vk, err := setupKeys(ccs)
+	setupTime := time.Since(start)
+	setupProgress.finish()
+	setupAllocs := stopTracking()
+	setupResources := stopSampler()
+	setupProfile := stopCPUProfile()
 	if err != nil {
-		log.Fatal("Setup failed:", err)
+		fatal("Setup failed", "error", err)
 	}
 
 	// Save the compiled circuit and keys
 	err = os.MkdirAll(outputDir, 0755)
 	if err != nil {
-		log.Fatal("Failed to create output directory:", err)
+		fatal("Failed to create output directory", "error", err)
 	}
 
 	// Save constraint system
-	f, err := os.Create(filepath.Join(outputDir, "circuit.r1cs"))
+	f, err := os.Create(filepath.Join(outputDir, circuitFileName()))
 	if err != nil {
-		log.Fatal("Failed to create circuit file:", err)
+		fatal("Failed to create circuit file", "error", err)
 	}
 	defer f.Close()
-	_, err = ccs.WriteTo(f)
+	circuitBytes, err := ccs.WriteTo(f)
 	if err != nil {
-		log.Fatal("Failed to write circuit:", err)
+		fatal("Failed to write circuit", "error", err)
 	}
 
 	// Save proving key
 	f, err = os.Create(filepath.Join(outputDir, "proving.key"))
 	if err != nil {
-		log.Fatal("Failed to create proving key file:", err)
+		fatal("Failed to create proving key file", "error", err)
 	}
 	defer f.Close()
-	_, err = pk.WriteTo(f)
+	pkBytes, err := pk.WriteTo(f)
 	if err != nil {
-		log.Fatal("Failed to write proving key:", err)
+		fatal("Failed to write proving key", "error", err)
 	}
 
 	// Save verifying key
 	f, err = os.Create(filepath.Join(outputDir, "verifying.key"))
 	if err != nil {
-		log.Fatal("Failed to create verifying key file:", err)
+		fatal("Failed to create verifying key file", "error", err)
 	}
 	defer f.Close()
-	_, err = vk.WriteTo(f)
+	vkBytes, err := vk.WriteTo(f)
 	if err != nil {
-		log.Fatal("Failed to write verifying key:", err)
+		fatal("Failed to write verifying key", "error", err)
 	}
 
-	fmt.Printf("Setup completed. Files saved to %s/ directory.\n", outputDir)
+	markInsecureSetup()
+	writeManifest(currentSettings(), hashArtifacts(circuitFileName(), "proving.key", "verifying.key"))
+
+	// Measurements of earlier artifacts no longer apply
+	clearResults()
+	compileResult := singleRun("compile", "", compileTime)
+	compileResult.setCircuitSize(ccs)
+	compileResult.Profile = constraintProfile
+	compileResult.CPUProfile = compileProfile
+	compileResult.Resources = compileResources
+	compileResult.CircuitBytes = circuitBytes
+	compileResult.Allocs = &compileAllocs
+	setupResult := singleRun("setup", "", setupTime)
+	setupResult.CPUProfile = setupProfile
+	setupResult.Resources = setupResources
+	setupResult.Allocs = &setupAllocs
+	setupResult.ProvingKeyBytes = pkBytes
+	setupResult.ProvingKeyRawBytes = rawSize(pk)
+	setupResult.VerifyingKeyBytes = vkBytes
+	setupResult.VerifyingKeyRawBytes = rawSize(vk)
+	recordResults(currentSettings(), compileResult, setupResult)
+
+	slog.Info("Setup completed", "secs", setupTime.Seconds(), "peak_heap", formatBytes(setupAllocs.PeakHeapBytes), "dir", outputDir,
+		"circuit", formatBytes(uint64(circuitBytes)), "proving_key", formatBytes(uint64(pkBytes)), "verifying_key", formatBytes(uint64(vkBytes)))
 }
 
-func generateProofs() {
-	fmt.Println("Generating proofs for all test cases...")
+// proofTestCases is the test cases prove and verify take, and whether they
+// take them as a batch: one test case alone is proved with its statistics,
+// while several, -all, -tag or -cases are proved one after the other from keys
+// loaded once, carrying on past any that fail unless -fail-fast is given.
+// Globs and directories among the files are expanded to the test cases in them.
+func proofTestCases(args []string) ([]string, bool) {
+	if failFast && keepGoing {
+		fatal("-fail-fast and -keep-going contradict each other: give one")
+	}
+	if skipExisting && overwriteProofs {
+		fatal("-skip-existing and -overwrite contradict each other: give one")
+	}
+	if selectAll && len(args) > 0 {
+		fatal("-all takes the test cases in -tests: give either -all or test case files", "files", len(args))
+	}
+	if len(args) == 0 && !selectAll && tagFilter == "" && caseRanges == "" {
+		fatal("Missing test case file. Give one or more, a glob, -cases or -all for every test case in -tests")
+	}
+	pattern := selectedCircuit().TestCases
+	args = expandTestCaseArgs(args, pattern)
+	if len(args) == 1 && tagFilter == "" && caseRanges == "" {
+		return args, false
+	}
+	testCaseFiles := selectTestCases(args, pattern)
+	if len(testCaseFiles) == 0 {
+		files, err := filepath.Glob(filepath.Join(testsDir, pattern))
+		if err != nil {
+			fatal("Failed to find test case files", "dir", testsDir, "error", err)
+		}
+		testCaseFiles = sortCases(files)
+	}
+	if len(testCaseFiles) == 0 {
+		fatal("No test case files found", "dir", testsDir, "pattern", pattern)
+	}
+	if caseRanges != "" {
+		testCaseFiles = selectCases(testCaseFiles)
+	}
+	return testCaseFiles, true
+}
 
-	// Load constraint system
-	ccs := groth16.NewCS(ecc.BN254)
-	f, err := os.Open(filepath.Join(outputDir, "circuit.r1cs"))
+// proofVerifies says whether a test case's proof is there and verifies with
+// the current keys, so a batch can resume past it. One cut short by an
+// interrupted run does not decode, and one made with the keys of an earlier
+// compile does not verify; both are proved again.
+func proofVerifies(proofFile, testFile string, vk artifact) bool {
+	if _, err := os.Stat(proofFile); err != nil {
+		return false
+	}
+	proof := newProof()
+	if err := readArtifact(proofFile, proof, &IOStats{}); err != nil {
+		return false
+	}
+	testCase, err := loadTestCase(testFile)
 	if err != nil {
-		log.Fatal("Failed to open circuit file:", err)
+		return false
 	}
-	defer f.Close()
-	_, err = ccs.ReadFrom(f)
+	publicWitness, err := createPublicWitness(testCase)
 	if err != nil {
-		log.Fatal("Failed to read circuit:", err)
+		return false
 	}
+	if err := verifyCircuit(proof, vk, publicWitness); err != nil {
+		slog.Info("Proving again test case whose proof does not verify with the current keys", "test_case", testCaseID(testFile), "file", proofFile)
+		return false
+	}
+	return true
+}
 
-	// Load proving key
-	pk := groth16.NewProvingKey(ecc.BN254)
-	f, err = os.Open(filepath.Join(outputDir, "proving.key"))
-	if err != nil {
-		log.Fatal("Failed to open proving key file:", err)
+// Exit codes of batch prove and verify, apart from 1 for an error that stops
+// the command and 2 for a wrong command line
+const (
+	exitPartialFailure = 3 // some test cases failed
+	exitTotalFailure   = 4 // every test case failed
+)
+
+// exitCode is the code main exits with once it has cleaned up
+var exitCode int
+
+// finishBatch sets the exit code of a batch of which succeeded of attempted
+// test cases succeeded, and says how many were left out when it stopped
+// early: at the first failure with -fail-fast, or when a phase was aborted
+func finishBatch(succeeded, attempted, total int, abort error) {
+	if attempted < total {
+		reason := "at the first failure (-fail-fast)"
+		if abort != nil {
+			reason = abortReason(abort)
+		}
+		slog.Warn("Stopped the batch "+reason, "skipped", total-attempted)
 	}
-	defer f.Close()
-	_, err = pk.ReadFrom(f)
-	if err != nil {
-		log.Fatal("Failed to read proving key:", err)
+	switch {
+	case errors.Is(abort, context.Canceled):
+		exitCode = exitInterrupted
+	case succeeded == total:
+		exitCode = 0
+	case succeeded == 0:
+		exitCode = exitTotalFailure
+	default:
+		exitCode = exitPartialFailure
 	}
+}
 
-	// Find all test case files
-	testFiles, err := filepath.Glob("tests/test_case_*.json")
-	if err != nil {
-		log.Fatal("Failed to find test case files:", err)
+// testCaseID is what names the proof and the results of a test case: N for
+// test_case_N.json, else the file name without .json, e.g. eth_test_case_N,
+// so that the test cases of the circuits never share an ID
+func testCaseID(testCaseFile string) string {
+	baseName := filepath.Base(testCaseFile)
+	if match := testCaseNumber.FindStringSubmatch(baseName); match != nil && match[1] == "test_case_" {
+		return match[2]
 	}
+	return strings.TrimSuffix(baseName, ".json")
+}
 
+func generateProofs(ctx context.Context, testFiles []string) {
+	resolveSettings()
+	startMetricsServer()
+
+	slog.Info("Generating proofs", "test_cases", len(testFiles))
+
+	// Leave out the test cases proved already, before loading the proving
+	// key. A proof only counts when it verifies with the current keys, so
+	// that the proofs of an earlier compile are proved again.
+	total := len(testFiles)
+	if skipExisting {
+		checkArtifacts("verifying.key")
+		vk := newVerifyingKey()
+		if err := readArtifact(filepath.Join(outputDir, "verifying.key"), vk, &IOStats{}); err != nil {
+			fatal("Failed to read verifying key", "dir", outputDir, "error", err)
+		}
+		var remaining []string
+		for _, testFile := range testFiles {
+			proofFile := filepath.Join(outputDir, proofFileName(testCaseID(testFile)))
+			if proofVerifies(proofFile, testFile, vk) {
+				slog.Info("Skipping test case with a proof", "test_case", testCaseID(testFile), "file", proofFile)
+				continue
+			}
+			remaining = append(remaining, testFile)
+		}
+		testFiles = remaining
+	}
+	skipped := total - len(testFiles)
 	if len(testFiles) == 0 {
-		log.Fatal("No test case files found in tests/ directory")
+		slog.Info("Proof generation completed", "generated", 0, "skipped", skipped, "failed", 0, "test_cases", total)
+		return
 	}
 
-	fmt.Printf("Found %d test cases\n", len(testFiles))
+	// Load constraint system and proving key
+	checkArtifacts(circuitFileName(), "proving.key")
+	ccs, pk := loadProvingArtifacts(ctx, &IOStats{})
 
 	// Process each test case
+	progress := startProgress("prove", len(testFiles))
+	successCount := 0
+	attempted := 0
+	var abort error
 	for _, testFile := range testFiles {
-		fmt.Printf("Processing %s...\n", testFile)
+		if failFast && attempted > successCount {
+			break
+		}
+		progress.update(attempted)
+		attempted++
+		testCaseNum := testCaseID(testFile)
+		slog.Debug("Processing test case", "file", testFile)
+		proofFile := filepath.Join(outputDir, proofFileName(testCaseNum))
 
 		// Load test case
 		testCase, err := loadTestCase(testFile)
 		if err != nil {
-			log.Printf("Failed to load test case %s: %v", testFile, err)
+			slog.Error("Failed to load test case", "file", testFile, "error", err)
 			continue
 		}
 
 		// Create witness
 		witness, err := createWitness(testCase)
 		if err != nil {
-			log.Printf("Failed to create witness for %s: %v", testFile, err)
+			slog.Error("Failed to create witness", "test_case", testCaseNum, "error", err)
 			continue
 		}
 
-		// Generate proof
+		// Generate proof. A timed-out proof goes on in the background, so
+		// the batch stops rather than measure the next ones beside it.
+		var proof artifact
+		var proverBackend string
+		var gpuFailed time.Duration
 		start := time.Now()
-		proof, err := groth16.Prove(ccs, pk, witness, backend.WithProverHashToFieldFunction(sha256.New()))
-		provingTime := time.Since(start)
-
+		err = runPhase(ctx, func() (err error) {
+			proof, proverBackend, gpuFailed, err = proveCircuitTimed(ccs, pk, witness)
+			return err
+		})
+		provingTime := time.Since(start) - gpuFailed
+
+		if aborted(err) {
+			recordAbort("prove", testCaseNum, start, err)
+			abort = err
+			break
+		}
 		if err != nil {
-			log.Printf("Failed to generate proof for %s: %v", testFile, err)
+			slog.Error("Failed to generate proof", "test_case", testCaseNum, "error", err)
 			continue
 		}
 
 		// Save proof
-		baseName := filepath.Base(testFile)
-		baseName = baseName[:len(baseName)-5] // Remove .json extension
-		proofFile := filepath.Join(outputDir, baseName+".proof")
-
-		f, err := os.Create(proofFile)
-		if err != nil {
-			log.Printf("Failed to create proof file %s: %v", proofFile, err)
-			continue
-		}
-		_, err = proof.WriteTo(f)
-		f.Close()
-		if err != nil {
-			log.Printf("Failed to write proof to %s: %v", proofFile, err)
+		if err := writeArtifact(proofFile, proof, &IOStats{}); err != nil {
+			slog.Error("Failed to write proof", "test_case", testCaseNum, "file", proofFile, "error", err)
 			continue
 		}
 
-		fmt.Printf("✓ Proof generated for %s in %v\n", baseName, provingTime)
+		slog.Info("Proof generated", "test_case", testCaseNum, "secs", provingTime.Seconds(), "prover", proverBackend)
+		observePhase("prove", provingTime)
+		proofsCompleted.WithLabelValues(proverBackend).Inc()
+		successCount++
 	}
+	progress.finish()
 
-	fmt.Println("Proof generation completed.")
+	slog.Info("Proof generation completed", "generated", successCount, "skipped", skipped, "failed", attempted-successCount, "test_cases", total)
+	finishBatch(skipped+successCount, skipped+attempted, total, abort)
 }
 
-func verifyProofs() {
-	fmt.Println("Verifying all generated proofs...")
-
-	// Load verifying key
-	vk := groth16.NewVerifyingKey(ecc.BN254)
-	f, err := os.Open(filepath.Join(outputDir, "verifying.key"))
-	if err != nil {
-		log.Fatal("Failed to open verifying key file:", err)
-	}
-	defer f.Close()
-	_, err = vk.ReadFrom(f)
-	if err != nil {
-		log.Fatal("Failed to read verifying key:", err)
-	}
+func verifyProofs(ctx context.Context, testFiles []string) {
+	resolveSettings()
+	startMetricsServer()
 
-	// Find all proof files
-	proofFiles, err := filepath.Glob(filepath.Join(outputDir, "test_case_*.proof"))
-	if err != nil {
-		log.Fatal("Failed to find proof files:", err)
-	}
+	slog.Info("Verifying proofs", "test_cases", len(testFiles))
 
-	if len(proofFiles) == 0 {
-		log.Fatal("No proof files found in data/ directory")
+	// Load verifying key
+	checkArtifacts("verifying.key")
+	var loadIO IOStats
+	vk := newVerifyingKey()
+	if err := readArtifact(filepath.Join(outputDir, "verifying.key"), vk, &loadIO); err != nil {
+		fatal("Failed to read verifying key", "dir", outputDir, "error", err)
 	}
 
-	fmt.Printf("Found %d proofs to verify\n", len(proofFiles))
-
 	successCount := 0
 
 	// Verify each proof
-	for _, proofFile := range proofFiles {
-		baseName := filepath.Base(proofFile)
-		baseName = baseName[:len(baseName)-6] // Remove .proof extension
-		testFile := filepath.Join("tests", baseName+".json")
+	progress := startProgress("verify", len(testFiles))
+	attempted := 0
+	var abort error
+	for _, testFile := range testFiles {
+		if failFast && attempted > successCount {
+			break
+		}
+		progress.update(attempted)
+		attempted++
+		testCaseNum := testCaseID(testFile)
+		proofFile := filepath.Join(outputDir, proofFileName(testCaseNum))
 
-		fmt.Printf("Verifying %s...\n", baseName)
+		slog.Debug("Verifying proof", "test_case", testCaseNum, "file", proofFile)
 
 		// Load test case
 		testCase, err := loadTestCase(testFile)
 		if err != nil {
-			log.Printf("Failed to load test case %s: %v", testFile, err)
+			slog.Error("Failed to load test case", "file", testFile, "error", err)
 			continue
 		}
 
 		// Create public witness
 		publicWitness, err := createPublicWitness(testCase)
 		if err != nil {
-			log.Printf("Failed to create public witness for %s: %v", baseName, err)
+			slog.Error("Failed to create public witness", "test_case", testCaseNum, "error", err)
 			continue
 		}
 
 		// Load proof
-		proof := groth16.NewProof(ecc.BN254)
-		f, err := os.Open(proofFile)
-		if err != nil {
-			log.Printf("Failed to open proof file %s: %v", proofFile, err)
-			continue
-		}
-		_, err = proof.ReadFrom(f)
-		f.Close()
-		if err != nil {
-			log.Printf("Failed to read proof from %s: %v", proofFile, err)
+		proof := newProof()
+		if err := readArtifact(proofFile, proof, &loadIO); err != nil {
+			slog.Error("Failed to read proof", "test_case", testCaseNum, "file", proofFile, "error", err)
 			continue
 		}
 
 		// Verify proof
 		start := time.Now()
-		err = groth16.Verify(proof, vk, publicWitness, backend.WithVerifierHashToFieldFunction(sha256.New()))
+		err = runPhase(ctx, func() error { return verifyCircuit(proof, vk, publicWitness) })
 		verifyTime := time.Since(start)
 
+		if aborted(err) {
+			recordAbort("verify", testCaseNum, start, err)
+			abort = err
+			break
+		}
 		if err != nil {
-			log.Printf("✗ Verification failed for %s: %v", baseName, err)
+			slog.Error("Verification failed", "test_case", testCaseNum, "error", err)
 			continue
 		}
 
-		fmt.Printf("✓ Proof verified for %s in %v\n", baseName, verifyTime)
+		slog.Info("Proof verified", "test_case", testCaseNum, "secs", verifyTime.Seconds())
+		observePhase("verify", verifyTime)
+		verificationsCompleted.Inc()
 		successCount++
 	}
+	progress.finish()
 
-	fmt.Printf("Verification completed. %d/%d proofs verified successfully.\n", successCount, len(proofFiles))
+	slog.Info("Verification completed", "verified", successCount, "test_cases", len(testFiles))
+	finishBatch(successCount, attempted, len(testFiles), abort)
 }
 
+// loadTestCase reads a test case and validates it, so that a malformed one is
+// reported by file and field before it reaches the circuit
 func loadTestCase(filename string) (*TestCase, error) {
 	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
+	testCase, err := parseTestCase(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return testCase, nil
+}
 
+// parseTestCase decodes and validates a test case, and fills in the message
+// hash and the public key coordinates it leaves to be computed
+func parseTestCase(data []byte) (*TestCase, error) {
 	var testCase TestCase
-	err = json.Unmarshal(data, &testCase)
-	if err != nil {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&testCase); err != nil {
 		return nil, err
 	}
+	if err := validateTestCase(&testCase); err != nil {
+		return nil, errors.New(strings.ReplaceAll(err.Error(), "\n", "; "))
+	}
+	if testCase.MsgHash == "" {
+		digest, _ := testCase.messageDigest()
+		testCase.MsgHash = fmt.Sprintf("0x%x", digest)
+	}
+	if testCase.PubKeyCompressed != "" {
+		x, y, _ := decompressPubKey(testCase.Curve, testCase.PubKeyCompressed)
+		testCase.PubKeyX, testCase.PubKeyY = fmt.Sprintf("0x%x", x), fmt.Sprintf("0x%x", y)
+	}
 
 	return &testCase, nil
 }
 
 func createWitness(testCase *TestCase) (witness.Witness, error) {
-	// Parse hex strings to big integers
-	r, err := parseHexToBigInt(testCase.R)
+	assignment, err := selectedCircuit().Assign(testCase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse R: %v", err)
+		return nil, err
 	}
 
-	s, err := parseHexToBigInt(testCase.S)
+	// Create witness
+	witness, err := frontend.NewWitness(assignment, selectedCurve().ScalarField())
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse S: %v", err)
+		return nil, err
 	}
 
-	msgHash, err := parseHexToBigInt(testCase.MsgHash)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse message hash: %v", err)
-	}
+	return witness, nil
+}
 
-	pubKeyX, err := parseHexToBigInt(testCase.PubKeyX)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse public key X: %v", err)
+// createAssignment fills the circuit with the values of a test case
+func createAssignment(testCase *TestCase) (*ECDSACircuit, error) {
+	if testCase.Curve != "" && testCase.Curve != curveP256 {
+		return nil, fmt.Errorf("the circuit verifies P-256 signatures, not %s", testCase.Curve)
 	}
 
-	pubKeyY, err := parseHexToBigInt(testCase.PubKeyY)
+	r, s, msgHash, pubKeyX, pubKeyY, err := signatureValues(testCase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse public key Y: %v", err)
+		return nil, err
 	}
 
 	// Create circuit assignment with emulated field elements
-	assignment := ECDSACircuit{
+	return &ECDSACircuit{
 		R:       emulated.ValueOf[emulated.P256Fr](r),
 		S:       emulated.ValueOf[emulated.P256Fr](s),
 		MsgHash: emulated.ValueOf[emulated.P256Fr](msgHash),
 		PubKeyX: emulated.ValueOf[emulated.P256Fp](pubKeyX),
 		PubKeyY: emulated.ValueOf[emulated.P256Fp](pubKeyY),
-	}
+	}, nil
+}
 
-	// Create witness
-	witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
-	if err != nil {
-		return nil, err
+// signatureValues parses the signature, message hash and public key of a
+// test case
+func signatureValues(testCase *TestCase) (r, s, msgHash, pubKeyX, pubKeyY *big.Int, err error) {
+	// Parse hex strings to big integers
+	if r, err = parseHexToBigInt(testCase.R); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to parse R: %v", err)
 	}
-
-	return witness, nil
+	if s, err = parseHexToBigInt(testCase.S); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to parse S: %v", err)
+	}
+	if msgHash, err = parseHexToBigInt(testCase.MsgHash); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to parse message hash: %v", err)
+	}
+	if pubKeyX, err = parseHexToBigInt(testCase.PubKeyX); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to parse public key X: %v", err)
+	}
+	if pubKeyY, err = parseHexToBigInt(testCase.PubKeyY); err != nil {
+		return nil, nil, nil, nil, nil, fmt.Errorf("failed to parse public key Y: %v", err)
+	}
+	return r, s, msgHash, pubKeyX, pubKeyY, nil
 }
 
 func createPublicWitness(testCase *TestCase) (witness.Witness, error) {
@@ -398,125 +930,227 @@ func parseHexToBigInt(hexStr string) (*big.Int, error) {
 	return bigInt, nil
 }
 
-func generateSingleProof(testCaseFile string) {
-	// Load constraint system
-	ccs := groth16.NewCS(ecc.BN254)
-	f, err := os.Open(filepath.Join(outputDir, "circuit.r1cs"))
-	if err != nil {
-		log.Fatal("Failed to open circuit file:", err)
-	}
-	defer f.Close()
-	_, err = ccs.ReadFrom(f)
-	if err != nil {
-		log.Fatal("Failed to read circuit:", err)
-	}
+func generateSingleProof(ctx context.Context, testCaseFile string) {
+	resolveSettings()
 
-	// Load proving key
-	pk := groth16.NewProvingKey(ecc.BN254)
-	f, err = os.Open(filepath.Join(outputDir, "proving.key"))
-	if err != nil {
-		log.Fatal("Failed to open proving key file:", err)
-	}
-	defer f.Close()
-	_, err = pk.ReadFrom(f)
-	if err != nil {
-		log.Fatal("Failed to read proving key:", err)
-	}
+	// Load constraint system and proving key
+	checkArtifacts(circuitFileName(), "proving.key")
+	var loadIO IOStats
+	start := time.Now()
+	ccs, pk := loadProvingArtifacts(ctx, &loadIO)
+	loadTime := time.Since(start)
 
 	// Load test case
 	testCase, err := loadTestCase(testCaseFile)
 	if err != nil {
-		log.Fatal("Failed to load test case:", err)
+		fatal("Failed to load test case", "error", err)
 	}
 
+	testCaseNum := testCaseID(testCaseFile)
+
 	// Create witness
+	var witnessAllocs allocCounter
+	stopMemProfile := startMemProfile("witness", testCaseNum)
+	stopSampler := startResourceSampler("witness", testCaseNum)
+	witnessAllocs.start()
+	start = time.Now()
 	witness, err := createWitness(testCase)
+	witnessTime := time.Since(start)
+	witnessAllocs.stop()
+	witnessResources := stopSampler()
+	witnessMemProfile := stopMemProfile()
 	if err != nil {
-		log.Fatal("Failed to create witness:", err)
+		fatal("Failed to create witness", "error", err)
 	}
 
 	// Generate proof
-	proof, err := groth16.Prove(ccs, pk, witness, backend.WithProverHashToFieldFunction(sha256.New()))
-	if err != nil {
-		log.Fatal("Failed to generate proof:", err)
+	var proveAllocs allocCounter
+	stopMemProfile = startMemProfile("prove", testCaseNum)
+	stopProfile := startCPUProfile("prove", testCaseNum)
+	stopEnergy := startEnergy()
+	stopSampler = startResourceSampler("prove", testCaseNum)
+	proverSolverClock.reset()
+	proveAllocs.start()
+	var proof artifact
+	var proverBackend string
+	var gpuFailed time.Duration
+	start = time.Now()
+	err = runPhase(ctx, func() (err error) {
+		proof, proverBackend, gpuFailed, err = proveCircuitTimed(ccs, pk, witness)
+		return err
+	})
+	provingTime := time.Since(start) - gpuFailed
+	if aborted(err) {
+		recordAbort("prove", testCaseNum, start, err)
+		exitAborted(err)
 	}
-
-	// Extract test case number from filename
-	baseName := filepath.Base(testCaseFile)
-	testCaseNum := ""
-	if match := regexp.MustCompile(`test_case_(\d+)\.json`).FindStringSubmatch(baseName); match != nil {
-		testCaseNum = match[1]
-	} else {
-		log.Fatal("Invalid test case filename format")
+	proveAllocs.stop()
+	proveResources := stopSampler()
+	energy := stopEnergy()
+	proveProfile := stopProfile()
+	proveMemProfile := stopMemProfile()
+	if err != nil {
+		fatal("Failed to generate proof", "error", err)
 	}
 
 	// Save proof
-	proofFile := filepath.Join(outputDir, "proof_"+testCaseNum+".groth16")
-	f, err = os.Create(proofFile)
+	var proofIO IOStats
+	err = writeArtifact(filepath.Join(outputDir, proofFileName(testCaseNum)), proof, &proofIO)
 	if err != nil {
-		log.Fatal("Failed to create proof file:", err)
+		fatal("Failed to write proof", "error", err)
 	}
-	defer f.Close()
-	_, err = proof.WriteTo(f)
-	if err != nil {
-		log.Fatal("Failed to write proof:", err)
+
+	loadResult := singleRun("load", testCaseNum, loadTime)
+	loadResult.IO = &loadIO
+	proveResult := singleRun("prove", testCaseNum, provingTime)
+	proveResult.IO = &proofIO
+	proveResult.Hardware = proverBackend
+	proveResult.ProofBytes = proofSize(proof)
+	proveResult.ProofRawBytes = rawSize(proof)
+	if publicWitness, err := witness.Public(); err == nil {
+		if data, ok := proofCalldata(proof, publicWitness); ok {
+			calldata := calldataCost(data)
+			proveResult.Calldata = &calldata
+		}
+	}
+	proveResult.CPUProfile = proveProfile
+	proveResult.MemProfile = proveMemProfile
+	proveResult.Resources = proveResources
+	proveResult.Allocs = proveAllocs.stats()
+	proveResult.EnergyJoules = energy
+	if proverStages {
+		proveResult.ProverStages = proverStageBreakdown(filepath.Join(outputDir, "benchmarks", proveProfile), provingTime)
 	}
+	witnessResult := singleRun("witness", testCaseNum, witnessTime)
+	witnessResult.MemProfile = witnessMemProfile
+	witnessResult.Resources = witnessResources
+	witnessResult.Allocs = witnessAllocs.stats()
+	results := []Measurement{loadResult, witnessResult, proveResult}
+	solveTime, solved := proverSolverClock.last()
+	if solved {
+		results = append(results, singleRun("prove_solve", testCaseNum, solveTime), singleRun("prove_backend", testCaseNum, provingTime-solveTime))
+	}
+	recordResults(artifactSettings(), results...)
+
+	if proveProfile != "" {
+		title := fmt.Sprintf("prove: %s over %s (%s range checks), test case %s, %s", provingBackend, curveName, rangeCheck, testCaseNum, proverBackend)
+		renderFlamegraph(filepath.Join(outputDir, "benchmarks", proveProfile), title)
+	}
+
+	attrs := []any{"test_case", testCaseNum, "secs", provingTime.Seconds(), "prover", proverBackend, "witness_secs", witnessTime.Seconds()}
+	if solved {
+		attrs = append(attrs, "solve_secs", solveTime.Seconds(), "backend_secs", (provingTime - solveTime).Seconds())
+	}
+	// Loading the circuit and proving key, of which reading from disk, and
+	// writing the proof
+	attrs = append(attrs, "load_secs", loadTime.Seconds(), "read", formatBytes(uint64(loadIO.ReadBytes)), "read_secs", loadIO.ReadSecs, "write_secs", proofIO.WriteSecs)
+	if c := proveResult.Calldata; c != nil {
+		attrs = append(attrs, "calldata_bytes", c.Bytes, "calldata_zero_bytes", c.ZeroBytes, "calldata_gas", c.Gas, "blob_gas", c.BlobGas)
+	}
+	if measureEnergy {
+		attrs = append(attrs, "energy_joules", energy)
+	}
+	slog.Info("Proof generated", attrs...)
+	if proveResult.ProverStages != nil {
+		printProverStages(proveResult.ProverStages)
+	}
+}
 
-	fmt.Printf("✓ Proof generated for test case %s\n", testCaseNum)
+// loadProvingArtifacts reads the compiled circuit and proving key, which takes
+// a while for a large circuit, giving up when interrupted or timed out
+func loadProvingArtifacts(ctx context.Context, stats *IOStats) (constraint.ConstraintSystem, artifact) {
+	ccs := newConstraintSystem()
+	pk := newProvingKey()
+	start := time.Now()
+	err := runPhase(ctx, func() error {
+		if err := readArtifact(filepath.Join(outputDir, circuitFileName()), ccs, stats); err != nil {
+			return fmt.Errorf("failed to read circuit: %w", err)
+		}
+		if err := readArtifact(filepath.Join(outputDir, "proving.key"), pk, stats); err != nil {
+			return fmt.Errorf("failed to read proving key: %w", err)
+		}
+		return nil
+	})
+	if aborted(err) {
+		recordAbort("load", "", start, err)
+		exitAborted(err)
+	}
+	if err != nil {
+		fatal("Failed to load the proving artifacts", "dir", outputDir, "error", err)
+	}
+	return ccs, pk
 }
 
-func verifySingleProof(testCaseFile string) {
+func verifySingleProof(ctx context.Context, testCaseFile string) {
+	resolveSettings()
+
 	// Load verifying key
-	vk := groth16.NewVerifyingKey(ecc.BN254)
+	checkArtifacts("verifying.key")
+	vk := newVerifyingKey()
 	f, err := os.Open(filepath.Join(outputDir, "verifying.key"))
 	if err != nil {
-		log.Fatal("Failed to open verifying key file:", err)
+		fatal("Failed to open verifying key file", "error", err)
 	}
 	defer f.Close()
 	_, err = vk.ReadFrom(f)
 	if err != nil {
-		log.Fatal("Failed to read verifying key:", err)
+		fatal("Failed to read verifying key", "error", err)
 	}
 
-	// Extract test case number from filename
-	baseName := filepath.Base(testCaseFile)
-	testCaseNum := ""
-	if match := regexp.MustCompile(`test_case_(\d+)\.json`).FindStringSubmatch(baseName); match != nil {
-		testCaseNum = match[1]
-	} else {
-		log.Fatal("Invalid test case filename format")
-	}
+	testCaseNum := testCaseID(testCaseFile)
 
 	// Load test case for public witness
 	testCase, err := loadTestCase(testCaseFile)
 	if err != nil {
-		log.Fatal("Failed to load test case:", err)
+		fatal("Failed to load test case", "error", err)
 	}
 
 	// Create public witness
 	publicWitness, err := createPublicWitness(testCase)
 	if err != nil {
-		log.Fatal("Failed to create public witness:", err)
+		fatal("Failed to create public witness", "error", err)
 	}
 
 	// Load proof
-	proofFile := filepath.Join(outputDir, "proof_"+testCaseNum+".groth16")
-	proof := groth16.NewProof(ecc.BN254)
+	proofFile := filepath.Join(outputDir, proofFileName(testCaseNum))
+	proof := newProof()
 	f, err = os.Open(proofFile)
 	if err != nil {
-		log.Fatal("Failed to open proof file:", err)
+		fatal("Failed to open proof file", "error", err)
 	}
 	defer f.Close()
 	_, err = proof.ReadFrom(f)
 	if err != nil {
-		log.Fatal("Failed to read proof:", err)
+		fatal("Failed to read proof", "error", err)
 	}
 
 	// Verify proof
-	err = groth16.Verify(proof, vk, publicWitness, backend.WithVerifierHashToFieldFunction(sha256.New()))
+	var verifyAllocs allocCounter
+	stopMemProfile := startMemProfile("verify", testCaseNum)
+	stopProfile := startCPUProfile("verify", testCaseNum)
+	stopSampler := startResourceSampler("verify", testCaseNum)
+	verifyAllocs.start()
+	start := time.Now()
+	err = runPhase(ctx, func() error { return verifyCircuit(proof, vk, publicWitness) })
+	verifyTime := time.Since(start)
+	if aborted(err) {
+		recordAbort("verify", testCaseNum, start, err)
+		exitAborted(err)
+	}
+	verifyAllocs.stop()
+	verifyResources := stopSampler()
+	verifyProfile := stopProfile()
+	verifyMemProfile := stopMemProfile()
 	if err != nil {
-		log.Fatal("Proof verification failed:", err)
+		fatal("Proof verification failed", "error", err)
 	}
 
-	fmt.Printf("✓ Proof verified for test case %s\n", testCaseNum)
+	verifyResult := singleRun("verify", testCaseNum, verifyTime)
+	verifyResult.CPUProfile = verifyProfile
+	verifyResult.MemProfile = verifyMemProfile
+	verifyResult.Resources = verifyResources
+	verifyResult.Allocs = verifyAllocs.stats()
+	recordResults(artifactSettings(), verifyResult)
+
+	slog.Info("Proof verified", "test_case", testCaseNum, "secs", verifyTime.Seconds())
 }
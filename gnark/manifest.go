@@ -0,0 +1,242 @@
+package main
+
+import (
+	"log/slog"
+	"path/filepath"
+	"runtime/debug"
+
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// manifestFile records the settings the artifacts in the output directory were
+// produced with, so later steps can check they use the same ones.
+const manifestFile = manifest.FileName
+
+// Manifest describes how the compiled circuit and keys were produced
+type Manifest = manifest.Manifest
+
+// manifestContents is what the manifest file holds: the settings and the
+// SHA-256 of the circuit and key files
+type manifestContents = manifest.Contents
+
+func writeManifest(settings Manifest, hashes map[string]string) {
+	if err := manifest.Write(outputDir, manifestContents{Manifest: settings, Artifacts: hashes}); err != nil {
+		fatal("Failed to write manifest", "error", err)
+	}
+}
+
+// currentSettings describes artifacts produced with the current settings
+func currentSettings() Manifest {
+	manifest := Manifest{
+		Curve:        curveName,
+		HashToField:  hashToField,
+		Backend:      provingBackend,
+		RangeCheck:   rangeCheck,
+		GnarkVersion: gnarkVersion(),
+		EntropyHash:  entropyHash,
+	}
+	if circuitName != defaultCircuit {
+		manifest.Circuit = circuitName
+	}
+	if provingBackend == backendPLONK {
+		manifest.ChallengeHash = challengeHash
+		manifest.SRS = srsMode
+		manifest.SRSHash = srsHash
+	}
+	return manifest
+}
+
+// hashArtifacts returns the SHA-256 of files in the output directory
+func hashArtifacts(names ...string) map[string]string {
+	hashes := map[string]string{}
+	for _, name := range names {
+		hash, err := manifest.HashFile(filepath.Join(outputDir, name))
+		if err != nil {
+			fatal("Failed to hash artifact", "artifact", name, "error", err)
+		}
+		hashes[name] = hash
+	}
+	return hashes
+}
+
+// checkArtifacts compares the files a command loads with the SHA-256 the
+// manifest records for them, and refuses to go on when one differs, as after
+// a key was copied in from another compile run: proofs of mismatched keys fail
+// to verify long after the fact. With -ignore-hashes it only warns. Artifacts
+// compiled before the manifest recorded hashes are let through with a warning.
+func checkArtifacts(names ...string) {
+	contents, err := loadManifestContents()
+	if err != nil {
+		fatal("Failed to load manifest", "dir", outputDir, "error", err)
+	}
+	if contents == nil || len(contents.Artifacts) == 0 {
+		slog.Warn("The manifest records no artifact hashes, so the artifacts are not checked; recompile to record them", "file", filepath.Join(outputDir, manifestFile))
+		return
+	}
+	for _, name := range names {
+		recorded, ok := contents.Artifacts[name]
+		if !ok {
+			slog.Warn("The manifest records no hash of an artifact", "file", filepath.Join(outputDir, manifestFile), "artifact", name)
+			continue
+		}
+		hash, err := manifest.HashFile(filepath.Join(outputDir, name))
+		if err != nil {
+			fatal("Failed to hash artifact", "artifact", name, "error", err)
+		}
+		if hash == recorded {
+			continue
+		}
+		if ignoreHashes {
+			slog.Warn("Artifact differs from the manifest", "file", filepath.Join(outputDir, name), "sha256", hash, "recorded", recorded)
+			continue
+		}
+		fatal("Artifact differs from the manifest; recompile, or give -ignore-hashes to use it anyway",
+			"file", filepath.Join(outputDir, name), "sha256", hash, "recorded", recorded)
+	}
+}
+
+// loadManifest reads the manifest from the output directory. It returns nil
+// without error if the artifacts predate manifests.
+func loadManifest() (*Manifest, error) {
+	contents, err := loadManifestContents()
+	if contents == nil || err != nil {
+		return nil, err
+	}
+	return &contents.Manifest, nil
+}
+
+// loadManifestContents reads the manifest with the artifact hashes
+func loadManifestContents() (*manifestContents, error) {
+	return manifest.Read(outputDir)
+}
+
+// defaultSettings fills in unset -circuit, -curve, -hash-to-field, -backend,
+// -range-check, -challenge-hash and -srs flags for commands that produce new
+// artifacts.
+func defaultSettings() {
+	if circuitName == "" {
+		circuitName = defaultCircuit
+	}
+	if curveName == "" {
+		curveName = defaultCurve
+	}
+	if hashToField == "" {
+		hashToField = defaultHashToField
+	}
+	if provingBackend == "" {
+		provingBackend = defaultBackend
+	}
+	if rangeCheck == "" {
+		rangeCheck = defaultRangeCheck
+	}
+	if challengeHash == "" {
+		challengeHash = defaultChallengeHash
+	}
+	if srsMode == "" {
+		srsMode = defaultSRS
+	}
+	if err := validateSRS(srsMode); err != nil {
+		fatal("Invalid -srs", "error", err)
+	}
+	validateSettings()
+}
+
+// resolveSettings settles the -circuit, -curve, -hash-to-field, -backend,
+// -range-check and -challenge-hash flags for commands that consume existing
+// artifacts. The values recorded in the manifest at compile time win; an
+// explicit flag that disagrees with them is an error, since the keys, proofs
+// and Solidity verifier would not match.
+func resolveSettings() {
+	manifest, err := loadManifest()
+	if err != nil {
+		fatal("Failed to load manifest", "error", err)
+	}
+	if manifest == nil {
+		manifest = &Manifest{}
+	}
+
+	if manifest.GnarkVersion != "" && manifest.GnarkVersion != gnarkVersion() {
+		slog.Warn("The artifacts were produced with another gnark version", "dir", outputDir, "artifacts", manifest.GnarkVersion, "binary", gnarkVersion())
+	}
+
+	circuitName = resolveSetting("circuit", circuitName, manifest.Circuit, defaultCircuit)
+	curveName = resolveSetting("curve", curveName, manifest.Curve, defaultCurve)
+	hashToField = resolveSetting("hash-to-field", hashToField, manifest.HashToField, defaultHashToField)
+	provingBackend = resolveSetting("backend", provingBackend, manifest.Backend, defaultBackend)
+	rangeCheck = resolveSetting("range-check", rangeCheck, manifest.RangeCheck, defaultRangeCheck)
+	challengeHash = resolveSetting("challenge-hash", challengeHash, manifest.ChallengeHash, defaultChallengeHash)
+	validateSettings()
+}
+
+func resolveSetting(flagName, value, recorded, fallback string) string {
+	switch {
+	case recorded == "":
+		if value == "" {
+			return fallback
+		}
+		return value
+	case value == "":
+		return recorded
+	case canonicalSetting(flagName, value) != canonicalSetting(flagName, recorded):
+		fatal("Setting mismatch: the artifacts were compiled with other settings", "flag", flagName, "value", value, "dir", outputDir, "compiled", recorded)
+	}
+	return recorded
+}
+
+func canonicalSetting(flagName, value string) string {
+	if flagName == "curve" {
+		if id, err := parseCurve(value); err == nil {
+			return curveDisplayName(id)
+		}
+	}
+	return value
+}
+
+func validateSettings() {
+	id, err := parseCurve(curveName)
+	if err != nil {
+		fatal("Invalid -curve", "error", err)
+	}
+	curveName = curveDisplayName(id)
+
+	if _, err := manifest.NewHashToField(hashToField); err != nil {
+		fatal("Invalid -hash-to-field", "error", err)
+	}
+	if err := validateBackend(provingBackend); err != nil {
+		fatal("Invalid -backend", "error", err)
+	}
+	if err := validateRangeCheck(rangeCheck); err != nil {
+		fatal("Invalid -range-check", "error", err)
+	}
+	if _, err := newChallengeHash(challengeHash); err != nil {
+		fatal("Invalid -challenge-hash", "error", err)
+	}
+	if useGPU {
+		if err := checkGPU(); err != nil {
+			fatal("-gpu cannot prove; leave it out", "error", err)
+		}
+	}
+}
+
+// gnarkVersion reports the gnark module version the binary was built against,
+// so results from benchmark runs across gnark releases can be told apart.
+func gnarkVersion() string {
+	return moduleVersion("github.com/consensys/gnark")
+}
+
+// moduleVersion reports the version of a module the binary was built against
+func moduleVersion(path string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == path {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
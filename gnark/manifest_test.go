@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gnark-ecdsa-benchmark/internal/manifest"
+)
+
+// checkArtifactsDirEnv names the output directory checkArtifacts runs on in
+// the subprocess of TestCheckArtifacts, and checkArtifactsIgnoreEnv sets
+// -ignore-hashes there
+const (
+	checkArtifactsDirEnv    = "CHECK_ARTIFACTS_DIR"
+	checkArtifactsIgnoreEnv = "CHECK_ARTIFACTS_IGNORE_HASHES"
+)
+
+// TestCheckArtifacts checks which artifacts stop a command and which are let
+// through with a warning. A mismatch exits the process, so checkArtifacts
+// runs in a subprocess of the test binary.
+func TestCheckArtifacts(t *testing.T) {
+	if dir := os.Getenv(checkArtifactsDirEnv); dir != "" {
+		outputDir = dir
+		ignoreHashes = os.Getenv(checkArtifactsIgnoreEnv) != ""
+		checkArtifacts("verifying.key")
+		return
+	}
+
+	const key = "verifying key"
+	keyHash, err := manifest.HashFile(writeFile(t, t.TempDir(), "verifying.key", key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHash := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name string
+		// artifacts are the hashes the manifest records, and no manifest
+		// is written when they are nil
+		artifacts    map[string]string
+		noKey        bool
+		ignoreHashes bool
+		wantExit     bool
+		wantLog      string
+	}{
+		{name: "matching hash", artifacts: map[string]string{"verifying.key": keyHash}},
+		{
+			name:      "mismatch",
+			artifacts: map[string]string{"verifying.key": otherHash},
+			wantExit:  true,
+			wantLog:   "Artifact differs from the manifest; recompile, or give -ignore-hashes to use it anyway",
+		},
+		{
+			name:         "mismatch with -ignore-hashes",
+			artifacts:    map[string]string{"verifying.key": otherHash},
+			ignoreHashes: true,
+			wantLog:      "WARN Artifact differs from the manifest file=",
+		},
+		{
+			name:      "missing hash",
+			artifacts: map[string]string{"proving.key": otherHash},
+			wantLog:   "WARN The manifest records no hash of an artifact",
+		},
+		{
+			name:      "no hashes",
+			artifacts: map[string]string{},
+			wantLog:   "WARN The manifest records no artifact hashes",
+		},
+		{
+			name:    "no manifest",
+			wantLog: "WARN The manifest records no artifact hashes",
+		},
+		{
+			name:      "missing artifact",
+			artifacts: map[string]string{"verifying.key": keyHash},
+			noKey:     true,
+			wantExit:  true,
+			wantLog:   "Failed to hash artifact",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if !tt.noKey {
+				writeFile(t, dir, "verifying.key", key)
+			}
+			if tt.artifacts != nil {
+				if err := manifest.Write(dir, manifestContents{Manifest: Manifest{Circuit: defaultCircuit}, Artifacts: tt.artifacts}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := exec.Command(os.Args[0], "-test.run=^TestCheckArtifacts$")
+			cmd.Env = append(os.Environ(), checkArtifactsDirEnv+"="+dir)
+			if tt.ignoreHashes {
+				cmd.Env = append(cmd.Env, checkArtifactsIgnoreEnv+"=1")
+			}
+			out, err := cmd.CombinedOutput()
+			var exitErr *exec.ExitError
+			if exited := errors.As(err, &exitErr); exited != tt.wantExit {
+				t.Errorf("exited: %v, want %v; output:\n%s", exited, tt.wantExit, out)
+			} else if err != nil && !exited {
+				t.Fatal(err)
+			}
+			if tt.wantLog == "" && strings.Contains(string(out), "WARN") {
+				t.Errorf("warned, want no warning; output:\n%s", out)
+			}
+			if !strings.Contains(string(out), tt.wantLog) {
+				t.Errorf("output does not hold %q:\n%s", tt.wantLog, out)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// matrixFile is the combined output of the matrix command
+const matrixFile = "matrix.json"
+
+// MatrixConfig enumerates the benchmark matrix. Every combination of circuit,
+// range check, curve and backend is compiled once, then benchmarked on every
+// test case set at every thread count. Omitted dimensions take the defaults of
+// compile and bench.
+type MatrixConfig struct {
+	// Circuits are the circuit variants (-circuit)
+	Circuits []string `yaml:"circuits" json:"circuits"`
+	// RangeChecks are how the emulated arithmetic range-checks its limbs
+	// (-range-check)
+	RangeChecks []string `yaml:"range_checks" json:"range_checks"`
+	Curves      []string `yaml:"curves" json:"curves"`
+	Backends    []string `yaml:"backends" json:"backends"`
+	Threads     []int    `yaml:"threads" json:"threads,omitempty"`
+	// TestCases names sets of test case files; each entry may be a glob.
+	// Without it, each circuit is benchmarked on all of its test cases in
+	// -tests.
+	TestCases map[string][]string `yaml:"test_cases" json:"test_cases,omitempty"`
+	Runs      int                 `yaml:"runs" json:"runs"`
+	// Warmup and RejectOutliers are passed to bench as -warmup and
+	// -reject-outliers
+	Warmup         int     `yaml:"warmup" json:"warmup,omitempty"`
+	RejectOutliers float64 `yaml:"reject_outliers" json:"reject_outliers,omitempty"`
+	// TargetCI and MaxRuns are passed to bench as -target-ci and -max-runs
+	TargetCI string `yaml:"target_ci" json:"target_ci,omitempty"`
+	MaxRuns  int    `yaml:"max_runs" json:"max_runs,omitempty"`
+	// Runtimes are native and wasm, the latter running the benchmark
+	// compiled to WebAssembly under WasmRuntime: wazero, wasmtime or node
+	Runtimes    []string `yaml:"runtimes" json:"runtimes,omitempty"`
+	WasmRuntime string   `yaml:"wasm_runtime" json:"wasm_runtime,omitempty"`
+}
+
+// MatrixCoordinates locate a measurement in the matrix
+type MatrixCoordinates struct {
+	Circuit    string `json:"circuit"`
+	RangeCheck string `json:"range_check"`
+	Curve      string `json:"curve"`
+	Backend    string `json:"backend"`
+	Threads    int    `json:"threads,omitempty"`
+	TestCases  string `json:"test_cases,omitempty"`
+	// Runtime is wasm for measurements of the WebAssembly build, and empty
+	// for the native one
+	Runtime string `json:"runtime,omitempty"`
+}
+
+// MatrixMeasurement is a measurement of one cell, tagged with its coordinates
+type MatrixMeasurement struct {
+	Matrix MatrixCoordinates `json:"matrix"`
+	Measurement
+}
+
+// WasmSlowdown is how many times longer a phase takes in the WebAssembly
+// build than natively, for one cell and test case set. The native reference
+// is single-threaded when the matrix measured one thread, since the wasm
+// build runs on one thread.
+type WasmSlowdown struct {
+	Matrix        MatrixCoordinates `json:"matrix"`
+	Phase         string            `json:"phase"`
+	NativeThreads int               `json:"native_threads,omitempty"`
+	NativeSecs    float64           `json:"native_secs"`
+	WasmSecs      float64           `json:"wasm_secs"`
+	Slowdown      float64           `json:"slowdown"`
+}
+
+// MatrixResults is the output of the matrix command
+type MatrixResults struct {
+	SchemaVersion int                 `json:"schema_version"`
+	Config        MatrixConfig        `json:"config"`
+	Environment   Environment         `json:"environment"`
+	Measurements  []MatrixMeasurement `json:"measurements"`
+	WasmSlowdowns []WasmSlowdown      `json:"wasm_slowdowns,omitempty"`
+}
+
+// runMatrix runs the benchmark matrix of a config file. Each combination of
+// circuit, range check, curve and backend is compiled into its own directory
+// under <dir>/matrix, then benchmarked with bench -skip-compile for each test case
+// set. The commands run as child processes, so every cell starts from a fresh
+// runtime. The measurements of all cells are collected, tagged with their
+// coordinates, into <dir>/benchmarks/matrix.json. With the wasm runtime, the
+// benchmark is also compiled to WebAssembly and each bench repeated under the
+// wasm runtime, without -threads, from the cell's wasm directory. A cell that
+// fails under wasm, e.g. by running out of its 4 GiB of memory, is skipped
+// with a warning. The slowdown of each phase over native is reported.
+func runMatrix(configFile string) {
+	config := loadMatrixConfig(configFile)
+	// The test case sets of each circuit
+	testCaseSets := make(map[string]map[string][]string)
+	for _, circuit := range config.Circuits {
+		sets := config.TestCases
+		if len(sets) == 0 {
+			sets = map[string][]string{"all": {filepath.Join(testsDir, circuitVariants[circuit].TestCases)}}
+		}
+		testCaseSets[circuit] = resolveTestCaseSets(sets)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fatal("Failed to find the benchmark binary", "error", err)
+	}
+	run := func(args ...string) {
+		cmd := exec.Command(self, args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fatal("Failed to run", "command", strings.Join(args, " "), "error", err)
+		}
+	}
+
+	var wasmModule string
+	if slices.Contains(config.Runtimes, runtimeWasm) {
+		// The wasm runtime mounts only the working directory
+		for _, sets := range testCaseSets {
+			for _, files := range sets {
+				for _, file := range append(files, outputDir) {
+					if filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+						fatal("A path is outside the working directory, which is all the wasm runtime can read", "path", file)
+					}
+				}
+			}
+		}
+		wasmModule = buildWasm(filepath.Join(outputDir, "matrix"))
+	}
+	native := slices.Contains(config.Runtimes, runtimeNative)
+
+	cells := len(config.Circuits) * len(config.RangeChecks) * len(config.Curves) * len(config.Backends)
+	slog.Info("Running the matrix", "cells", cells, "runs", config.Runs)
+
+	var measurements []MatrixMeasurement
+	cell := 0
+	for _, circuit := range config.Circuits {
+		setNames := make([]string, 0, len(testCaseSets[circuit]))
+		for name := range testCaseSets[circuit] {
+			setNames = append(setNames, name)
+		}
+		sort.Strings(setNames)
+		for _, rangeCheck := range config.RangeChecks {
+			for _, curve := range config.Curves {
+				for _, backend := range config.Backends {
+					cell++
+					coordinates := MatrixCoordinates{Circuit: circuit, RangeCheck: rangeCheck, Curve: curve, Backend: backend}
+					// The cell's commands put the artifacts of a circuit other
+					// than the default one in a subdirectory of it
+					dir := filepath.Join(outputDir, "matrix", backend, curve, rangeCheck)
+					slog.Info("Matrix cell", "cell", cell, "cells", cells, "circuit", circuit, "backend", backend, "curve", curve, "range_check", rangeCheck)
+
+					run("compile", "-d", dir, "-circuit", circuit, "-curve", curve, "-backend", backend, "-range-check", rangeCheck)
+					for _, m := range readCellResults(circuitDir(dir, circuit)) {
+						if m.TestCase == "" {
+							measurements = append(measurements, MatrixMeasurement{Matrix: coordinates, Measurement: m})
+						}
+					}
+
+					for _, name := range setNames {
+						files := testCaseSets[circuit][name]
+						inSet := make(map[string]bool)
+						for _, file := range files {
+							inSet[testCaseID(file)] = true
+						}
+						benchArgs := func(dir string) []string {
+							args := []string{"bench", "-d", dir, "-circuit", circuit, "-skip-compile", "-runs", strconv.Itoa(config.Runs),
+								"-warmup", strconv.Itoa(config.Warmup), "-reject-outliers", strconv.FormatFloat(config.RejectOutliers, 'g', -1, 64)}
+							if config.TargetCI != "" {
+								args = append(args, "-target-ci", config.TargetCI, "-max-runs", strconv.Itoa(config.MaxRuns))
+							}
+							return args
+						}
+
+						if wasmModule != "" {
+							wasmDir := filepath.Join(dir, runtimeWasm)
+							linkArtifacts(circuitDir(dir, circuit), circuitDir(wasmDir, circuit))
+							slog.Info("Running under wasm", "runtime", config.WasmRuntime)
+							cmd := wasmCommand(config.WasmRuntime, wasmModule, append(benchArgs(wasmDir), files...)...)
+							cmd.Stdout = os.Stdout
+							cmd.Stderr = os.Stderr
+							if err := cmd.Run(); err != nil {
+								slog.Warn("Cell failed under wasm", "circuit", circuit, "backend", backend, "curve", curve, "range_check", rangeCheck, "error", err)
+							} else {
+								for _, m := range readCellResults(circuitDir(wasmDir, circuit)) {
+									if inSet[m.TestCase] {
+										tagged := coordinates
+										tagged.TestCases = name
+										tagged.Runtime = runtimeWasm
+										measurements = append(measurements, MatrixMeasurement{Matrix: tagged, Measurement: m})
+									}
+								}
+							}
+						}
+						if !native {
+							continue
+						}
+
+						args := benchArgs(dir)
+						if len(config.Threads) > 0 {
+							threads := make([]string, len(config.Threads))
+							for i, n := range config.Threads {
+								threads[i] = strconv.Itoa(n)
+							}
+							args = append(args, "-threads", strings.Join(threads, ","))
+						}
+						run(append(args, files...)...)
+
+						for _, m := range readCellResults(circuitDir(dir, circuit)) {
+							if inSet[m.TestCase] {
+								tagged := coordinates
+								tagged.Threads = m.Threads
+								tagged.TestCases = name
+								measurements = append(measurements, MatrixMeasurement{Matrix: tagged, Measurement: m})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		fatal("Failed to create results directory", "error", err)
+	}
+	slowdowns := wasmSlowdowns(measurements)
+	data, err := json.MarshalIndent(MatrixResults{
+		SchemaVersion: resultsSchemaVersion,
+		Config:        config,
+		Environment:   currentEnvironment(),
+		Measurements:  measurements,
+		WasmSlowdowns: slowdowns,
+	}, "", "  ")
+	if err != nil {
+		fatal("Failed to encode matrix results", "error", err)
+	}
+	path := filepath.Join(resultsDir, matrixFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fatal("Failed to write matrix results", "error", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("%-10s %-8s %-10s %-10s %-10s %-10s %8s %10s %10s\n", "Circuit", "Backend", "Curve", "Range", "Set", "Test case", "Threads", "Prove", "Verify")
+	for _, m := range measurements {
+		if m.Phase != "prove" || m.Matrix.Runtime != "" {
+			continue
+		}
+		verify := "-"
+		for _, v := range measurements {
+			if v.Phase == "verify" && v.Matrix == m.Matrix && v.TestCase == m.TestCase {
+				verify = formatSecs(v.MeanSecs)
+			}
+		}
+		threads := "-"
+		if m.Threads > 0 {
+			threads = strconv.Itoa(m.Threads)
+		}
+		fmt.Printf("%-10s %-8s %-10s %-10s %-10s %-10s %8s %10s %10s\n", m.Matrix.Circuit, m.Matrix.Backend, m.Matrix.Curve, m.Matrix.RangeCheck,
+			m.Matrix.TestCases, m.TestCase, threads, formatSecs(m.MeanSecs), verify)
+	}
+	if len(slowdowns) > 0 {
+		fmt.Println()
+		fmt.Printf("%-10s %-8s %-10s %-10s %-10s %-10s %10s %10s %9s\n", "Circuit", "Backend", "Curve", "Range", "Set", "Phase", "Native", "Wasm", "Slowdown")
+		for _, s := range slowdowns {
+			fmt.Printf("%-10s %-8s %-10s %-10s %-10s %-10s %10s %10s %8.1fx\n", s.Matrix.Circuit, s.Matrix.Backend, s.Matrix.Curve, s.Matrix.RangeCheck,
+				s.Matrix.TestCases, s.Phase, formatSecs(s.NativeSecs), formatSecs(s.WasmSecs), s.Slowdown)
+		}
+	}
+	slog.Info("Matrix results saved", "file", path)
+}
+
+// wasmSlowdowns compares every phase measured under wasm with the same phase
+// measured natively, averaging each over the test cases of its set
+func wasmSlowdowns(measurements []MatrixMeasurement) []WasmSlowdown {
+	var slowdowns []WasmSlowdown
+	for _, w := range measurements {
+		if w.Matrix.Runtime != runtimeWasm {
+			continue
+		}
+		cell := w.Matrix
+		seen := false
+		for _, s := range slowdowns {
+			if s.Matrix == cell && s.Phase == w.Phase {
+				seen = true
+			}
+		}
+		if seen {
+			continue
+		}
+
+		// Prefer the single-threaded native runs when the matrix has them
+		nativeCell := cell
+		nativeCell.Runtime = ""
+		for _, m := range measurements {
+			if m.Matrix.Threads == 1 && m.Phase == w.Phase {
+				single := m.Matrix
+				single.Threads = 0
+				if single == nativeCell {
+					nativeCell.Threads = 1
+				}
+			}
+		}
+		var wasmSum, nativeSum float64
+		var n int
+		for _, m := range measurements {
+			if m.Matrix != cell || m.Phase != w.Phase {
+				continue
+			}
+			for _, o := range measurements {
+				if o.Matrix == nativeCell && o.Phase == m.Phase && o.TestCase == m.TestCase {
+					wasmSum += m.MeanSecs
+					nativeSum += o.MeanSecs
+					n++
+				}
+			}
+		}
+		if n == 0 || nativeSum == 0 {
+			continue
+		}
+		slowdowns = append(slowdowns, WasmSlowdown{
+			Matrix:        cell,
+			Phase:         w.Phase,
+			NativeThreads: nativeCell.Threads,
+			NativeSecs:    nativeSum / float64(n),
+			WasmSecs:      wasmSum / float64(n),
+			Slowdown:      wasmSum / nativeSum,
+		})
+	}
+	return slowdowns
+}
+
+// loadMatrixConfig reads and validates a matrix config, filling in defaults
+// for the dimensions it leaves out
+func loadMatrixConfig(path string) MatrixConfig {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read matrix config", "error", err)
+	}
+	var config MatrixConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		fatal("Invalid matrix config", "file", path, "error", err)
+	}
+
+	if len(config.Circuits) == 0 {
+		config.Circuits = []string{defaultCircuit}
+	}
+	if len(config.RangeChecks) == 0 {
+		config.RangeChecks = []string{defaultRangeCheck}
+	}
+	if len(config.Curves) == 0 {
+		config.Curves = []string{defaultCurve}
+	}
+	if len(config.Backends) == 0 {
+		config.Backends = []string{defaultBackend}
+	}
+	if config.Runs == 0 {
+		config.Runs = benchRuns
+	}
+	if config.Warmup == 0 {
+		config.Warmup = benchWarmup
+	}
+	if config.RejectOutliers == 0 {
+		config.RejectOutliers = outlierThreshold
+	}
+	if config.TargetCI == "" {
+		config.TargetCI = targetCI
+	}
+	if config.MaxRuns == 0 {
+		config.MaxRuns = maxRuns
+	}
+	if len(config.Runtimes) == 0 {
+		config.Runtimes = []string{runtimeNative}
+	}
+	if config.WasmRuntime == "" {
+		config.WasmRuntime = "wazero"
+	}
+
+	for _, circuit := range config.Circuits {
+		if _, ok := circuitVariants[circuit]; !ok {
+			fatal("Invalid matrix circuit, use one of: "+strings.Join(circuitNames(), ", "), "circuit", circuit)
+		}
+	}
+	for _, rc := range config.RangeChecks {
+		if err := validateRangeCheck(rc); err != nil {
+			fatal("Invalid matrix range check", "error", err)
+		}
+	}
+	for i, curve := range config.Curves {
+		id, err := parseCurve(curve)
+		if err != nil {
+			fatal("Invalid matrix curve", "error", err)
+		}
+		config.Curves[i] = curveDisplayName(id)
+	}
+	for _, backend := range config.Backends {
+		if err := validateBackend(backend); err != nil {
+			fatal("Invalid matrix backend", "error", err)
+		}
+	}
+	for _, n := range config.Threads {
+		if n < 1 {
+			fatal("Invalid matrix thread count", "threads", n)
+		}
+	}
+	for _, r := range config.Runtimes {
+		if r != runtimeNative && r != runtimeWasm {
+			fatal(fmt.Sprintf("Invalid matrix runtime (use %s or %s)", runtimeNative, runtimeWasm), "runtime", r)
+		}
+	}
+	if err := validateWasmRuntime(config.WasmRuntime); err != nil {
+		fatal("Invalid matrix wasm_runtime", "error", err)
+	}
+	if config.Runs < 1 {
+		fatal("Matrix runs must be at least 1")
+	}
+	if config.Warmup < 0 || config.RejectOutliers < 0 {
+		fatal("Matrix warmup and reject_outliers must not be negative")
+	}
+	if config.TargetCI != "" {
+		if width, err := parseRegressionLimit(config.TargetCI); err != nil || width <= 0 {
+			fatal("Invalid matrix target_ci, use a percentage of the mean such as 2%", "target_ci", config.TargetCI)
+		}
+		if config.MaxRuns < config.Runs {
+			fatal("Matrix max_runs must be at least runs")
+		}
+	}
+	return config
+}
+
+// resolveTestCaseSets expands the globs of each test case set
+func resolveTestCaseSets(sets map[string][]string) map[string][]string {
+	resolved := make(map[string][]string)
+	for name, patterns := range sets {
+		var files []string
+		for _, pattern := range patterns {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				fatal("Invalid test case pattern", "pattern", pattern, "error", err)
+			}
+			files = append(files, matches...)
+		}
+		if len(files) == 0 {
+			fatal("Test case set matches no files", "set", name)
+		}
+		resolved[name] = files
+	}
+	return resolved
+}
+
+// readCellResults reads the measurements the child commands recorded in a
+// cell's results file
+func readCellResults(dir string) []Measurement {
+	path := filepath.Join(dir, "benchmarks", resultsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read results", "error", err)
+	}
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		fatal("Invalid results file", "file", path, "error", err)
+	}
+	return results.Measurements
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// AllocStats summarizes the memory allocated while a phase was running
+type AllocStats struct {
+	TotalAllocBytes uint64 `json:"total_alloc_bytes"`
+	Mallocs         uint64 `json:"mallocs"`
+	PeakHeapBytes   uint64 `json:"peak_heap_bytes"`
+	NumGC           uint32 `json:"num_gc"`
+	// Averages over every timed run, as go test -benchmem reports them. The
+	// totals above may cover only the first run (bench) or be left out.
+	BytesPerOp  uint64  `json:"bytes_per_op,omitempty"`
+	AllocsPerOp uint64  `json:"allocs_per_op,omitempty"`
+	GCPerOp     float64 `json:"gc_per_op,omitempty"`
+}
+
+// allocCounter sums the allocations of the timed runs of a phase from
+// runtime.MemStats deltas. Reading the stats briefly stops the world, so
+// start and stop belong outside the timed code.
+type allocCounter struct {
+	runs           int
+	bytes, mallocs uint64
+	gcs            uint32
+	before         runtime.MemStats
+}
+
+func (c *allocCounter) start() {
+	runtime.ReadMemStats(&c.before)
+}
+
+func (c *allocCounter) stop() {
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	c.runs++
+	c.bytes += after.TotalAlloc - c.before.TotalAlloc
+	c.mallocs += after.Mallocs - c.before.Mallocs
+	c.gcs += after.NumGC - c.before.NumGC
+}
+
+// setPerOp fills in the averages per run of the counted runs
+func (a *AllocStats) setPerOp(c *allocCounter) {
+	if c.runs == 0 {
+		return
+	}
+	a.BytesPerOp = c.bytes / uint64(c.runs)
+	a.AllocsPerOp = c.mallocs / uint64(c.runs)
+	a.GCPerOp = float64(c.gcs) / float64(c.runs)
+}
+
+// stats is the allocations of all counted runs, with their averages
+func (c *allocCounter) stats() *AllocStats {
+	stats := &AllocStats{TotalAllocBytes: c.bytes, Mallocs: c.mallocs, NumGC: c.gcs}
+	stats.setPerOp(c)
+	return stats
+}
+
+// trackAllocs starts recording allocations and samples the in-use heap to find
+// its peak. Call the returned function when the phase is over.
+func trackAllocs() func() AllocStats {
+	runtime.GC()
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	peak := before.HeapInuse
+	done := make(chan struct{})
+	sampled := make(chan uint64)
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		var m runtime.MemStats
+		for {
+			select {
+			case <-ticker.C:
+				runtime.ReadMemStats(&m)
+				if m.HeapInuse > peak {
+					peak = m.HeapInuse
+				}
+			case <-done:
+				sampled <- peak
+				return
+			}
+		}
+	}()
+
+	return func() AllocStats {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+
+		close(done)
+		peak := <-sampled
+		if after.HeapInuse > peak {
+			peak = after.HeapInuse
+		}
+
+		return AllocStats{
+			TotalAllocBytes: after.TotalAlloc - before.TotalAlloc,
+			Mallocs:         after.Mallocs - before.Mallocs,
+			PeakHeapBytes:   peak,
+			NumGC:           after.NumGC - before.NumGC,
+		}
+	}
+}
+
+// formatBytes renders a byte count with a binary unit suffix
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/google/pprof/profile"
+)
+
+var (
+	// command line flags
+	memProfile bool
+)
+
+// profilerFrames matches the functions that write and read heap profiles
+var profilerFrames = regexp.MustCompile(`^(runtime/pprof|github\.com/google/pprof/profile)\.`)
+
+// enableMemProfile makes the runtime record every allocation rather than one
+// per 512 KiB, so the short phases such as verification show up in their
+// profiles. It must run before the phases, right after parsing the flags.
+func enableMemProfile() {
+	if memProfile {
+		runtime.MemProfileRate = 1
+	}
+}
+
+// startMemProfile captures the allocations of one phase into the profiles
+// directory of the results, e.g. benchmarks/profiles/mem_prove_1.pprof. It
+// does nothing without -memprofile. The runtime only keeps the allocations
+// since the process started, so the profile taken before the phase is
+// subtracted from the one after it, as go tool pprof -base does. The returned
+// function returns the path of the profile relative to the results directory,
+// or "" when no profile was taken.
+func startMemProfile(phase, testCase string) func() string {
+	if !memProfile {
+		return func() string { return "" }
+	}
+
+	before := readAllocsProfile()
+	return func() string {
+		after := readAllocsProfile()
+		before.Scale(-1)
+		delta, err := profile.Merge([]*profile.Profile{after, before})
+		if err != nil {
+			fatal("Failed to subtract heap profiles", "error", err)
+		}
+		// Taking the profiles allocates too, leave that out
+		delta.FilterSamplesByName(nil, profilerFrames, nil, nil)
+		// Call sites that only allocated before the phase cancel out
+		samples := delta.Sample[:0]
+		for _, sample := range delta.Sample {
+			for _, v := range sample.Value {
+				if v != 0 {
+					samples = append(samples, sample)
+					break
+				}
+			}
+		}
+		delta.Sample = samples
+		delta.DefaultSampleType = "alloc_space"
+
+		relPath, path := profilePath("mem", phase, testCase)
+		f, err := os.Create(path)
+		if err != nil {
+			fatal("Failed to create heap profile", "error", err)
+		}
+		defer f.Close()
+		if err := delta.Write(f); err != nil {
+			fatal("Failed to write heap profile", "error", err)
+		}
+		return relPath
+	}
+}
+
+// readAllocsProfile reads the allocations profiled so far. The runtime
+// publishes them at the end of a garbage collection, so it runs one first.
+func readAllocsProfile() *profile.Profile {
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("allocs").WriteTo(&buf, 0); err != nil {
+		fatal("Failed to read heap profile", "error", err)
+	}
+	p, err := profile.Parse(&buf)
+	if err != nil {
+		fatal("Failed to parse heap profile", "error", err)
+	}
+	return p
+}
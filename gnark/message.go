@@ -0,0 +1,59 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Hash functions a test case may sign its message with
+const (
+	hashSHA256    = "sha256"
+	hashSHA384    = "sha384"
+	hashSHA512    = "sha512"
+	hashKeccak256 = "keccak256"
+)
+
+// messageHash returns the hash function of a hash_alg, SHA-256 when empty,
+// or nil for an unknown one
+func messageHash(alg string) func() hash.Hash {
+	switch alg {
+	case "", hashSHA256:
+		return sha256.New
+	case hashSHA384:
+		return sha512.New384
+	case hashSHA512:
+		return sha512.New
+	case hashKeccak256:
+		return sha3.NewLegacyKeccak256
+	default:
+		return nil
+	}
+}
+
+// messageDigest hashes the raw message of a test case with its hash_alg, and
+// keeps the leftmost 256 bits of a longer hash as ECDSA does with the order
+// of a 256-bit curve
+func (t *TestCase) messageDigest() (*big.Int, error) {
+	newHash := messageHash(t.HashAlg)
+	if newHash == nil {
+		return nil, fmt.Errorf("field \"hash_alg\": %q is not %s, %s, %s or %s", t.HashAlg, hashSHA256, hashSHA384, hashSHA512, hashKeccak256)
+	}
+	message, err := hex.DecodeString(strings.TrimPrefix(t.Message, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("field \"message\": not hex: %v", err)
+	}
+	h := newHash()
+	h.Write(message)
+	digest := h.Sum(nil)
+	if len(digest) > 32 {
+		digest = digest[:32]
+	}
+	return new(big.Int).SetBytes(digest), nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// command line flags
+	metricsAddr string
+)
+
+// Metrics of long benchmark campaigns, served on -metrics-addr. Updating them
+// costs next to nothing when they are not served.
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	proofsCompleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ecdsa_bench_proofs_total",
+		Help: "Proofs generated, by prover hardware.",
+	}, []string{"hardware"})
+	verificationsCompleted = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "ecdsa_bench_verifications_total",
+		Help: "Proofs verified.",
+	})
+	phaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "ecdsa_bench_phase_duration_seconds",
+		Help: "Duration of each run of a benchmark phase.",
+		// 1ms to about 9 minutes, from verification to a large setup
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 20),
+	}, []string{"phase"})
+	daemonJobs = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ecdsa_bench_daemon_jobs_total",
+		Help: "Daemon jobs finished, by status.",
+	}, []string{"status"})
+)
+
+// startMetricsServer serves the benchmark metrics, along with the Go runtime
+// and process metrics, at /metrics on -metrics-addr. It does nothing when the
+// flag is not set.
+func startMetricsServer() {
+	if metricsAddr == "" {
+		return
+	}
+
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		proofsCompleted,
+		verificationsCompleted,
+		phaseDuration,
+		daemonJobs,
+	)
+
+	// Listen before starting the benchmark, so a busy port fails right away
+	listener, err := net.Listen("tcp", metricsAddr)
+	if err != nil {
+		fatal("Failed to listen for metrics", "error", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	go func() {
+		fatal("Metrics server failed", "error", http.Serve(listener, mux))
+	}()
+	slog.Info("Serving metrics", "url", fmt.Sprintf("http://%s/metrics", listener.Addr()))
+}
+
+// observePhase records the duration of one run of a phase
+func observePhase(phase string, d time.Duration) {
+	phaseDuration.WithLabelValues(phase).Observe(d.Seconds())
+}
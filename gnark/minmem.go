@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+)
+
+var (
+	// command line flags
+	memoryStep  float64
+	maxSlowdown float64
+)
+
+// MemoryRun is one proof under a memory limit
+type MemoryRun struct {
+	LimitBytes    uint64  `json:"limit_bytes"`
+	Secs          float64 `json:"secs"`
+	PeakHeapBytes uint64  `json:"peak_heap_bytes"`
+	Fits          bool    `json:"fits"`
+}
+
+// findMinMemory proves a test case under decreasing soft memory limits
+// (debug.SetMemoryLimit) to find the smallest memory budget proving fits in.
+// The Go runtime never fails an allocation over a soft limit; it collects
+// garbage harder instead. A limit only counts as met when the peak heap stays
+// under it and proving is at most -max-slowdown times slower than without a
+// limit. Limits start at the unlimited peak heap and drop by -memory-step of it
+// until proving no longer fits.
+func findMinMemory(testCaseFile string) {
+	resolveSettings()
+	if memoryStep <= 0 || memoryStep >= 1 {
+		fatal("-memory-step must be between 0 and 1")
+	}
+	if maxSlowdown < 1 {
+		fatal("-max-slowdown must be at least 1")
+	}
+
+	ccs := newConstraintSystem()
+	loadArtifact(filepath.Join(outputDir, circuitFileName()), ccs)
+	pk := newProvingKey()
+	loadArtifact(filepath.Join(outputDir, "proving.key"), pk)
+
+	testCase, err := loadTestCase(testCaseFile)
+	if err != nil {
+		fatal("Failed to load test case", "error", err)
+	}
+	witness, err := createWitness(testCase)
+	if err != nil {
+		fatal("Failed to create witness", "error", err)
+	}
+	testCaseNum := testCaseID(testCaseFile)
+
+	prove := func(limit uint64) MemoryRun {
+		debug.SetMemoryLimit(int64(limit))
+		stopTracking := trackAllocs()
+		start := time.Now()
+		_, _, err := proveCircuit(ccs, pk, witness)
+		elapsed := time.Since(start)
+		allocs := stopTracking()
+		if err != nil {
+			fatal("Failed to generate proof", "error", err)
+		}
+		return MemoryRun{LimitBytes: limit, Secs: elapsed.Seconds(), PeakHeapBytes: allocs.PeakHeapBytes}
+	}
+	defer debug.SetMemoryLimit(math.MaxInt64)
+
+	slog.Info("Proving without a memory limit", "test_case", testCaseNum)
+	unlimited := prove(math.MaxInt64)
+	slog.Info("Proved without a memory limit", "secs", unlimited.Secs, "peak_heap", formatBytes(unlimited.PeakHeapBytes))
+
+	fmt.Printf("%12s %10s %12s  %s\n", "Limit", "Time", "Peak heap", "Fits")
+	var runs []MemoryRun
+	best := -1
+	step := uint64(float64(unlimited.PeakHeapBytes) * memoryStep)
+	for limit := unlimited.PeakHeapBytes; limit > step; limit -= step {
+		run := prove(limit)
+		run.Fits = run.PeakHeapBytes <= limit && run.Secs <= maxSlowdown*unlimited.Secs
+		runs = append(runs, run)
+		fmt.Printf("%12s %10s %12s  %v\n", formatBytes(limit), formatSecs(run.Secs), formatBytes(run.PeakHeapBytes), run.Fits)
+		if !run.Fits {
+			break
+		}
+		best = len(runs) - 1
+	}
+
+	if best < 0 {
+		fatal("Proving did not fit in its own unlimited peak heap", "peak_heap", formatBytes(unlimited.PeakHeapBytes))
+	}
+	fit := runs[best]
+
+	result := singleRun("prove_min_memory", testCaseNum, time.Duration(fit.Secs*float64(time.Second)))
+	result.Allocs = &AllocStats{PeakHeapBytes: fit.PeakHeapBytes}
+	result.MemoryLimitBytes = fit.LimitBytes
+	result.MemoryRuns = runs
+	recordResults(artifactSettings(), result)
+
+	fmt.Printf("\n✓ Proving fits in %s (%s, %.2fx the unlimited time)\n", formatBytes(fit.LimitBytes),
+		formatSecs(fit.Secs), fit.Secs/unlimited.Secs)
+}
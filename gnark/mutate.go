@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// command line flags
+	mutationBits string
+)
+
+// tagMutation is the tag of the test cases gen-mutants writes
+const tagMutation = "mutation"
+
+// generateMutants writes, for every valid P-256 test case given, or every
+// test_case_*.json in -tests, one variant per bit of -bits flipped in each
+// of r, s, msghash, pubkey_x and pubkey_y, as
+// mutant_<test case>_<field>_<bit>.json with expect_valid false, replacing
+// the mutants already there. None of them is a valid signature: each is
+// checked with crypto/ecdsa, and one that still verifies is left out. Run
+// them with negative -tag mutation, which fails if the circuit proves any,
+// as a regression suite for the soundness of the circuit.
+func generateMutants(testCaseFiles []string) {
+	bits, err := parseMutationBits(mutationBits)
+	if err != nil {
+		fatal("Invalid -bits", "error", err)
+	}
+	if len(testCaseFiles) == 0 {
+		testCaseFiles, err = filepath.Glob(filepath.Join(testsDir, circuitVariants["p256"].TestCases))
+		if err != nil {
+			fatal("Failed to find test cases", "error", err)
+		}
+		if len(testCaseFiles) == 0 {
+			fatal("No test cases found", "dir", testsDir)
+		}
+	}
+	sort.Strings(testCaseFiles)
+	stale, err := filepath.Glob(filepath.Join(testsDir, "mutant_*.json"))
+	if err != nil {
+		fatal("Failed to find test cases", "error", err)
+	}
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil {
+			fatal("Failed to remove old test case", "error", err)
+		}
+	}
+
+	written, skipped := 0, 0
+	for _, testCaseFile := range testCaseFiles {
+		testCase, err := loadTestCase(testCaseFile)
+		if err != nil {
+			fatal("Failed to load test case", "error", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(testCaseFile), ".json")
+		if valid, either := testCase.expectation(); !valid || either || curveOrDefault(testCase.Curve) != curveP256 {
+			fmt.Printf("  skipping %s: not a valid P-256 signature\n", name)
+			skipped++
+			continue
+		}
+
+		for _, field := range []struct {
+			name  string
+			value *string
+		}{
+			{"r", &testCase.R},
+			{"s", &testCase.S},
+			{"msghash", &testCase.MsgHash},
+			{"pubkey_x", &testCase.PubKeyX},
+			{"pubkey_y", &testCase.PubKeyY},
+		} {
+			for _, bit := range bits {
+				original := *field.value
+				v, _ := parseHexField(original)
+				*field.value = fmt.Sprintf("0x%x", new(big.Int).SetBit(v, bit, v.Bit(bit)^1))
+				mutant := mutantOf(testCase, filepath.Base(testCaseFile), fmt.Sprintf("bit %d of %s flipped", bit, field.name))
+				*field.value = original
+
+				if mutantVerifies(mutant) {
+					fmt.Printf("  skipping bit %d of %s in %s: still a valid signature\n", bit, field.name, name)
+					continue
+				}
+				writeTestCase(filepath.Join(testsDir, fmt.Sprintf("mutant_%s_%s_%d.json", name, field.name, bit)), mutant)
+				written++
+			}
+		}
+	}
+	slog.Info("Mutants written", "mutants", written, "test_cases", len(testCaseFiles)-skipped, "dir", testsDir)
+}
+
+// mutantOf is a test case with only the values of another, expected invalid.
+// The message and compressed key are left out, since a mutant no longer
+// matches them.
+func mutantOf(testCase *TestCase, name, description string) *TestCase {
+	invalid := false
+	return &TestCase{
+		R:           testCase.R,
+		S:           testCase.S,
+		MsgHash:     testCase.MsgHash,
+		PubKeyX:     testCase.PubKeyX,
+		PubKeyY:     testCase.PubKeyY,
+		ExpectValid: &invalid,
+		Source:      "gen-mutants of " + name,
+		Description: description,
+		Tags:        []string{tagMutation},
+	}
+}
+
+// mutantVerifies says whether a mutant is still a valid P-256 signature
+func mutantVerifies(testCase *TestCase) bool {
+	var values []*big.Int
+	for _, value := range []string{testCase.R, testCase.S, testCase.MsgHash, testCase.PubKeyX, testCase.PubKeyY} {
+		v, err := parseHexField(value)
+		if err != nil {
+			return false
+		}
+		values = append(values, v)
+	}
+	r, s, hash, x, y := values[0], values[1], values[2], values[3], values[4]
+	if !elliptic.P256().IsOnCurve(x, y) {
+		return false
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	return ecdsa.Verify(pub, hash.FillBytes(make([]byte, 32)), r, s)
+}
+
+// parseMutationBits parses -bits: comma-separated bit positions in [0, 256),
+// or all for every one
+func parseMutationBits(value string) ([]int, error) {
+	var bits []int
+	if value == "all" {
+		for bit := 0; bit < 256; bit++ {
+			bits = append(bits, bit)
+		}
+		return bits, nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		bit, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || bit < 0 || bit >= 256 {
+			return nil, fmt.Errorf("%q is not a bit position in [0, 256)", part)
+		}
+		bits = append(bits, bit)
+	}
+	return bits, nil
+}
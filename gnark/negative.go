@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/consensys/gnark/backend/witness"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// Outcomes of proving a test case in the negative run
+const (
+	outcomeProven      = "proven"
+	outcomeRejected    = "rejected"
+	outcomeUnsupported = "unsupported"
+)
+
+// expectation is whether a test case should produce a verifiable proof. Test
+// cases say so with expect_valid; without it they are expected valid, except
+// those Wycheproof marks acceptable, which may go either way.
+func (t *TestCase) expectation() (valid, either bool) {
+	if t.ExpectValid != nil {
+		return *t.ExpectValid, false
+	}
+	return true, t.Result == "acceptable"
+}
+
+// runNegative proves every test case and checks the outcome against what
+// the test case expects. A valid signature must produce a proof that
+// verifies. An invalid one must stop the prover: the witness does not solve,
+// so no proof comes out, or at worst the proof does not verify. An invalid
+// case that produces a verifiable proof means the circuit accepts a
+// signature it should not, and is reported as unsound. The test cases are
+// the given files, or every *.json in -tests, so both the generated and
+// imported ones run. Batch test cases are proven with the batched circuit,
+// compiled and set up for each size they come in, and are expected to fail
+// when any of their signatures is invalid. Test cases on a curve the circuit
+// does not verify are listed but not counted, and valid ones tagged
+// known-failure are expected not to prove. The command fails on any
+// unexpected outcome.
+func runNegative(testCaseFiles []string) {
+	resolveSettings()
+	if len(testCaseFiles) == 0 {
+		var err error
+		testCaseFiles, err = filepath.Glob(filepath.Join(testsDir, "*.json"))
+		if err != nil {
+			fatal("Failed to find test cases", "error", err)
+		}
+		if len(testCaseFiles) == 0 {
+			fatal("No test cases found", "dir", testsDir)
+		}
+	}
+	sort.Strings(testCaseFiles)
+
+	ccs := newConstraintSystem()
+	loadArtifact(filepath.Join(outputDir, circuitFileName()), ccs)
+	pk := newProvingKey()
+	loadArtifact(filepath.Join(outputDir, "proving.key"), pk)
+	vk := newVerifyingKey()
+	loadArtifact(filepath.Join(outputDir, "verifying.key"), vk)
+
+	slog.Info("Proving test cases, expecting invalid signatures to fail", "test_cases", len(testCaseFiles), "backend", provingBackend, "curve", curveName)
+	var unsound, incomplete, knownFailures []string
+	counts := map[string]int{}
+	batchCircuits := map[int]*batchCircuit{}
+	for _, testCaseFile := range testCaseFiles {
+		name := filepath.Base(testCaseFile)
+		var valid, either, known bool
+		var outcome, reason string
+		if data, err := os.ReadFile(testCaseFile); err == nil && isBatchTestCase(data) {
+			batch, err := loadBatchTestCase(testCaseFile)
+			if err != nil {
+				fatal("Failed to load test case", "test_case", name, "error", err)
+			}
+			valid, either = batch.expectation()
+			c := batchCircuits[len(batch.Signatures)]
+			if c == nil {
+				c = setupBatchCircuit(len(batch.Signatures))
+				batchCircuits[len(batch.Signatures)] = c
+			}
+			outcome, reason = proveExpecting(c.ccs, c.pk, c.vk, func() (witness.Witness, error) { return createBatchWitness(batch) })
+		} else {
+			testCase, err := loadTestCase(testCaseFile)
+			if err != nil {
+				fatal("Failed to load test case", "test_case", name, "error", err)
+			}
+			valid, either = testCase.expectation()
+			known = testCase.hasTag([]string{tagKnownFailure})
+			outcome, reason = proveExpecting(ccs, pk, vk, func() (witness.Witness, error) { return createWitness(testCase) })
+		}
+		counts[outcome]++
+
+		expected := "valid"
+		if either {
+			expected = "either"
+		} else if !valid {
+			expected = "invalid"
+		}
+		mark := "✓"
+		switch {
+		case outcome == outcomeUnsupported:
+			mark = "-"
+		case either:
+		case valid && outcome != outcomeProven && known:
+			mark = "~"
+			knownFailures = append(knownFailures, name)
+		case valid && outcome != outcomeProven:
+			mark = "✗"
+			incomplete = append(incomplete, name)
+		case !valid && outcome == outcomeProven:
+			mark = "✗"
+			unsound = append(unsound, name)
+		}
+		line := fmt.Sprintf("%s %-40s expected %-8s %s", mark, name, expected, outcome)
+		if reason != "" {
+			line += ": " + reason
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Printf("\n%d proven, %d rejected, %d unsupported\n", counts[outcomeProven], counts[outcomeRejected], counts[outcomeUnsupported])
+	for _, name := range knownFailures {
+		fmt.Printf("KNOWN FAILURE: %s is a valid signature gnark cannot prove\n", name)
+	}
+	for _, name := range unsound {
+		fmt.Printf("UNSOUND: %s is an invalid signature with a verifiable proof\n", name)
+	}
+	for _, name := range incomplete {
+		fmt.Printf("FAILED: %s is a valid signature without a verifiable proof\n", name)
+	}
+	if len(unsound) > 0 || len(incomplete) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("✓ Every test case had the expected outcome")
+}
+
+// batchCircuit is the batched circuit of one size, with its keys
+type batchCircuit struct {
+	ccs    constraint.ConstraintSystem
+	pk, vk artifact
+}
+
+// setupBatchCircuit compiles and sets up the batched circuit for a batch
+// test case, since the keys in -d are for single signatures
+func setupBatchCircuit(size int) *batchCircuit {
+	slog.Info("Compiling and setting up the batched circuit", "signatures", size)
+	circuit := BatchECDSACircuit{Signatures: make([]ECDSACircuit, size)}
+	ccs, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), &circuit)
+	if err != nil {
+		fatal("Circuit compilation failed", "error", err)
+	}
+	pk, vk, err := setupKeys(ccs)
+	if err != nil {
+		fatal("Setup failed", "error", err)
+	}
+	return &batchCircuit{ccs: ccs, pk: pk, vk: vk}
+}
+
+// proveExpecting proves a test case and verifies the proof, and says why no
+// verifiable proof came out when none did. Invalid witnesses can make the
+// solver's hints panic, which counts as a rejection like a solver error.
+func proveExpecting(ccs constraint.ConstraintSystem, pk, vk artifact, newWitness func() (witness.Witness, error)) (outcome, reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			outcome, reason = outcomeRejected, fmt.Sprint("panic: ", r)
+		}
+	}()
+
+	witness, err := newWitness()
+	if err != nil {
+		return outcomeUnsupported, err.Error()
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		fatal("Failed to create public witness", "error", err)
+	}
+	proof, _, err := proveCircuit(ccs, pk, witness)
+	if err != nil {
+		return outcomeRejected, "proving failed"
+	}
+	if err := verifyCircuit(proof, vk, publicWitness); err != nil {
+		return outcomeRejected, "proof does not verify"
+	}
+	return outcomeProven, ""
+}
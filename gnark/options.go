@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"log/slog"
+
+	"github.com/consensys/gnark/backend"
+	"github.com/consensys/gnark/constraint/solver"
+	"golang.org/x/crypto/sha3"
+)
+
+// Fiat-Shamir challenge hashes for PLONK. The same hash also folds the KZG
+// opening proofs. gnark uses sha256; the prover and verifier must agree.
+const (
+	challengeHashSHA256    = "sha256"
+	challengeHashKeccak256 = "keccak256"
+
+	defaultChallengeHash = challengeHashSHA256
+)
+
+var (
+	// command line flags
+	challengeHash string
+	solverTasks   int
+	statisticalZK bool
+)
+
+func newChallengeHash(name string) (hash.Hash, error) {
+	switch name {
+	case challengeHashSHA256:
+		return sha256.New(), nil
+	case challengeHashKeccak256:
+		return sha3.NewLegacyKeccak256(), nil
+	default:
+		return nil, fmt.Errorf("unknown challenge hash %q (use %s or %s)", name, challengeHashSHA256, challengeHashKeccak256)
+	}
+}
+
+// solverOptions returns the options passed to the constraint solver, both when
+// proving and when running the solve command
+func solverOptions() []solver.Option {
+	var opts []solver.Option
+	if solverTasks > 0 {
+		opts = append(opts, solver.WithNbTasks(solverTasks))
+	}
+	return opts
+}
+
+// proverOptions collects the gnark prover options selected on the command line
+func proverOptions() []backend.ProverOption {
+	opts := []backend.ProverOption{
+		proverHashToFieldOption(),
+		backend.WithSolverOptions(solverOptions()...),
+	}
+	if provingBackend == backendPLONK {
+		h, err := newChallengeHash(challengeHash)
+		if err != nil {
+			fatal("Invalid -challenge-hash", "error", err)
+		}
+		opts = append(opts,
+			backend.WithProverChallengeHashFunction(h),
+			backend.WithProverKZGFoldingHashFunction(h),
+		)
+		if statisticalZK {
+			opts = append(opts, backend.WithStatisticalZeroKnowledge())
+		}
+	} else if statisticalZK {
+		slog.Warn("-statistical-zk only applies to plonk, ignoring it")
+	}
+	return opts
+}
+
+// verifierOptions collects the gnark verifier options matching proverOptions
+func verifierOptions() []backend.VerifierOption {
+	opts := []backend.VerifierOption{verifierHashToFieldOption()}
+	if provingBackend == backendPLONK {
+		h, err := newChallengeHash(challengeHash)
+		if err != nil {
+			fatal("Invalid -challenge-hash", "error", err)
+		}
+		opts = append(opts,
+			backend.WithVerifierChallengeHashFunction(h),
+			backend.WithVerifierKZGFoldingHashFunction(h),
+		)
+	}
+	return opts
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// runtimeProfile is a preset of options for a common scenario, and the soft
+// memory limit it runs under, 0 for none
+type runtimeProfile struct {
+	Description      string
+	Options          map[string]string
+	MemoryLimitBytes uint64
+}
+
+// runtimeProfiles are the presets of -profile
+var runtimeProfiles = map[string]runtimeProfile{
+	"ci": {
+		Description:      "CI runner: 2 CPUs, 6 GiB, warnings only, one run per phase",
+		Options:          map[string]string{"cpus": "2", "log-level": "warn", "runs": "1", "warmup": "0"},
+		MemoryLimitBytes: 6 << 30,
+	},
+	"server": {
+		Description: "dedicated server: every CPU, 10 runs per phase after 2 warm-up runs, discarding outliers",
+		Options:     map[string]string{"runs": "10", "warmup": "2", "reject-outliers": "3.5"},
+	},
+	"mobile-sim": {
+		Description: "phone: the midrange-android device's CPU and memory limits, 3 runs per phase",
+		Options:     map[string]string{"device": "midrange-android", "runs": "3", "warmup": "1"},
+	},
+	"low-memory": {
+		Description:      "low-memory machine: 2 CPUs and a 1 GiB soft memory limit, 3 runs per phase",
+		Options:          map[string]string{"cpus": "2", "runs": "3"},
+		MemoryLimitBytes: 1 << 30,
+	},
+}
+
+var (
+	// command line flags
+	runtimeProfileName string
+)
+
+// applyRuntimeProfile sets the options of the -profile preset that the command
+// takes and neither the command line nor the config file gives, and its
+// memory limit. Like -device's, the limit is a soft one: the Go runtime
+// collects garbage harder near it rather than failing.
+func applyRuntimeProfile(fs *flag.FlagSet, command string) {
+	if runtimeProfileName == "" {
+		return
+	}
+	profile, ok := runtimeProfiles[runtimeProfileName]
+	if !ok {
+		fatal("Unknown -profile, use one of: "+strings.Join(runtimeProfileNames(), ", "), "profile", runtimeProfileName)
+	}
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[canonicalFlag(f.Name)] = true })
+	for name, value := range profile.Options {
+		if given[name] || !flagApplies(fs.Lookup(name), command) {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			fatal("Invalid option of -profile", "option", name, "profile", runtimeProfileName, "error", err)
+		}
+	}
+	if profile.MemoryLimitBytes > 0 {
+		debug.SetMemoryLimit(int64(profile.MemoryLimitBytes))
+	}
+}
+
+// runtimeProfileNames lists the presets of -profile
+func runtimeProfileNames() []string {
+	names := make([]string, 0, len(runtimeProfiles))
+	for name := range runtimeProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gnarkprofile "github.com/consensys/gnark/profile"
+	"github.com/google/pprof/profile"
+)
+
+// constraintProfileFile is the pprof profile of the constraints added by each
+// call site, written next to the compiled circuit. View it with
+// go tool pprof -top -cum <file>.
+const constraintProfileFile = "constraints.pprof"
+
+// profileTop is how many functions the compile command reports
+const profileTop = 20
+
+var (
+	// command line flags
+	profileConstraints bool
+)
+
+// ProfileEntry is the number of constraints added by a function and the
+// gadgets it calls
+type ProfileEntry struct {
+	Function    string  `json:"function"`
+	Constraints int64   `json:"constraints"`
+	Share       float64 `json:"share"`
+}
+
+// startConstraintProfile records the call site of every constraint added
+// until the returned function is called, which writes the profile and
+// summarizes it. Recording the call stacks slows compilation down.
+func startConstraintProfile() func() []ProfileEntry {
+	err := os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		fatal("Failed to create output directory", "error", err)
+	}
+	path := filepath.Join(outputDir, constraintProfileFile)
+	p := gnarkprofile.Start(gnarkprofile.WithPath(path))
+
+	return func() []ProfileEntry {
+		p.Stop()
+		entries, err := summarizeConstraintProfile(path)
+		if err != nil {
+			fatal("Failed to read constraint profile", "error", err)
+		}
+		return entries
+	}
+}
+
+// summarizeConstraintProfile ranks the functions of a constraint profile by
+// the constraints added under them. Checks that gnark defers to the end of
+// compilation, such as range check lookups and emulated multiplication checks,
+// appear under frontend.callDeferred rather than under the circuit's Define.
+// Functions every constraint goes through and the builder primitives that only
+// add constraints are left out.
+func summarizeConstraintProfile(path string) ([]ProfileEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prof, err := profile.Parse(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	cumulative := make(map[string]int64)
+	for _, sample := range prof.Sample {
+		total += sample.Value[0]
+		seen := make(map[string]bool)
+		for _, location := range sample.Location {
+			for _, line := range location.Line {
+				name := line.Function.Name
+				if !seen[name] {
+					seen[name] = true
+					cumulative[name] += sample.Value[0]
+				}
+			}
+		}
+	}
+
+	var entries []ProfileEntry
+	for name, n := range cumulative {
+		if n == total || strings.HasPrefix(name, "r1cs.") || strings.HasPrefix(name, "scs.") {
+			continue
+		}
+		entries = append(entries, ProfileEntry{
+			Function:    name,
+			Constraints: n,
+			Share:       float64(n) / float64(total),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Constraints != entries[j].Constraints {
+			return entries[i].Constraints > entries[j].Constraints
+		}
+		return entries[i].Function < entries[j].Function
+	})
+	if len(entries) > profileTop {
+		entries = entries[:profileTop]
+	}
+	return entries, nil
+}
+
+func printConstraintProfile(entries []ProfileEntry) {
+	fmt.Println()
+	fmt.Printf("%12s %7s  %s\n", "Constraints", "Share", "Function (cumulative)")
+	for _, e := range entries {
+		fmt.Printf("%12d %6.1f%%  %s\n", e.Constraints, e.Share*100, e.Function)
+	}
+	slog.Info("Constraint profile saved, read it with go tool pprof -top -cum", "file", filepath.Join(outputDir, constraintProfileFile))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	// command line flags
+	progressInterval time.Duration
+)
+
+// progress logs how far a long phase has got every -progress-interval, so
+// that minutes without output do not look like a hang: for a batch, how many
+// of its test cases are done and the time left at the pace so far, and for a
+// single phase such as setup, the time left after the last time it ran on the
+// same machine with the same settings, if it did
+type progress struct {
+	phase    string
+	total    int
+	estimate time.Duration
+	start    time.Time
+
+	mu     sync.Mutex
+	done   int
+	doneAt time.Duration
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// startProgress starts logging the progress of a batch of total test cases
+func startProgress(phase string, total int) *progress {
+	return startProgressEstimate(phase, total, 0)
+}
+
+// startPhaseProgress starts logging the progress of a single phase, using its
+// last recorded duration as the estimate
+func startPhaseProgress(phase string) *progress {
+	return startProgressEstimate(phase, 0, previousPhaseTime(phase))
+}
+
+func startProgressEstimate(phase string, total int, estimate time.Duration) *progress {
+	p := &progress{
+		phase:    phase,
+		total:    total,
+		estimate: estimate,
+		start:    time.Now(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if progressInterval <= 0 {
+		close(p.stopped)
+		return p
+	}
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.log()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// update sets how many test cases of the batch are done, whether they
+// succeeded or not
+func (p *progress) update(done int) {
+	p.mu.Lock()
+	if done != p.done {
+		p.done, p.doneAt = done, time.Since(p.start)
+	}
+	p.mu.Unlock()
+}
+
+// finish stops logging the progress
+func (p *progress) finish() {
+	select {
+	case <-p.stopped:
+	default:
+		close(p.stop)
+		<-p.stopped
+	}
+}
+
+func (p *progress) log() {
+	p.mu.Lock()
+	done, doneAt := p.done, p.doneAt
+	p.mu.Unlock()
+
+	elapsed := time.Since(p.start)
+	attrs := []any{"phase", p.phase}
+	var left time.Duration
+	if p.total > 0 {
+		attrs = append(attrs, "done", done, "total", p.total)
+		if done > 0 {
+			left = doneAt/time.Duration(done)*time.Duration(p.total-done) - (elapsed - doneAt)
+		}
+	} else if p.estimate > elapsed {
+		left = p.estimate - elapsed
+	}
+	attrs = append(attrs, "elapsed", elapsed.Round(time.Second).String())
+	if left > 0 {
+		attrs = append(attrs, "eta", left.Round(time.Second).String())
+	}
+	slog.Info("Progress", attrs...)
+}
+
+// previousPhaseTime is the mean time of a phase recorded in the results of
+// the output directory on this machine with the current settings, 0 if there
+// is none
+func previousPhaseTime(phase string) time.Duration {
+	data, err := os.ReadFile(filepath.Join(outputDir, "benchmarks", resultsFile))
+	if err != nil {
+		return 0
+	}
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil || results.Settings != currentSettings() {
+		return 0
+	}
+	if !results.Environment.sameMachine(currentEnvironment()) {
+		return 0
+	}
+	for _, m := range results.Measurements {
+		if m.Phase == phase && m.TestCase == "" {
+			return time.Duration(m.MeanSecs * float64(time.Second))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+var (
+	// command line flags
+	proverStages bool
+)
+
+// proverStage matches the functions of one stage of the prover. gnark does not
+// time its stages, so they are told apart by the functions in a CPU profile.
+type proverStage struct {
+	name      string
+	functions *regexp.Regexp
+}
+
+// proverStageFunctions lists the stages a proof is split into. Functions outside
+// all of them, such as the commitments' hashing and the polynomial arithmetic
+// between FFTs, count as "other".
+var proverStageFunctions = []proverStage{
+	// gnark-crypto's multi-scalar multiplications, including the goroutines
+	// that process each chunk of scalars
+	{"msm", regexp.MustCompile(`(?i)multiexp|\.msm|processChunk`)},
+	{"fft", regexp.MustCompile(`/fft\.`)},
+	// The constraint solver and the hints it calls, such as the emulated
+	// arithmetic's
+	{"solve", regexp.MustCompile(`github\.com/consensys/gnark/(constraint|std)/`)},
+	{"gc", regexp.MustCompile(`^runtime\.(gc|bgsweep|bgscavenge|markroot|scanobject)`)},
+}
+
+// StageTime is the share of a proof's CPU time spent in one stage. EstimatedSecs
+// scales the proving time by the share, which assumes every stage makes the
+// same use of the CPUs.
+type StageTime struct {
+	Stage         string  `json:"stage"`
+	CPUSecs       float64 `json:"cpu_secs"`
+	Share         float64 `json:"share"`
+	EstimatedSecs float64 `json:"estimated_secs"`
+}
+
+// proverStageBreakdown attributes the samples of a proving CPU profile to the
+// stages of the prover. Each sample goes to the innermost function on its
+// stack that belongs to a stage, so an FFT called while committing counts as
+// FFT. It returns nil, with a warning, when the profile holds no samples.
+func proverStageBreakdown(profilePath string, provingTime time.Duration) []StageTime {
+	f, err := os.Open(profilePath)
+	if err != nil {
+		fatal("Failed to open CPU profile", "error", err)
+	}
+	defer f.Close()
+	prof, err := profile.Parse(f)
+	if err != nil {
+		fatal("Failed to read CPU profile", "error", err)
+	}
+
+	// Go CPU profiles hold the sample count, then the CPU time in nanoseconds
+	valueIndex := len(prof.SampleType) - 1
+	cpuNanos := make(map[string]int64)
+	var total int64
+	for _, sample := range prof.Sample {
+		stage := "other"
+	stack:
+		for _, location := range sample.Location {
+			for _, line := range location.Line {
+				for _, s := range proverStageFunctions {
+					if s.functions.MatchString(line.Function.Name) {
+						stage = s.name
+						break stack
+					}
+				}
+			}
+		}
+		cpuNanos[stage] += sample.Value[valueIndex]
+		total += sample.Value[valueIndex]
+	}
+	if total == 0 {
+		slog.Warn("No samples in the profile, proving was too short to split into stages", "file", profilePath)
+		return nil
+	}
+
+	var stages []StageTime
+	for _, s := range append(proverStageFunctions, proverStage{name: "other"}) {
+		share := float64(cpuNanos[s.name]) / float64(total)
+		stages = append(stages, StageTime{
+			Stage:         s.name,
+			CPUSecs:       float64(cpuNanos[s.name]) / 1e9,
+			Share:         share,
+			EstimatedSecs: share * provingTime.Seconds(),
+		})
+	}
+	return stages
+}
+
+func printProverStages(stages []StageTime) {
+	fmt.Printf("  %-8s %10s %7s %10s\n", "Stage", "CPU time", "Share", "≈ Time")
+	for _, s := range stages {
+		fmt.Printf("  %-8s %10s %6.1f%% %10s\n", s.Stage, formatSecs(s.CPUSecs), s.Share*100, formatSecs(s.EstimatedSecs))
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+// proveAndVerify is prove-and-verify: it loads the circuit and both keys once,
+// then proves each test case, verifies the proof straight away and writes it
+// to -d, recording the witness, proving and verification times and the proof
+// size in the results. A test case whose proof fails to verify counts as
+// failed, as in a batch, so the exit code is that of a batch even for one.
+func proveAndVerify(ctx context.Context, testFiles []string) {
+	resolveSettings()
+	startMetricsServer()
+
+	checkArtifacts(circuitFileName(), "proving.key", "verifying.key")
+	var loadIO IOStats
+	start := time.Now()
+	ccs, pk := loadProvingArtifacts(ctx, &loadIO)
+	vk := newVerifyingKey()
+	if err := readArtifact(filepath.Join(outputDir, "verifying.key"), vk, &loadIO); err != nil {
+		fatal("Failed to read verifying key", "dir", outputDir, "error", err)
+	}
+	loadResult := singleRun("load", "", time.Since(start))
+	loadResult.IO = &loadIO
+	recordResults(artifactSettings(), loadResult)
+
+	progress := startProgress("prove-and-verify", len(testFiles))
+	var proveTotal, verifyTotal time.Duration
+	var proofBytes int64
+	successCount := 0
+	attempted := 0
+	var abort error
+	for _, testFile := range testFiles {
+		if failFast && attempted > successCount {
+			break
+		}
+		progress.update(attempted)
+		attempted++
+		testCaseNum := testCaseID(testFile)
+
+		results, err := proveAndVerifyOne(ctx, ccs, pk, vk, testFile)
+		if aborted(err) {
+			abort = err
+			break
+		}
+		if err != nil {
+			slog.Error("Failed to prove and verify", "test_case", testCaseNum, "error", err)
+			continue
+		}
+		recordResults(artifactSettings(), results...)
+
+		prove, verify := results[1], results[2]
+		slog.Info("Proof generated and verified", "test_case", testCaseNum, "prove_secs", prove.MeanSecs, "verify_secs", verify.MeanSecs, "proof_bytes", prove.ProofBytes, "prover", prove.Hardware)
+		proveTotal += time.Duration(prove.MeanSecs * float64(time.Second))
+		verifyTotal += time.Duration(verify.MeanSecs * float64(time.Second))
+		proofBytes = prove.ProofBytes
+		successCount++
+	}
+	progress.finish()
+
+	attrs := []any{"verified", successCount, "failed", attempted - successCount, "test_cases", len(testFiles), "load_secs", loadResult.MeanSecs}
+	if successCount > 0 {
+		n := time.Duration(successCount)
+		attrs = append(attrs, "mean_prove_secs", (proveTotal / n).Seconds(), "mean_verify_secs", (verifyTotal / n).Seconds(), "proof_bytes", proofBytes)
+	}
+	slog.Info("Prove and verify completed", attrs...)
+	finishBatch(successCount, attempted, len(testFiles), abort)
+}
+
+// proveAndVerifyOne proves a test case, verifies its proof and writes it, and
+// returns the witness, prove and verify measurements. An aborted phase is
+// recorded and its error returned.
+func proveAndVerifyOne(ctx context.Context, ccs constraint.ConstraintSystem, pk, vk artifact, testFile string) ([]Measurement, error) {
+	testCaseNum := testCaseID(testFile)
+	testCase, err := loadTestCase(testFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load test case: %w", err)
+	}
+
+	start := time.Now()
+	fullWitness, err := createWitness(testCase)
+	witnessTime := time.Since(start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create witness: %w", err)
+	}
+	publicWitness, err := fullWitness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create public witness: %w", err)
+	}
+
+	var proof artifact
+	var proverBackend string
+	var gpuFailed time.Duration
+	start = time.Now()
+	err = runPhase(ctx, func() (err error) {
+		proof, proverBackend, gpuFailed, err = proveCircuitTimed(ccs, pk, fullWitness)
+		return err
+	})
+	provingTime := time.Since(start) - gpuFailed
+	if aborted(err) {
+		recordAbort("prove", testCaseNum, start, err)
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate proof: %w", err)
+	}
+	observePhase("prove", provingTime)
+	proofsCompleted.WithLabelValues(proverBackend).Inc()
+
+	start = time.Now()
+	err = runPhase(ctx, func() error { return verifyCircuit(proof, vk, publicWitness) })
+	verifyTime := time.Since(start)
+	if aborted(err) {
+		recordAbort("verify", testCaseNum, start, err)
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("proof verification failed: %w", err)
+	}
+	observePhase("verify", verifyTime)
+	verificationsCompleted.Inc()
+
+	var proofIO IOStats
+	if err := writeArtifact(filepath.Join(outputDir, proofFileName(testCaseNum)), proof, &proofIO); err != nil {
+		return nil, fmt.Errorf("failed to write proof: %w", err)
+	}
+
+	proveResult := singleRun("prove", testCaseNum, provingTime)
+	proveResult.IO = &proofIO
+	proveResult.Hardware = proverBackend
+	proveResult.ProofBytes = proofSize(proof)
+	proveResult.ProofRawBytes = rawSize(proof)
+	return []Measurement{
+		singleRun("witness", testCaseNum, witnessTime),
+		proveResult,
+		singleRun("verify", testCaseNum, verifyTime),
+	}, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fp"
+)
+
+// compressedKeyLen is the length of a SEC 1 compressed public key: a 0x02 or
+// 0x03 prefix for the parity of y, then the 32 bytes of x
+const compressedKeyLen = 33
+
+// decompressPubKey recovers the coordinates of a SEC 1 compressed public key
+// on the curve of a test case
+func decompressPubKey(curve, compressed string) (x, y *big.Int, err error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(compressed, "0x"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("not hex: %v", err)
+	}
+	if len(data) != compressedKeyLen {
+		return nil, nil, fmt.Errorf("%d bytes, not the %d of a compressed key", len(data), compressedKeyLen)
+	}
+	if data[0] != 2 && data[0] != 3 {
+		return nil, nil, fmt.Errorf("prefix 0x%02x is not 0x02 or 0x03", data[0])
+	}
+
+	switch curve {
+	case "", curveP256:
+		x, y = elliptic.UnmarshalCompressed(elliptic.P256(), data)
+		if x == nil {
+			return nil, nil, fmt.Errorf("not a point of %s", curveP256)
+		}
+		return x, y, nil
+	case curveSecp256k1:
+		// y² = x³ + 7, with the root of the parity of the prefix
+		p := fp.Modulus()
+		x = new(big.Int).SetBytes(data[1:])
+		if x.Cmp(p) >= 0 {
+			return nil, nil, fmt.Errorf("x is not below the field modulus")
+		}
+		rhs := new(big.Int).Exp(x, big.NewInt(3), p)
+		rhs.Add(rhs, big.NewInt(7)).Mod(rhs, p)
+		y = new(big.Int).ModSqrt(rhs, p)
+		if y == nil {
+			return nil, nil, fmt.Errorf("not a point of %s", curveSecp256k1)
+		}
+		if y.Bit(0) != uint(data[0]&1) {
+			y.Sub(p, y)
+		}
+		return x, y, nil
+	default:
+		return nil, nil, fmt.Errorf("cannot decompress a key of curve %q", curve)
+	}
+}
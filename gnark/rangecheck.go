@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+// Range checks used by the emulated P-256 arithmetic. gnark checks limbs with
+// a log-derivative lookup argument when the builder can commit to variables,
+// and falls back to decomposing them into bits otherwise.
+const (
+	rangeCheckLookup    = "lookup"
+	rangeCheckDecompose = "decompose"
+
+	defaultRangeCheck = rangeCheckLookup
+)
+
+var (
+	// command line flags
+	rangeCheck string
+)
+
+func validateRangeCheck(name string) error {
+	switch name {
+	case rangeCheckLookup, rangeCheckDecompose:
+		return nil
+	default:
+		return fmt.Errorf("unknown range check %q (use %s or %s)", name, rangeCheckLookup, rangeCheckDecompose)
+	}
+}
+
+// withRangeCheck wraps a builder so that it produces the range checks selected
+// with -range-check
+func withRangeCheck(newBuilder frontend.NewBuilder) frontend.NewBuilder {
+	if rangeCheck != rangeCheckDecompose {
+		return newBuilder
+	}
+	return func(field *big.Int, config frontend.CompileConfig) (frontend.Builder, error) {
+		builder, err := newBuilder(field, config)
+		if err != nil {
+			return nil, err
+		}
+		return decomposingBuilder{builder}, nil
+	}
+}
+
+// decomposingBuilder hides the builder's commitment support from the range
+// checker, which then decomposes values into bits instead of using lookups.
+type decomposingBuilder struct {
+	frontend.Builder
+}
+
+// keyValueStore matches gnark's internal builder key-value store
+type keyValueStore interface {
+	SetKeyValue(key, value any)
+	GetKeyValue(key any) any
+}
+
+// SetKeyValue and GetKeyValue keep the builder's key-value store visible, so
+// emulated fields are still shared across gadgets.
+func (b decomposingBuilder) SetKeyValue(key, value any) {
+	b.Builder.(keyValueStore).SetKeyValue(key, value)
+}
+
+func (b decomposingBuilder) GetKeyValue(key any) any {
+	return b.Builder.(keyValueStore).GetKeyValue(key)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// command line flags
+	baselineFile    string
+	regressionLimit string
+)
+
+// Baseline is a stored copy of the results to compare later benchmarks
+// against. It is a results file with the verifier gas measured alongside, so a
+// plain results.json also works as a baseline.
+type Baseline struct {
+	Results
+	VerifierGas map[string]int64 `json:"verifier_gas,omitempty"`
+}
+
+// comparison is one metric measured in both the baseline and the current results
+type comparison struct {
+	metric            string
+	baseline, current float64
+	format            func(float64) string
+}
+
+// change is the relative increase of the metric over the baseline
+func (c comparison) change() float64 {
+	if c.baseline == 0 {
+		return 0
+	}
+	return (c.current - c.baseline) / c.baseline
+}
+
+// compareBaseline compares the results in the output directory with
+// -baseline. When the baseline does not exist yet, the results are stored as
+// the baseline instead. With -fail-on-regression, any proving time, memory,
+// constraint count, or verifier gas that grew by more than the threshold is a
+// fatal error.
+func compareBaseline() {
+	var limit float64
+	if regressionLimit != "" {
+		var err error
+		limit, err = parseRegressionLimit(regressionLimit)
+		if err != nil {
+			fatal("Invalid -fail-on-regression", "error", err)
+		}
+	}
+
+	currentPath := filepath.Join(outputDir, "benchmarks", resultsFile)
+	data, err := os.ReadFile(currentPath)
+	if err != nil {
+		fatal("Failed to read results", "file", currentPath, "error", err)
+	}
+	var current Results
+	if err := json.Unmarshal(data, &current); err != nil {
+		fatal("Invalid results file", "file", currentPath, "error", err)
+	}
+	currentGas := verifierGas(current, currentPath)
+
+	data, err = os.ReadFile(baselineFile)
+	if os.IsNotExist(err) {
+		data, err := json.MarshalIndent(Baseline{Results: current, VerifierGas: currentGas}, "", "  ")
+		if err != nil {
+			fatal("Failed to encode baseline", "error", err)
+		}
+		err = os.WriteFile(baselineFile, append(data, '\n'), 0644)
+		if err != nil {
+			fatal("Failed to write baseline", "file", baselineFile, "error", err)
+		}
+		slog.Info("No baseline yet, saved these results as the baseline", "file", baselineFile)
+		return
+	}
+	if err != nil {
+		fatal("Failed to read baseline", "file", baselineFile, "error", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		fatal("Invalid baseline", "file", baselineFile, "error", err)
+	}
+	if baseline.SchemaVersion != resultsSchemaVersion {
+		fatal("Baseline of another results schema version", "file", baselineFile, "schema_version", baseline.SchemaVersion, "supported", resultsSchemaVersion)
+	}
+	if baseline.Settings != current.Settings {
+		slog.Warn("Baseline measured with other settings", "file", baselineFile,
+			"backend", baseline.Settings.Backend, "curve", baseline.Settings.Curve, "range_check", baseline.Settings.RangeCheck)
+	}
+	if !baseline.Environment.sameMachine(current.Environment) {
+		slog.Warn("Baseline measured on another machine", "file", baselineFile,
+			"machine", baseline.Environment.describe(), "this_machine", current.Environment.describe())
+	}
+	if baseline.VerifierGas == nil {
+		baseline.VerifierGas = verifierGas(baseline.Results, baselineFile)
+	}
+
+	comparisons := compareResults(baseline, Baseline{Results: current, VerifierGas: currentGas})
+	if len(comparisons) == 0 {
+		slog.Warn("No metrics in common with the baseline", "file", baselineFile)
+		return
+	}
+
+	fmt.Printf("\nCompared with the baseline %s:\n\n", baselineFile)
+	fmt.Printf("%-28s %14s %14s %9s\n", "Metric", "Baseline", "Current", "Change")
+	regressions := 0
+	for _, c := range comparisons {
+		marker := ""
+		if regressionLimit != "" && c.change() > limit {
+			marker = "  REGRESSION"
+			regressions++
+		}
+		fmt.Printf("%-28s %14s %14s %+8.1f%%%s\n", c.metric, c.format(c.baseline), c.format(c.current), c.change()*100, marker)
+	}
+
+	if regressions > 0 {
+		fatal("Metrics regressed by more than -fail-on-regression", "regressions", regressions, "limit", regressionLimit, "baseline", baselineFile)
+	}
+}
+
+// compareResults pairs up the metrics guarded against regressions: the mean
+// proving times, the memory of phases that track allocations, the constraint
+// count, and the verifier gas of each test case
+func compareResults(baseline, current Baseline) []comparison {
+	formatBytesFloat := func(v float64) string { return formatBytes(uint64(v)) }
+	formatCount := func(v float64) string { return strconv.FormatInt(int64(v), 10) }
+
+	var comparisons []comparison
+	for _, pair := range pairMeasurements(baseline.Measurements, current.Measurements) {
+		b, m := pair.old, pair.new
+		name := m.label()
+		if m.Phase == "prove" || m.Phase == "prove_cold" {
+			comparisons = append(comparisons, comparison{name + " time", b.MeanSecs, m.MeanSecs, formatSecs})
+		}
+		if m.Allocs != nil && b.Allocs != nil && m.Allocs.PeakHeapBytes > 0 && b.Allocs.PeakHeapBytes > 0 {
+			comparisons = append(comparisons,
+				comparison{name + " peak heap", float64(b.Allocs.PeakHeapBytes), float64(m.Allocs.PeakHeapBytes), formatBytesFloat},
+				comparison{name + " allocated", float64(b.Allocs.TotalAllocBytes), float64(m.Allocs.TotalAllocBytes), formatBytesFloat})
+		}
+		if m.Constraints > 0 && b.Constraints > 0 {
+			comparisons = append(comparisons, comparison{"constraints", float64(b.Constraints), float64(m.Constraints), formatCount})
+		}
+	}
+
+	for _, testCase := range gasTestCases(current.VerifierGas) {
+		if gas, ok := baseline.VerifierGas[testCase]; ok {
+			comparisons = append(comparisons, comparison{"verifier gas " + testCase, float64(gas), float64(current.VerifierGas[testCase]), formatCount})
+		}
+	}
+	return comparisons
+}
+
+// gasTestCases is the test cases of verifier gas measurements, in numeric
+// order
+func gasTestCases(gas map[string]int64) []string {
+	testCases := make([]string, 0, len(gas))
+	for testCase := range gas {
+		testCases = append(testCases, testCase)
+	}
+	sort.Slice(testCases, func(i, j int) bool {
+		a, _ := strconv.Atoi(testCases[i])
+		b, _ := strconv.Atoi(testCases[j])
+		return a < b
+	})
+	return testCases
+}
+
+// parseRegressionLimit reads a threshold such as 10% or 10 as a fraction
+func parseRegressionLimit(value string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent < 0 {
+		return 0, fmt.Errorf("%q is not a percentage", value)
+	}
+	return percent / 100, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestParseRegressionLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"10%", 0.1, false},
+		{"10", 0.1, false},
+		{" 5.5% ", 0.055, false},
+		{"0", 0, false},
+		{"150%", 1.5, false},
+		{"-1%", 0, true},
+		{"ten", 0, true},
+		{"%", 0, true},
+		{"", 0, true},
+		{"10%%", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRegressionLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRegressionLimit(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !approxEqual(got, tt.want) {
+			t.Errorf("parseRegressionLimit(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
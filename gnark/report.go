@@ -0,0 +1,556 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GasResults is the gas report written by scripts/benchmark-gas.sh
+type GasResults struct {
+	Results []struct {
+		TestCase int   `json:"test_case"`
+		Mean     int64 `json:"mean"`
+	} `json:"results"`
+}
+
+// reportSection is one results file with the gas used by its verifier
+type reportSection struct {
+	results Results
+	gas     map[string]int64
+}
+
+// printReport renders results files as Markdown, with an overview comparing
+// their configurations followed by the measurements of each, or with -html as
+// a dashboard. Files default to the results in the output directory. Verifier gas is taken from the
+// results when the gas command recorded it, or else read from the gas-reports directory next to
+// each file's benchmarks directory, when present.
+func printReport(resultsFiles []string) {
+	if len(resultsFiles) == 0 {
+		resultsFiles = []string{filepath.Join(outputDir, "benchmarks", resultsFile)}
+	}
+
+	var sections []reportSection
+	for _, path := range resultsFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fatal("Failed to read results", "error", err)
+		}
+		var results Results
+		if err := json.Unmarshal(data, &results); err != nil {
+			fatal("Invalid results file", "file", path, "error", err)
+		}
+		if results.SchemaVersion != resultsSchemaVersion {
+			fatal("Unsupported results schema version", "file", path, "version", results.SchemaVersion, "supported", resultsSchemaVersion)
+		}
+		sections = append(sections, reportSection{results: results, gas: verifierGas(results, path)})
+	}
+	if reportHTML {
+		printDashboard(sections)
+		return
+	}
+
+	fmt.Println("# ECDSA benchmark report")
+	fmt.Println()
+	fmt.Println("| Configuration | Constraints | Setup | Prove | Verify | Proving key | Verifying key | Proof size | Verifier gas |")
+	fmt.Println("|---|---:|---:|---:|---:|---:|---:|---:|---:|")
+	for _, s := range sections {
+		constraints := "-"
+		if m := s.find("compile", ""); m != nil && m.Constraints > 0 {
+			constraints = fmt.Sprint(m.Constraints)
+		}
+		setup, pkSize, vkSize := "-", "-", "-"
+		if m := s.find("setup", ""); m != nil {
+			setup = formatSecs(m.MeanSecs)
+			pkSize = formatSize(m.ProvingKeyBytes)
+			vkSize = formatSize(m.VerifyingKeyBytes)
+		}
+		fmt.Printf("| %s | %s | %s | %s | %s | %s | %s | %s | %s |\n", s.title(), constraints, setup,
+			s.meanOver("prove"), s.meanOver("verify"), pkSize, vkSize, s.proofSize(), s.meanGas())
+	}
+	for _, s := range sections[1:] {
+		if !s.results.Environment.sameMachine(sections[0].results.Environment) {
+			fmt.Println()
+			fmt.Println("> **Note:** these configurations were measured on different machines, so their timings do not compare directly. See the machine of each below.")
+			break
+		}
+	}
+
+	// GC pressure, averaged over the test cases
+	var gcPressure bool
+	for _, s := range sections {
+		if _, ok := s.allocsPerOp("prove"); ok {
+			gcPressure = true
+		}
+	}
+	if gcPressure {
+		fmt.Println()
+		fmt.Println("| Configuration | Witness bytes/op | Witness allocs/op | Prove bytes/op | Prove allocs/op | GCs per proof | Verify bytes/op | Verify allocs/op |")
+		fmt.Println("|---|---:|---:|---:|---:|---:|---:|---:|")
+		for _, s := range sections {
+			cells := make([]string, 0, 7)
+			for _, phase := range []string{"witness", "prove", "verify"} {
+				a, ok := s.allocsPerOp(phase)
+				if !ok {
+					cells = append(cells, "-", "-")
+				} else {
+					cells = append(cells, formatBytes(a.BytesPerOp), fmt.Sprint(a.AllocsPerOp))
+				}
+				if phase == "prove" {
+					if ok {
+						cells = append(cells, fmt.Sprintf("%.1f", a.GCPerOp))
+					} else {
+						cells = append(cells, "-")
+					}
+				}
+			}
+			fmt.Printf("| %s | %s |\n", s.title(), strings.Join(cells, " | "))
+		}
+	}
+
+	// What verifying a proof costs off-chain, in Go, and on-chain
+	var onChain bool
+	for _, s := range sections {
+		if _, ok := s.calldataBytes(); ok || len(s.gas) > 0 {
+			onChain = true
+		}
+	}
+	if onChain {
+		fmt.Println()
+		fmt.Println("## Verification cost, off-chain and on-chain")
+		fmt.Println()
+		fmt.Println("| Configuration | Verify (Go) | Verifier gas | Calldata | Proof size |")
+		fmt.Println("|---|---:|---:|---:|---:|")
+		for _, s := range sections {
+			calldata := "-"
+			if n, ok := s.calldataBytes(); ok {
+				calldata = fmt.Sprintf("%d B", n)
+			}
+			fmt.Printf("| %s | %s | %s | %s | %s |\n", s.title(), s.meanOver("verify"), s.meanGas(), calldata, s.proofSize())
+		}
+		fmt.Println()
+		fmt.Println("Calldata is the size of the call to the Solidity verifier's `verifyProof`: the proof as the verifier takes it, and the public inputs. Configurations without calldata have no Solidity verifier.")
+	}
+
+	// What the calldata costs, apart from executing the verifier, for
+	// comparing data costs on rollups
+	var calldataCosts bool
+	for _, s := range sections {
+		if _, ok := s.calldataCost(); ok {
+			calldataCosts = true
+		}
+	}
+	if calldataCosts {
+		fmt.Println()
+		fmt.Println("## Calldata cost")
+		fmt.Println()
+		fmt.Println("| Configuration | Calldata | Zero bytes | Calldata gas | Floor gas (EIP-7623) | Blob gas | Share of a blob |")
+		fmt.Println("|---|---:|---:|---:|---:|---:|---:|")
+		for _, s := range sections {
+			c, ok := s.calldataCost()
+			if !ok {
+				fmt.Printf("| %s | - | - | - | - | - | - |\n", s.title())
+				continue
+			}
+			fmt.Printf("| %s | %d B | %d | %d | %d | %d | %.2f%% |\n", s.title(), c.Bytes, c.ZeroBytes, c.Gas, c.FloorGas, c.BlobGas, 100*c.BlobShare)
+		}
+		fmt.Println()
+		fmt.Println("Calldata gas is charged under EIP-2028, 4 gas per zero byte and 16 per other byte, on top of the verifier gas. A transaction carrying little else pays at least the EIP-7623 floor. Blob gas is the cost of posting the same bytes in an EIP-4844 blob instead, priced in its own market.")
+	}
+
+	for _, s := range sections {
+		r := s.results
+		fmt.Println()
+		fmt.Printf("## %s\n", s.title())
+		fmt.Println()
+		fmt.Printf("%s %s, hash-to-field %s", r.Stack, r.Settings.GnarkVersion, r.Settings.HashToField)
+		if r.Settings.SRS != "" {
+			fmt.Printf(", %s SRS", r.Settings.SRS)
+		}
+		fmt.Printf(". Measured with %s", r.Environment.GoVersion)
+		if r.Environment.GnarkVersion != "" && r.Environment.GnarkVersion != r.Settings.GnarkVersion {
+			fmt.Printf(" and gnark %s", r.Environment.GnarkVersion)
+		}
+		fmt.Printf(" on %s.\n", r.Environment.describe())
+		if m := s.find("compile", ""); m != nil && m.InternalVariables > 0 {
+			fmt.Println()
+			fmt.Printf("The circuit compiles in %s to %d constraints over %d public, %d secret and %d internal variables, with %d distinct coefficients.\n",
+				formatSecs(m.MeanSecs), m.Constraints, m.PublicVariables, m.SecretVariables, m.InternalVariables, m.Coefficients)
+		}
+		fmt.Println()
+		fmt.Println("| Phase | Test case | Runs | Mean | Median | Stddev | Min | Max | P95 | Peak heap | Bytes/op | Allocs/op |")
+		fmt.Println("|---|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|")
+		var sweep, limited []Measurement
+		for _, m := range r.Measurements {
+			if m.Threads > 0 {
+				sweep = append(sweep, m)
+				continue
+			}
+			if m.CPULimit != "" || m.Device != "" {
+				limited = append(limited, m)
+				continue
+			}
+			testCase := m.TestCase
+			if testCase == "" {
+				testCase = "-"
+			}
+			peakHeap, bytesPerOp, allocsPerOp := "-", "-", "-"
+			if m.Allocs != nil {
+				peakHeap = formatSize(int64(m.Allocs.PeakHeapBytes))
+				if m.Allocs.AllocsPerOp > 0 {
+					bytesPerOp = formatBytes(m.Allocs.BytesPerOp)
+					allocsPerOp = fmt.Sprint(m.Allocs.AllocsPerOp)
+				}
+			}
+			fmt.Printf("| %s | %s | %d | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n", m.Phase, testCase, m.Runs,
+				formatSecs(m.MeanSecs), formatSecs(m.MedianSecs), formatSecs(m.StdDevSecs),
+				formatSecs(m.MinSecs), formatSecs(m.MaxSecs), formatSecs(m.P95Secs), peakHeap, bytesPerOp, allocsPerOp)
+		}
+
+		if len(sweep) > 0 {
+			fmt.Println()
+			fmt.Println("| Phase | Test case | Threads | Mean | Speedup | Efficiency |")
+			fmt.Println("|---|---|---:|---:|---:|---:|")
+			for _, m := range sweep {
+				fmt.Printf("| %s | %s | %d | %s | %.2fx | %.0f%% |\n", m.Phase, m.TestCase, m.Threads,
+					formatSecs(m.MeanSecs), m.Speedup, m.Efficiency*100)
+			}
+		}
+
+		if len(limited) > 0 {
+			fmt.Println()
+			fmt.Println("| Phase | Test case | CPU limit | Runs | Mean | P95 | Slowdown |")
+			fmt.Println("|---|---|---|---:|---:|---:|---:|")
+			for _, m := range limited {
+				testCase := m.TestCase
+				if testCase == "" {
+					testCase = "-"
+				}
+				slowdown := "-"
+				if full := s.find(m.Phase, m.TestCase); full != nil && full.MeanSecs > 0 {
+					slowdown = fmt.Sprintf("%.2fx", m.MeanSecs/full.MeanSecs)
+				}
+				fmt.Printf("| %s | %s | %s | %d | %s | %s | %s |\n", m.Phase, testCase, m.cpuBudget(), m.Runs,
+					formatSecs(m.MeanSecs), formatSecs(m.P95Secs), slowdown)
+			}
+		}
+
+		for _, m := range r.Measurements {
+			if m.MemoryLimitBytes > 0 {
+				fmt.Println()
+				fmt.Printf("Proving test case %s fits in a memory limit of %s (peak heap %s, %s).\n", m.TestCase,
+					formatBytes(m.MemoryLimitBytes), formatBytes(m.Allocs.PeakHeapBytes), formatSecs(m.MeanSecs))
+			}
+			if m.Workers > 0 {
+				fmt.Println()
+				fmt.Printf("%d concurrent provers complete %.3f proofs/s, with a mean latency of %s and %.0f%% CPU utilization.\n",
+					m.Workers, m.ProofsPerSec, formatSecs(m.MeanSecs), m.CPUUtilization*100)
+			}
+		}
+
+		var withIO []Measurement
+		for _, m := range r.Measurements {
+			if m.IO != nil {
+				withIO = append(withIO, m)
+			}
+		}
+		if len(withIO) > 0 {
+			fmt.Println()
+			fmt.Println("| Phase | Test case | Reading | Decoding | Writing | Read | Written |")
+			fmt.Println("|---|---|---:|---:|---:|---:|---:|")
+			for _, m := range withIO {
+				reading, decoding, writing := "-", "-", "-"
+				if m.IO.ReadBytes > 0 {
+					reading = formatSecs(m.IO.ReadSecs)
+					decoding = formatSecs(m.MeanSecs - m.IO.ReadSecs)
+				}
+				if m.IO.WriteBytes > 0 {
+					writing = formatSecs(m.IO.WriteSecs)
+				}
+				fmt.Printf("| %s | %s | %s | %s | %s | %s | %s |\n", m.Phase, m.TestCase, reading, decoding, writing,
+					formatSize(m.IO.ReadBytes), formatSize(m.IO.WriteBytes))
+			}
+		}
+
+		for _, m := range r.Measurements {
+			if len(m.ProverStages) == 0 {
+				continue
+			}
+			fmt.Println()
+			fmt.Printf("Proving test case %s, by stage of the prover:\n\n", m.TestCase)
+			fmt.Println("| Stage | CPU time | Share | ≈ Time |")
+			fmt.Println("|---|---:|---:|---:|")
+			for _, st := range m.ProverStages {
+				fmt.Printf("| %s | %s | %.1f%% | %s |\n", st.Stage, formatSecs(st.CPUSecs), st.Share*100, formatSecs(st.EstimatedSecs))
+			}
+		}
+
+		if m := s.find("compile", ""); m != nil && len(m.Profile) > 0 {
+			fmt.Println()
+			fmt.Println("| Function (cumulative) | Constraints | Share |")
+			fmt.Println("|---|---:|---:|")
+			for _, e := range m.Profile {
+				fmt.Printf("| `%s` | %d | %.1f%% |\n", e.Function, e.Constraints, e.Share*100)
+			}
+		}
+
+		compile, setup := s.find("compile", ""), s.find("setup", "")
+		if compile != nil && compile.CircuitBytes > 0 || setup != nil && setup.ProvingKeyBytes > 0 {
+			fmt.Println()
+			fmt.Println("| Artifact | Compressed | Uncompressed |")
+			fmt.Println("|---|---:|---:|")
+			if compile != nil && compile.CircuitBytes > 0 {
+				fmt.Printf("| Circuit | %s | - |\n", formatSize(compile.CircuitBytes))
+			}
+			if setup != nil && setup.ProvingKeyBytes > 0 {
+				fmt.Printf("| Proving key | %s | %s |\n", formatSize(setup.ProvingKeyBytes), formatSize(setup.ProvingKeyRawBytes))
+				fmt.Printf("| Verifying key | %s | %s |\n", formatSize(setup.VerifyingKeyBytes), formatSize(setup.VerifyingKeyRawBytes))
+			}
+		}
+
+		testCases := s.testCases()
+		if len(testCases) == 0 {
+			continue
+		}
+		fmt.Println()
+		fmt.Println("| Test case | Proof size | Uncompressed | Energy per proof | Verifier gas |")
+		fmt.Println("|---|---:|---:|---:|---:|")
+		for _, testCase := range testCases {
+			size, rawSize, energy := "-", "-", "-"
+			if m := s.find("prove", testCase); m != nil {
+				size = formatSize(m.ProofBytes)
+				rawSize = formatSize(m.ProofRawBytes)
+				if m.EnergyJoules > 0 {
+					energy = fmt.Sprintf("%.2f J", m.EnergyJoules)
+				}
+			}
+			gas := "-"
+			if g, ok := s.gas[testCase]; ok {
+				gas = fmt.Sprint(g)
+			}
+			fmt.Printf("| %s | %s | %s | %s | %s |\n", testCase, size, rawSize, energy, gas)
+		}
+	}
+}
+
+// verifierGas is the verifier gas per test case recorded in the results by the
+// gas command, falling back to the gas reports next to resultsPath when it
+// has not run
+func verifierGas(results Results, resultsPath string) map[string]int64 {
+	gas := make(map[string]int64)
+	for _, m := range results.Measurements {
+		if m.Phase == "verify" && m.Gas != nil {
+			gas[m.TestCase] = m.Gas.Mean
+		}
+	}
+	if len(gas) > 0 || resultsPath == "" {
+		return gas
+	}
+	return loadGas(resultsPath)
+}
+
+// loadGas reads the verifier gas per test case recorded for the output
+// directory holding resultsPath
+func loadGas(resultsPath string) map[string]int64 {
+	gasFile := filepath.Join(filepath.Dir(filepath.Dir(resultsPath)), "gas-reports", "reports", "all_gas_data.json")
+	data, err := os.ReadFile(gasFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		fatal("Failed to read gas results", "error", err)
+	}
+
+	var gasResults GasResults
+	if err := json.Unmarshal(data, &gasResults); err != nil {
+		fatal("Invalid gas results", "file", gasFile, "error", err)
+	}
+	gas := make(map[string]int64)
+	for _, r := range gasResults.Results {
+		gas[fmt.Sprint(r.TestCase)] = r.Mean
+	}
+	return gas
+}
+
+func (s reportSection) title() string {
+	settings := s.results.Settings
+	backend := settings.Backend
+	if backend == "" {
+		backend = defaultBackend
+	}
+	rc := settings.RangeCheck
+	if rc == "" {
+		rc = defaultRangeCheck
+	}
+	title := fmt.Sprintf("%s %s over %s (%s range checks)", s.results.Stack, strings.ToUpper(backend), settings.Curve, rc)
+	if provers := s.provers(); provers != "" {
+		title += ", proved on " + provers
+	}
+	return title
+}
+
+// provers names the hardware the proofs were made on when any was made on
+// the GPU, so that GPU timings are never read as CPU ones
+func (s reportSection) provers() string {
+	var gpu, cpu bool
+	for _, m := range s.results.Measurements {
+		if strings.HasPrefix(m.Phase, "prove") {
+			gpu = gpu || m.Hardware == "gpu"
+			cpu = cpu || m.Hardware != "gpu"
+		}
+	}
+	switch {
+	case gpu && cpu:
+		return "GPU and CPU"
+	case gpu:
+		return "GPU"
+	}
+	return ""
+}
+
+// find returns the measurement of a phase outside of thread sweeps
+func (s reportSection) find(phase, testCase string) *Measurement {
+	for i, m := range s.results.Measurements {
+		if m.Phase == phase && m.TestCase == testCase && m.fullCPU() {
+			return &s.results.Measurements[i]
+		}
+	}
+	return nil
+}
+
+// testCases lists the test cases with measurements, in the order recorded
+func (s reportSection) testCases() []string {
+	var testCases []string
+	seen := make(map[string]bool)
+	for _, m := range s.results.Measurements {
+		if m.TestCase != "" && !seen[m.TestCase] {
+			seen[m.TestCase] = true
+			testCases = append(testCases, m.TestCase)
+		}
+	}
+	return testCases
+}
+
+// meanOver averages the mean time of a phase across test cases, leaving out
+// thread sweeps
+func (s reportSection) meanOver(phase string) string {
+	mean, ok := s.meanSecs(phase)
+	if !ok {
+		return "-"
+	}
+	return formatSecs(mean)
+}
+
+func (s reportSection) meanSecs(phase string) (float64, bool) {
+	var sum float64
+	var n int
+	for _, m := range s.results.Measurements {
+		if m.Phase == phase && m.TestCase != "" && m.fullCPU() {
+			sum += m.MeanSecs
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return sum / float64(n), true
+}
+
+func (s reportSection) proofSize() string {
+	for _, m := range s.results.Measurements {
+		if m.Phase == "prove" && m.ProofBytes > 0 {
+			return formatSize(m.ProofBytes)
+		}
+	}
+	return "-"
+}
+
+// calldataBytes is the size of a call to the Solidity verifier, when there is
+// one for the configuration and the public inputs were recorded at compile
+// time
+func (s reportSection) calldataBytes() (int, bool) {
+	m := s.find("compile", "")
+	if m == nil || m.PublicVariables == 0 {
+		return 0, false
+	}
+	// The constant one wire counts as a public variable but is not passed
+	return verifierCalldataBytes(s.results.Settings, m.PublicVariables-1)
+}
+
+// calldataCost is the data cost of passing a proof to the Solidity verifier,
+// averaged over the test cases proved, since their zero bytes differ
+func (s reportSection) calldataCost() (CalldataStats, bool) {
+	var sum CalldataStats
+	var n int
+	for _, m := range s.results.Measurements {
+		if m.Phase != "prove" || m.Calldata == nil {
+			continue
+		}
+		sum.Bytes += m.Calldata.Bytes
+		sum.ZeroBytes += m.Calldata.ZeroBytes
+		sum.Gas += m.Calldata.Gas
+		sum.FloorGas += m.Calldata.FloorGas
+		sum.BlobGas += m.Calldata.BlobGas
+		sum.BlobShare += m.Calldata.BlobShare
+		n++
+	}
+	if n == 0 {
+		return CalldataStats{}, false
+	}
+	return CalldataStats{
+		Bytes:     sum.Bytes / n,
+		ZeroBytes: sum.ZeroBytes / n,
+		Gas:       sum.Gas / int64(n),
+		FloorGas:  sum.FloorGas / int64(n),
+		BlobGas:   sum.BlobGas / int64(n),
+		BlobShare: sum.BlobShare / float64(n),
+	}, true
+}
+
+// allocsPerOp averages the allocations per run of a phase over the test cases
+// it was measured on
+func (s reportSection) allocsPerOp(phase string) (AllocStats, bool) {
+	var sum AllocStats
+	n := 0
+	for _, m := range s.results.Measurements {
+		if m.Phase == phase && m.fullCPU() && m.Allocs != nil && m.Allocs.AllocsPerOp > 0 {
+			sum.BytesPerOp += m.Allocs.BytesPerOp
+			sum.AllocsPerOp += m.Allocs.AllocsPerOp
+			sum.GCPerOp += m.Allocs.GCPerOp
+			n++
+		}
+	}
+	if n == 0 {
+		return AllocStats{}, false
+	}
+	return AllocStats{BytesPerOp: sum.BytesPerOp / uint64(n), AllocsPerOp: sum.AllocsPerOp / uint64(n), GCPerOp: sum.GCPerOp / float64(n)}, true
+}
+
+func (s reportSection) meanGas() string {
+	gas, ok := s.gasMean()
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprint(gas)
+}
+
+func (s reportSection) gasMean() (int64, bool) {
+	if len(s.gas) == 0 {
+		return 0, false
+	}
+	var sum int64
+	for _, g := range s.gas {
+		sum += g
+	}
+	return sum / int64(len(s.gas)), true
+}
+
+// formatSize renders an artifact size, or "-" when it was not recorded
+func formatSize(n int64) string {
+	if n <= 0 {
+		return "-"
+	}
+	return formatBytes(uint64(n))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"time"
+)
+
+var (
+	// command line flags
+	resourceInterval time.Duration
+)
+
+// resourceSample is the state of the process at one tick of the sampler. CPU
+// is the share of one CPU used since the previous sample, as top reports it,
+// and the GC fields count the collections and their pauses since then.
+type resourceSample struct {
+	elapsed    time.Duration
+	rssBytes   uint64
+	cpuPercent float64
+	goroutines int
+	heapBytes  uint64
+	gcs        uint32
+	gcPause    time.Duration
+}
+
+// resourceColumns is the header of the timeseries files
+var resourceColumns = []string{"elapsed_secs", "rss_bytes", "cpu_percent", "goroutines", "heap_bytes", "gc_count", "gc_pause_secs"}
+
+// startResourceSampler records the resource usage of the process every
+// -sample-resources interval while a phase runs, into the resources directory
+// of the results, e.g. benchmarks/resources/resources_prove_1.csv. It does
+// nothing without -sample-resources. The returned function stops sampling,
+// writes the timeseries, and returns its path relative to the results
+// directory, or "" when nothing was sampled.
+func startResourceSampler(phase, testCase string) func() string {
+	if resourceInterval <= 0 {
+		return func() string { return "" }
+	}
+
+	start := time.Now()
+	var samples []resourceSample
+	var prevStats runtime.MemStats
+	runtime.ReadMemStats(&prevStats)
+	prevCPU, _ := processCPUTime()
+	prevTime := start
+	sample := func() {
+		var m runtime.MemStats
+		runtime.ReadMemStats(&m)
+		now := time.Now()
+		s := resourceSample{
+			elapsed:    now.Sub(start),
+			rssBytes:   residentBytes(),
+			goroutines: runtime.NumGoroutine(),
+			heapBytes:  m.HeapInuse,
+			gcs:        m.NumGC - prevStats.NumGC,
+			gcPause:    time.Duration(m.PauseTotalNs - prevStats.PauseTotalNs),
+		}
+		if cpu, ok := processCPUTime(); ok && now.After(prevTime) {
+			s.cpuPercent = float64(cpu-prevCPU) / float64(now.Sub(prevTime)) * 100
+			prevCPU = cpu
+		}
+		prevStats, prevTime = m, now
+		samples = append(samples, s)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(resourceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sample()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() string {
+		close(done)
+		<-stopped
+		// Short phases end before the first tick, so always sample the end
+		sample()
+
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(resourceColumns)
+		for _, s := range samples {
+			w.Write([]string{
+				strconv.FormatFloat(s.elapsed.Seconds(), 'f', 3, 64),
+				strconv.FormatUint(s.rssBytes, 10),
+				strconv.FormatFloat(s.cpuPercent, 'f', 1, 64),
+				strconv.Itoa(s.goroutines),
+				strconv.FormatUint(s.heapBytes, 10),
+				strconv.FormatUint(uint64(s.gcs), 10),
+				strconv.FormatFloat(s.gcPause.Seconds(), 'f', 6, 64),
+			})
+		}
+		w.Flush()
+
+		name := "resources_" + phase
+		if testCase != "" {
+			name += "_" + testCase
+		}
+		relPath := filepath.Join("resources", name+".csv")
+		path := filepath.Join(outputDir, "benchmarks", relPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			fatal("Failed to create resources directory", "error", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			fatal("Failed to write resource usage", "error", err)
+		}
+		return relPath
+	}
+}
+
+// residentBytes is the resident set size of the process. Outside Linux, where
+// /proc is missing, it falls back to the memory the Go runtime has mapped and
+// not released, which leaves out memory allocated by C libraries.
+func residentBytes() uint64 {
+	if data, err := os.ReadFile("/proc/self/statm"); err == nil {
+		fields := bytes.Fields(data)
+		if len(fields) > 1 {
+			if pages, err := strconv.ParseUint(string(fields[1]), 10, 64); err == nil {
+				return pages * uint64(os.Getpagesize())
+			}
+		}
+	}
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
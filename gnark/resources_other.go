@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// processCPUTime is not available here, so the timeseries leaves CPU at 0
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/consensys/gnark/constraint"
+)
+
+// resultsSchemaVersion is bumped whenever a field of Results or Measurement
+// changes meaning or is removed. Adding optional fields does not bump it. The
+// schema is documented in results.schema.json.
+const resultsSchemaVersion = 1
+
+// resultsFile collects every measurement taken on the artifacts in the output
+// directory, in a form other tooling and the other stacks can read
+const resultsFile = "results.json"
+
+// Results is the document stored in <output dir>/benchmarks/results.json
+type Results struct {
+	SchemaVersion int           `json:"schema_version"`
+	Stack         string        `json:"stack"`
+	Settings      Manifest      `json:"settings"`
+	Environment   Environment   `json:"environment"`
+	Measurements  []Measurement `json:"measurements"`
+	// Aborted are the phases that were interrupted or timed out since they
+	// last completed
+	Aborted []Abort `json:"aborted,omitempty"`
+}
+
+// Abort records a phase that was interrupted or timed out (-timeout), and how
+// long it had run
+type Abort struct {
+	Phase       string  `json:"phase"`
+	TestCase    string  `json:"test_case,omitempty"`
+	Reason      string  `json:"reason"`
+	ElapsedSecs float64 `json:"elapsed_secs"`
+	RecordedAt  string  `json:"recorded_at"`
+}
+
+// Environment describes the machine the measurements were taken on, and the
+// toolchain that took them
+type Environment struct {
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	// GnarkVersion is the gnark release of the binary that measured, which
+	// may differ from the one the artifacts were compiled with (settings)
+	GnarkVersion string `json:"gnark_version,omitempty"`
+	CPUModel     string `json:"cpu_model,omitempty"`
+	MemoryBytes  uint64 `json:"memory_bytes,omitempty"`
+	// Machine is the fingerprint of the hardware, as in the history
+	Machine string `json:"machine,omitempty"`
+}
+
+// currentEnvironment describes the machine and toolchain of this process
+func currentEnvironment() Environment {
+	machine := currentMachine()
+	return Environment{
+		GoVersion:    runtime.Version(),
+		OS:           machine.OS,
+		Arch:         machine.Arch,
+		CPUs:         machine.CPUs,
+		GnarkVersion: gnarkVersion(),
+		CPUModel:     machine.CPUModel,
+		MemoryBytes:  machine.MemoryBytes,
+		Machine:      machine.Fingerprint,
+	}
+}
+
+// sameMachine reports whether two environments are the same kind of machine.
+// Results from before the fingerprint was recorded match any machine.
+func (e Environment) sameMachine(other Environment) bool {
+	return e.Machine == "" || other.Machine == "" || e.Machine == other.Machine
+}
+
+// describe renders the machine for reports and warnings
+func (e Environment) describe() string {
+	description := fmt.Sprintf("%s/%s, %d CPUs", e.OS, e.Arch, e.CPUs)
+	if e.CPUModel != "" {
+		description = fmt.Sprintf("%s (%s)", description, e.CPUModel)
+	}
+	if e.MemoryBytes > 0 {
+		description += ", " + formatBytes(e.MemoryBytes) + " RAM"
+	}
+	return description
+}
+
+// Measurement is the timing of one phase, for one test case when the phase
+// depends on it. Single runs report the same value for every statistic.
+type Measurement struct {
+	PhaseStats
+	Hardware    string      `json:"hardware,omitempty"`
+	Constraints int         `json:"constraints,omitempty"`
+	Allocs      *AllocStats `json:"allocs,omitempty"`
+	IO          *IOStats    `json:"io,omitempty"`
+	// Size of the compiled circuit beyond its constraints (compile)
+	PublicVariables   int `json:"public_variables,omitempty"`
+	SecretVariables   int `json:"secret_variables,omitempty"`
+	InternalVariables int `json:"internal_variables,omitempty"`
+	Coefficients      int `json:"coefficients,omitempty"`
+	// Serialized sizes of the artifacts a phase produces, in bytes: compressed
+	// as written to disk, and raw without point compression
+	CircuitBytes         int64 `json:"circuit_bytes,omitempty"`
+	ProvingKeyBytes      int64 `json:"proving_key_bytes,omitempty"`
+	ProvingKeyRawBytes   int64 `json:"proving_key_raw_bytes,omitempty"`
+	VerifyingKeyBytes    int64 `json:"verifying_key_bytes,omitempty"`
+	VerifyingKeyRawBytes int64 `json:"verifying_key_raw_bytes,omitempty"`
+	ProofBytes           int64 `json:"proof_bytes,omitempty"`
+	ProofRawBytes        int64 `json:"proof_raw_bytes,omitempty"`
+	// Profile ranks the functions adding the most constraints (compile -profile-constraints)
+	Profile []ProfileEntry `json:"constraint_profile,omitempty"`
+	// CPUProfile is the pprof file of the phase, relative to the results
+	// directory (-cpuprofile)
+	CPUProfile string `json:"cpu_profile,omitempty"`
+	// MemProfile is the pprof file of the heap allocations of the phase,
+	// relative to the results directory (-memprofile)
+	MemProfile string `json:"mem_profile,omitempty"`
+	// ProverStages splits the proving time into MSM, FFT, solving, and GC, by
+	// the functions in its CPU profile (prove -prover-stages)
+	ProverStages []StageTime `json:"prover_stages,omitempty"`
+	// Resources is the CSV timeseries of the process's resource usage during
+	// the phase, relative to the results directory (-sample-resources)
+	Resources string `json:"resources,omitempty"`
+	// MemoryLimitBytes is the smallest memory limit proving fit in, and
+	// MemoryRuns the proofs under each limit tried (minmem)
+	MemoryLimitBytes uint64      `json:"memory_limit_bytes,omitempty"`
+	MemoryRuns       []MemoryRun `json:"memory_runs,omitempty"`
+	// Concurrent proving (throughput): the number of provers, the proofs they
+	// completed per second, and the fraction of the available CPU time used
+	Workers        int     `json:"workers,omitempty"`
+	ProofsPerSec   float64 `json:"proofs_per_sec,omitempty"`
+	CPUUtilization float64 `json:"cpu_utilization,omitempty"`
+	// Proving under a Poisson arrival rate of jobs per second (loadtest): the
+	// p99 latency from arrival to proof, the most jobs queued at once, and
+	// whether the provers fell behind the rate
+	ArrivalRate float64 `json:"arrival_rate,omitempty"`
+	P99Secs     float64 `json:"p99_secs,omitempty"`
+	MaxQueued   int     `json:"max_queued,omitempty"`
+	Saturated   bool    `json:"saturated,omitempty"`
+	// Gas is what the Solidity verifier used to verify the proof, merged from
+	// forge's gas report (verify, gas)
+	Gas *GasStats `json:"gas,omitempty"`
+	// Calldata is what passing the proof and its public inputs to the
+	// Solidity verifier costs in data, apart from Gas (prove)
+	Calldata *CalldataStats `json:"calldata,omitempty"`
+	// CPULimit is the simulated CPU budget the phase ran under (-cpus,
+	// -cpu-quota), e.g. "2 CPUs at 50%"
+	CPULimit string `json:"cpu_limit,omitempty"`
+	// Device is the preset of CPU and memory limits approximating a device
+	// the phase ran under (-device)
+	Device string `json:"device,omitempty"`
+	// EnergyJoules is the mean processor energy used per run (-energy)
+	EnergyJoules float64 `json:"energy_joules,omitempty"`
+	RecordedAt   string  `json:"recorded_at"`
+}
+
+// setCircuitSize records the size of a compiled circuit on its compile
+// measurement
+func (m *Measurement) setCircuitSize(ccs constraint.ConstraintSystem) {
+	m.Constraints = ccs.GetNbConstraints()
+	m.PublicVariables = ccs.GetNbPublicVariables()
+	m.SecretVariables = ccs.GetNbSecretVariables()
+	m.InternalVariables = ccs.GetNbInternalVariables()
+	m.Coefficients = ccs.GetNbCoefficients()
+}
+
+// fullCPU reports whether the phase ran with every CPU of the machine, rather
+// than in a thread sweep or under a CPU limit or device preset
+func (m *Measurement) fullCPU() bool {
+	return m.Threads == 0 && m.CPULimit == "" && m.Device == ""
+}
+
+// cpuBudget describes the CPU limit of the phase, with the device preset it
+// stands for
+func (m *Measurement) cpuBudget() string {
+	if m.Device == "" {
+		return m.CPULimit
+	}
+	return fmt.Sprintf("%s: %s", m.Device, m.CPULimit)
+}
+
+// singleRun is the measurement of a phase that ran once
+func singleRun(phase, testCase string, d time.Duration) Measurement {
+	return Measurement{PhaseStats: summarize(phase, testCase, []time.Duration{d})}
+}
+
+// recordResults adds measurements to the results file, replacing earlier ones
+// for the same phase, test case, and thread count. Results recorded with other
+// settings, such as those of artifacts compiled over, are discarded. Every
+// measurement is also appended to the history.
+func recordResults(settings Manifest, measurements ...Measurement) {
+	results := loadResultsFor(settings)
+
+	recordedAt := time.Now().UTC().Format(time.RFC3339)
+	for i := range measurements {
+		measurements[i].RecordedAt = recordedAt
+		measurements[i].CPULimit = currentCPULimit
+		measurements[i].Device = currentDevice
+		checkDeviceMemory(measurements[i])
+		results.Aborted = slices.DeleteFunc(results.Aborted, func(a Abort) bool {
+			return a.Phase == measurements[i].Phase && a.TestCase == measurements[i].TestCase
+		})
+	}
+	results.Measurements = upsertMeasurements(results.Measurements, measurements)
+	saveResults(results)
+
+	appendHistory(settings, results.Environment, recordedAt, measurements)
+}
+
+// recordAborted adds an aborted phase to the results file, replacing an
+// earlier abort of it
+func recordAborted(settings Manifest, abort Abort) {
+	results := loadResultsFor(settings)
+	abort.RecordedAt = time.Now().UTC().Format(time.RFC3339)
+	results.Aborted = slices.DeleteFunc(results.Aborted, func(a Abort) bool {
+		return a.Phase == abort.Phase && a.TestCase == abort.TestCase
+	})
+	results.Aborted = append(results.Aborted, abort)
+	saveResults(results)
+}
+
+// loadResultsFor reads the results file to add to, keeping what it records
+// only when it was recorded with the same settings on this machine
+func loadResultsFor(settings Manifest) Results {
+	path := filepath.Join(outputDir, "benchmarks", resultsFile)
+	results := Results{
+		SchemaVersion: resultsSchemaVersion,
+		Stack:         "gnark",
+		Settings:      settings,
+		Environment:   currentEnvironment(),
+	}
+
+	if data, err := os.ReadFile(path); err == nil {
+		var previous Results
+		if err := json.Unmarshal(data, &previous); err != nil {
+			slog.Warn("Ignoring unreadable results", "file", path, "error", err)
+		} else if previous.SchemaVersion == resultsSchemaVersion && previous.Settings == settings {
+			// Measurements from another machine do not compare with these, the
+			// history keeps them
+			if previous.Environment.sameMachine(results.Environment) {
+				results.Measurements = previous.Measurements
+				results.Aborted = previous.Aborted
+			} else {
+				slog.Warn("Discarding the measurements taken on another machine", "file", path, "machine", previous.Environment.describe())
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		fatal("Failed to read results", "file", path, "error", err)
+	}
+	return results
+}
+
+func saveResults(results Results) {
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	err := os.MkdirAll(resultsDir, 0755)
+	if err != nil {
+		fatal("Failed to create results directory", "dir", resultsDir, "error", err)
+	}
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		fatal("Failed to encode results", "error", err)
+	}
+	path := filepath.Join(resultsDir, resultsFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fatal("Failed to write results", "file", path, "error", err)
+	}
+}
+
+// sameRun reports whether two measurements are of the same phase, test case,
+// thread count, CPU limit, device, and arrival rate, so that one replaces the
+// other in a results file and they are compared with each other
+func (m *Measurement) sameRun(o *Measurement) bool {
+	return m.Phase == o.Phase && m.TestCase == o.TestCase && m.Threads == o.Threads &&
+		m.CPULimit == o.CPULimit && m.Device == o.Device && m.ArrivalRate == o.ArrivalRate
+}
+
+// label names the run of a measurement in comparisons, e.g.
+// "prove 1 @4 (iphone12: 2 CPUs at 50%)"
+func (m *Measurement) label() string {
+	name := m.Phase
+	if m.TestCase != "" {
+		name += " " + m.TestCase
+	}
+	if m.Threads > 0 {
+		name += fmt.Sprintf(" @%d", m.Threads)
+	}
+	if m.ArrivalRate > 0 {
+		name += fmt.Sprintf(" at %g/s", m.ArrivalRate)
+	}
+	if m.CPULimit != "" {
+		name += " (" + m.cpuBudget() + ")"
+	}
+	return name
+}
+
+// measurementPair is a measurement and the same run in the results it is
+// compared with
+type measurementPair struct {
+	old, new *Measurement
+}
+
+// pairMeasurements pairs every measurement of after with the same run in
+// before, leaving out those before has no run of
+func pairMeasurements(before, after []Measurement) []measurementPair {
+	var pairs []measurementPair
+	for i := range after {
+		for j := range before {
+			if after[i].sameRun(&before[j]) {
+				pairs = append(pairs, measurementPair{old: &before[j], new: &after[i]})
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// upsertMeasurements replaces the measurements of the same run, and appends
+// the others
+func upsertMeasurements(existing, measurements []Measurement) []Measurement {
+	for _, m := range measurements {
+		replaced := false
+		for i, previous := range existing {
+			if previous.sameRun(&m) {
+				existing[i] = m
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			existing = append(existing, m)
+		}
+	}
+	return existing
+}
+
+// artifactSettings returns the settings the artifacts in the output directory
+// were compiled with, for commands that measure existing artifacts
+func artifactSettings() Manifest {
+	manifest, err := loadManifest()
+	if err != nil {
+		fatal("Failed to load manifest", "error", err)
+	}
+	if manifest == nil {
+		return currentSettings()
+	}
+	return *manifest
+}
+
+// proofSize is the serialized size of a proof in bytes
+func proofSize(proof artifact) int64 {
+	n, err := proof.WriteTo(io.Discard)
+	if err != nil {
+		fatal("Failed to measure proof size", "error", err)
+	}
+	return n
+}
+
+// rawSize is the size of a key or proof serialized without point compression,
+// or 0 when it has no uncompressed encoding
+func rawSize(a artifact) int64 {
+	raw, ok := a.(interface {
+		WriteRawTo(w io.Writer) (int64, error)
+	})
+	if !ok {
+		return 0
+	}
+	n, err := raw.WriteRawTo(io.Discard)
+	if err != nil {
+		fatal("Failed to measure uncompressed size", "error", err)
+	}
+	return n
+}
+
+// clearResults removes the results file, for when the artifacts are replaced
+func clearResults() {
+	err := os.Remove(filepath.Join(outputDir, "benchmarks", resultsFile))
+	if err != nil && !os.IsNotExist(err) {
+		fatal("Failed to remove results", "error", err)
+	}
+}
@@ -42,26 +42,71 @@ unset IFS
 NUM_TEST_CASES=${#TEST_CASE_NUMBERS[@]}
 echo "🔍 Discovered $NUM_TEST_CASES test cases: ${TEST_CASE_NUMBERS[*]}"
 
+# The Solidity verifier is only generated for Groth16 proofs
+BACKEND=$(jq -r '.backend // "groth16"' /out/manifest.json 2>/dev/null || echo groth16)
+if [ "$BACKEND" != "groth16" ]; then
+    print_message "$CYAN" "⏭️  No Solidity verifier for the $BACKEND backend, skipping gas benchmark"
+    exit 0
+fi
+
+# The verifier depends on the proving curve recorded at compile time
+CURVE=$(jq -r '.curve // "bn254"' /out/manifest.json 2>/dev/null || echo bn254)
+if [ "$CURVE" != "bn254" ] && [ "$CURVE" != "bls12-381" ]; then
+    echo "❌ No Solidity verifier for curve $CURVE (use bn254 or bls12-381)"
+    exit 1
+fi
+echo "📐 Proving curve: $CURVE"
+
 echo "🔧 Setting up Foundry..."
 forge init --no-git
 
 echo "🔨 Generating Solidity verifier..."
-# Run the Go command from the /app directory where go.mod is located
-# The generate_verifier command creates the file directly, so no redirection needed
-(cd /app && go run cmd/generate_verifier/main.go > /dev/null 2>&1)
+# Run the Go command from the /app directory where go.mod is located, writing
+# the verifier straight into the Foundry src directory
+FOUNDRY_DIR=$(pwd)
+(cd /app && go run ./cmd/generate_verifier -data-dir /out -out "$FOUNDRY_DIR/src" > /dev/null 2>&1)
+
+if [ "$CURVE" = "bls12-381" ]; then
+    # The EIP-2537 precompiles only exist from the Prague hardfork on
+    echo "📝 Creating foundry.toml to use solc 0.8.30 with the Prague EVM..."
+    cat > foundry.toml << EOF
+[profile.default]
+solc = "0.8.30"
+evm_version = "prague"
+EOF
+
+    echo "📝 Creating test contract..."
+    cat > src/GasTest.sol << EOF
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.0;
+
+import "./Groth16Verifier.sol";
+
+contract GasTest {
+    Verifier verifier;
 
-# Copy the generated verifier to the Foundry src directory
-cp /app/src/Groth16Verifier.sol src/
+    constructor() {
+        verifier = new Verifier();
+    }
 
-# Create foundry.toml to specify solc version
-echo "📝 Creating foundry.toml to use solc 0.8.20..."
-cat > foundry.toml << EOF
+    function verifyProof(
+        bytes calldata proof,
+        uint256[4] calldata input
+    ) public view {
+        verifier.verifyProof(proof, input);
+    }
+}
+EOF
+else
+    # Create foundry.toml to specify solc version
+    echo "📝 Creating foundry.toml to use solc 0.8.20..."
+    cat > foundry.toml << EOF
 [profile.default]
 solc = "0.8.20"
 EOF
 
-echo "📝 Creating test contract..."
-cat > src/GasTest.sol << EOF
+    echo "📝 Creating test contract..."
+    cat > src/GasTest.sol << EOF
 // SPDX-License-Identifier: MIT
 pragma solidity ^0.8.0;
 
@@ -84,6 +129,7 @@ contract GasTest {
     }
 }
 EOF
+fi
 
 forge build
 
@@ -107,7 +153,7 @@ for idx in "${!TEST_CASE_NUMBERS[@]}"; do
     print_message "$CYAN" "⛽ Benchmarking gas usage for test case $test_case..."
     
     # Generate proof data and insert into a temporary test file
-    (cd /app && go run cmd/generate_test_data/main.go "$test_case" "/app/tests/test_case_${test_case}.json" "/out/proof_${test_case}.groth16" > /tmp/test_data_${test_case}.sol)
+    (cd /app && go run ./cmd/generate_test_data "$test_case" "/app/tests/test_case_${test_case}.json" "/out/proof_${test_case}.groth16" > /tmp/test_data_${test_case}.sol)
     
     # Copy the generated test file to the test directory
     cp /tmp/test_data_${test_case}.sol test/GasTest.t.sol
@@ -128,6 +174,9 @@ for idx in "${!TEST_CASE_NUMBERS[@]}"; do
     fi
     
     print_message "$GREEN" "✅ Gas usage for test case $test_case: $GAS_USAGE gas"
+
+    # Keep forge's JSON gas report too, which the gas command merges into results.json
+    forge test --match-test "testVerifyProof${test_case}" --gas-report --json > "../reports/gas_report_${test_case}.json"
     
     # Add to summary
     echo "Test Case $test_case:" >> ../reports/summary.txt
@@ -156,6 +205,9 @@ done
 echo "  ]" >> ../reports/all_gas_data.json
 echo "}" >> ../reports/all_gas_data.json
 
+echo "📝 Adding the verifier gas to the results..."
+(cd /app && go run . gas -d /out)
+
 echo "✅ Gas benchmarking complete! Check the /out/gas-reports directory for results."
 echo "📊 Summary of gas usage:"
 cat /out/gas-reports/reports/summary.txt
@@ -0,0 +1,85 @@
+#!/bin/bash
+
+set -e
+
+CYAN='\033[0;36m'
+GREEN='\033[0;32m'
+RED='\033[0;31m'
+NC='\033[0m'
+
+print_message() {
+  local color=$1
+  local message=$2
+  echo -e "${color}${message}${NC}"
+}
+
+# Curves to compare; override with e.g. GNARK_CURVES="bn254 bls24-315"
+CURVES=(${GNARK_CURVES:-bn254 bls12-377 bls12-381 bls24-315})
+
+print_message "$CYAN" "📐 Comparing proving curves: ${CURVES[*]}"
+
+# Ensure we're in the correct directory
+cd /app
+
+# Discover test cases
+TEST_CASE_FILES=(tests/test_case_*.json)
+if [ ! -e "${TEST_CASE_FILES[0]}" ]; then
+    print_message "$RED" "No test case files found in tests directory!"
+    exit 1
+fi
+
+# Extract test case numbers and sort them
+TEST_CASE_NUMBERS=()
+for file in "${TEST_CASE_FILES[@]}"; do
+    if [[ $file =~ test_case_([0-9]+)\.json ]]; then
+        TEST_CASE_NUMBERS+=(${BASH_REMATCH[1]})
+    fi
+done
+
+# Sort the test case numbers
+IFS=$'\n' TEST_CASE_NUMBERS=($(sort -n <<<"${TEST_CASE_NUMBERS[*]}"))
+unset IFS
+
+NUM_TEST_CASES=${#TEST_CASE_NUMBERS[@]}
+print_message "$CYAN" "🔍 Discovered $NUM_TEST_CASES test cases: ${TEST_CASE_NUMBERS[*]}"
+TEST_CASES_LIST=$(printf "%s," "${TEST_CASE_NUMBERS[@]}" | sed 's/,$//')
+
+# Build once so hyperfine measures the prover rather than the Go toolchain
+go build -o /tmp/gnark-ecdsa .
+
+mkdir -p /out/benchmarks
+SUMMARY=/out/benchmarks/curve_comparison.md
+echo "| Curve | Setup (s) | Avg Prove (s) | Avg Verify (ms) |" > $SUMMARY
+echo "|-------|-----------|---------------|-----------------|" >> $SUMMARY
+
+for curve in "${CURVES[@]}"; do
+    CURVE_DIR=/out/curves/$curve
+    mkdir -p $CURVE_DIR
+
+    print_message "$CYAN" "🔨 [$curve] Compiling circuit and running setup..."
+    setup_start=$(date +%s.%N)
+    /tmp/gnark-ecdsa compile -d $CURVE_DIR -curve $curve -hash-to-field "${GNARK_HASH_TO_FIELD:-sha256}"
+    setup_time=$(echo "$(date +%s.%N) - $setup_start" | bc -l)
+
+    print_message "$CYAN" "🔐 [$curve] Generating proofs..."
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --export-json $CURVE_DIR/proofs_benchmark.json \
+        "/tmp/gnark-ecdsa prove -d $CURVE_DIR tests/test_case_{test_case}.json"
+
+    print_message "$CYAN" "🔍 [$curve] Verifying proofs..."
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --export-json $CURVE_DIR/verifications_benchmark.json \
+        "/tmp/gnark-ecdsa verify -d $CURVE_DIR tests/test_case_{test_case}.json"
+
+    prove_avg=$(jq -r '([.results[].mean] | add) / ([.results[].mean] | length)' $CURVE_DIR/proofs_benchmark.json)
+    verify_avg=$(jq -r '([.results[].mean] | add) / ([.results[].mean] | length)' $CURVE_DIR/verifications_benchmark.json)
+
+    printf "| %s | %.2f | %.3f | %.1f |\n" $curve $setup_time $prove_avg $(echo "$verify_avg * 1000" | bc -l) >> $SUMMARY
+    print_message "$GREEN" "✅ [$curve] done"
+done
+
+print_message "$CYAN" ""
+print_message "$CYAN" "📊 Curve comparison:"
+cat $SUMMARY
@@ -0,0 +1,90 @@
+#!/bin/bash
+
+set -e
+set -o pipefail
+
+CYAN='\033[0;36m'
+GREEN='\033[0;32m'
+RED='\033[0;31m'
+NC='\033[0m'
+
+print_message() {
+  local color=$1
+  local message=$2
+  echo -e "${color}${message}${NC}"
+}
+
+# Range checks to compare for the emulated P-256 arithmetic, on the backend
+# given by GNARK_BACKEND (PLONK by default)
+RANGE_CHECKS=(${GNARK_RANGE_CHECKS:-lookup decompose})
+BACKEND=${GNARK_BACKEND:-plonk}
+
+print_message "$CYAN" "📏 Comparing range checks on $BACKEND: ${RANGE_CHECKS[*]}"
+
+# Ensure we're in the correct directory
+cd /app
+
+# Discover test cases
+TEST_CASE_FILES=(tests/test_case_*.json)
+if [ ! -e "${TEST_CASE_FILES[0]}" ]; then
+    print_message "$RED" "No test case files found in tests directory!"
+    exit 1
+fi
+
+# Extract test case numbers and sort them
+TEST_CASE_NUMBERS=()
+for file in "${TEST_CASE_FILES[@]}"; do
+    if [[ $file =~ test_case_([0-9]+)\.json ]]; then
+        TEST_CASE_NUMBERS+=(${BASH_REMATCH[1]})
+    fi
+done
+
+# Sort the test case numbers
+IFS=$'\n' TEST_CASE_NUMBERS=($(sort -n <<<"${TEST_CASE_NUMBERS[*]}"))
+unset IFS
+
+NUM_TEST_CASES=${#TEST_CASE_NUMBERS[@]}
+print_message "$CYAN" "🔍 Discovered $NUM_TEST_CASES test cases: ${TEST_CASE_NUMBERS[*]}"
+TEST_CASES_LIST=$(printf "%s," "${TEST_CASE_NUMBERS[@]}" | sed 's/,$//')
+
+# Build once so hyperfine measures the prover rather than the Go toolchain
+go build -o /tmp/gnark-ecdsa .
+
+mkdir -p /out/benchmarks
+SUMMARY=/out/benchmarks/range_check_comparison_$BACKEND.md
+echo "| Range check | Constraints | Setup (s) | Avg Prove (s) | Avg Verify (ms) |" > $SUMMARY
+echo "|-------------|-------------|-----------|---------------|-----------------|" >> $SUMMARY
+
+for range_check in "${RANGE_CHECKS[@]}"; do
+    RUN_DIR=/out/range-checks/$BACKEND-$range_check
+    mkdir -p $RUN_DIR
+
+    print_message "$CYAN" "🔨 [$range_check] Compiling circuit and running setup..."
+    setup_start=$(date +%s.%N)
+    /tmp/gnark-ecdsa compile -d $RUN_DIR -backend $BACKEND -range-check $range_check \
+        -hash-to-field "${GNARK_HASH_TO_FIELD:-sha256}" | tee $RUN_DIR/compile.log
+    setup_time=$(echo "$(date +%s.%N) - $setup_start" | bc -l)
+    constraints=$(jq -r '.measurements[] | select(.phase == "compile") | .constraints' $RUN_DIR/benchmarks/results.json)
+
+    print_message "$CYAN" "🔐 [$range_check] Generating proofs..."
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --export-json $RUN_DIR/proofs_benchmark.json \
+        "/tmp/gnark-ecdsa prove -d $RUN_DIR tests/test_case_{test_case}.json"
+
+    print_message "$CYAN" "🔍 [$range_check] Verifying proofs..."
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --export-json $RUN_DIR/verifications_benchmark.json \
+        "/tmp/gnark-ecdsa verify -d $RUN_DIR tests/test_case_{test_case}.json"
+
+    prove_avg=$(jq -r '([.results[].mean] | add) / ([.results[].mean] | length)' $RUN_DIR/proofs_benchmark.json)
+    verify_avg=$(jq -r '([.results[].mean] | add) / ([.results[].mean] | length)' $RUN_DIR/verifications_benchmark.json)
+
+    printf "| %s | %d | %.2f | %.3f | %.1f |\n" $range_check $constraints $setup_time $prove_avg $(echo "$verify_avg * 1000" | bc -l) >> $SUMMARY
+    print_message "$GREEN" "✅ [$range_check] done"
+done
+
+print_message "$CYAN" ""
+print_message "$CYAN" "📊 Range check comparison ($BACKEND):"
+cat $SUMMARY
@@ -0,0 +1,135 @@
+#!/bin/bash
+
+set -e
+set -o pipefail
+
+CYAN='\033[0;36m'
+GREEN='\033[0;32m'
+RED='\033[0;31m'
+NC='\033[0m'
+
+print_message() {
+  local color=$1
+  local message=$2
+  echo -e "${color}${message}${NC}"
+}
+
+# gnark releases to compare; the first one is the baseline for the deltas.
+# Override with e.g. GNARK_VERSIONS="v0.11.0 v0.12.0 master"
+VERSIONS=(${GNARK_VERSIONS:-v0.11.0 v0.12.0})
+
+# gnark_tags prints the build tags that select the files written for a gnark
+# release, where its API differs from the one go.mod pins (see
+# internal/gnarkcompat)
+gnark_tags() {
+    case $1 in
+        v0.11.*) echo gnark_v0_11 ;;
+        *) echo "" ;;
+    esac
+}
+
+print_message "$CYAN" "🏷️  Comparing gnark versions: ${VERSIONS[*]}"
+
+# Ensure we're in the correct directory
+cd /app
+
+# Discover test cases
+TEST_CASE_FILES=(tests/test_case_*.json)
+if [ ! -e "${TEST_CASE_FILES[0]}" ]; then
+    print_message "$RED" "No test case files found in tests directory!"
+    exit 1
+fi
+
+# Extract test case numbers and sort them
+TEST_CASE_NUMBERS=()
+for file in "${TEST_CASE_FILES[@]}"; do
+    if [[ $file =~ test_case_([0-9]+)\.json ]]; then
+        TEST_CASE_NUMBERS+=(${BASH_REMATCH[1]})
+    fi
+done
+
+# Sort the test case numbers
+IFS=$'\n' TEST_CASE_NUMBERS=($(sort -n <<<"${TEST_CASE_NUMBERS[*]}"))
+unset IFS
+
+NUM_TEST_CASES=${#TEST_CASE_NUMBERS[@]}
+print_message "$CYAN" "🔍 Discovered $NUM_TEST_CASES test cases: ${TEST_CASE_NUMBERS[*]}"
+TEST_CASES_LIST=$(printf "%s," "${TEST_CASE_NUMBERS[@]}" | sed 's/,$//')
+
+mkdir -p /out/benchmarks
+RESULTS=/out/benchmarks/version_comparison.tsv
+: > $RESULTS
+
+for version in "${VERSIONS[@]}"; do
+    VERSION_DIR=/out/versions/$version
+    BUILD_DIR=/tmp/gnark-build-$version
+    BINARY=/tmp/gnark-ecdsa-$version
+    mkdir -p $VERSION_DIR
+
+    # Build the same sources in a private copy of the module pinned to this
+    # gnark release. The whole module is copied, as the build embeds files
+    # such as vectors/ and imports internal/, but not the test cases or
+    # artifacts. gnark-crypto is dropped first so that tidy brings back the
+    # version the selected gnark release requires.
+    print_message "$CYAN" "📦 [$version] Building against gnark $version..."
+    rm -rf $BUILD_DIR
+    mkdir -p $BUILD_DIR
+    tar -c --exclude=./data --exclude=./tests --exclude=./gnark-ecdsa-benchmark . | tar -x -C $BUILD_DIR
+    if ! (cd $BUILD_DIR \
+        && go mod edit -droprequire github.com/consensys/gnark-crypto \
+        && go get github.com/consensys/gnark@$version \
+        && go mod tidy \
+        && go build -tags "$(gnark_tags $version)" -o $BINARY .); then
+        print_message "$RED" "❌ [$version] Build failed, skipping"
+        printf "%s\tbuild failed\n" $version >> $RESULTS
+        continue
+    fi
+
+    print_message "$CYAN" "🔨 [$version] Compiling circuit and running setup..."
+    setup_start=$(date +%s.%N)
+    $BINARY compile -d $VERSION_DIR -hash-to-field "${GNARK_HASH_TO_FIELD:-sha256}" | tee $VERSION_DIR/compile.log
+    setup_time=$(echo "$(date +%s.%N) - $setup_start" | bc -l)
+    constraints=$(jq -r '.measurements[] | select(.phase == "compile") | .constraints' $VERSION_DIR/benchmarks/results.json)
+
+    print_message "$CYAN" "🔐 [$version] Generating proofs..."
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --export-json $VERSION_DIR/proofs_benchmark.json \
+        "$BINARY prove -d $VERSION_DIR tests/test_case_{test_case}.json"
+
+    print_message "$CYAN" "🔍 [$version] Verifying proofs..."
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --export-json $VERSION_DIR/verifications_benchmark.json \
+        "$BINARY verify -d $VERSION_DIR tests/test_case_{test_case}.json"
+
+    prove_avg=$(jq -r '([.results[].mean] | add) / ([.results[].mean] | length)' $VERSION_DIR/proofs_benchmark.json)
+    verify_avg=$(jq -r '([.results[].mean] | add) / ([.results[].mean] | length)' $VERSION_DIR/verifications_benchmark.json)
+
+    printf "%s\t%s\t%s\t%s\t%s\n" $version $constraints $setup_time $prove_avg $verify_avg >> $RESULTS
+    print_message "$GREEN" "✅ [$version] done"
+done
+
+# Delta report against the first version that built
+SUMMARY=/out/benchmarks/version_comparison.md
+awk -F'\t' '
+function delta(value, base) {
+    if (base == 0) return "-"
+    return sprintf("%+.1f%%", (value - base) / base * 100)
+}
+BEGIN {
+    print "| gnark | Constraints | Setup (s) | Avg Prove (s) | Δ Prove | Avg Verify (ms) | Δ Verify |"
+    print "|-------|-------------|-----------|---------------|---------|-----------------|----------|"
+}
+$2 == "build failed" {
+    printf "| %s | build failed | | | | | |\n", $1
+    next
+}
+{
+    if (!baseline) { baseline = 1; base_prove = $4; base_verify = $5 }
+    printf "| %s | %d | %.2f | %.3f | %s | %.1f | %s |\n", $1, $2, $3, $4, delta($4, base_prove), $5 * 1000, delta($5, base_verify)
+}' $RESULTS > $SUMMARY
+
+print_message "$CYAN" ""
+print_message "$CYAN" "📊 gnark version comparison:"
+cat $SUMMARY
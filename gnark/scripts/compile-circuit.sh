@@ -20,10 +20,16 @@ print_message "$CYAN" "🔨 [1/4] Compiling circuit..."
 
 # Compile the circuit and run setup
 print_message "$CYAN" "Compiling ECDSA circuit..."
-go run main.go circuit.go compile -d /out
+go run . compile -d /out \
+    -backend "${GNARK_BACKEND:-groth16}" \
+    -range-check "${GNARK_RANGE_CHECK:-lookup}" \
+    -curve "${GNARK_CURVE:-bn254}" \
+    -hash-to-field "${GNARK_HASH_TO_FIELD:-sha256}" \
+    -challenge-hash "${GNARK_CHALLENGE_HASH:-sha256}" \
+    -srs "${GNARK_SRS:-dev}"
 
 # Check if circuit files were created
-if [ ! -f "/out/circuit.r1cs" ] || [ ! -f "/out/proving.key" ] || [ ! -f "/out/verifying.key" ]; then
+if { [ ! -f "/out/circuit.r1cs" ] && [ ! -f "/out/circuit.scs" ]; } || [ ! -f "/out/proving.key" ] || [ ! -f "/out/verifying.key" ]; then
     print_message "$RED" "Circuit compilation failed - missing required files!"
     exit 1
 fi
@@ -19,7 +19,7 @@ print_message "$CYAN" "🔐 Generating proofs for all test cases..."
 cd /app
 
 # Check if circuit is compiled
-if [ ! -f "/out/circuit.r1cs" ]; then
+if [ ! -f "/out/circuit.r1cs" ] && [ ! -f "/out/circuit.scs" ]; then
     print_message "$RED" "Circuit not found. Please run compile-circuit.sh first."
     exit 1
 fi
@@ -58,7 +58,7 @@ hyperfine --min-runs 1 --max-runs 1 \
     --show-output \
     --export-json /out/benchmarks/all_proofs_benchmark.json \
     --export-markdown /out/benchmarks/proofs_summary.md \
-    'go run main.go circuit.go prove -d /out tests/test_case_{test_case}.json'
+    "go run . prove -d /out ${GNARK_PROVER_FLAGS:-} tests/test_case_{test_case}.json"
 
 print_message "$GREEN" "✅ All proofs generated successfully!"
 
@@ -88,4 +88,31 @@ if [ -f "/out/benchmarks/all_proofs_benchmark.json" ]; then
     fi
 fi
 
-print_message "$CYAN" "----------------------------------------" 
\ No newline at end of file
+print_message "$CYAN" "----------------------------------------"
+
+# Optional GPU pass: set GNARK_GPU=1 to re-run proving with ICICLE acceleration
+# and compare against the CPU timings above. Requires a binary built with the
+# 'icicle' tag (GNARK_BUILD_TAGS=icicle); otherwise prove -gpu stops with an error.
+if [ "${GNARK_GPU:-0}" = "1" ]; then
+    print_message "$CYAN" "🎮 Generating proofs with GPU acceleration..."
+
+    hyperfine --min-runs 1 --max-runs 1 \
+        -L test_case $TEST_CASES_LIST \
+        --show-output \
+        --export-json /out/benchmarks/all_proofs_gpu_benchmark.json \
+        --export-markdown /out/benchmarks/proofs_gpu_summary.md \
+        "go run -tags \"${GNARK_BUILD_TAGS:-}\" . prove -gpu -d /out ${GNARK_PROVER_FLAGS:-} tests/test_case_{test_case}.json"
+
+    cpu_avg=$(jq -r '([.results[].mean | select(. != null)] | add) / ([.results[].mean | select(. != null)] | length)' /out/benchmarks/all_proofs_benchmark.json 2>/dev/null)
+    gpu_avg=$(jq -r '([.results[].mean | select(. != null)] | add) / ([.results[].mean | select(. != null)] | length)' /out/benchmarks/all_proofs_gpu_benchmark.json 2>/dev/null)
+
+    print_message "$CYAN" ""
+    print_message "$CYAN" "📈 GPU vs CPU Proving:"
+    print_message "$CYAN" "----------------------------------------"
+    if [[ "$cpu_avg" =~ ^[0-9]+\.?[0-9]*$ ]] && [[ "$gpu_avg" =~ ^[0-9]+\.?[0-9]*$ ]]; then
+        printf "CPU Average Time: %.3f seconds\n" $cpu_avg
+        printf "GPU Average Time: %.3f seconds\n" $gpu_avg
+        printf "Speedup: %.2fx\n" $(echo "$cpu_avg / $gpu_avg" | bc -l)
+    fi
+    print_message "$CYAN" "----------------------------------------"
+fi 
\ No newline at end of file
@@ -46,10 +46,13 @@ unset IFS
 NUM_TEST_CASES=${#TEST_CASE_NUMBERS[@]}
 print_message "$CYAN" "🔍 Discovered $NUM_TEST_CASES test cases: ${TEST_CASE_NUMBERS[*]}"
 
+# Proof files are named after the backend recorded at compile time
+BACKEND=$(jq -r '.backend // "groth16"' /out/manifest.json 2>/dev/null || echo groth16)
+
 # Check if proof files exist
 missing_proofs=()
 for test_case in "${TEST_CASE_NUMBERS[@]}"; do
-    if [ ! -f "/out/proof_${test_case}.groth16" ]; then
+    if [ ! -f "/out/proof_${test_case}.${BACKEND}" ]; then
         missing_proofs+=($test_case)
     fi
 done
@@ -69,7 +72,7 @@ hyperfine --min-runs 1 --max-runs 1 \
     --show-output \
     --export-json /out/benchmarks/all_verifications_benchmark.json \
     --export-markdown /out/benchmarks/verifications_summary.md \
-    'go run main.go circuit.go verify -d /out tests/test_case_{test_case}.json'
+    'go run . verify -d /out tests/test_case_{test_case}.json'
 
 print_message "$GREEN" "✅ All proofs verified successfully!"
 
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// insecureSeedMarker is written next to the keys when they were generated from
+// a seed, so that seeded artifacts are never mistaken for a real setup.
+const insecureSeedMarker = "INSECURE_SEEDED_SETUP"
+
+var (
+	// command line flags
+	insecureSeed string
+)
+
+// seededReader is a deterministic byte stream: SHA-256(key || counter) for
+// counter = 0, 1, 2, ... It is only as unpredictable as its key; keyed from a
+// known seed it is NOT a secure source of randomness. It is safe for
+// concurrent use, as crypto/rand.Reader must be: throughput and loadtest prove
+// in parallel. Which proof gets which bytes then depends on scheduling, so
+// only sequential runs are reproducible.
+type seededReader struct {
+	mu      sync.Mutex
+	key     [32]byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *seededReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			var block [40]byte
+			copy(block[:32], r.key[:])
+			binary.BigEndian.PutUint64(block[32:], r.counter)
+			sum := sha256.Sum256(block[:])
+			r.buf = sum[:]
+			r.counter++
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
+}
+
+// useInsecureSeed replaces crypto/rand.Reader with a stream derived from seed.
+// gnark samples the setup toxic waste, the commitment keys and the prover's
+// blinding factors from crypto/rand.Reader, so this makes keys, proofs and the
+// exported Solidity verifier byte-identical across runs and machines.
+//
+// Anyone who knows the seed can recover the toxic waste and forge proofs. This
+// is meant only for CI and cross-machine artifact comparison.
+func useInsecureSeed(seed string) {
+	slog.Warn("Using INSECURE seeded randomness. Keys and proofs are forgeable; never deploy them.", "seed", seed)
+	reseed()
+}
+
+// reseed restarts the seeded stream from -insecure-seed, so that the next
+// draws repeat the first ones
+func reseed() {
+	rand.Reader = &seededReader{key: sha256.Sum256([]byte(insecureSeed))}
+}
+
+// markInsecureSetup labels the output directory when keys were generated with
+// a seed, and clears a stale label when they were not.
+func markInsecureSetup() {
+	marker := filepath.Join(outputDir, insecureSeedMarker)
+	if insecureSeed == "" {
+		if err := os.Remove(marker); err != nil && !os.IsNotExist(err) {
+			fatal("Failed to remove insecure setup marker", "error", err)
+		}
+		return
+	}
+
+	content := fmt.Sprintf("Keys in this directory were generated from the seed %q.\nThey provide no security and must not be deployed.\n", insecureSeed)
+	if err := os.WriteFile(marker, []byte(content), 0644); err != nil {
+		fatal("Failed to write insecure setup marker", "error", err)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// serializationFile is the output of the serialization command
+const serializationFile = "serialization.json"
+
+var (
+	// command line flags
+	bandwidthMbps float64
+)
+
+// SerializationStats is the cost of shipping one artifact in one format: its
+// encoding, with or without point compression, and the compression of the
+// file on top of it. Fetch is the time to download it at -bandwidth, and
+// Total the end-to-end latency of fetching, decompressing and decoding it.
+type SerializationStats struct {
+	Artifact        string  `json:"artifact"`
+	Encoding        string  `json:"encoding"`
+	Compression     string  `json:"compression"`
+	Bytes           int64   `json:"bytes"`
+	EncodeSecs      float64 `json:"encode_secs"`
+	CompressSecs    float64 `json:"compress_secs,omitempty"`
+	FetchSecs       float64 `json:"fetch_secs"`
+	DecompressSecs  float64 `json:"decompress_secs,omitempty"`
+	DecodeSecs      float64 `json:"decode_secs"`
+	TotalSecs       float64 `json:"total_secs"`
+	BytesVsSmallest float64 `json:"bytes_vs_smallest"`
+}
+
+// SerializationResults is the output of the serialization command
+type SerializationResults struct {
+	Settings      Manifest             `json:"settings"`
+	Environment   Environment          `json:"environment"`
+	Runs          int                  `json:"runs"`
+	BandwidthMbps float64              `json:"bandwidth_mbps"`
+	Results       []SerializationStats `json:"results"`
+}
+
+// artifactEncoding serializes a key or proof, with or without point
+// compression
+type artifactEncoding struct {
+	name   string
+	encode func(io.Writer) (int64, error)
+}
+
+// artifactCompression compresses a file for transfer, and back
+type artifactCompression struct {
+	name       string
+	compress   func([]byte) ([]byte, error)
+	decompress func([]byte) ([]byte, error)
+}
+
+var artifactCompressions = []artifactCompression{
+	{name: "none"},
+	{
+		name: "gzip",
+		compress: func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			if _, err := w.Write(data); err != nil {
+				return nil, err
+			}
+			err := w.Close()
+			return buf.Bytes(), err
+		},
+		decompress: func(data []byte) ([]byte, error) {
+			r, err := gzip.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(r)
+		},
+	},
+	{
+		name: "zstd",
+		compress: func(data []byte) ([]byte, error) {
+			w, err := zstd.NewWriter(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer w.Close()
+			return w.EncodeAll(data, nil), nil
+		},
+		decompress: func(data []byte) ([]byte, error) {
+			r, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return r.DecodeAll(data, nil)
+		},
+	},
+}
+
+// benchSerialization measures the end-to-end latency of shipping the proving
+// key, verifying key, and a proof to a client in each format: encoded with
+// point compression, as gnark writes them, or raw without it, and each file
+// either as is or compressed with gzip or zstd. Point compression trades a
+// smaller file for decoding that recovers every y coordinate with a square
+// root; file compression recovers little from either, since the points look
+// random, but raw encodings leave more redundancy. Every step runs -runs
+// times and the means are reported, with the download time at -bandwidth.
+func benchSerialization(testCaseFile string) {
+	resolveSettings()
+	if benchRuns < 1 {
+		fatal("-runs must be at least 1")
+	}
+	if bandwidthMbps <= 0 {
+		fatal("-bandwidth must be positive")
+	}
+
+	testCaseNum := testCaseID(testCaseFile)
+	artifacts := []struct {
+		name  string
+		path  string
+		fresh func() artifact
+	}{
+		{"proving key", filepath.Join(outputDir, "proving.key"), newProvingKey},
+		{"verifying key", filepath.Join(outputDir, "verifying.key"), newVerifyingKey},
+		{"proof", filepath.Join(outputDir, proofFileName(testCaseNum)), newProof},
+	}
+
+	slog.Info("Measuring serialization", "backend", provingBackend, "curve", curveName, "runs", benchRuns, "mbit_per_sec", bandwidthMbps)
+	var results []SerializationStats
+	for _, a := range artifacts {
+		value := a.fresh()
+		loadArtifact(a.path, value)
+
+		encodings := []artifactEncoding{{"compressed", value.WriteTo}}
+		if raw, ok := value.(interface {
+			WriteRawTo(w io.Writer) (int64, error)
+		}); ok {
+			encodings = append(encodings, artifactEncoding{"raw", raw.WriteRawTo})
+		}
+
+		first := len(results)
+		for _, e := range encodings {
+			var encoded []byte
+			encodeSecs := meanSecs(func() {
+				var buf bytes.Buffer
+				if _, err := e.encode(&buf); err != nil {
+					fatal("Failed to encode", "artifact", a.name, "error", err)
+				}
+				encoded = buf.Bytes()
+			})
+			decodeSecs := meanSecs(func() {
+				if _, err := a.fresh().ReadFrom(bytes.NewReader(encoded)); err != nil {
+					fatal("Failed to decode", "artifact", a.name, "error", err)
+				}
+			})
+
+			for _, c := range artifactCompressions {
+				stats := SerializationStats{
+					Artifact:    a.name,
+					Encoding:    e.name,
+					Compression: c.name,
+					Bytes:       int64(len(encoded)),
+					EncodeSecs:  encodeSecs,
+					DecodeSecs:  decodeSecs,
+				}
+				if c.compress != nil {
+					var compressed []byte
+					stats.CompressSecs = meanSecs(func() {
+						var err error
+						if compressed, err = c.compress(encoded); err != nil {
+							fatal("Failed to compress", "artifact", a.name, "codec", c.name, "error", err)
+						}
+					})
+					stats.DecompressSecs = meanSecs(func() {
+						if _, err := c.decompress(compressed); err != nil {
+							fatal("Failed to decompress", "artifact", a.name, "codec", c.name, "error", err)
+						}
+					})
+					stats.Bytes = int64(len(compressed))
+				}
+				stats.FetchSecs = float64(stats.Bytes) * 8 / (bandwidthMbps * 1e6)
+				stats.TotalSecs = stats.FetchSecs + stats.DecompressSecs + stats.DecodeSecs
+				results = append(results, stats)
+			}
+		}
+
+		smallest := results[first].Bytes
+		for _, r := range results[first:] {
+			smallest = min(smallest, r.Bytes)
+		}
+		for i := first; i < len(results); i++ {
+			results[i].BytesVsSmallest = float64(results[i].Bytes) / float64(smallest)
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%-14s %-11s %-5s %12s %10s %10s %10s %10s %10s\n", "Artifact", "Encoding", "File", "Size", "Encode", "Fetch", "Unpack", "Decode", "Total")
+	for _, r := range results {
+		fmt.Printf("%-14s %-11s %-5s %12s %10s %10s %10s %10s %10s\n", r.Artifact, r.Encoding, r.Compression,
+			formatBytes(uint64(r.Bytes)), formatSecs(r.EncodeSecs), formatSecs(r.FetchSecs),
+			formatSecs(r.DecompressSecs), formatSecs(r.DecodeSecs), formatSecs(r.TotalSecs))
+	}
+
+	// The fastest way to get each artifact to a client at this bandwidth
+	fmt.Println()
+	for i := 0; i < len(results); {
+		best := i
+		j := i
+		for ; j < len(results) && results[j].Artifact == results[i].Artifact; j++ {
+			if results[j].TotalSecs < results[best].TotalSecs {
+				best = j
+			}
+		}
+		b := results[best]
+		fmt.Printf("Fastest %s at %g Mbit/s: %s encoding, %s compression, %s end to end\n", b.Artifact, bandwidthMbps, b.Encoding, b.Compression, formatSecs(b.TotalSecs))
+		i = j
+	}
+
+	resultsDir := filepath.Join(outputDir, "benchmarks")
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		fatal("Failed to create results directory", "error", err)
+	}
+	data, err := json.MarshalIndent(SerializationResults{
+		Settings:      artifactSettings(),
+		Environment:   currentEnvironment(),
+		Runs:          benchRuns,
+		BandwidthMbps: bandwidthMbps,
+		Results:       results,
+	}, "", "  ")
+	if err != nil {
+		fatal("Failed to encode serialization results", "error", err)
+	}
+	path := filepath.Join(resultsDir, serializationFile)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		fatal("Failed to write serialization results", "error", err)
+	}
+	slog.Info("Results saved", "file", path)
+}
+
+// meanSecs runs f -runs times and returns its mean duration in seconds
+func meanSecs(f func()) float64 {
+	var total time.Duration
+	for run := 0; run < benchRuns; run++ {
+		start := time.Now()
+		f()
+		total += time.Since(start)
+	}
+	return total.Seconds() / float64(benchRuns)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/logger"
+	"github.com/rs/zerolog"
+)
+
+// solverLogMessage is what gnark logs, with the time taken, when the
+// constraint solver finishes inside a prover
+const solverLogMessage = "constraint system solver done"
+
+// solverClock picks the solve time out of gnark's log, so a proof can be split
+// into solving the witness and the proving backend without solving it twice
+type solverClock struct {
+	mu   sync.Mutex
+	took time.Duration
+	seen bool
+}
+
+var proverSolverClock = &solverClock{}
+
+// setGnarkLogger sends gnark's log to w, and tees every record of it, whatever
+// the level w keeps, into proverSolverClock
+func setGnarkLogger(w io.Writer) {
+	logger.Set(zerolog.New(zerolog.MultiLevelWriter(w, proverSolverClock)).With().Timestamp().Logger())
+}
+
+func (c *solverClock) Write(p []byte) (int, error) {
+	var entry struct {
+		Message string  `json:"message"`
+		TookMs  float64 `json:"took"`
+	}
+	if json.Unmarshal(p, &entry) == nil && entry.Message == solverLogMessage {
+		c.mu.Lock()
+		c.took = time.Duration(entry.TookMs * float64(time.Millisecond))
+		c.seen = true
+		c.mu.Unlock()
+	}
+	return len(p), nil
+}
+
+// reset forgets the last solve, before a new proof
+func (c *solverClock) reset() {
+	c.mu.Lock()
+	c.seen = false
+	c.mu.Unlock()
+}
+
+// last returns the time the last solve took, if one was logged since reset
+func (c *solverClock) last() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.took, c.seen
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark-crypto/kzg"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/test/unsafekzg"
+
+	kzg_bls12377 "github.com/consensys/gnark-crypto/ecc/bls12-377/kzg"
+	kzg_bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381/kzg"
+	kzg_bls24315 "github.com/consensys/gnark-crypto/ecc/bls24-315/kzg"
+	kzg_bls24317 "github.com/consensys/gnark-crypto/ecc/bls24-317/kzg"
+	kzg_bn254 "github.com/consensys/gnark-crypto/ecc/bn254/kzg"
+	kzg_bw6633 "github.com/consensys/gnark-crypto/ecc/bw6-633/kzg"
+	kzg_bw6761 "github.com/consensys/gnark-crypto/ecc/bw6-761/kzg"
+)
+
+// KZG SRS sources for the PLONK setup. A dev SRS is generated locally from
+// random toxic waste: fast to iterate with, but whoever ran the setup can forge
+// proofs. A file SRS comes from a powers-of-tau ceremony, serialized in
+// gnark-crypto's kzg.SRS format, and is what published benchmarks should use.
+const (
+	srsDev        = "dev"
+	srsFilePrefix = "file:"
+
+	defaultSRS = srsDev
+)
+
+var (
+	// command line flags
+	srsMode string
+
+	// srsHash is the SHA-256 of the SRS file, recorded in the manifest so
+	// results can be traced back to the ceremony they were produced with
+	srsHash string
+)
+
+func validateSRS(mode string) error {
+	switch {
+	case mode == srsDev:
+		return nil
+	case strings.HasPrefix(mode, srsFilePrefix) && len(mode) > len(srsFilePrefix):
+		return nil
+	default:
+		return fmt.Errorf("unknown SRS %q (use %s or %s<path>)", mode, srsDev, srsFilePrefix)
+	}
+}
+
+// newKZGSRS returns the canonical and Lagrange form SRS for the PLONK setup of
+// ccs, from the source selected with -srs
+func newKZGSRS(ccs constraint.ConstraintSystem) (kzg.SRS, kzg.SRS, error) {
+	if srsMode == srsDev {
+		slog.Warn("Generating a local dev KZG SRS for PLONK. Do not use the keys in production.")
+		return unsafekzg.NewSRS(ccs)
+	}
+
+	path := strings.TrimPrefix(srsMode, srsFilePrefix)
+	slog.Info("Loading KZG SRS", "file", path)
+	canonical, err := loadSRSFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sizeLagrange := ecc.NextPowerOfTwo(uint64(ccs.GetNbConstraints() + ccs.GetNbPublicVariables()))
+	return truncateSRS(canonical, int(sizeLagrange+3), int(sizeLagrange))
+}
+
+// loadSRSFile reads an SRS, checking that every point is on the curve and in
+// the right subgroup, and hashes the file as it goes
+func loadSRSFile(path string) (kzg.SRS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	srs := kzg.NewSRS(selectedCurve())
+	if _, err := srs.ReadFrom(bufio.NewReader(io.TeeReader(f, h))); err != nil {
+		return nil, fmt.Errorf("invalid SRS %s: %v", path, err)
+	}
+	// Hash any trailing bytes too, so the digest covers the whole file
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	srsHash = hex.EncodeToString(h.Sum(nil))
+
+	return srs, nil
+}
+
+// truncateSRS cuts the ceremony's powers of tau down to what the circuit needs
+// and derives the Lagrange form the PLONK setup expects
+func truncateSRS(srs kzg.SRS, sizeCanonical, sizeLagrange int) (kzg.SRS, kzg.SRS, error) {
+	checkSize := func(n int) error {
+		if n < sizeCanonical {
+			return fmt.Errorf("SRS has %d points but the circuit needs %d", n, sizeCanonical)
+		}
+		return nil
+	}
+
+	switch srs := srs.(type) {
+	case *kzg_bn254.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bn254.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bn254.SRS{Pk: kzg_bn254.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	case *kzg_bls12381.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bls12381.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bls12381.SRS{Pk: kzg_bls12381.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	case *kzg_bls12377.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bls12377.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bls12377.SRS{Pk: kzg_bls12377.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	case *kzg_bls24315.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bls24315.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bls24315.SRS{Pk: kzg_bls24315.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	case *kzg_bls24317.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bls24317.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bls24317.SRS{Pk: kzg_bls24317.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	case *kzg_bw6761.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bw6761.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bw6761.SRS{Pk: kzg_bw6761.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	case *kzg_bw6633.SRS:
+		if err := checkSize(len(srs.Pk.G1)); err != nil {
+			return nil, nil, err
+		}
+		srs.Pk.G1 = srs.Pk.G1[:sizeCanonical]
+		g1, err := kzg_bw6633.ToLagrangeG1(srs.Pk.G1[:sizeLagrange])
+		return srs, &kzg_bw6633.SRS{Pk: kzg_bw6633.ProvingKey{G1: g1}, Vk: srs.Vk}, err
+	default:
+		return nil, nil, fmt.Errorf("unsupported SRS type %T", srs)
+	}
+}
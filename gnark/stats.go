@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/frontend/cs/r1cs"
+	"github.com/consensys/gnark/frontend/cs/scs"
+)
+
+// ConstraintStats holds the size of the circuit compiled with one builder
+type ConstraintStats struct {
+	Builder         string
+	RangeCheck      string
+	Constraints     int
+	PublicWires     int
+	SecretWires     int
+	InternalWires   int
+	TotalWires      int
+	Coefficients    int
+	CompileTimeSecs float64
+}
+
+// circuitStats compiles the circuit with the R1CS (Groth16) and SCS (PLONK)
+// builders and reports their sizes side by side; -backend compiles only the
+// builder of that backend. Unless -range-check is given, each builder is
+// compiled with both lookup and decomposition range checks to show their
+// impact. The sizes of the configuration the output directory holds are
+// recorded in its results.
+func circuitStats() {
+	rangeChecks := []string{rangeCheckLookup, rangeCheckDecompose}
+	if rangeCheck != "" {
+		rangeChecks = []string{rangeCheck}
+	}
+	builders := []struct {
+		name    string
+		backend string
+		builder frontend.NewBuilder
+	}{
+		{"r1cs", backendGroth16, r1cs.NewBuilder},
+		{"scs", backendPLONK, scs.NewBuilder},
+	}
+	backend := provingBackend
+	defaultSettings()
+	recorded := artifactSettings()
+
+	slog.Info("Collecting constraint statistics", "circuit", circuitName, "curve", curveName)
+
+	var stats []ConstraintStats
+	var measurements []Measurement
+	for _, b := range builders {
+		if backend != "" && b.backend != backend {
+			continue
+		}
+		for _, rc := range rangeChecks {
+			slog.Info("Compiling", "builder", b.name, "range_check", rc)
+
+			provingBackend, rangeCheck = b.backend, rc
+			start := time.Now()
+			ccs, err := frontend.Compile(selectedCurve().ScalarField(), withRangeCheck(b.builder), selectedCircuit().New())
+			compileTime := time.Since(start)
+			if err != nil {
+				fatal("Circuit compilation failed", "builder", b.name, "error", err)
+			}
+
+			public := ccs.GetNbPublicVariables()
+			secret := ccs.GetNbSecretVariables()
+			internal := ccs.GetNbInternalVariables()
+			stats = append(stats, ConstraintStats{
+				Builder:         b.name,
+				RangeCheck:      rc,
+				Constraints:     ccs.GetNbConstraints(),
+				PublicWires:     public,
+				SecretWires:     secret,
+				InternalWires:   internal,
+				TotalWires:      public + secret + internal,
+				Coefficients:    ccs.GetNbCoefficients(),
+				CompileTimeSecs: compileTime.Seconds(),
+			})
+			if sameCircuit(currentSettings(), recorded) {
+				m := singleRun("stats", "", compileTime)
+				m.setCircuitSize(ccs)
+				measurements = append(measurements, m)
+			}
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%-16s", "")
+	for _, s := range stats {
+		fmt.Printf(" %16s", strings.ToUpper(s.Builder)+"/"+s.RangeCheck)
+	}
+	fmt.Println()
+	printRow := func(label string, value func(ConstraintStats) string) {
+		fmt.Printf("%-16s", label)
+		for _, s := range stats {
+			fmt.Printf(" %16s", value(s))
+		}
+		fmt.Println()
+	}
+	printRow("Constraints", func(s ConstraintStats) string { return fmt.Sprint(s.Constraints) })
+	printRow("Public wires", func(s ConstraintStats) string { return fmt.Sprint(s.PublicWires) })
+	printRow("Secret wires", func(s ConstraintStats) string { return fmt.Sprint(s.SecretWires) })
+	printRow("Internal wires", func(s ConstraintStats) string { return fmt.Sprint(s.InternalWires) })
+	printRow("Total wires", func(s ConstraintStats) string { return fmt.Sprint(s.TotalWires) })
+	printRow("Coefficients", func(s ConstraintStats) string { return fmt.Sprint(s.Coefficients) })
+	printRow("Compile time", func(s ConstraintStats) string { return fmt.Sprintf("%.2fs", s.CompileTimeSecs) })
+
+	// results.json describes one configuration, so the others are only shown
+	if len(measurements) == 0 {
+		slog.Info("None of the configurations is the one of the output directory, so nothing is recorded",
+			"dir", outputDir, "backend", recorded.Backend, "range_check", recorded.RangeCheck)
+		return
+	}
+	recordResults(recorded, measurements...)
+	slog.Info("Statistics recorded", "file", filepath.Join(outputDir, "benchmarks", resultsFile),
+		"backend", recorded.Backend, "range_check", recorded.RangeCheck)
+}
+
+// sameCircuit reports whether two settings compile the same constraint system
+func sameCircuit(a, b Manifest) bool {
+	return a.Circuit == b.Circuit && canonicalSetting("curve", a.Curve) == canonicalSetting("curve", b.Curve) &&
+		a.HashToField == b.HashToField && a.Backend == b.Backend && a.RangeCheck == b.RangeCheck
+}
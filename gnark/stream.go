@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Encodings of the proof prove -stdin writes to stdout
+const (
+	proofFormatHex    = "hex"
+	proofFormatBase64 = "base64"
+	proofFormatBinary = "binary"
+)
+
+var (
+	// command line flags
+	proveStdin  bool
+	proofFormat string
+)
+
+// proofOutput is the stdout of the process while -stdin is given, which then
+// carries the proof alone; everything else printed goes to stderr
+var proofOutput io.Writer
+
+// reserveStdout keeps stdout for the proof when -stdin is given, by sending
+// what the commands print to stdout, and gnark's log, to stderr instead
+func reserveStdout() {
+	if !proveStdin || proofOutput != nil {
+		return
+	}
+	proofOutput = os.Stdout
+	os.Stdout = os.Stderr
+}
+
+// proveFromStdin is prove -stdin: it reads one test case as JSON from stdin,
+// proves it with the circuit and proving key in -d and writes the proof to
+// stdout in -proof-format, so that the prover can sit in a pipe. Nothing is
+// written to -d: neither the proof nor results.
+func proveFromStdin(ctx context.Context, args []string) {
+	if len(args) > 0 || selectAll || tagFilter != "" || caseRanges != "" {
+		fatal("-stdin reads the test case from stdin: give no test case files, -all, -tag or -cases")
+	}
+	encode, err := proofEncoder(proofFormat)
+	if err != nil {
+		fatal("Invalid -proof-format", "error", err)
+	}
+	resolveSettings()
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fatal("Failed to read stdin", "error", err)
+	}
+	testCase, err := parseTestCase(data)
+	if err != nil {
+		fatal("Invalid test case on stdin", "error", err)
+	}
+	witness, err := createWitness(testCase)
+	if err != nil {
+		fatal("Failed to create witness", "error", err)
+	}
+
+	checkArtifacts(circuitFileName(), "proving.key")
+	ccs, pk := loadProvingArtifacts(ctx, &IOStats{})
+
+	var proof artifact
+	var proverBackend string
+	var gpuFailed time.Duration
+	start := time.Now()
+	err = runPhase(ctx, func() (err error) {
+		proof, proverBackend, gpuFailed, err = proveCircuitTimed(ccs, pk, witness)
+		return err
+	})
+	provingTime := time.Since(start) - gpuFailed
+	if aborted(err) {
+		slog.Error("Aborted prove", "reason", abortReason(err), "secs", provingTime.Seconds())
+		exitAborted(err)
+	}
+	if err != nil {
+		fatal("Failed to generate proof", "error", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := proof.WriteTo(&buf); err != nil {
+		fatal("Failed to encode proof", "error", err)
+	}
+	if _, err := proofOutput.Write(encode(buf.Bytes())); err != nil {
+		fatal("Failed to write proof", "error", err)
+	}
+	slog.Info("Proof generated", "secs", provingTime.Seconds(), "prover", proverBackend, "bytes", buf.Len(), "format", proofFormat)
+}
+
+// proofEncoder returns how to encode a proof for -proof-format: hex and
+// base64 end with a newline, binary is the proof as gnark writes it to a file
+func proofEncoder(format string) (func([]byte) []byte, error) {
+	switch format {
+	case proofFormatHex:
+		return func(b []byte) []byte { return []byte(hex.EncodeToString(b) + "\n") }, nil
+	case proofFormatBase64:
+		return func(b []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(b) + "\n") }, nil
+	case proofFormatBinary:
+		return func(b []byte) []byte { return b }, nil
+	default:
+		return nil, fmt.Errorf("unknown -proof-format %q (use %s, %s or %s)", format, proofFormatHex, proofFormatBase64, proofFormatBinary)
+	}
+}
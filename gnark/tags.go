@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// command line flags
+	tagFilter string
+)
+
+// Tags the generators and importers give their test cases
+const (
+	tagGenerated  = "generated"
+	tagEdgeCase   = "edge-case"
+	tagWycheproof = "wycheproof"
+	tagWebAuthn   = "webauthn"
+	tagFIDO2      = "fido2"
+	tagHardware   = "hardware"
+	tagEthereum   = "ethereum"
+
+	// tagKnownFailure marks a valid signature the circuit cannot prove
+	// because of a limitation of gnark. negative expects it to fail.
+	tagKnownFailure = "known-failure"
+)
+
+// tagPattern is what a tag may be: lowercase words joined by hyphens, so
+// that -tag can list them with commas
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// filterTags are the tags of -tag
+func filterTags() []string {
+	var tags []string
+	for _, tag := range strings.Split(tagFilter, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// hasTag says whether a test case carries any of the tags
+func (t *TestCase) hasTag(tags []string) bool {
+	for _, want := range tags {
+		for _, tag := range t.Tags {
+			if tag == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectTestCases is the test cases a command runs on: the given files, or
+// every test case in -tests when -tag is set and none are given, keeping
+// those that carry a tag of -tag. Batch test cases are left out, since
+// their signatures are tagged rather than the batch. Without -tag the files
+// are taken as they are.
+func selectTestCases(files []string, pattern string) []string {
+	tags := filterTags()
+	if len(tags) == 0 {
+		return files
+	}
+	if len(files) == 0 {
+		var err error
+		files, err = filepath.Glob(filepath.Join(testsDir, pattern))
+		if err != nil {
+			fatal("Failed to find test cases", "error", err)
+		}
+		sortCases(files)
+	}
+
+	var selected []string
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil && isBatchTestCase(data) {
+			continue
+		}
+		testCase, err := loadTestCase(file)
+		if err != nil {
+			fatal("Failed to load test case", "error", err)
+		}
+		if testCase.hasTag(tags) {
+			selected = append(selected, file)
+		}
+	}
+	if len(selected) == 0 {
+		fatal("No test cases tagged", "tags", strings.Join(tags, " or "))
+	}
+	fmt.Printf("Selected %d of %d test cases tagged %s\n", len(selected), len(files), strings.Join(tags, " or "))
+	return selected
+}
+
+// tagOf turns a name such as a Wycheproof flag, e.g. SignatureMalleability,
+// into a tag, signature-malleability
+func tagOf(name string) string {
+	var b strings.Builder
+	var prev rune
+	for _, c := range name {
+		switch {
+		case unicode.IsUpper(c):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteByte('-')
+			}
+			b.WriteRune(unicode.ToLower(c))
+		case unicode.IsLower(c) || unicode.IsDigit(c):
+			b.WriteRune(c)
+		default:
+			if prev != '-' && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			c = '-'
+		}
+		prev = c
+	}
+	return strings.Trim(b.String(), "-")
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// defaultMessage is the message the test cases sign, as in the Rust generator
+const defaultMessage = "Test message for signature"
+
+var (
+	// command line flags
+	genCount   int
+	genMessage string
+	genSeed    string
+)
+
+// Nonce derivations recorded in the test cases
+const (
+	nonceRandom  = "random"
+	nonceRFC6979 = "rfc6979-hmac-sha256"
+)
+
+// generateTestData writes -count test cases into -tests, replacing the test
+// cases already there. Each signs -message with a fresh P-256 key, or a random
+// 32-byte message when -message is empty, with crypto/ecdsa. The message is
+// hashed with SHA-256, and s is normalized to the lower half of the group
+// order as the Rust generator does, so the files match what it writes for
+// gnark. With -seed the keys and random messages are drawn from the seed and
+// the nonces derived with RFC 6979, so the same seed writes the same files on
+// any machine. Larger corpora extend smaller ones: -count 1000 -seed 42
+// starts with the test cases of -count 10 -seed 42. A seeded run prints a
+// digest of the corpus to compare across machines. With -edge-cases it writes
+// the boundary vectors instead, and with -batch batches of signatures.
+func generateTestData() {
+	if genEdgeCases {
+		generateEdgeCases()
+		return
+	}
+	if genBatch > 0 {
+		generateBatchTestData()
+		return
+	}
+	if genCount < 1 {
+		fatal("-count must be at least 1")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+	stale, err := filepath.Glob(filepath.Join(testsDir, "test_case_*.json"))
+	if err != nil {
+		fatal("Failed to find test cases", "error", err)
+	}
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil {
+			fatal("Failed to remove old test case", "error", err)
+		}
+	}
+
+	// Keys and messages come from the seed when there is one. The nonces
+	// never do: RFC 6979 derives them from the key and the message hash.
+	var random io.Reader = rand.Reader
+	if genSeed != "" {
+		random = &seededReader{key: sha256.Sum256([]byte("gen-testdata " + genSeed))}
+	}
+
+	slog.Info("Generating P-256 test cases", "count", genCount, "dir", testsDir)
+	corpus := sha256.New()
+	for i := 1; i <= genCount; i++ {
+		message := []byte(genMessage)
+		if genMessage == "" {
+			message = make([]byte, 32)
+			if _, err := io.ReadFull(random, message); err != nil {
+				fatal("Failed to generate message", "error", err)
+			}
+		}
+		hash := sha256.Sum256(message)
+
+		var testCase *TestCase
+		if genSeed == "" {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			if err != nil {
+				fatal("Failed to generate key", "error", err)
+			}
+			r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+			if err != nil {
+				fatal("Failed to sign", "error", err)
+			}
+			testCase = newTestCase(&key.PublicKey, hash[:], r, s)
+			testCase.Nonce = nonceRandom
+		} else {
+			key := seededKey(random)
+			r, s := signRFC6979(key, hash[:])
+			testCase = newTestCase(&key.PublicKey, hash[:], r, s)
+			testCase.Nonce = nonceRFC6979
+			testCase.Source = fmt.Sprintf("gen-testdata -seed %q, test case %d", genSeed, i)
+		}
+		testCase.Tags = []string{tagGenerated}
+		corpus.Write(writeTestCase(filepath.Join(testsDir, fmt.Sprintf("test_case_%d.json", i)), testCase))
+	}
+	slog.Info("Test cases written", "first", "test_case_1.json", "last", fmt.Sprintf("test_case_%d.json", genCount))
+	// A seeded corpus is the same everywhere, which its digest confirms
+	// without comparing the files
+	if genSeed != "" {
+		fmt.Printf("Corpus SHA-256: %x\n", corpus.Sum(nil))
+	}
+}
+
+// seededKey draws a P-256 private key from a deterministic stream.
+// crypto/ecdsa.GenerateKey cannot be used, as it does not read its random
+// source deterministically.
+func seededKey(random io.Reader) *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	n := curve.Params().N
+	buf := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(random, buf); err != nil {
+			fatal("Failed to generate key", "error", err)
+		}
+		d := new(big.Int).SetBytes(buf)
+		if d.Sign() > 0 && d.Cmp(n) < 0 {
+			key := &ecdsa.PrivateKey{D: d}
+			key.PublicKey.Curve = curve
+			key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(buf)
+			return key
+		}
+	}
+}
+
+// signRFC6979 signs a SHA-256 hash with the nonce RFC 6979 derives from the
+// key and the hash, so the signature depends on nothing else
+func signRFC6979(key *ecdsa.PrivateKey, hash []byte) (r, s *big.Int) {
+	curve := key.Curve
+	n := curve.Params().N
+	k := rfc6979Nonce(n, key.D, hash)
+	x, _ := curve.ScalarBaseMult(k.Bytes())
+	r = new(big.Int).Mod(x, n)
+	s = new(big.Int).Mul(r, key.D)
+	s.Add(s, new(big.Int).SetBytes(hash))
+	s.Mul(s, new(big.Int).ModInverse(k, n))
+	s.Mod(s, n)
+	if r.Sign() == 0 || s.Sign() == 0 {
+		fatal("Failed to sign: the RFC 6979 nonce gave a zero r or s")
+	}
+	return r, s
+}
+
+// rfc6979Nonce derives the nonce of RFC 6979 section 3.2 with HMAC-SHA256,
+// for a 256-bit group order n, as P-256 and secp256k1 have, and a 256-bit hash
+func rfc6979Nonce(n, d *big.Int, hash []byte) *big.Int {
+	x := d.FillBytes(make([]byte, 32))
+	h := new(big.Int).SetBytes(hash[:32])
+	h1 := h.Mod(h, n).FillBytes(make([]byte, 32))
+
+	v := make([]byte, 32)
+	for i := range v {
+		v[i] = 0x01
+	}
+	k := make([]byte, 32)
+	mac := func(key []byte, data ...[]byte) []byte {
+		m := hmac.New(sha256.New, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+	k = mac(k, v, []byte{0x00}, x, h1)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h1)
+	v = mac(k, v)
+	for {
+		v = mac(k, v)
+		nonce := new(big.Int).SetBytes(v)
+		if nonce.Sign() > 0 && nonce.Cmp(n) < 0 {
+			return nonce
+		}
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
+
+// newTestCase encodes a signature over a message hash in the test case
+// format, with s normalized to the lower half of the group order
+func newTestCase(pub *ecdsa.PublicKey, hash []byte, r, s *big.Int) *TestCase {
+	n := pub.Curve.Params().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s = new(big.Int).Sub(n, s)
+	}
+	return &TestCase{
+		R:       fmt.Sprintf("0x%x", r),
+		S:       fmt.Sprintf("0x%x", s),
+		MsgHash: fmt.Sprintf("0x%x", new(big.Int).SetBytes(hash)),
+		PubKeyX: fmt.Sprintf("0x%x", pub.X),
+		PubKeyY: fmt.Sprintf("0x%x", pub.Y),
+	}
+}
+
+// writeTestCase writes a test case file in the format the Rust generator
+// uses, and returns what it wrote
+func writeTestCase(path string, testCase *TestCase) []byte {
+	data, err := json.MarshalIndent(testCase, "", "  ")
+	if err != nil {
+		fatal("Failed to encode test case", "error", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		fatal("Failed to write test case", "error", err)
+	}
+	return data
+}
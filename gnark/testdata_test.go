@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+)
+
+func hexInt(t *testing.T, s string) *big.Int {
+	t.Helper()
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		t.Fatalf("invalid hex %q", s)
+	}
+	return n
+}
+
+// The vectors of RFC 6979 appendix A.2.5: P-256 with SHA-256
+func TestRFC6979(t *testing.T) {
+	curve := elliptic.P256()
+	d := hexInt(t, "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+
+	tests := []struct {
+		message string
+		k, r, s string
+	}{
+		{
+			message: "sample",
+			k:       "A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60",
+			r:       "EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			s:       "F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8",
+		},
+		{
+			message: "test",
+			k:       "D16B6AE827F17175E040871A1C7EC3500192C4C92677336EC2537ACAEE0008E0",
+			r:       "F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			s:       "019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			hash := sha256.Sum256([]byte(tt.message))
+			if k := rfc6979Nonce(curve.Params().N, d, hash[:]); k.Cmp(hexInt(t, tt.k)) != 0 {
+				t.Errorf("nonce = %X, want %s", k, tt.k)
+			}
+			r, s := signRFC6979(key, hash[:])
+			if r.Cmp(hexInt(t, tt.r)) != 0 || s.Cmp(hexInt(t, tt.s)) != 0 {
+				t.Errorf("signature = (%X, %X), want (%s, %s)", r, s, tt.r, tt.s)
+			}
+			if !ecdsa.Verify(&key.PublicKey, hash[:], r, s) {
+				t.Error("signature does not verify")
+			}
+		})
+	}
+}
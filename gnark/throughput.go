@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/consensys/gnark/backend/witness"
+)
+
+var (
+	// command line flags
+	throughputWorkers int
+	throughputProofs  int
+)
+
+// measureThroughput models a proving service: -workers provers share the
+// circuit and proving key and prove test cases concurrently, round robin,
+// until -proofs proofs are done. It reports the proofs per second, the latency
+// of each proof under that load, and how much of the available CPU time the
+// provers used. Witnesses are created before timing starts.
+func measureThroughput(testCaseFiles []string) {
+	resolveSettings()
+	startMetricsServer()
+	if throughputWorkers == 0 {
+		throughputWorkers = runtime.NumCPU()
+	}
+	if throughputProofs == 0 {
+		throughputProofs = 4 * throughputWorkers
+	}
+	if throughputWorkers < 1 || throughputProofs < 1 {
+		fatal("-workers and -proofs must be at least 1")
+	}
+	if len(testCaseFiles) == 0 {
+		var err error
+		testCaseFiles, err = filepath.Glob(filepath.Join(testsDir, selectedCircuit().TestCases))
+		if err != nil {
+			fatal("Failed to find test cases", "error", err)
+		}
+		if len(testCaseFiles) == 0 {
+			fatal("No test cases found", "dir", testsDir)
+		}
+	}
+
+	ccs := newConstraintSystem()
+	loadArtifact(filepath.Join(outputDir, circuitFileName()), ccs)
+	pk := newProvingKey()
+	loadArtifact(filepath.Join(outputDir, "proving.key"), pk)
+
+	witnesses := make([]witness.Witness, len(testCaseFiles))
+	for i, testCaseFile := range testCaseFiles {
+		testCase, err := loadTestCase(testCaseFile)
+		if err != nil {
+			fatal("Failed to load test case", "error", err)
+		}
+		witnesses[i], err = createWitness(testCase)
+		if err != nil {
+			fatal("Failed to create witness", "error", err)
+		}
+	}
+
+	slog.Info("Proving concurrently", "proofs", throughputProofs, "test_cases", len(witnesses), "workers", throughputWorkers)
+	jobs := make(chan int)
+	latencies := make([]time.Duration, throughputProofs)
+	var wg sync.WaitGroup
+
+	stopAllocs := trackAllocs()
+	cpuBefore := readCPUClasses()
+	start := time.Now()
+	for w := 0; w < throughputWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				proofStart := time.Now()
+				_, hardware, err := proveCircuit(ccs, pk, witnesses[i%len(witnesses)])
+				latencies[i] = time.Since(proofStart)
+				if err != nil {
+					fatal("Failed to generate proof", "error", err)
+				}
+				observePhase("prove_throughput", latencies[i])
+				proofsCompleted.WithLabelValues(hardware).Inc()
+			}
+		}()
+	}
+	for i := 0; i < throughputProofs; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+	cpu := readCPUClasses().sub(cpuBefore)
+	allocs := stopAllocs()
+
+	result := Measurement{PhaseStats: summarize("prove_throughput", "", latencies)}
+	result.Workers = throughputWorkers
+	result.ProofsPerSec = float64(throughputProofs) / elapsed.Seconds()
+	result.CPUUtilization = cpu.utilization()
+	result.Allocs = &allocs
+	recordResults(artifactSettings(), result)
+
+	fmt.Printf("\n✓ %d proofs in %s: %.3f proofs/s with %d workers\n", throughputProofs, formatSecs(elapsed.Seconds()),
+		result.ProofsPerSec, throughputWorkers)
+	fmt.Printf("  Latency under load: mean %s, median %s, p95 %s\n", formatSecs(result.MeanSecs),
+		formatSecs(result.MedianSecs), formatSecs(result.P95Secs))
+	fmt.Printf("  CPU utilization: %.0f%% of %d CPUs, %s CPU time per proof, peak heap %s\n", result.CPUUtilization*100,
+		runtime.GOMAXPROCS(0), formatSecs(cpu.used()/float64(throughputProofs)), formatBytes(allocs.PeakHeapBytes))
+}
+
+// cpuClasses is the CPU time of the Go runtime's /cpu/classes metrics: the
+// time available to the process under GOMAXPROCS, and the part of it left idle
+type cpuClasses struct {
+	total, idle float64
+}
+
+// readCPUClasses samples the CPU time metrics. They are only brought up to date
+// by a garbage collection, so it runs one first.
+func readCPUClasses() cpuClasses {
+	runtime.GC()
+	samples := []metrics.Sample{
+		{Name: "/cpu/classes/total:cpu-seconds"},
+		{Name: "/cpu/classes/idle:cpu-seconds"},
+	}
+	metrics.Read(samples)
+	return cpuClasses{total: samples[0].Value.Float64(), idle: samples[1].Value.Float64()}
+}
+
+func (c cpuClasses) sub(before cpuClasses) cpuClasses {
+	return cpuClasses{total: c.total - before.total, idle: c.idle - before.idle}
+}
+
+// used is the CPU time spent running Go code, including the runtime
+func (c cpuClasses) used() float64 {
+	return c.total - c.idle
+}
+
+// utilization is the fraction of the available CPU time that was used
+func (c cpuClasses) utilization() float64 {
+	if c.total <= 0 {
+		return 0
+	}
+	return c.used() / c.total
+}
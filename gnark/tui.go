@@ -0,0 +1,550 @@
+//go:build tui && !wasm
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tuiProgressInterval is how often the commands the TUI runs log progress
+const tuiProgressInterval = "1s"
+
+var (
+	tuiTitle    = lipgloss.NewStyle().Bold(true)
+	tuiHeader   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6"))
+	tuiSelected = lipgloss.NewStyle().Reverse(true)
+	tuiRunning  = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	tuiFailed   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	tuiFaint    = lipgloss.NewStyle().Faint(true)
+)
+
+// tuiJob is a cell of the grid queued or running: its circuit is compiled
+// into the cell when it is not there, then every test case is proved and
+// verified, each step a child process as in the matrix
+type tuiJob struct {
+	circuit, backend, curve, rangeCheck string
+	step                                string
+	started                             time.Time
+	stop                                chan struct{}
+}
+
+// tuiLogMsg is a line a running job printed, and tuiJobDoneMsg its end
+type tuiLogMsg struct {
+	line   string
+	record map[string]any
+}
+
+type tuiJobDoneMsg struct{ err error }
+
+// tuiStepMsg says which step of the running job started
+type tuiStepMsg string
+
+type tuiTickMsg time.Time
+
+// tuiModel is the state of the TUI: the grid of circuit × backend on a curve
+// and range check, the selected cell and the jobs queued
+type tuiModel struct {
+	circuits    []string
+	backends    []string
+	curves      []string
+	rangeChecks []string
+	curve       int
+	rangeCheck  int
+	row, col    int
+
+	queue   []*tuiJob
+	running *tuiJob
+	events  chan tea.Msg
+	// progress is the last progress of the running job, lastLine the last
+	// line it printed, and failed the error of each cell whose last job failed
+	progress string
+	lastLine string
+	failed   map[string]string
+	results  map[string]*Results
+	quitting bool
+}
+
+// runTUI is the tui command: a grid of the circuit variants by proving
+// backend, each cell showing the proving and verification times and proof
+// size measured in its directory under <dir>/matrix, where the matrix
+// command keeps its cells. Enter runs the selected cell, a every cell, with
+// live progress, and the results of the selected cell are listed below.
+func runTUI() {
+	if fi, err := os.Stdout.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		fatal("The tui command needs a terminal")
+	}
+	m := &tuiModel{
+		circuits:    circuitNames(),
+		backends:    []string{backendGroth16, backendPLONK},
+		rangeChecks: []string{rangeCheckLookup, rangeCheckDecompose},
+		events:      make(chan tea.Msg, 64),
+		failed:      map[string]string{},
+		results:     map[string]*Results{},
+	}
+	for _, id := range supportedCurves {
+		m.curves = append(m.curves, curveDisplayName(id))
+	}
+	if curveName != "" {
+		id, err := parseCurve(curveName)
+		if err != nil {
+			fatal("Invalid -curve", "error", err)
+		}
+		m.curve = slices.Index(m.curves, curveDisplayName(id))
+	}
+	if rangeCheck != "" {
+		if err := validateRangeCheck(rangeCheck); err != nil {
+			fatal("Invalid -range-check", "error", err)
+		}
+		m.rangeCheck = slices.Index(m.rangeChecks, rangeCheck)
+	}
+	if provingBackend == backendPLONK {
+		m.col = 1
+	}
+	m.refresh()
+
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		fatal("TUI failed", "error", err)
+	}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.waitForEvent(), tuiTick())
+}
+
+func tuiTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return tuiTickMsg(t) })
+}
+
+func (m *tuiModel) waitForEvent() tea.Cmd {
+	return func() tea.Msg { return <-m.events }
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		return m, m.handleKey(msg.String())
+	case tuiTickMsg:
+		return m, tuiTick()
+	case tuiStepMsg:
+		m.running.step = string(msg)
+		m.progress = ""
+		return m, m.waitForEvent()
+	case tuiLogMsg:
+		if msg.record != nil && msg.record["msg"] == "Progress" {
+			m.progress = formatProgressRecord(m.running.step, msg.record)
+		} else {
+			m.lastLine = describeLogLine(msg)
+		}
+		return m, m.waitForEvent()
+	case tuiJobDoneMsg:
+		job := m.running
+		m.running = nil
+		key := tuiKey(job.circuit, job.backend, job.curve, job.rangeCheck)
+		delete(m.failed, key)
+		if msg.err != nil {
+			m.failed[key] = fmt.Sprintf("%s failed: %v", job.step, msg.err)
+		}
+		m.refresh()
+		if m.quitting {
+			return m, tea.Quit
+		}
+		m.startNext()
+		return m, m.waitForEvent()
+	}
+	return m, nil
+}
+
+func (m *tuiModel) handleKey(key string) tea.Cmd {
+	switch key {
+	case "q", "ctrl+c", "esc":
+		m.queue = nil
+		if m.running == nil {
+			return tea.Quit
+		}
+		// Interrupt the running command, which records the abort, and quit
+		// once it has exited
+		if !m.quitting {
+			m.quitting = true
+			close(m.running.stop)
+		}
+	case "up", "k":
+		m.row = max(0, m.row-1)
+	case "down", "j":
+		m.row = min(len(m.circuits)-1, m.row+1)
+	case "left", "h":
+		m.col = max(0, m.col-1)
+	case "right", "l":
+		m.col = min(len(m.backends)-1, m.col+1)
+	case "c":
+		m.curve = (m.curve + 1) % len(m.curves)
+		m.refresh()
+	case "r":
+		m.rangeCheck = (m.rangeCheck + 1) % len(m.rangeChecks)
+		m.refresh()
+	case "enter":
+		m.enqueue(m.circuits[m.row], m.backends[m.col])
+	case "a":
+		for _, circuit := range m.circuits {
+			for _, backend := range m.backends {
+				m.enqueue(circuit, backend)
+			}
+		}
+	case "x":
+		m.queue = nil
+	}
+	return nil
+}
+
+// enqueue queues a cell on the current curve and range check, unless it is
+// queued or running already
+func (m *tuiModel) enqueue(circuit, backend string) {
+	job := &tuiJob{circuit: circuit, backend: backend, curve: m.curves[m.curve], rangeCheck: m.rangeChecks[m.rangeCheck]}
+	key := tuiKey(job.circuit, job.backend, job.curve, job.rangeCheck)
+	if m.jobState(key) != "" {
+		return
+	}
+	m.queue = append(m.queue, job)
+	m.startNext()
+}
+
+// startNext starts the first queued job when none is running. Jobs run one at
+// a time, so that they do not slow each other down.
+func (m *tuiModel) startNext() {
+	if m.running != nil || len(m.queue) == 0 || m.quitting {
+		return
+	}
+	m.running, m.queue = m.queue[0], m.queue[1:]
+	m.running.started = time.Now()
+	m.running.stop = make(chan struct{})
+	m.progress, m.lastLine = "", ""
+	go m.running.run(m.events)
+}
+
+// run runs the steps of a job, sending what they print and when they end
+func (j *tuiJob) run(events chan<- tea.Msg) {
+	self, err := os.Executable()
+	if err != nil {
+		events <- tuiJobDoneMsg{err}
+		return
+	}
+	base := tuiCellBase(j.backend, j.curve, j.rangeCheck)
+	common := []string{"-d", base, "-circuit", j.circuit, "-log-format", "json", "-progress-interval", tuiProgressInterval}
+	var steps [][]string
+	if !tuiCompiled(tuiCellDir(j.circuit, j.backend, j.curve, j.rangeCheck)) {
+		steps = append(steps, append([]string{"compile", "-backend", j.backend, "-curve", j.curve, "-range-check", j.rangeCheck}, common...))
+	}
+	proveArgs := append([]string{"prove-and-verify", "-tests", testsDir}, common...)
+	if caseRanges != "" {
+		proveArgs = append(proveArgs, "-cases", caseRanges)
+	} else {
+		proveArgs = append(proveArgs, "-all")
+	}
+	steps = append(steps, proveArgs)
+
+	for _, args := range steps {
+		events <- tuiStepMsg(args[0])
+		if err := runTUIStep(self, args, j.stop, events); err != nil {
+			events <- tuiJobDoneMsg{err}
+			return
+		}
+	}
+	events <- tuiJobDoneMsg{}
+}
+
+// runTUIStep runs a command of the benchmark, sending each line it prints,
+// and interrupts it when stop is closed
+func runTUIStep(self string, args []string, stop <-chan struct{}, events chan<- tea.Msg) error {
+	select {
+	case <-stop:
+		return fmt.Errorf("interrupted")
+	default:
+	}
+	reader, writer := io.Pipe()
+	cmd := exec.Command(self, args...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+			if err := cmd.Process.Signal(os.Interrupt); err != nil {
+				cmd.Process.Kill()
+			}
+		case <-done:
+		}
+	}()
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var record map[string]any
+			if json.Unmarshal([]byte(line), &record) != nil {
+				record = nil
+			}
+			events <- tuiLogMsg{line: line, record: record}
+		}
+		io.Copy(io.Discard, reader)
+	}()
+	err := cmd.Wait()
+	close(done)
+	writer.Close()
+	<-scanned
+	return err
+}
+
+// tuiCellBase is the directory of the cells of a backend, curve and range
+// check, as the matrix lays them out, and tuiCellDir that of a circuit in it:
+// the default circuit in the directory itself and the others below it, as
+// -circuit places them
+func tuiCellBase(backend, curve, rangeCheck string) string {
+	return filepath.Join(outputDir, "matrix", backend, curve, rangeCheck)
+}
+
+func tuiCellDir(circuit, backend, curve, rangeCheck string) string {
+	dir := tuiCellBase(backend, curve, rangeCheck)
+	if circuit != defaultCircuit {
+		dir = filepath.Join(dir, circuit)
+	}
+	return dir
+}
+
+// tuiCompiled says whether the circuit and keys of a cell are there
+func tuiCompiled(dir string) bool {
+	for _, name := range []string{manifestFile, "proving.key", "verifying.key"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func tuiKey(circuit, backend, curve, rangeCheck string) string {
+	return strings.Join([]string{circuit, backend, curve, rangeCheck}, "/")
+}
+
+// refresh reads the results of the cells on the current curve and range check
+func (m *tuiModel) refresh() {
+	for _, circuit := range m.circuits {
+		for _, backend := range m.backends {
+			dir := tuiCellDir(circuit, backend, m.curves[m.curve], m.rangeChecks[m.rangeCheck])
+			m.results[dir] = loadCellResults(dir)
+		}
+	}
+}
+
+// loadCellResults reads the results file of a cell, nil when there is none
+func loadCellResults(dir string) *Results {
+	data, err := os.ReadFile(filepath.Join(dir, "benchmarks", resultsFile))
+	if err != nil {
+		return nil
+	}
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil
+	}
+	return &results
+}
+
+// jobState says whether the job of a cell is running or queued
+func (m *tuiModel) jobState(key string) string {
+	if j := m.running; j != nil && tuiKey(j.circuit, j.backend, j.curve, j.rangeCheck) == key {
+		return "running"
+	}
+	for _, j := range m.queue {
+		if tuiKey(j.circuit, j.backend, j.curve, j.rangeCheck) == key {
+			return "queued"
+		}
+	}
+	return ""
+}
+
+// cellSummary is what a cell of the grid shows: the mean proving and
+// verification times over the test cases and the proof size, or its state
+func (m *tuiModel) cellSummary(circuit, backend string) string {
+	curve, rangeCheck := m.curves[m.curve], m.rangeChecks[m.rangeCheck]
+	key := tuiKey(circuit, backend, curve, rangeCheck)
+	switch m.jobState(key) {
+	case "running":
+		status := fmt.Sprintf("▶ %s %s", m.running.step, time.Since(m.running.started).Round(time.Second))
+		if m.progress != "" {
+			status += ", " + m.progress
+		}
+		return tuiRunning.Render(status)
+	case "queued":
+		return tuiFaint.Render("queued")
+	}
+	if err, ok := m.failed[key]; ok {
+		return tuiFailed.Render(err)
+	}
+	dir := tuiCellDir(circuit, backend, curve, rangeCheck)
+	prove, verify, proofBytes := meanProofTimes(m.results[dir])
+	switch {
+	case prove > 0:
+		return fmt.Sprintf("prove %s  verify %s  %d B", formatSecs(prove), formatSecs(verify), proofBytes)
+	case tuiCompiled(dir):
+		return "compiled, not proved"
+	default:
+		return tuiFaint.Render("not run")
+	}
+}
+
+// meanProofTimes averages the proving and verification times of the test
+// cases of a results file, and takes the size of their proofs
+func meanProofTimes(results *Results) (prove, verify float64, proofBytes int64) {
+	if results == nil {
+		return 0, 0, 0
+	}
+	var proves, verifies int
+	for _, m := range results.Measurements {
+		if m.TestCase == "" || m.Threads != 0 {
+			continue
+		}
+		switch m.Phase {
+		case "prove":
+			prove += m.MeanSecs
+			proves++
+			proofBytes = m.ProofBytes
+		case "verify":
+			verify += m.MeanSecs
+			verifies++
+		}
+	}
+	if proves > 0 {
+		prove /= float64(proves)
+	}
+	if verifies > 0 {
+		verify /= float64(verifies)
+	}
+	return prove, verify, proofBytes
+}
+
+func (m *tuiModel) View() string {
+	if m.quitting && m.running != nil {
+		return "Interrupting " + m.running.step + "...\n"
+	}
+	var b strings.Builder
+	curve, rangeCheck := m.curves[m.curve], m.rangeChecks[m.rangeCheck]
+	fmt.Fprintf(&b, "%s  %s, %s range checks, test cases in %s\n\n", tuiTitle.Render("gnark ECDSA benchmarks"), curve, rangeCheck, testsDir)
+
+	const circuitWidth, cellWidth = 12, 44
+	b.WriteString(strings.Repeat(" ", circuitWidth))
+	for _, backend := range m.backends {
+		b.WriteString(tuiHeader.Width(cellWidth).Render(backend))
+	}
+	b.WriteString("\n")
+	for row, circuit := range m.circuits {
+		b.WriteString(tuiHeader.Width(circuitWidth).Render(circuit))
+		for col, backend := range m.backends {
+			cell := lipgloss.NewStyle().Width(cellWidth - 2).MaxHeight(1).Render(m.cellSummary(circuit, backend))
+			if row == m.row && col == m.col {
+				cell = tuiSelected.Render(cell)
+			}
+			b.WriteString(cell + "  ")
+		}
+		b.WriteString("\n")
+	}
+
+	circuit, backend := m.circuits[m.row], m.backends[m.col]
+	dir := tuiCellDir(circuit, backend, curve, rangeCheck)
+	fmt.Fprintf(&b, "\n%s %s\n", tuiTitle.Render(circuit+" × "+backend), tuiFaint.Render(dir))
+	b.WriteString(m.resultsTable(m.results[dir]))
+
+	if m.running != nil {
+		fmt.Fprintf(&b, "\n%s\n", tuiFaint.Render(truncate(m.lastLine, 120)))
+	}
+	if len(m.queue) > 0 {
+		fmt.Fprintf(&b, "%d queued\n", len(m.queue))
+	}
+	b.WriteString("\n" + tuiFaint.Render("←↑↓→ select  enter run  a run all  x clear queue  c curve  r range check  q quit") + "\n")
+	return b.String()
+}
+
+// resultsTable lists the measurements of a cell
+func (m *tuiModel) resultsTable(results *Results) string {
+	if results == nil || len(results.Measurements) == 0 {
+		return tuiFaint.Render("  No results yet: enter runs the cell") + "\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %-14s %-10s %5s %10s %10s %10s %8s\n", "Phase", "Test case", "Runs", "Mean", "Min", "Max", "Proof")
+	for _, r := range results.Measurements {
+		if r.Threads != 0 {
+			continue
+		}
+		proof := ""
+		if r.ProofBytes > 0 {
+			proof = fmt.Sprintf("%d B", r.ProofBytes)
+		}
+		fmt.Fprintf(&b, "  %-14s %-10s %5d %10s %10s %10s %8s\n", r.Phase, r.TestCase, r.Runs,
+			formatSecs(r.MeanSecs), formatSecs(r.MinSecs), formatSecs(r.MaxSecs), proof)
+	}
+	for _, a := range results.Aborted {
+		fmt.Fprintf(&b, "  %s\n", tuiFailed.Render(fmt.Sprintf("%s %s aborted: %s after %s", a.Phase, a.TestCase, a.Reason, formatSecs(a.ElapsedSecs))))
+	}
+	return b.String()
+}
+
+// describeLogLine is a line a running command printed, with the message of
+// a JSON log record rather than the record
+func describeLogLine(msg tuiLogMsg) string {
+	if msg.record == nil {
+		return msg.line
+	}
+	text := msg.line
+	for _, key := range []string{"msg", "message"} {
+		if message, ok := msg.record[key].(string); ok {
+			text = message
+			break
+		}
+	}
+	if level, ok := msg.record["level"].(string); ok {
+		text = strings.ToUpper(level) + " " + text
+	}
+	if err, ok := msg.record["error"].(string); ok {
+		text += ": " + err
+	}
+	return text
+}
+
+// formatProgressRecord describes a progress log record of a step, such as
+// "setup, 1m12s left" or "3/8, 40s left" for the test cases of a batch
+func formatProgressRecord(step string, record map[string]any) string {
+	var parts []string
+	if phase, ok := record["phase"].(string); ok && phase != step {
+		parts = append(parts, phase)
+	}
+	if total, ok := record["total"].(float64); ok {
+		parts = append(parts, fmt.Sprintf("%v/%v", record["done"], total))
+	}
+	if eta, ok := record["eta"].(string); ok {
+		parts = append(parts, eta+" left")
+	}
+	return strings.Join(parts, ", ")
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n-1] + "…"
+	}
+	return s
+}
@@ -0,0 +1,11 @@
+//go:build !tui || wasm
+
+package main
+
+// The TUI is built only with -tags tui, as Bubble Tea queries the terminal
+// when the binary starts, whatever the command, and the wasm runtimes give no
+// terminal
+
+func runTUI() {
+	fatal("The tui command is not in this build: build with -tags tui, e.g. go run -tags tui . tui")
+}
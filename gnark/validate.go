@@ -0,0 +1,164 @@
+package main
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fp"
+	"github.com/consensys/gnark-crypto/ecc/secp256k1/fr"
+)
+
+// maxHexDigits is the width of every value of a test case: the circuit takes
+// 256-bit scalars and coordinates
+const maxHexDigits = 64
+
+// validateTestCase checks a test case against testcase.schema.json before
+// any witness is built from it, and names every malformed field: a missing
+// value, a character that is not hex, a value wider than 256 bits, a message
+// hash that is not the hash of the raw message, or a value out of range for
+// its curve. The range checks (r and s in [1, n), the public key
+// a point of the curve) only apply to test cases expected to verify, since
+// negative test cases are out of range on purpose.
+func validateTestCase(testCase *TestCase) error {
+	var errs []error
+	values := map[string]*big.Int{}
+	for _, field := range []struct {
+		name, value string
+	}{
+		{"r", testCase.R},
+		{"s", testCase.S},
+		{"msghash", testCase.MsgHash},
+		{"pubkey_x", testCase.PubKeyX},
+		{"pubkey_y", testCase.PubKeyY},
+	} {
+		if field.name == "msghash" && field.value == "" && testCase.Message != "" {
+			continue // hashed from the message
+		}
+		if strings.HasPrefix(field.name, "pubkey_") && field.value == "" && testCase.PubKeyCompressed != "" {
+			continue // decompressed from pubkey_compressed
+		}
+		v, err := parseHexField(field.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field %q: %v", field.name, err))
+			continue
+		}
+		values[field.name] = v
+	}
+
+	var n, p *big.Int
+	onCurve := func(x, y *big.Int) bool { return false }
+	switch testCase.Curve {
+	case "", curveP256:
+		params := elliptic.P256().Params()
+		n, p = params.N, params.P
+		onCurve = func(x, y *big.Int) bool { return elliptic.P256().IsOnCurve(x, y) }
+	case curveSecp256k1:
+		n, p = fr.Modulus(), fp.Modulus()
+		onCurve = func(x, y *big.Int) bool {
+			// y² = x³ + 7
+			lhs := new(big.Int).Exp(y, big.NewInt(2), p)
+			rhs := new(big.Int).Exp(x, big.NewInt(3), p)
+			rhs.Add(rhs, big.NewInt(7)).Mod(rhs, p)
+			return lhs.Cmp(rhs) == 0
+		}
+	default:
+		errs = append(errs, fmt.Errorf("field \"curve\": %q is not %s or %s", testCase.Curve, curveP256, curveSecp256k1))
+	}
+
+	if testCase.PubKeyCompressed != "" && n != nil {
+		x, y, err := decompressPubKey(testCase.Curve, testCase.PubKeyCompressed)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("field \"pubkey_compressed\": %v", err))
+		case values["pubkey_x"] != nil && values["pubkey_x"].Cmp(x) != 0,
+			values["pubkey_y"] != nil && values["pubkey_y"].Cmp(y) != 0:
+			errs = append(errs, fmt.Errorf("field \"pubkey_compressed\": the point (0x%x, 0x%x) is not pubkey_x, pubkey_y", x, y))
+		default:
+			values["pubkey_x"], values["pubkey_y"] = x, y
+		}
+	}
+
+	if testCase.Message != "" {
+		digest, err := testCase.messageDigest()
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case values["msghash"] != nil && values["msghash"].Cmp(digest) != 0:
+			errs = append(errs, fmt.Errorf("field \"msghash\": 0x%x is not the %s of the message, 0x%x", values["msghash"], hashAlgOrDefault(testCase.HashAlg), digest))
+		}
+	} else if testCase.HashAlg != "" {
+		errs = append(errs, fmt.Errorf("field \"hash_alg\": set without a message to hash"))
+	}
+
+	switch testCase.Result {
+	case "", "valid", "invalid", "acceptable":
+	default:
+		errs = append(errs, fmt.Errorf("field \"result\": %q is not valid, invalid or acceptable", testCase.Result))
+	}
+	switch testCase.Nonce {
+	case "", nonceRandom, nonceRFC6979:
+	default:
+		errs = append(errs, fmt.Errorf("field \"nonce\": %q is not %s or %s", testCase.Nonce, nonceRandom, nonceRFC6979))
+	}
+
+	for i, tag := range testCase.Tags {
+		if !tagPattern.MatchString(tag) {
+			errs = append(errs, fmt.Errorf("field \"tags\": tag %d, %q, is not lowercase words joined by hyphens", i, tag))
+		}
+	}
+
+	if valid, either := testCase.expectation(); valid && !either && n != nil && len(errs) == 0 {
+		for _, name := range []string{"r", "s"} {
+			if v := values[name]; v.Sign() == 0 || v.Cmp(n) >= 0 {
+				errs = append(errs, fmt.Errorf("field %q: 0x%x is out of range [1, n) for a valid signature", name, v))
+			}
+		}
+		x, y := values["pubkey_x"], values["pubkey_y"]
+		switch {
+		case x.Cmp(p) >= 0 || y.Cmp(p) >= 0:
+			errs = append(errs, fmt.Errorf("fields \"pubkey_x\", \"pubkey_y\": a coordinate is not below the field modulus"))
+		case !onCurve(x, y):
+			errs = append(errs, fmt.Errorf("fields \"pubkey_x\", \"pubkey_y\": the public key is not a point of %s", curveOrDefault(testCase.Curve)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseHexField parses a value of a test case: hex digits with an optional
+// 0x prefix, at most 256 bits wide
+func parseHexField(value string) (*big.Int, error) {
+	digits := strings.TrimPrefix(value, "0x")
+	if digits == "" {
+		if value == "" {
+			return nil, fmt.Errorf("missing or empty")
+		}
+		return nil, fmt.Errorf("no hex digits after 0x")
+	}
+	for i, c := range digits {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return nil, fmt.Errorf("non-hex character %q at position %d of %q", c, len(value)-len(digits)+i, value)
+		}
+	}
+	if len(strings.TrimLeft(digits, "0")) > maxHexDigits {
+		return nil, fmt.Errorf("%d hex digits, wider than the %d of a 256-bit value", len(strings.TrimLeft(digits, "0")), maxHexDigits)
+	}
+	v, _ := new(big.Int).SetString(digits, 16)
+	return v, nil
+}
+
+func hashAlgOrDefault(alg string) string {
+	if alg == "" {
+		return hashSHA256
+	}
+	return alg
+}
+
+func curveOrDefault(curve string) string {
+	if curve == "" {
+		return curveP256
+	}
+	return curve
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// validTestCase is the first RFC 6979 vector of P-256 with SHA-256, the
+// message "sample", as a test case that passes validation
+func validTestCase(t *testing.T) TestCase {
+	t.Helper()
+	curve := elliptic.P256()
+	d := hexInt(t, "C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")
+	key := &ecdsa.PrivateKey{D: d}
+	key.PublicKey.Curve = curve
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(d.Bytes())
+	hash := sha256.Sum256([]byte("sample"))
+	r, s := signRFC6979(key, hash[:])
+	return *newTestCase(&key.PublicKey, hash[:], r, s)
+}
+
+func TestValidateTestCase(t *testing.T) {
+	n, p := elliptic.P256().Params().N, elliptic.P256().Params().P
+	hex := func(v *big.Int) string { return fmt.Sprintf("0x%x", v) }
+	invalid := false
+	tests := []struct {
+		name   string
+		modify func(tc *TestCase)
+		// wantErr is part of the error, empty when the test case is valid
+		wantErr string
+	}{
+		{"valid", func(tc *TestCase) {}, ""},
+		{"valid with message", func(tc *TestCase) { tc.Message = "0x73616d706c65" }, ""},
+		{"valid without msghash", func(tc *TestCase) { tc.Message, tc.MsgHash = "73616d706c65", "" }, ""},
+		{"missing r", func(tc *TestCase) { tc.R = "" }, `field "r": missing or empty`},
+		{"no digits after 0x", func(tc *TestCase) { tc.S = "0x" }, `field "s": no hex digits after 0x`},
+		{"bad hex", func(tc *TestCase) { tc.PubKeyX = "0x12g4" }, `field "pubkey_x": non-hex character 'g' at position 4`},
+		{"r wider than 256 bits", func(tc *TestCase) { tc.R = "0x1" + strings.Repeat("0", 64) }, `field "r": 65 hex digits`},
+		{"leading zeros are not too wide", func(tc *TestCase) { tc.R = "0x00" + strings.TrimPrefix(tc.R, "0x") }, ""},
+		{"hash wider than 256 bits", func(tc *TestCase) { tc.MsgHash += "00" }, `field "msghash": 66 hex digits`},
+		{"hash not of the message", func(tc *TestCase) { tc.Message = "0x74657374" }, `field "msghash": `},
+		{"message not hex", func(tc *TestCase) { tc.Message = "0xsample" }, `field "message": not hex`},
+		{"unknown hash", func(tc *TestCase) { tc.Message, tc.HashAlg = "0x73616d706c65", "md5" }, `field "hash_alg": "md5" is not`},
+		{"hash without message", func(tc *TestCase) { tc.HashAlg = hashSHA384 }, `field "hash_alg": set without a message`},
+		{"r zero", func(tc *TestCase) { tc.R = "0x0" }, `field "r": 0x0 is out of range [1, n)`},
+		{"s equal to n", func(tc *TestCase) { tc.S = hex(n) }, `field "s": ` + hex(n) + ` is out of range [1, n)`},
+		{"off-curve key", func(tc *TestCase) {
+			y := hexInt(t, strings.TrimPrefix(tc.PubKeyY, "0x"))
+			tc.PubKeyY = hex(y.Add(y, big.NewInt(1)))
+		}, "the public key is not a point of " + curveP256},
+		{"coordinate above the modulus", func(tc *TestCase) { tc.PubKeyX = hex(p) }, "a coordinate is not below the field modulus"},
+		{"off-curve key on secp256k1", func(tc *TestCase) { tc.Curve = curveSecp256k1 }, "the public key is not a point of " + curveSecp256k1},
+		{"out of range but expected invalid", func(tc *TestCase) {
+			tc.R, tc.PubKeyX = "0x0", "0x1"
+			tc.ExpectValid = &invalid
+		}, ""},
+		{"out of range but acceptable", func(tc *TestCase) { tc.S, tc.Result = hex(n), "acceptable" }, ""},
+		{"compressed key", func(tc *TestCase) {
+			y := hexInt(t, strings.TrimPrefix(tc.PubKeyY, "0x"))
+			tc.PubKeyCompressed = fmt.Sprintf("0x%02x%s", 2+y.Bit(0), strings.TrimPrefix(tc.PubKeyX, "0x"))
+			tc.PubKeyX, tc.PubKeyY = "", ""
+		}, ""},
+		{"compressed key of another point", func(tc *TestCase) {
+			tc.PubKeyCompressed = "0x036b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296"
+		}, `field "pubkey_compressed": the point`},
+		{"unknown curve", func(tc *TestCase) { tc.Curve = "p384" }, `field "curve": "p384" is not`},
+		{"unknown result", func(tc *TestCase) { tc.Result = "maybe" }, `field "result": "maybe" is not`},
+		{"unknown nonce", func(tc *TestCase) { tc.Nonce = "fixed" }, `field "nonce": "fixed" is not`},
+		{"malformed tag", func(tc *TestCase) { tc.Tags = []string{"low-s", "High S"} }, `field "tags": tag 1, "High S"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := validTestCase(t)
+			tt.modify(&tc)
+			err := validateTestCase(&tc)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateTestCase = %v, want no error", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("validateTestCase succeeded, want an error with %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("validateTestCase = %v, want an error with %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateTestCaseNamesEveryField checks that the errors of several
+// malformed fields are all reported, not only the first
+func TestValidateTestCaseNamesEveryField(t *testing.T) {
+	tc := validTestCase(t)
+	tc.R, tc.S, tc.PubKeyY = "0xx", "", "0x"
+	err := validateTestCase(&tc)
+	if err == nil {
+		t.Fatal("validateTestCase succeeded, want an error")
+	}
+	for _, field := range []string{`"r"`, `"s"`, `"pubkey_y"`} {
+		if !strings.Contains(err.Error(), "field "+field) {
+			t.Errorf("validateTestCase = %v, does not name field %s", err, field)
+		}
+	}
+}
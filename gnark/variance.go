@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// varianceZScore is how many standard errors of the run-to-run noise a test
+// case must differ from the others by to be flagged
+const varianceZScore = 3
+
+var (
+	// command line flags
+	varianceThreshold float64
+)
+
+// testCaseVariance compares one test case with the others of its phase
+type testCaseVariance struct {
+	m Measurement
+	// change is the relative difference of its mean from the mean of the
+	// other test cases, and z that difference in standard errors of the noise
+	change, z float64
+	anomaly   bool
+}
+
+// analyzeVariance checks whether the phases measured per test case take
+// longer for some signatures than others. For a fixed circuit they should
+// not: the constraint system is the same for every input, so a difference
+// beyond the run-to-run noise points at nondeterminism in the solver or at
+// hints whose work depends on the input. Each test case is compared with the
+// runs of all the others, and flagged when it differs by more than
+// -variance-threshold and, when the runs allow estimating the noise, by more
+// than three standard errors. The allocations of proving are compared too.
+func analyzeVariance() {
+	path := filepath.Join(outputDir, "benchmarks", resultsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fatal("Failed to read results", "error", err)
+	}
+	var results Results
+	if err := json.Unmarshal(data, &results); err != nil {
+		fatal("Invalid results file", "file", path, "error", err)
+	}
+
+	phases := make(map[string][]Measurement)
+	var phaseOrder []string
+	for _, m := range results.Measurements {
+		if m.TestCase == "" || !m.fullCPU() {
+			continue
+		}
+		if _, ok := phases[m.Phase]; !ok {
+			phaseOrder = append(phaseOrder, m.Phase)
+		}
+		phases[m.Phase] = append(phases[m.Phase], m)
+	}
+
+	anomalies := 0
+	analyzed := 0
+	for _, phase := range phaseOrder {
+		ms := phases[phase]
+		if len(ms) < 2 {
+			continue
+		}
+		analyzed++
+		sort.Slice(ms, func(i, j int) bool {
+			a, _ := strconv.Atoi(ms[i].TestCase)
+			b, _ := strconv.Atoi(ms[j].TestCase)
+			return a < b
+		})
+
+		variances, noise := compareTestCases(ms)
+		fmt.Printf("%s across %d test cases", phase, len(ms))
+		if noise > 0 {
+			fmt.Printf(", run-to-run noise %s (stddev)\n", formatSecs(noise))
+		} else {
+			fmt.Printf(", no repeated runs to estimate the noise from\n")
+		}
+		fmt.Printf("%-10s %6s %10s %10s %10s %8s\n", "Test case", "Runs", "Mean", "Stddev", "Δ others", "z")
+		for _, v := range variances {
+			z, flag := "-", ""
+			if noise > 0 {
+				z = fmt.Sprintf("%.1f", v.z)
+			}
+			if v.anomaly {
+				flag = "  ⚠"
+				anomalies++
+			}
+			fmt.Printf("%-10s %6d %10s %10s %+9.1f%% %8s%s\n", v.m.TestCase, v.m.Runs, formatSecs(v.m.MeanSecs),
+				formatSecs(v.m.StdDevSecs), v.change*100, z, flag)
+		}
+
+		// Allocations follow the work done closely, so they only get the
+		// relative threshold
+		var allocs []uint64
+		for _, m := range ms {
+			if m.Allocs != nil && m.Allocs.BytesPerOp > 0 {
+				allocs = append(allocs, m.Allocs.BytesPerOp)
+			} else if m.Allocs != nil && m.Allocs.TotalAllocBytes > 0 {
+				allocs = append(allocs, m.Allocs.TotalAllocBytes)
+			}
+		}
+		if len(allocs) == len(ms) {
+			low, high := allocs[0], allocs[0]
+			for _, a := range allocs {
+				low, high = min(low, a), max(high, a)
+			}
+			spread := float64(high-low) / float64(low)
+			flag := ""
+			if spread > varianceThreshold {
+				flag = "  ⚠"
+				anomalies++
+			}
+			fmt.Printf("Allocated %s to %s per run (%.1f%% spread)%s\n", formatBytes(low), formatBytes(high), spread*100, flag)
+		}
+		fmt.Println()
+	}
+	if analyzed == 0 {
+		fatal("No phase measured on at least two test cases, run bench on several test cases first", "file", path)
+	}
+
+	if anomalies > 0 {
+		fmt.Println("The flagged test cases differ beyond the noise. The circuit is the same for every input, so look")
+		fmt.Println("for hints whose work depends on the input (prove_solve, solve) or for nondeterminism in the")
+		fmt.Println("prover (prove_backend). Rerun with more -runs to rule out a noisy machine.")
+		fatal("Found anomalies across test cases", "anomalies", anomalies)
+	}
+	fmt.Printf("✓ No phase varies across test cases by more than %.0f%% or the noise\n", varianceThreshold*100)
+}
+
+// compareTestCases compares the mean of each test case with that of the runs
+// of the other test cases. The noise is the pooled standard deviation of the
+// runs within each test case, or 0 when no test case ran more than once.
+func compareTestCases(ms []Measurement) ([]testCaseVariance, float64) {
+	var runs int
+	var sumSquares float64
+	for _, m := range ms {
+		runs += m.Runs
+		sumSquares += float64(m.Runs-1) * m.StdDevSecs * m.StdDevSecs
+	}
+	var noise float64
+	if runs > len(ms) {
+		noise = math.Sqrt(sumSquares / float64(runs-len(ms)))
+	}
+
+	variances := make([]testCaseVariance, len(ms))
+	for i, m := range ms {
+		var otherRuns int
+		var otherSum float64
+		for j, other := range ms {
+			if j != i {
+				otherRuns += other.Runs
+				otherSum += float64(other.Runs) * other.MeanSecs
+			}
+		}
+		otherMean := otherSum / float64(otherRuns)
+		v := testCaseVariance{m: m}
+		if otherMean > 0 {
+			v.change = (m.MeanSecs - otherMean) / otherMean
+		}
+		significant := true
+		if noise > 0 {
+			v.z = (m.MeanSecs - otherMean) / (noise * math.Sqrt(1/float64(m.Runs)+1/float64(otherRuns)))
+			significant = math.Abs(v.z) > varianceZScore
+		}
+		v.anomaly = significant && math.Abs(v.change) > varianceThreshold
+		variances[i] = v
+	}
+	return variances, noise
+}
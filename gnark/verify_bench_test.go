@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/consensys/gnark/logger"
+)
+
+// The verifier path benchmarks run against artifacts from `compile` and
+// `prove`, since compiling and setting up the circuit takes too long to do per
+// benchmark:
+//
+//	go test -run '^$' -bench . -benchmem -data data -test-case tests/test_case_1.json
+var (
+	benchDataDir  = flag.String("data", "data", "Directory holding the compiled circuit, keys and proofs")
+	benchTestCase = flag.String("test-case", filepath.Join("tests", "test_case_1.json"), "Test case whose proof is benchmarked")
+)
+
+var benchSettingsOnce sync.Once
+
+// benchSettings points the package at -data and settles the curve and backend
+// from its manifest, once for all benchmarks
+func benchSettings(b *testing.B) {
+	b.Helper()
+	benchSettingsOnce.Do(func() {
+		// gnark logs every verification, which would drown the results
+		logger.Disable()
+		outputDir = *benchDataDir
+		resolveSettings()
+	})
+}
+
+// benchTestCaseNum is the ID of the -test-case file, which names its proof
+func benchTestCaseNum(b *testing.B) string {
+	b.Helper()
+	return testCaseID(*benchTestCase)
+}
+
+// readBenchFile reads an artifact, skipping the benchmark when it has not been
+// generated yet
+func readBenchFile(b *testing.B, path string) []byte {
+	b.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		b.Skipf("%s not found, run compile and prove with -d %s first", path, *benchDataDir)
+	}
+	if err != nil {
+		b.Fatal("Failed to read artifact:", err)
+	}
+	return data
+}
+
+func loadBenchTestCase(b *testing.B) *TestCase {
+	b.Helper()
+	if _, err := os.Stat(*benchTestCase); os.IsNotExist(err) {
+		b.Skipf("%s not found, generate the test cases first", *benchTestCase)
+	}
+	testCase, err := loadTestCase(*benchTestCase)
+	if err != nil {
+		b.Fatal("Failed to load test case:", err)
+	}
+	return testCase
+}
+
+func BenchmarkCreateWitness(b *testing.B) {
+	benchSettings(b)
+	testCase := loadBenchTestCase(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := createWitness(testCase); err != nil {
+			b.Fatal("Failed to create witness:", err)
+		}
+	}
+}
+
+func BenchmarkCreatePublicWitness(b *testing.B) {
+	benchSettings(b)
+	testCase := loadBenchTestCase(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := createPublicWitness(testCase); err != nil {
+			b.Fatal("Failed to create public witness:", err)
+		}
+	}
+}
+
+func BenchmarkReadProof(b *testing.B) {
+	benchSettings(b)
+	data := readBenchFile(b, filepath.Join(outputDir, proofFileName(benchTestCaseNum(b))))
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := newProof().ReadFrom(bytes.NewReader(data)); err != nil {
+			b.Fatal("Failed to read proof:", err)
+		}
+	}
+}
+
+func BenchmarkReadVerifyingKey(b *testing.B) {
+	benchSettings(b)
+	data := readBenchFile(b, filepath.Join(outputDir, "verifying.key"))
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := newVerifyingKey().ReadFrom(bytes.NewReader(data)); err != nil {
+			b.Fatal("Failed to read verifying key:", err)
+		}
+	}
+}
+
+// BenchmarkVerify verifies the proof of -test-case with the backend it was
+// compiled for, Groth16 unless the manifest says otherwise
+func BenchmarkVerify(b *testing.B) {
+	benchSettings(b)
+	vk := newVerifyingKey()
+	if _, err := vk.ReadFrom(bytes.NewReader(readBenchFile(b, filepath.Join(outputDir, "verifying.key")))); err != nil {
+		b.Fatal("Failed to read verifying key:", err)
+	}
+	proof := newProof()
+	if _, err := proof.ReadFrom(bytes.NewReader(readBenchFile(b, filepath.Join(outputDir, proofFileName(benchTestCaseNum(b)))))); err != nil {
+		b.Fatal("Failed to read proof:", err)
+	}
+	publicWitness, err := createPublicWitness(loadBenchTestCase(b))
+	if err != nil {
+		b.Fatal("Failed to create public witness:", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := verifyCircuit(proof, vk, publicWitness); err != nil {
+			b.Fatal("Proof verification failed:", err)
+		}
+	}
+}
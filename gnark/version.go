@@ -0,0 +1,120 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/consensys/gnark/frontend"
+)
+
+var (
+	// command line flags
+	hashCircuits bool
+)
+
+// printVersion is the version command: what built the binary and what it
+// can prove, so that archived results can be matched to the code and circuit
+// that produced them. The hash of a circuit is the SHA-256 of its compiled
+// constraint system, as the manifest records it for the file in -d; circuits
+// not compiled in -d show none unless -hash-circuits compiles them.
+func printVersion() {
+	fmt.Printf("gnark-ecdsa-benchmark %s\n", toolVersion())
+	fmt.Printf("  gnark         %s\n", gnarkVersion())
+	fmt.Printf("  gnark-crypto  %s\n", moduleVersion("github.com/consensys/gnark-crypto"))
+	fmt.Printf("  Go            %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	if hashCircuits {
+		defaultSettings()
+		fmt.Printf("\nCircuits, compiled for %s over %s with %s range checks:\n", provingBackend, curveName, rangeCheck)
+	} else {
+		fmt.Printf("\nCircuits, as compiled in %s:\n", outputDir)
+	}
+	for _, name := range circuitNames() {
+		fmt.Printf("  %-10s %s\n", name, circuitVariants[name].Description)
+		if hashCircuits {
+			fmt.Printf("  %-10s %s\n", "", compiledCircuitHash(name))
+		} else {
+			fmt.Printf("  %-10s %s\n", "", recordedCircuitHash(name))
+		}
+	}
+
+	curves := make([]string, len(supportedCurves))
+	for i, id := range supportedCurves {
+		curves[i] = curveDisplayName(id)
+	}
+	fmt.Println()
+	fmt.Printf("Curves:         %s\n", strings.Join(curves, ", "))
+	fmt.Printf("Backends:       %s, %s\n", backendGroth16, backendPLONK)
+	fmt.Printf("Range checks:   %s, %s\n", rangeCheckLookup, rangeCheckDecompose)
+	fmt.Printf("Hash-to-field:  %s\n", strings.Join(hashToFieldFunctions, ", "))
+}
+
+// toolVersion is the version of the binary: its module version when installed
+// from a release, else the commit it was built from
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	commit, dirty := gitCommit()
+	if commit == "" {
+		return "(devel)"
+	}
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if dirty {
+		commit += ", modified"
+	}
+	return "(devel, commit " + commit + ")"
+}
+
+// recordedCircuitHash describes the hash of a variant's constraint system the
+// manifest in its directory records, and the settings it was compiled with
+func recordedCircuitHash(name string) string {
+	dir := outputDir
+	if name != defaultCircuit {
+		dir = filepath.Join(outputDir, name)
+	}
+	saved := outputDir
+	outputDir = dir
+	contents, err := loadManifestContents()
+	outputDir = saved
+	if err != nil {
+		return fmt.Sprintf("unreadable manifest in %s: %v", dir, err)
+	}
+	if contents == nil {
+		return "not compiled in " + dir
+	}
+	if compiled := cmp.Or(contents.Circuit, defaultCircuit); compiled != name {
+		return fmt.Sprintf("not compiled in %s, which holds %s", dir, compiled)
+	}
+	file := "circuit.r1cs"
+	if contents.Backend == backendPLONK {
+		file = "circuit.scs"
+	}
+	hash, ok := contents.Artifacts[file]
+	if !ok {
+		return fmt.Sprintf("compiled in %s with no hash recorded; recompile to record one", dir)
+	}
+	return fmt.Sprintf("sha256 %s (%s over %s, %s range checks)", hash, contents.Backend, contents.Curve, contents.RangeCheck)
+}
+
+// compiledCircuitHash compiles a variant with the current settings and
+// returns the SHA-256 of its constraint system, as compile would write it
+func compiledCircuitHash(name string) string {
+	ccs, err := frontend.Compile(selectedCurve().ScalarField(), circuitBuilder(), circuitVariants[name].New())
+	if err != nil {
+		fatal("Failed to compile the circuit", "circuit", name, "error", err)
+	}
+	h := sha256.New()
+	if _, err := ccs.WriteTo(h); err != nil {
+		fatal("Failed to hash the circuit", "circuit", name, "error", err)
+	}
+	return fmt.Sprintf("sha256 %s, %d constraints", hex.EncodeToString(h.Sum(nil)), ccs.GetNbConstraints())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// Runtimes the matrix runs the prover under: the native binary, or the same
+// code compiled to WebAssembly (GOOS=wasip1 GOARCH=wasm) as a browser would
+// run it
+const (
+	runtimeNative = "native"
+	runtimeWasm   = "wasm"
+)
+
+// nodeWASI runs a WASI module under Node.js, passing the command line on and
+// mounting the working directory as the module's root. Go takes its working
+// directory from PWD, which must point at that root.
+const nodeWASI = `const { WASI } = require("node:wasi");
+const fs = require("node:fs");
+const env = { ...process.env, PWD: "/" };
+const wasi = new WASI({ version: "preview1", args: process.argv.slice(2), env, preopens: { "/": process.cwd() } });
+WebAssembly.instantiate(fs.readFileSync(process.argv[2]), wasi.getImportObject())
+  .then(({ instance }) => process.exit(wasi.start(instance)));
+`
+
+func validateWasmRuntime(name string) error {
+	switch name {
+	case "wazero", "wasmtime", "node":
+		return nil
+	default:
+		return fmt.Errorf("unknown wasm runtime %q (use wazero, wasmtime or node)", name)
+	}
+}
+
+// buildWasm compiles the benchmark into a WASI module in dir, from the source
+// in the working directory
+func buildWasm(dir string) string {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fatal("Failed to create matrix directory", "error", err)
+	}
+	module := filepath.Join(dir, "gnark-ecdsa-benchmark.wasm")
+	slog.Info("Building the wasm module", "file", module)
+	cmd := exec.Command("go", "build", "-o", module, ".")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fatal("Failed to build the benchmark for wasm, run the matrix from the gnark directory", "error", err)
+	}
+	return module
+}
+
+// wasmCommand runs the benchmark module with args under a wasm runtime. The
+// runtime mounts the working directory as the module's root, so the paths in
+// args must be relative to it.
+func wasmCommand(wasmRuntime, module string, args ...string) *exec.Cmd {
+	switch wasmRuntime {
+	case "wasmtime":
+		return exec.Command("wasmtime", append([]string{"run", "--dir=.::/", module}, args...)...)
+	case "node":
+		script := filepath.Join(filepath.Dir(module), "wasi.cjs")
+		if err := os.WriteFile(script, []byte(nodeWASI), 0644); err != nil {
+			fatal("Failed to write the Node.js WASI runner", "error", err)
+		}
+		return exec.Command("node", append([]string{"--no-warnings", script, module}, args...)...)
+	default:
+		return exec.Command("wazero", append([]string{"run", "-mount=.:/", module}, args...)...)
+	}
+}
+
+// linkArtifacts makes the compiled circuit, keys and manifest of a cell
+// available in another directory, hard-linked where possible, so that a run
+// under another runtime records its results apart from the native ones
+func linkArtifacts(from, to string) {
+	if err := os.MkdirAll(to, 0755); err != nil {
+		fatal("Failed to create directory", "error", err)
+	}
+	for _, name := range []string{"circuit.r1cs", "circuit.scs", "proving.key", "verifying.key", manifestFile, insecureSeedMarker} {
+		src, dst := filepath.Join(from, name), filepath.Join(to, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		os.Remove(dst)
+		if err := os.Link(src, dst); err == nil {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			fatal("Failed to copy", "file", src, "error", err)
+		}
+	}
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// WebAuthnData is what a WebAuthn assertion signs, for circuits that rebuild
+// the signed message from the authenticator data and the client data rather
+// than taking its hash. msghash is SHA-256(authenticator_data ||
+// SHA-256(client_data_json)).
+type WebAuthnData struct {
+	AuthenticatorData string `json:"authenticator_data"`
+	ClientDataJSON    string `json:"client_data_json"`
+	Challenge         string `json:"challenge"`
+	Origin            string `json:"origin"`
+}
+
+// webAuthnAssertion is a captured assertion as PublicKeyCredential.toJSON()
+// gives it, with the COSE public key the credential was registered with added
+// as publicKey. Binary fields are base64url, as in toJSON().
+type webAuthnAssertion struct {
+	ID       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+	} `json:"response"`
+	PublicKey string `json:"publicKey"`
+}
+
+// COSE key parameters of an EC2 P-256 ES256 key (RFC 9053)
+const (
+	coseKty   = 1
+	coseAlg   = 3
+	coseCrv   = -1
+	coseX     = -2
+	coseY     = -3
+	coseEC2   = 2
+	coseES256 = -7
+	coseP256  = 1
+)
+
+// webAuthnGet is the client data type of an assertion
+const webAuthnGet = "webauthn.get"
+
+// convertWebAuthn turns captured WebAuthn assertions into test cases in
+// -tests, named webauthn_<file>.json. The authenticator signs authenticator
+// data || SHA-256(client data JSON) with ES256, so the test case takes the
+// SHA-256 of that as its message hash, the r and s of the DER signature, and
+// the coordinates of the COSE key. That makes it a plain ECDSA test case, and
+// the webauthn field keeps the authenticator and client data for a circuit
+// that hashes them itself. Authenticators do not normalize s, so the
+// signature is kept as it is. Every assertion must verify with crypto/ecdsa.
+func convertWebAuthn(files []string) {
+	if len(files) == 0 {
+		fatal("Missing WebAuthn assertion file for webauthn command")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fatal("Failed to read WebAuthn assertion", "error", err)
+		}
+		var assertion webAuthnAssertion
+		if err := json.Unmarshal(data, &assertion); err != nil {
+			fatal("Failed to decode WebAuthn assertion", "file", file, "error", err)
+		}
+		fail := func(format string, args ...any) {
+			fatal(fmt.Sprintf(format, args...), "file", file)
+		}
+
+		clientData, err := decodeBase64URL(assertion.Response.ClientDataJSON)
+		if err != nil {
+			fail("invalid clientDataJSON: %v", err)
+		}
+		authData, err := decodeBase64URL(assertion.Response.AuthenticatorData)
+		if err != nil {
+			fail("invalid authenticatorData: %v", err)
+		}
+		// The authenticator data starts with the RP ID hash, the flags and
+		// the signature counter
+		if len(authData) < 37 {
+			fail("authenticatorData is %d bytes, shorter than its fixed 37", len(authData))
+		}
+		sig, err := decodeBase64URL(assertion.Response.Signature)
+		if err != nil {
+			fail("invalid signature: %v", err)
+		}
+		coseKey, err := decodeBase64URL(assertion.PublicKey)
+		if err != nil || len(coseKey) == 0 {
+			fail("missing or invalid publicKey, add the credential's COSE public key as base64url")
+		}
+
+		var client struct {
+			Type      string `json:"type"`
+			Challenge string `json:"challenge"`
+			Origin    string `json:"origin"`
+		}
+		if err := json.Unmarshal(clientData, &client); err != nil {
+			fail("invalid client data JSON: %v", err)
+		}
+		if client.Type != webAuthnGet {
+			fail("client data type is %q, not an assertion (%s)", client.Type, webAuthnGet)
+		}
+
+		pub, err := parseCOSEKey(coseKey)
+		if err != nil {
+			fail("%v", err)
+		}
+		var parsed struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &parsed); err != nil || len(rest) > 0 {
+			fail("signature is not a DER ECDSA signature")
+		}
+
+		clientHash := sha256.Sum256(clientData)
+		hash := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+		if !ecdsa.Verify(pub, hash[:], parsed.R, parsed.S) {
+			fail("the signature does not verify for the public key")
+		}
+
+		valid := true
+		testCase := &TestCase{
+			R:           fmt.Sprintf("0x%x", parsed.R),
+			S:           fmt.Sprintf("0x%x", parsed.S),
+			MsgHash:     fmt.Sprintf("0x%x", new(big.Int).SetBytes(hash[:])),
+			PubKeyX:     fmt.Sprintf("0x%x", pub.X),
+			PubKeyY:     fmt.Sprintf("0x%x", pub.Y),
+			ExpectValid: &valid,
+			Source:      fmt.Sprintf("webauthn assertion %s from %s", filepath.Base(file), client.Origin),
+			Tags:        []string{tagWebAuthn},
+			WebAuthn: &WebAuthnData{
+				AuthenticatorData: fmt.Sprintf("0x%x", authData),
+				ClientDataJSON:    fmt.Sprintf("0x%x", clientData),
+				Challenge:         client.Challenge,
+				Origin:            client.Origin,
+			},
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		path := filepath.Join(testsDir, "webauthn_"+name+".json")
+		writeTestCase(path, testCase)
+		slog.Info("Test case written", "file", path, "origin", client.Origin)
+	}
+}
+
+// parseCOSEKey decodes an ES256 public key in COSE format
+func parseCOSEKey(data []byte) (*ecdsa.PublicKey, error) {
+	var key map[int]any
+	if err := cbor.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("public key is not a COSE key: %v", err)
+	}
+	if kty, _ := key[coseKty].(uint64); kty != coseEC2 {
+		return nil, fmt.Errorf("COSE key type is %v, not EC2", key[coseKty])
+	}
+	if alg, ok := key[coseAlg].(int64); ok && alg != coseES256 {
+		return nil, fmt.Errorf("COSE algorithm is %d, not ES256", alg)
+	}
+	if crv, _ := key[coseCrv].(uint64); crv != coseP256 {
+		return nil, fmt.Errorf("COSE curve is %v, not P-256", key[coseCrv])
+	}
+	x, okX := key[coseX].([]byte)
+	y, okY := key[coseY].([]byte)
+	if !okX || !okY {
+		return nil, fmt.Errorf("COSE key without x and y coordinates")
+	}
+	pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, fmt.Errorf("COSE public key is not on P-256")
+	}
+	return pub, nil
+}
+
+// decodeBase64URL decodes base64url as toJSON() writes it, without padding,
+// and also accepts padded or standard base64
+func decodeBase64URL(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(s)
+	return base64.RawURLEncoding.DecodeString(s)
+}
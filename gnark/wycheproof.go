@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Curves of the imported test cases, named as Wycheproof names them. A test
+// case without a curve is on P-256.
+const (
+	curveP256      = "secp256r1"
+	curveSecp256k1 = "secp256k1"
+)
+
+// wycheproofFile is a Wycheproof ECDSA verification vector file, such as
+// ecdsa_secp256r1_sha256_test.json. Older files name the public key "key",
+// newer ones "publicKey".
+type wycheproofFile struct {
+	Algorithm  string `json:"algorithm"`
+	TestGroups []struct {
+		Type      string           `json:"type"`
+		SHA       string           `json:"sha"`
+		Key       *wycheproofKey   `json:"key"`
+		PublicKey *wycheproofKey   `json:"publicKey"`
+		Tests     []wycheproofTest `json:"tests"`
+	} `json:"testGroups"`
+}
+
+type wycheproofKey struct {
+	Curve string `json:"curve"`
+	WX    string `json:"wx"`
+	WY    string `json:"wy"`
+}
+
+type wycheproofTest struct {
+	ID      int      `json:"tcId"`
+	Comment string   `json:"comment"`
+	Msg     string   `json:"msg"`
+	Sig     string   `json:"sig"`
+	Result  string   `json:"result"`
+	Flags   []string `json:"flags"`
+}
+
+// importWycheproof converts Wycheproof ECDSA P-256 and secp256k1 vector files,
+// given as paths or URLs, into test cases in -tests. Each test case keeps the
+// expected result of its vector, valid, invalid or acceptable, as expect_valid
+// for the negative command, and where it came from. The circuit takes r and s as numbers, so vectors whose signature
+// has no such reading, such as malformed DER or a negative or oversized
+// integer, are skipped and counted. The files are named
+// wycheproof_<file>_<tcId>.json, apart from the test_case_*.json the
+// benchmarks run over, since many of them are expected to fail.
+func importWycheproof(sources []string) {
+	if len(sources) == 0 {
+		fatal("Missing Wycheproof vector file or URL for wycheproof command")
+	}
+	if err := os.MkdirAll(testsDir, 0755); err != nil {
+		fatal("Failed to create test case directory", "error", err)
+	}
+
+	results := map[string]int{}
+	skipped := map[string]int{}
+	for _, source := range sources {
+		vectors := readWycheproof(source)
+		name := strings.TrimSuffix(filepath.Base(source), ".json")
+		name = strings.TrimSuffix(name, "_test")
+		for _, group := range vectors.TestGroups {
+			key := group.PublicKey
+			if key == nil {
+				key = group.Key
+			}
+			if key == nil {
+				fatal("Test group without a public key", "source", source)
+			}
+			if key.Curve != curveP256 && key.Curve != curveSecp256k1 {
+				skipped["curve "+key.Curve]++
+				continue
+			}
+			hashAlg := wycheproofHash(group.SHA)
+			if hashAlg == "" {
+				skipped["hash "+group.SHA]++
+				continue
+			}
+			for _, t := range group.Tests {
+				r, s, err := wycheproofSignature(group.Type, t.Sig)
+				if err != nil {
+					skipped[err.Error()]++
+					continue
+				}
+				message := &TestCase{Message: "0x" + t.Msg, HashAlg: hashAlg}
+				digest, err := message.messageDigest()
+				if err != nil {
+					fatal("Invalid message", "source", source, "test", t.ID, "error", err)
+				}
+
+				x, okX := new(big.Int).SetString(key.WX, 16)
+				y, okY := new(big.Int).SetString(key.WY, 16)
+				if !okX || !okY {
+					fatal("Invalid public key", "source", source, "test", t.ID)
+				}
+				testCase := &TestCase{
+					R:       fmt.Sprintf("0x%x", r),
+					S:       fmt.Sprintf("0x%x", s),
+					MsgHash: fmt.Sprintf("0x%x", digest),
+					PubKeyX: fmt.Sprintf("0x%x", x),
+					PubKeyY: fmt.Sprintf("0x%x", y),
+					Curve:   key.Curve,
+					Result:  t.Result,
+					Source:  fmt.Sprintf("wycheproof %s tcId %d: %s", filepath.Base(source), t.ID, t.Comment),
+					Message: message.Message,
+					HashAlg: hashAlg,
+
+					Description: t.Comment,
+					Tags:        []string{tagWycheproof},
+				}
+				// Acceptable signatures may go either way, so they keep no
+				// expectation
+				switch t.Result {
+				case "valid", "invalid":
+					expectValid := t.Result == "valid"
+					testCase.ExpectValid = &expectValid
+				}
+				if len(t.Flags) > 0 {
+					testCase.Source += " [" + strings.Join(t.Flags, ", ") + "]"
+					for _, flag := range t.Flags {
+						testCase.Tags = append(testCase.Tags, tagOf(flag))
+					}
+				}
+				writeTestCase(filepath.Join(testsDir, fmt.Sprintf("wycheproof_%s_%d.json", name, t.ID)), testCase)
+				results[t.Result]++
+			}
+		}
+	}
+
+	total := 0
+	for _, n := range results {
+		total += n
+	}
+	slog.Info("Imported test cases", "count", total, "dir", testsDir, "valid", results["valid"], "invalid", results["invalid"], "acceptable", results["acceptable"])
+	reasons := make([]string, 0, len(skipped))
+	for reason := range skipped {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Printf("  skipped %d: %s\n", skipped[reason], reason)
+	}
+}
+
+// readWycheproof reads a vector file from a path, or downloads it from an
+// http(s) URL
+func readWycheproof(source string) *wycheproofFile {
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := http.Get(source)
+		if err != nil {
+			fatal("Failed to download Wycheproof vectors", "error", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			fatal("Failed to download Wycheproof vectors", "url", source, "status", resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			fatal("Failed to open Wycheproof vectors", "error", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var vectors wycheproofFile
+	if err := json.NewDecoder(r).Decode(&vectors); err != nil {
+		fatal("Failed to decode Wycheproof vectors", "source", source, "error", err)
+	}
+	if vectors.Algorithm != "ECDSA" {
+		fatal("Not ECDSA vectors", "source", source, "algorithm", vectors.Algorithm)
+	}
+	return &vectors
+}
+
+// wycheproofHash returns the hash_alg of a Wycheproof hash name, or "" for
+// one test cases cannot name
+func wycheproofHash(name string) string {
+	switch name {
+	case "SHA-256":
+		return hashSHA256
+	case "SHA-384":
+		return hashSHA384
+	case "SHA-512":
+		return hashSHA512
+	default:
+		return ""
+	}
+}
+
+// wycheproofSignature decodes the r and s of a signature, DER encoded in
+// EcdsaVerify groups and concatenated in EcdsaP1363Verify groups. Its error
+// says why the signature cannot be given to the circuit.
+func wycheproofSignature(groupType, sig string) (r, s *big.Int, err error) {
+	data, err := hex.DecodeString(sig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signature not hex")
+	}
+	switch groupType {
+	case "EcdsaVerify":
+		var parsed struct{ R, S *big.Int }
+		rest, err := asn1.Unmarshal(data, &parsed)
+		if err != nil || len(rest) > 0 {
+			return nil, nil, fmt.Errorf("signature not DER")
+		}
+		// encoding/asn1 accepts some BER, so insist on the one DER encoding
+		if encoded, err := asn1.Marshal(parsed); err != nil || string(encoded) != string(data) {
+			return nil, nil, fmt.Errorf("signature not DER")
+		}
+		r, s = parsed.R, parsed.S
+	case "EcdsaP1363Verify":
+		if len(data) != 64 {
+			return nil, nil, fmt.Errorf("signature not 64 bytes")
+		}
+		r, s = new(big.Int).SetBytes(data[:32]), new(big.Int).SetBytes(data[32:])
+	default:
+		return nil, nil, fmt.Errorf("group type %s", groupType)
+	}
+	if r.Sign() < 0 || s.Sign() < 0 {
+		return nil, nil, fmt.Errorf("negative r or s")
+	}
+	if r.BitLen() > 256 || s.BitLen() > 256 {
+		return nil, nil, fmt.Errorf("r or s wider than 256 bits")
+	}
+	return r, s, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// wycheproofFixtures are excerpts of Wycheproof vector files, kept with their
+// tcIds, in testdata/wycheproof
+const wycheproofFixtures = "testdata/wycheproof"
+
+// TestImportWycheproof imports the excerpts of DER and P1363 vectors for
+// P-256, and of vectors for a curve the circuits do not take, and checks
+// which vectors become test cases and what each expects
+func TestImportWycheproof(t *testing.T) {
+	dir := t.TempDir()
+	defer func(tests string) { testsDir = tests }(testsDir)
+	testsDir = dir
+
+	// The excerpts hold no acceptable vector with a signature the circuit can
+	// take, so one is made of a valid vector
+	data, err := os.ReadFile(filepath.Join(wycheproofFixtures, "ecdsa_secp256r1_sha256_test.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var acceptable wycheproofFile
+	if err := json.Unmarshal(data, &acceptable); err != nil {
+		t.Fatal(err)
+	}
+	group := &acceptable.TestGroups[0]
+	test := group.Tests[2]
+	if test.ID != 3 || test.Result != "valid" {
+		t.Fatalf("the third vector is tcId %d, %s, want tcId 3, valid", test.ID, test.Result)
+	}
+	test.ID, test.Result, test.Flags = 1000, "acceptable", []string{"MadeAcceptable"}
+	group.Tests = []wycheproofTest{test}
+	data, err = json.Marshal(acceptable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceptableFile := filepath.Join(t.TempDir(), "ecdsa_acceptable_test.json")
+	if err := os.WriteFile(acceptableFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	importWycheproof([]string{
+		filepath.Join(wycheproofFixtures, "ecdsa_secp256r1_sha256_test.json"),
+		filepath.Join(wycheproofFixtures, "ecdsa_secp256r1_sha256_p1363_test.json"),
+		filepath.Join(wycheproofFixtures, "ecdsa_secp224r1_sha224_test.json"),
+		acceptableFile,
+	})
+
+	valid, invalid := true, false
+	tests := []struct {
+		file   string
+		result string
+		expect *bool
+		tags   []string
+	}{
+		// tcId 2 has a negative s, 4 and 153 are not DER, and 131 has an
+		// r wider than 256 bits
+		{"wycheproof_ecdsa_secp256r1_sha256_1.json", "valid", &valid, []string{tagWycheproof}},
+		{"wycheproof_ecdsa_secp256r1_sha256_3.json", "valid", &valid, []string{tagWycheproof}},
+		{"wycheproof_ecdsa_secp256r1_sha256_144.json", "invalid", &invalid, []string{tagWycheproof, "edge-case"}},
+		// tcIds 2 and 119 are not 64 bytes
+		{"wycheproof_ecdsa_secp256r1_sha256_p1363_1.json", "valid", &valid, []string{tagWycheproof}},
+		{"wycheproof_ecdsa_secp256r1_sha256_p1363_9.json", "invalid", &invalid, []string{tagWycheproof, "edge-case"}},
+		{"wycheproof_ecdsa_acceptable_1000.json", "acceptable", nil, []string{tagWycheproof, "made-acceptable"}},
+	}
+	var want []string
+	for _, tt := range tests {
+		want = append(want, tt.file)
+	}
+	sort.Strings(want)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imported %v, want %v", got, want)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			tc, err := loadTestCase(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.Result != tt.result {
+				t.Errorf("result = %q, want %q", tc.Result, tt.result)
+			}
+			if !reflect.DeepEqual(tc.ExpectValid, tt.expect) {
+				t.Errorf("expect_valid = %v, want %v", tc.ExpectValid, tt.expect)
+			}
+			if !reflect.DeepEqual(tc.Tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", tc.Tags, tt.tags)
+			}
+			if tc.Curve != curveP256 || tc.HashAlg != hashSHA256 || tc.Message != "0x313233343030" {
+				t.Errorf("curve %s, hash %s and message %s, want %s, %s and 0x313233343030", tc.Curve, tc.HashAlg, tc.Message, curveP256, hashSHA256)
+			}
+			if tc.PubKeyX != "0x2927b10512bae3eddcfe467828128bad2903269919f7086069c8c4df6c732838" {
+				t.Errorf("pubkey_x = %s, want the key of the group", tc.PubKeyX)
+			}
+		})
+	}
+}
+
+// TestWycheproofSignature checks the signatures the circuit can take apart
+// from the ones that are skipped, and why
+func TestWycheproofSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		groupType string
+		sig       string
+		r, s      string
+		wantErr   string
+	}{
+		{"DER", "EcdsaVerify", "3006020101020102", "1", "2", ""},
+		{"DER with leading zero", "EcdsaVerify", "30070202008002017f", "80", "7f", ""},
+		{"BER length", "EcdsaVerify", "308106020101020102", "", "", "signature not DER"},
+		{"trailing bytes", "EcdsaVerify", "300602010102010200", "", "", "signature not DER"},
+		{"negative", "EcdsaVerify", "3006020101020180", "", "", "negative r or s"},
+		{"wider than 256 bits", "EcdsaVerify", "3026022101" + strings.Repeat("0", 64) + "020101", "", "", "r or s wider than 256 bits"},
+		{"P1363", "EcdsaP1363Verify", strings.Repeat("0", 62) + "01" + strings.Repeat("0", 62) + "02", "1", "2", ""},
+		{"P1363 too short", "EcdsaP1363Verify", "0102", "", "", "signature not 64 bytes"},
+		{"not hex", "EcdsaVerify", "30zz", "", "", "signature not hex"},
+		{"unknown group", "EddsaVerify", "00", "", "", "group type EddsaVerify"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, s, err := wycheproofSignature(tt.groupType, tt.sig)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("wycheproofSignature = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Text(16) != tt.r || s.Text(16) != tt.s {
+				t.Errorf("r, s = %x, %x, want %s, %s", r, s, tt.r, tt.s)
+			}
+		})
+	}
+}